@@ -0,0 +1,119 @@
+package radicron
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"time"
+)
+
+// AdminHandler returns the http.Handler for the admin endpoints
+// not to be exposed with the podcast feed
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/api/low-bandwidth", handleLowBandwidth)
+	mux.HandleFunc("/api/backpressure", handleBackpressure)
+	mux.HandleFunc("/api/usage", handleUsage)
+	mux.HandleFunc("/api/blackout", handleBlackout)
+	return mux
+}
+
+// handleBackpressure returns the backpressure of the post-processing on the segment downloads
+func handleBackpressure(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(PostProcessStats()); err != nil {
+		log.Printf("failed to encode the backpressure: %s", err)
+	}
+}
+
+// handleUsage returns the bytes downloaded per day and month
+func handleUsage(w http.ResponseWriter, r *http.Request) {
+	path, err := HistoryPath()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	history, err := LoadHistory(path)
+	if err != nil {
+		log.Printf("failed to load the history: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(history.UsageStats()); err != nil {
+		log.Printf("failed to encode the usage: %s", err)
+	}
+}
+
+// handleLowBandwidth returns the low-bandwidth mode, or toggles it with POST enabled=true|false
+func handleLowBandwidth(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			http.Error(w, "enabled must be true or false", http.StatusBadRequest)
+			return
+		}
+		SetLowBandwidth(enabled)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"enabled":     LowBandwidth(),
+		"concurrency": slots.Limit(),
+	}); err != nil {
+		log.Printf("failed to encode the low-bandwidth mode: %s", err)
+	}
+}
+
+// handleBlackout returns the blackout in effect, or pauses the scheduling with POST paused=true
+// (until=YYYY-MM-DD or YYYY-MM-DD hh:mm, indefinitely if unset) and resumes it with paused=false
+func handleBlackout(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		paused, err := strconv.ParseBool(r.FormValue("paused"))
+		if err != nil {
+			http.Error(w, "paused must be true or false", http.StatusBadRequest)
+			return
+		}
+		if !paused {
+			ResumeScheduling(time.Now())
+			break
+		}
+		var until time.Time
+		if s := r.FormValue("until"); s != "" {
+			if until, err = parseBlackoutTime(s, true); err != nil || !until.After(time.Now()) {
+				http.Error(w, "until must be a future YYYY-MM-DD or YYYY-MM-DD hh:mm", http.StatusBadRequest)
+				return
+			}
+		}
+		PauseScheduling(until)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	status := map[string]any{"paused": false}
+	if until, ok := BlackoutUntil(time.Now()); ok {
+		status["paused"] = true
+		if !until.IsZero() {
+			status["until"] = until
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("failed to encode the blackout: %s", err)
+	}
+}
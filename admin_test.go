@@ -0,0 +1,114 @@
+package radicron
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	handler := AdminHandler()
+	var admintests = []struct {
+		target string
+		code   int
+	}{
+		{"/debug/pprof/", http.StatusOK},
+		{"/debug/pprof/heap", http.StatusOK},
+		{"/debug/pprof/goroutine?debug=1", http.StatusOK},
+		{"/api/backpressure", http.StatusOK},
+		{"/api/usage", http.StatusOK},
+		{"/feed.xml", http.StatusNotFound},
+	}
+	for _, tt := range admintests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, http.NoBody))
+		if rec.Code != tt.code {
+			t.Errorf("GET %v => %v, want %v", tt.target, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestAdminLowBandwidth(t *testing.T) {
+	defer SetLowBandwidth(false)
+	handler := AdminHandler()
+	var lowbandwidthtests = []struct {
+		method  string
+		target  string
+		code    int
+		enabled bool
+	}{
+		{http.MethodGet, "/api/low-bandwidth", http.StatusOK, false},
+		{http.MethodPost, "/api/low-bandwidth?enabled=true", http.StatusOK, true},
+		{http.MethodGet, "/api/low-bandwidth", http.StatusOK, true},
+		{http.MethodPost, "/api/low-bandwidth?enabled=maybe", http.StatusBadRequest, true},
+		{http.MethodDelete, "/api/low-bandwidth", http.StatusMethodNotAllowed, true},
+		{http.MethodPost, "/api/low-bandwidth?enabled=false", http.StatusOK, false},
+	}
+	for _, tt := range lowbandwidthtests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, http.NoBody))
+		if rec.Code != tt.code {
+			t.Errorf("%v %v => %v, want %v", tt.method, tt.target, rec.Code, tt.code)
+		}
+		if rec.Code == http.StatusOK {
+			var got struct {
+				Enabled bool `json:"enabled"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Enabled != tt.enabled {
+				t.Errorf("%v %v => enabled %v, want %v", tt.method, tt.target, got.Enabled, tt.enabled)
+			}
+		}
+		if LowBandwidth() != tt.enabled {
+			t.Errorf("LowBandwidth after %v %v => %v, want %v", tt.method, tt.target, LowBandwidth(), tt.enabled)
+		}
+	}
+}
+
+func TestAdminBlackout(t *testing.T) {
+	resetBlackout(t)
+	handler := AdminHandler()
+	tomorrow := time.Now().AddDate(0, 0, 1).In(Location).Format(BlackoutDateLayout)
+	var blackouttests = []struct {
+		method string
+		target string
+		code   int
+		paused bool
+	}{
+		{http.MethodGet, "/api/blackout", http.StatusOK, false},
+		{http.MethodPost, "/api/blackout?paused=true", http.StatusOK, true},
+		{http.MethodPost, "/api/blackout?paused=false", http.StatusOK, false},
+		{http.MethodPost, "/api/blackout?paused=true&until=" + tomorrow, http.StatusOK, true},
+		{http.MethodGet, "/api/blackout", http.StatusOK, true},
+		{http.MethodPost, "/api/blackout?paused=true&until=2000-01-01", http.StatusBadRequest, true},
+		{http.MethodPost, "/api/blackout?paused=maybe", http.StatusBadRequest, true},
+		{http.MethodDelete, "/api/blackout", http.StatusMethodNotAllowed, true},
+		{http.MethodPost, "/api/blackout?paused=false", http.StatusOK, false},
+	}
+	for _, tt := range blackouttests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, http.NoBody))
+		if rec.Code != tt.code {
+			t.Errorf("%v %v => %v, want %v", tt.method, tt.target, rec.Code, tt.code)
+		}
+		if rec.Code == http.StatusOK {
+			var got struct {
+				Paused bool `json:"paused"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Paused != tt.paused {
+				t.Errorf("%v %v => paused %v, want %v", tt.method, tt.target, got.Paused, tt.paused)
+			}
+		}
+		if _, paused := BlackoutUntil(time.Now()); paused != tt.paused {
+			t.Errorf("BlackoutUntil after %v %v => %v, want %v", tt.method, tt.target, paused, tt.paused)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package radicron
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// adtsSampleRates indexed by the sampling frequency index
+var adtsSampleRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// ADTSInfo is the stream parameters of the ADTS frames
+type ADTSInfo struct {
+	Profile    int
+	SampleRate int
+	Channels   int
+	Frames     int
+}
+
+// SameFormat returns true if the stream parameters are the same
+func (ai *ADTSInfo) SameFormat(other *ADTSInfo) bool {
+	return ai.Profile == other.Profile && ai.SampleRate == other.SampleRate && ai.Channels == other.Channels
+}
+
+func (ai *ADTSInfo) String() string {
+	return fmt.Sprintf("profile=%d rate=%d channels=%d", ai.Profile, ai.SampleRate, ai.Channels)
+}
+
+// ValidateADTS reads the ADTS frames skipping the ID3 tags and returns the stream parameters,
+// or an error if the frames are not continuous or the parameters change in the stream
+func ValidateADTS(r io.Reader) (*ADTSInfo, error) {
+	br := bufio.NewReader(r)
+	if err := skipID3(br); err != nil {
+		return nil, err
+	}
+
+	var info *ADTSInfo
+	header := make([]byte, ADTSHeaderLength)
+	for {
+		// the segments may carry the timed metadata between the frames
+		if err := skipID3(br); err != nil {
+			return info, fmt.Errorf("truncated ID3 tag after %d frames", info.frames())
+		}
+		if _, err := io.ReadFull(br, header); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return info, fmt.Errorf("truncated header after %d frames", info.frames())
+		}
+		if header[0] != 0xFF || header[1]&0xF0 != 0xF0 {
+			return info, fmt.Errorf("lost sync after %d frames", info.frames())
+		}
+		frame := &ADTSInfo{
+			Profile:  int(header[2]>>6) + 1,
+			Channels: int(header[2]&0x01)<<2 | int(header[3]>>6),
+		}
+		rateIndex := int(header[2]>>2) & 0x0F
+		if rateIndex >= len(adtsSampleRates) {
+			return info, fmt.Errorf("invalid sampling frequency index: %d", rateIndex)
+		}
+		frame.SampleRate = adtsSampleRates[rateIndex]
+		length := int(header[3]&0x03)<<11 | int(header[4])<<3 | int(header[5]>>5)
+		if length < ADTSHeaderLength {
+			return info, fmt.Errorf("invalid frame length: %d", length)
+		}
+
+		if info == nil {
+			info = frame
+		} else if !info.SameFormat(frame) {
+			return info, fmt.Errorf("format changed after %d frames: %s => %s", info.Frames, info, frame)
+		}
+		if _, err := io.CopyN(io.Discard, br, int64(length-ADTSHeaderLength)); err != nil {
+			return info, fmt.Errorf("truncated frame after %d frames", info.Frames)
+		}
+		info.Frames++
+	}
+	if info == nil {
+		return nil, errors.New("no ADTS frames")
+	}
+	return info, nil
+}
+
+// frames returns the number of the frames read so far
+func (ai *ADTSInfo) frames() int {
+	if ai == nil {
+		return 0
+	}
+	return ai.Frames
+}
+
+// skipID3 skips the ID3v2 tag at the start if any
+func skipID3(br *bufio.Reader) error {
+	header, err := br.Peek(10)
+	if err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	size := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+	_, err = io.CopyN(io.Discard, br, 10+size)
+	return err
+}
+
+// validateSegments checks the ADTS frames in the downloaded segments,
+// and marks a discontinuity where the encoder settings change to re-encode there
+// the invalid segments are rejected if strict, or logged otherwise
+func validateSegments(dir string, segments Segments, strict bool) error {
+	var prev *ADTSInfo
+	for _, s := range segments {
+		if !strings.EqualFold(filepath.Ext(s.FileName()), ".aac") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, s.FileName()))
+		if err != nil {
+			return err
+		}
+		info, err := ValidateADTS(f)
+		f.Close()
+		if err != nil {
+			if strict {
+				return fmt.Errorf("invalid segment %s: %s", s.URI, err)
+			}
+			log.Printf("invalid segment %s: %s", s.URI, err)
+		}
+		if info == nil {
+			continue
+		}
+		if prev != nil && !prev.SameFormat(info) && !s.Discontinuity {
+			log.Printf("the format changed at %s: %s => %s", s.URI, prev, info)
+			s.Discontinuity = true
+		}
+		prev = info
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package radicron
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// adtsFrame returns an ADTS frame with the payload
+func adtsFrame(rateIndex, channels int, payload []byte) []byte {
+	length := ADTSHeaderLength + len(payload)
+	header := []byte{
+		0xFF,
+		0xF1,                                    // MPEG-4, no CRC
+		byte(1<<6 | rateIndex<<2 | channels>>2), // AAC LC
+		byte(channels&0x03<<6 | length>>11),
+		byte(length >> 3 & 0xFF),
+		byte(length&0x07<<5 | 0x1F),
+		0xFC,
+	}
+	return append(header, payload...)
+}
+
+func TestValidateADTS(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x21}, 100)
+	stereo48k := adtsFrame(3, 2, payload)
+	mono24k := adtsFrame(6, 1, payload)
+	id3 := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 2, 0, 0}
+
+	var adtstests = []struct {
+		name   string
+		in     []byte
+		frames int
+		valid  bool
+	}{
+		{"continuous", bytes.Repeat(stereo48k, 3), 3, true},
+		{"id3", append(append([]byte{}, id3...), stereo48k...), 1, true},
+		{"id3 between frames", append(append(append([]byte{}, stereo48k...), id3...), stereo48k...), 2, true},
+		{"format change", append(append([]byte{}, stereo48k...), mono24k...), 1, false},
+		{"lost sync", append(append([]byte{}, stereo48k...), 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06), 1, false},
+		{"truncated", stereo48k[:50], 0, false},
+		{"empty", []byte{}, 0, false},
+	}
+	for _, tt := range adtstests {
+		info, err := ValidateADTS(bytes.NewReader(tt.in))
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateADTS(%v) => %v, want valid=%v", tt.name, err, tt.valid)
+		}
+		if info.frames() != tt.frames {
+			t.Errorf("ValidateADTS(%v) => %v frames, want %v", tt.name, info.frames(), tt.frames)
+		}
+	}
+
+	info, _ := ValidateADTS(bytes.NewReader(stereo48k))
+	if info.SampleRate != 48000 || info.Channels != 2 || info.Profile != 2 {
+		t.Errorf("ValidateADTS => %s, want profile=2 rate=48000 channels=2", info)
+	}
+}
+
+func TestValidateSegments(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x21}, 100)
+	dir := t.TempDir()
+	segments := Segments{}
+	for i, frame := range [][]byte{adtsFrame(3, 2, payload), adtsFrame(3, 2, payload), adtsFrame(6, 1, payload), {0x00}} {
+		s := &Segment{Index: i, URI: "https://radiko.jp/a.aac"}
+		if err := os.WriteFile(filepath.Join(dir, s.FileName()), frame, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, s)
+	}
+
+	if err := validateSegments(dir, segments, false); err != nil {
+		t.Fatal(err)
+	}
+	if !segments[2].Discontinuity || segments[1].Discontinuity {
+		t.Error("validateSegments should mark the discontinuity at the format change")
+	}
+	if err := validateSegments(dir, segments, true); err == nil {
+		t.Error("validateSegments(strict) with a broken segment => nil, want error")
+	}
+}
+
+func BenchmarkValidateSegments(b *testing.B) {
+	dir := b.TempDir()
+	// 5s of 48kHz frames per segment
+	chunk := bytes.Repeat(adtsFrame(3, 2, bytes.Repeat([]byte{0x21}, 300)), 5*48000/1024)
+	segments := Segments{}
+	for i := 0; i < 720; i++ {
+		s := &Segment{Index: i, URI: "https://radiko.jp/a.aac"}
+		if err := os.WriteFile(filepath.Join(dir, s.FileName()), chunk, 0o600); err != nil {
+			b.Fatal(err)
+		}
+		segments = append(segments, s)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := validateSegments(dir, segments, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
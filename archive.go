@@ -0,0 +1,122 @@
+package radicron
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// GuideArchive stores the fetched programs as a compressed JSON per day
+type GuideArchive struct {
+	Dir string
+}
+
+// Add merges the programs into the archive of the day they start
+func (ga *GuideArchive) Add(progs Progs) error {
+	days := map[string]Progs{}
+	for _, p := range progs {
+		if len(p.Ft) < len(ArchiveDayLayout) {
+			continue
+		}
+		day := p.Ft[:len(ArchiveDayLayout)]
+		days[day] = append(days[day], p)
+	}
+	for day, dayProgs := range days {
+		archived, err := ga.Load(day)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			// e.g., truncated by a crash, rebuild it from the programs
+			corrupted := fmt.Sprintf("%s.%s%s", ga.path(day), time.Now().Format(DatetimeLayout), ArchiveCorruptSuffix)
+			if err = os.Rename(ga.path(day), corrupted); err != nil {
+				return err
+			}
+			log.Printf("moved the corrupted guide archive to %s", corrupted)
+			archived = Progs{}
+		}
+		if err = ga.save(day, mergeProgs(archived, dayProgs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load returns the archived programs of the day (e.g., "20230605")
+func (ga *GuideArchive) Load(day string) (Progs, error) {
+	progs := Progs{}
+	f, err := os.Open(ga.path(day))
+	if err != nil {
+		return progs, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return progs, err
+	}
+	defer zr.Close()
+	err = json.NewDecoder(zr).Decode(&progs)
+	return progs, err
+}
+
+func (ga *GuideArchive) path(day string) string {
+	return filepath.Join(ga.Dir, fmt.Sprintf("%s.json.gz", day))
+}
+
+// save writes the programs to a temporary file and renames it
+func (ga *GuideArchive) save(day string, progs Progs) error {
+	tmp := ga.path(day) + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	err = json.NewEncoder(zw).Encode(progs)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, ga.path(day))
+}
+
+// NewGuideArchive returns a GuideArchive in ${RADICRON_HOME}/guide
+func NewGuideArchive() (*GuideArchive, error) {
+	dir, err := getRadicronPath("guide")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &GuideArchive{Dir: dir}, nil
+}
+
+// mergeProgs returns the programs with the newer ones replacing the same ID
+func mergeProgs(older, newer Progs) Progs {
+	merged := map[string]*Prog{}
+	for _, p := range older {
+		merged[p.StationID+p.ID] = p
+	}
+	for _, p := range newer {
+		merged[p.StationID+p.ID] = p
+	}
+	progs := Progs{}
+	for _, p := range merged {
+		progs = append(progs, p)
+	}
+	sort.Slice(progs, func(i, j int) bool {
+		if progs[i].Ft == progs[j].Ft {
+			return progs[i].StationID < progs[j].StationID
+		}
+		return progs[i].Ft < progs[j].Ft
+	})
+	return progs
+}
@@ -0,0 +1,62 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGuideArchive(t *testing.T) {
+	ga := &GuideArchive{Dir: t.TempDir()}
+	progs := Progs{
+		&Prog{ID: "1", StationID: "FMT", Ft: "20230605130000", Title: "old"},
+		&Prog{ID: "2", StationID: "FMT", Ft: "20230606130000", Title: "next day"},
+	}
+	if err := ga.Add(progs); err != nil {
+		t.Fatal(err)
+	}
+	// the updated program replaces the archived one
+	if err := ga.Add(Progs{&Prog{ID: "1", StationID: "FMT", Ft: "20230605130000", Title: "new"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ga.Load("20230605")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Title != "new" {
+		t.Errorf("Load(20230605) => %v, want [new]", got)
+	}
+	got, err = ga.Load("20230606")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Title != "next day" {
+		t.Errorf("Load(20230606) => %v, want [next day]", got)
+	}
+	if _, err = ga.Load("20230607"); err == nil {
+		t.Error("Load(20230607) => nil, want an error")
+	}
+}
+
+func TestGuideArchiveCorrupted(t *testing.T) {
+	ga := &GuideArchive{Dir: t.TempDir()}
+	// truncated by a crash
+	if err := os.WriteFile(ga.path("20230605"), []byte{0x1f, 0x8b, 0x08}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	progs := Progs{&Prog{ID: "1", StationID: "FMT", Ft: "20230605130000", Title: "rebuilt"}}
+	if err := ga.Add(progs); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := ga.Load("20230605")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || archived[0].Title != "rebuilt" {
+		t.Errorf("Load => %v, want the rebuilt", archived)
+	}
+	if corrupted, _ := filepath.Glob(ga.path("20230605") + ".*" + ArchiveCorruptSuffix); len(corrupted) != 1 {
+		t.Errorf("corrupted => %v, want the one moved aside", corrupted)
+	}
+}
@@ -0,0 +1,84 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bogem/id3v2"
+)
+
+// loadArtwork reads the image from the local file or URL with its MIME type
+func loadArtwork(ctx context.Context, src string) ([]byte, string, error) {
+	var data []byte
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, http.NoBody)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("failed to get %s: %s", src, resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, "", err
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(src); err != nil {
+			return nil, "", err
+		}
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, "", fmt.Errorf("not an image: %s (%s)", src, mimeType)
+	}
+	return data, mimeType, nil
+}
+
+// addArtwork attaches the artwork of the rule, or the program image, as the front cover
+func addArtwork(ctx context.Context, tag *id3v2.Tag, prog *Prog, encoding id3v2.Encoding) error {
+	src := prog.Artwork
+	if src == "" {
+		src = prog.Img
+	}
+	if src == "" {
+		return nil
+	}
+	data, mimeType, err := loadArtwork(ctx, src)
+	if err != nil {
+		return err
+	}
+	tag.DeleteFrames(tag.CommonID("Attached picture"))
+	tag.AddAttachedPicture(id3v2.PictureFrame{
+		Encoding:    encoding,
+		MimeType:    mimeType,
+		PictureType: id3v2.PTFrontCover,
+		Description: prog.Title,
+		Picture:     data,
+	})
+	return nil
+}
+
+// ReadArtwork returns the front cover in the ID3v2 tag of the audio
+func ReadArtwork(path string) ([]byte, string, error) {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true, ParseFrames: []string{"Attached picture"}})
+	if err != nil {
+		return nil, "", err
+	}
+	defer tag.Close()
+	for _, f := range tag.GetFrames(tag.CommonID("Attached picture")) {
+		if pf, ok := f.(id3v2.PictureFrame); ok {
+			return pf.Picture, pf.MimeType, nil
+		}
+	}
+	return nil, "", errors.New("no artwork")
+}
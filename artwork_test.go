@@ -0,0 +1,69 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bogem/id3v2"
+)
+
+func TestArtwork(t *testing.T) {
+	dir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>not found</html>"))
+	}))
+	defer ts.Close()
+	local := filepath.Join(dir, "cover.png")
+	if err := os.WriteFile(local, png, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var artworktests = []struct {
+		name    string
+		prog    *Prog
+		want    []byte
+		wantErr bool
+	}{
+		{"no image", &Prog{Title: "Title"}, nil, false},
+		{"override", &Prog{Title: "Title", Img: ts.URL, Artwork: local}, png, false},
+		{"not an image", &Prog{Title: "Title", Img: ts.URL}, nil, true},
+		{"missing", &Prog{Title: "Title", Artwork: filepath.Join(dir, "missing.png")}, nil, true},
+	}
+	for _, tt := range artworktests {
+		t.Run(tt.name, func(t *testing.T) {
+			audio := filepath.Join(dir, tt.name+".aac")
+			if err := os.WriteFile(audio, make([]byte, 64), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			tag, err := id3v2.Open(audio, id3v2.Options{Parse: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = addArtwork(context.Background(), tag, tt.prog, id3v2.EncodingUTF8)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("addArtwork => %v, want error %v", err, tt.wantErr)
+			}
+			if err = tag.Save(); err != nil {
+				t.Fatal(err)
+			}
+			tag.Close()
+
+			data, mimeType, err := ReadArtwork(audio)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("ReadArtwork => %v, want no artwork", mimeType)
+				}
+				return
+			}
+			if err != nil || mimeType != "image/png" || !bytes.Equal(data, tt.want) {
+				t.Errorf("ReadArtwork => %v, %v, %v", len(data), mimeType, err)
+			}
+		})
+	}
+}
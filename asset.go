@@ -11,7 +11,9 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"time"
 
@@ -23,6 +25,9 @@ var (
 	// Base64FullKey holds the /assets/flutter_assets/assets/key/android.jpg in the v8 APK
 	//go:embed assets/base64-full.key
 	Base64FullKey embed.FS
+	// ConfigTemplate is the starter config written by the init command
+	//go:embed assets/config.yml.template
+	ConfigTemplate []byte
 	// CoordinatesJSON is a JSON contains the base GPS locations
 	//go:embed assets/coordinates.json
 	CoordinatesJSON embed.FS
@@ -32,6 +37,9 @@ var (
 	// VersionsJSON is a JSON contains the valid SDK versions
 	//go:embed assets/versions.json
 	VersionsJSON embed.FS
+	// WebAssets holds the web UI served with the podcast feed
+	//go:embed assets/web
+	WebAssets embed.FS
 )
 
 type Area struct {
@@ -40,20 +48,84 @@ type Area struct {
 }
 
 type Asset struct {
+	// AreaID of this host, detected or configured, to authorize the stations on air there
+	AreaID            string
 	AvailableStations []string
-	AreaDevices       Devices
+	AuthSessions      *AuthSessions
+	// AvailabilityDelay to wait after the program ends before fetching the playlist
+	AvailabilityDelay time.Duration
 	Base64Key         string
-	Coordinates       Coordinates
-	DefaultClient     *radiko.Client
+	// BlacklistExpiry for the programs failed BlacklistThreshold times
+	BlacklistExpiry    time.Duration
+	BlacklistThreshold int
+	Coordinates        Coordinates
+	DefaultClient      *radiko.Client
+	// DirectWrite to write the segments straight to the output without the concat pass
+	DirectWrite bool
+	// DryRun to report the programs to be downloaded without downloading or writing anything
+	DryRun bool
+	// Emergency to watch the news stations for the emergency programming, nil if not configured
+	Emergency *Emergency
+	// Enrichers to improve the metadata before tagging
+	Enrichers []Enricher
+	// EpisodeTitle template for the title tag, e.g., "{title} {date:2006-01-02}"
+	EpisodeTitle string
+	// Events to log the lifecycle of the programs for the timeline
+	Events *EventLog
+	// ExplicitDir to save the explicit programs apart from the downloads, relative to RADICRON_HOME if not absolute
+	ExplicitDir string
+	// GaplessPriming samples to drop at the start of each segment, 0 to disable
+	GaplessPriming int
+	// GuideArchive to archive the fetched programs if enabled
+	GuideArchive   *GuideArchive
+	HeaderProfiles HeaderProfiles
+	History        *History
+	// KeepDuplicates to download the simulcasts of a program on all the affiliate stations
+	KeepDuplicates bool
+	// KeepFailedTmp to keep the tmp files of the failed programs for, 0 to remove at once
+	KeepFailedTmp time.Duration
+	// LenientPlaylist to parse the playlists loosely
+	LenientPlaylist bool
+	// LiveBootAhead to start the live capture before the program, to authorize and skip the segments before it
+	LiveBootAhead time.Duration
+	// MetadataOnly to save the metadata of the matched programs without the audio
+	MetadataOnly bool
 	// MinimumOutputSize in bytes for the downloaded audio
 	MinimumOutputSize int64
 	NextFetchTime     *time.Time
 	OutputFormat      string
-	Regions           Regions
-	Rules             Rules
-	Schedules         Schedules
-	Stations          Stations
-	Versions          Versions
+	// Radiru to record the NHK programs from, nil unless a rule matches them
+	Radiru *Radiru
+	// Pending to resume the downloads interrupted by a crash or a restart, nil if not journaled
+	Pending *Pending
+	// Premium to authorize the stations outside the area if logged in, nil otherwise
+	Premium *Premium
+	// Providers to fetch the audio from another source than the timefree, tried in order
+	Providers []Provider
+	// Queue to leave the downloads to the workers if dispatching
+	Queue   JobQueue
+	Regions Regions
+	// Report to write the stats of each recording next to the audio
+	Report bool
+	// Retry policy for the segment downloads
+	Retry     *RetryPolicy
+	Rules     Rules
+	Schedules Schedules
+	// Script with the hooks for the matching and the naming, nil if not set
+	Script *Script
+	// ScanInterval to scan the guide again at the latest in the daemon mode
+	ScanInterval time.Duration
+	// SegmentFailureThreshold is the ratio of the segments allowed to be missing
+	SegmentFailureThreshold float64
+	StationSettings         StationSettings
+	Stations                Stations
+	// Storages to keep the saved recordings elsewhere
+	Storages []Storage
+	// StrictADTS to reject the segments with the invalid ADTS frames
+	StrictADTS bool
+	// Upcoming to remember the programs until the timefree becomes available
+	Upcoming *Upcoming
+	Versions Versions
 }
 
 // AddExtraStations appends stations to AvailableStations
@@ -91,6 +163,40 @@ func (a *Asset) GenerateGPSForAreaID(areaID string) string {
 	return ""
 }
 
+// AuthAreaID returns the AreaID to authorize for the station, the area of the member if area-free,
+// or the area of this host if the station is on air there
+func (a *Asset) AuthAreaID(stationID string) string {
+	if a.Premium.IsAreaFree() && a.Premium.AreaID != "" {
+		return a.Premium.AreaID
+	}
+	if s, ok := a.Stations[stationID]; ok && a.AreaID != "" {
+		for _, areaID := range s.Areas {
+			if areaID == a.AreaID {
+				return areaID
+			}
+		}
+	}
+	return a.GetAreaIDByStationID(stationID)
+}
+
+// AuthAreaIDs returns the areas to authorize for the programs on radiko ended by t
+func (a *Asset) AuthAreaIDs(progs Progs, t time.Time) []string {
+	end := t.In(Location).Format(DatetimeLayout)
+	areaIDs := []string{}
+	seen := map[string]bool{}
+	for _, p := range progs {
+		if IsRadiruStation(p.StationID) || p.To > end {
+			continue
+		}
+		if areaID := a.AuthAreaID(p.StationID); areaID != "" && !seen[areaID] {
+			seen[areaID] = true
+			areaIDs = append(areaIDs, areaID)
+		}
+	}
+	sort.Strings(areaIDs)
+	return areaIDs
+}
+
 // GetAreaIDByStationID returns the first AreaID for the station
 func (a *Asset) GetAreaIDByStationID(stationID string) string {
 	if s, ok := a.Stations[stationID]; ok {
@@ -99,6 +205,37 @@ func (a *Asset) GetAreaIDByStationID(stationID string) string {
 	return ""
 }
 
+// GetAvailabilityDelay returns the availability delay for the station
+func (a *Asset) GetAvailabilityDelay(stationID string) time.Duration {
+	if s, ok := a.StationSettings[stationID]; ok && s.AvailabilityDelay != nil {
+		return *s.AvailabilityDelay
+	}
+	return a.AvailabilityDelay
+}
+
+// GetHeaders returns the request headers for the endpoint
+// with the default and the station's header profile applied
+func (a *Asset) GetHeaders(endpoint, stationID string, base map[string]string) http.Header {
+	headers := http.Header{}
+	for k, v := range base {
+		headers.Set(k, v)
+	}
+	profiles := []string{DefaultHeaderProfile}
+	if s, ok := a.StationSettings[stationID]; ok && s.HeaderProfile != "" {
+		profiles = append(profiles, s.HeaderProfile)
+	}
+	for _, name := range profiles {
+		for k, v := range a.HeaderProfiles[name][endpoint] {
+			if v == "" {
+				headers.Del(k) // an empty value removes the header
+			} else {
+				headers.Set(k, v)
+			}
+		}
+	}
+	return headers
+}
+
 // GetPartialKey returns the partial key for auth2 API
 func (a *Asset) GetPartialKey(offset, length int64) (string, error) {
 	authKey, err := base64.StdEncoding.DecodeString(a.Base64Key)
@@ -122,14 +259,23 @@ func (a *Asset) GetStationIDsByAreaID(areaID string) []string {
 	return sids
 }
 
-// LoadAvailableStations loads up the avaialable stations
+// LoadAvailableStations loads up the avaialable stations, all the stations if area-free
 func (a *Asset) LoadAvailableStations(areaID string) {
+	a.AreaID = areaID
+	if a.Premium.IsAreaFree() {
+		a.AvailableStations = []string{}
+		for sid := range a.Stations {
+			a.AvailableStations = append(a.AvailableStations, sid)
+		}
+		sort.Strings(a.AvailableStations)
+		return
+	}
 	// AvailableStations
 	a.AvailableStations = a.GetStationIDsByAreaID(areaID)
 }
 
 // NewDevice returns a pointer to a new authorized Device
-func (a *Asset) NewDevice(areaID string) (*Device, error) {
+func (a *Asset) NewDevice(ctx context.Context, areaID string) (*Device, error) {
 	// generate userID
 	blob := make([]byte, UserIDLength)
 	if _, err := cr.Read(blob); err != nil {
@@ -190,13 +336,11 @@ func (a *Asset) NewDevice(areaID string) (*Device, error) {
 	device.Name = fmt.Sprintf("%s.%s", sdk.ID, model)
 
 	// get token
-	err := device.Auth(a, areaID)
+	err := device.Auth(ctx, a, areaID)
 	if err != nil {
 		return device, err
 	}
 
-	// save the device for areaID
-	a.AreaDevices[areaID] = device
 	return device, nil
 }
 
@@ -260,21 +404,17 @@ type Device struct {
 	UserID     string
 }
 
-func (d *Device) Auth(a *Asset, areaID string) error {
+func (d *Device) Auth(ctx context.Context, a *Asset, areaID string) error {
 	client := a.DefaultClient
 	// auth1
-	req, _ := http.NewRequest("GET", "https://radiko.jp/v2/api/auth1", http.NoBody)
-	req = req.WithContext(context.Background())
-	headers := map[string]string{
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://radiko.jp/v2/api/auth1", http.NoBody)
+	req.Header = a.GetHeaders(EndpointAuth1, "", map[string]string{
 		UserAgentHeader:        d.UserAgent,
 		RadikoAppHeader:        d.AppName,
 		RadikoAppVersionHeader: d.AppVersion,
 		RadikoDeviceHeader:     d.Name,
 		RadikoUserHeader:       d.UserID,
-	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
+	})
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -282,6 +422,7 @@ func (d *Device) Auth(a *Asset, areaID string) error {
 	defer resp.Body.Close()
 	// auth2
 	d.AuthToken = resp.Header.Get(RadikoAuthTokenHeader)
+	ReplaceSecret("auth-token:"+areaID, d.AuthToken)
 	offset, err := strconv.ParseInt(resp.Header.Get(RadikoKeyOffsetHeader), 10, 64)
 	if err != nil {
 		return err
@@ -295,9 +436,13 @@ func (d *Device) Auth(a *Asset, areaID string) error {
 		return err
 	}
 	location := a.GenerateGPSForAreaID(areaID)
-	req, _ = http.NewRequest("GET", "https://radiko.jp/v2/api/auth2", http.NoBody)
-	req = req.WithContext(context.Background())
-	headers = map[string]string{
+	auth2 := "https://radiko.jp/v2/api/auth2"
+	if session := a.Premium.session(); session != "" {
+		// authorized as the premium member
+		auth2 += "?" + url.Values{RadikoSessionKey: []string{session}}.Encode()
+	}
+	req, _ = http.NewRequestWithContext(ctx, "GET", auth2, http.NoBody)
+	req.Header = a.GetHeaders(EndpointAuth2, "", map[string]string{
 		UserAgentHeader:        d.UserAgent,
 		RadikoAppHeader:        d.AppName,
 		RadikoAppVersionHeader: d.AppVersion,
@@ -307,22 +452,37 @@ func (d *Device) Auth(a *Asset, areaID string) error {
 		RadikoLocationHeader:   location,
 		RadikoConnectionHeader: d.Connection,
 		RadikoPartialKeyHeader: partialKey,
-	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
+	})
 	resp, err = client.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
+	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth2 failed for %s: %s", areaID, resp.Status)
+	}
 	return nil
 }
 
-type Devices map[string]*Device
+// HeaderProfile maps an endpoint to the headers to override
+type HeaderProfile map[string]map[string]string
+
+type HeaderProfiles map[string]HeaderProfile
 
 type Regions map[string][]Area
 
+// AreaIDs returns all the areas in the regions
+func (rs Regions) AreaIDs() []string {
+	areaIDs := []string{}
+	for _, areas := range rs {
+		for _, area := range areas {
+			areaIDs = append(areaIDs, area.ID)
+		}
+	}
+	sort.Strings(areaIDs)
+	return areaIDs
+}
+
 type Schedules []*Prog
 
 func (ss Schedules) HasDuplicate(prog *Prog) bool {
@@ -340,13 +500,26 @@ type SDK struct {
 }
 
 type Station struct {
-	Areas []string
-	Name  string
-	Ruby  string
+	Areas []string `json:"areas"`
+	Name  string   `json:"name"`
+	Ruby  string   `json:"ruby"`
 }
 
 type Stations map[string]*Station
 
+// StationSetting contains the per-station overrides
+type StationSetting struct {
+	// AvailabilityDelay overrides the global one, including 0 to download immediately
+	AvailabilityDelay *time.Duration `mapstructure:"availability-delay"` // optional
+	HeaderProfile     string         `mapstructure:"header-profile"`     // optional
+	// Live to record the matched programs from the radiko live stream as they air, e.g., without the timefree
+	Live bool `mapstructure:"live"` // optional
+	// Simulcast of the station-owned HLS to capture live as the standby for the timefree
+	Simulcast string `mapstructure:"simulcast"` // optional
+}
+
+type StationSettings map[string]*StationSetting
+
 type Versions struct {
 	Apps   []string        `json:"apps"`
 	Models []string        `json:"models"`
@@ -362,10 +535,16 @@ func GetAsset(ctx context.Context) *Asset {
 	return asset
 }
 
-func NewAsset(client *radiko.Client) (*Asset, error) {
+func NewAsset(ctx context.Context, client *radiko.Client) (*Asset, error) {
 	asset := &Asset{}
-	// empty AreaDevices
-	asset.AreaDevices = map[string]*Device{}
+	// empty AuthSessions
+	asset.AuthSessions = NewAuthSessions()
+	// empty HeaderProfiles
+	asset.HeaderProfiles = HeaderProfiles{}
+	// default AvailabilityDelay
+	asset.AvailabilityDelay = BufferMinutes * time.Minute
+	// default RetryPolicy
+	asset.Retry = NewRetryPolicy()
 	// the base64 key
 	blob, err := Base64FullKey.ReadFile("assets/base64-full.key")
 	if err != nil {
@@ -380,6 +559,8 @@ func NewAsset(client *radiko.Client) (*Asset, error) {
 	asset.NextFetchTime = nil
 	// empty Schedules
 	asset.Schedules = Schedules{}
+	// empty StationSettings
+	asset.StationSettings = StationSettings{}
 
 	// Region
 	regionsJSON, err := RegionsJSON.Open("assets/regions.json")
@@ -409,25 +590,10 @@ func NewAsset(client *radiko.Client) (*Asset, error) {
 	}
 
 	// Station
-	xmlRegion, err := FetchXMLRegion()
+	asset.Stations, err = LoadStations(ctx, asset.Regions.AreaIDs())
 	if err != nil {
 		return asset, err
 	}
-	asset.Stations = Stations{}
-	for _, xmlStations := range xmlRegion.Region {
-		for _, xmlStation := range xmlStations.Stations {
-			if station, ok := asset.Stations[xmlStation.ID]; ok {
-				station.Areas = append(station.Areas, xmlStation.AreaID)
-			} else {
-				station := &Station{
-					Areas: []string{xmlStation.AreaID},
-					Name:  xmlStation.Name,
-					Ruby:  xmlStation.Ruby,
-				}
-				asset.Stations[xmlStation.ID] = station
-			}
-		}
-	}
 
 	// Versions
 	versionsJSON, err := VersionsJSON.Open("assets/versions.json")
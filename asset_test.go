@@ -1,11 +1,15 @@
 package radicron
 
 import (
+	"context"
 	"math"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -21,7 +25,7 @@ func TestNewAsset(t *testing.T) {
 		t.Error(err)
 	}
 
-	asset, err := NewAsset(client)
+	asset, err := NewAsset(context.Background(), client)
 	if err != nil {
 		t.Errorf("failed to parse the asset %s", err)
 	}
@@ -68,7 +72,7 @@ func TestGenerateGPSForAreaID(t *testing.T) {
 		t.Error(err)
 	}
 
-	asset, _ := NewAsset(client)
+	asset, _ := NewAsset(context.Background(), client)
 	var gpstests = []struct {
 		in  string
 		out bool
@@ -107,7 +111,7 @@ func TestGetAreaIDByStationID(t *testing.T) {
 		t.Error(err)
 	}
 
-	asset, _ := NewAsset(client)
+	asset, _ := NewAsset(context.Background(), client)
 	var areatests = []struct {
 		in  string
 		out string
@@ -139,7 +143,7 @@ func TestGetStationIDsByAreaID(t *testing.T) {
 		t.Error(err)
 	}
 
-	asset, _ := NewAsset(client)
+	asset, _ := NewAsset(context.Background(), client)
 	var stationtests = []struct {
 		in  string
 		out []string
@@ -174,13 +178,158 @@ func TestGetStationIDsByAreaID(t *testing.T) {
 	}
 }
 
+func TestAuthAreaID(t *testing.T) {
+	a := &Asset{Stations: Stations{
+		"TBS":  &Station{Areas: []string{"JP13", "JP14"}},
+		"MBS":  &Station{Areas: []string{"JP27"}},
+		"HBC":  &Station{Areas: []string{"JP1"}},
+		"JORF": &Station{Areas: []string{"JP13"}},
+	}}
+	var areatests = []struct {
+		premium   *Premium
+		stationID string
+		areaID    string
+		available []string
+	}{
+		{nil, "MBS", "JP27", []string{"JORF", "TBS"}},
+		{&Premium{AreaID: "JP13", Session: "s", AreaFree: false}, "MBS", "JP27", []string{"JORF", "TBS"}},
+		{&Premium{AreaID: "JP13", Session: "s", AreaFree: true}, "MBS", "JP13", []string{"HBC", "JORF", "MBS", "TBS"}},
+		{&Premium{AreaID: "JP13", AreaFree: true}, "HBC", "JP1", []string{"JORF", "TBS"}},
+	}
+	for _, tt := range areatests {
+		a.Premium = tt.premium
+		if got := a.AuthAreaID(tt.stationID); got != tt.areaID {
+			t.Errorf("AuthAreaID(%s) with %+v => %s, want %s", tt.stationID, tt.premium, got, tt.areaID)
+		}
+		a.LoadAvailableStations("JP13")
+		sort.Strings(a.AvailableStations)
+		if !reflect.DeepEqual(a.AvailableStations, tt.available) {
+			t.Errorf("LoadAvailableStations(JP13) with %+v => %v, want %v", tt.premium, a.AvailableStations, tt.available)
+		}
+	}
+
+	// the area of this host is preferred if the station is on air there
+	a.Premium = nil
+	a.LoadAvailableStations("JP14")
+	for stationID, want := range map[string]string{"TBS": "JP14", "JORF": "JP13", "MBS": "JP27"} {
+		if got := a.AuthAreaID(stationID); got != want {
+			t.Errorf("AuthAreaID(%s) in JP14 => %s, want %s", stationID, got, want)
+		}
+	}
+}
+
+func TestAuthAreaIDs(t *testing.T) {
+	a := &Asset{Stations: Stations{
+		"TBS": &Station{Areas: []string{"JP13", "JP14"}},
+		"MBS": &Station{Areas: []string{"JP27"}},
+		"HBC": &Station{Areas: []string{"JP1"}},
+	}}
+	progs := Progs{
+		{StationID: "TBS", To: "20240101120000"},
+		{StationID: "MBS", To: "20240101130000"},
+		{StationID: "TBS", To: "20240101140000"},
+		{StationID: "HBC", To: "20240102000000"},
+		{StationID: "NHK-FM", To: "20240101120000"},
+	}
+	got := a.AuthAreaIDs(progs, time.Date(2024, 1, 1, 18, 0, 0, 0, Location))
+	if want := []string{"JP13", "JP27"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AuthAreaIDs() => %v, want %v", got, want)
+	}
+}
+
+func TestGetAvailabilityDelay(t *testing.T) {
+	tenMinutes, immediately := 10*time.Minute, time.Duration(0)
+	a := &Asset{
+		AvailabilityDelay: 5 * time.Minute,
+		StationSettings: StationSettings{
+			"FMT": &StationSetting{AvailabilityDelay: &tenMinutes},
+			"TBS": &StationSetting{},
+			"LFR": &StationSetting{AvailabilityDelay: &immediately},
+		},
+	}
+	var delaytests = []struct {
+		in  string
+		out time.Duration
+	}{
+		{"FMT", 10 * time.Minute},
+		{"TBS", 5 * time.Minute},
+		{"QRR", 5 * time.Minute},
+		{"LFR", 0},
+	}
+	for _, tt := range delaytests {
+		got := a.GetAvailabilityDelay(tt.in)
+		if got != tt.out {
+			t.Errorf("GetAvailabilityDelay(%v) => %v, want %v", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestGetHeaders(t *testing.T) {
+	a := &Asset{
+		HeaderProfiles: HeaderProfiles{
+			DefaultHeaderProfile: HeaderProfile{
+				EndpointPlaylist: {"cache-control": "no-cache"},
+			},
+			"noua": HeaderProfile{
+				EndpointPlaylist: {"user-agent": ""},
+			},
+		},
+		StationSettings: StationSettings{
+			"FMT": &StationSetting{HeaderProfile: "noua"},
+		},
+	}
+	base := map[string]string{
+		UserAgentHeader:    "Dalvik/2.1.0",
+		RadikoAreaIDHeader: "JP13",
+	}
+	var headertests = []struct {
+		endpoint  string
+		stationID string
+		out       map[string]string
+	}{
+		{
+			EndpointPlaylist,
+			"TBS",
+			map[string]string{
+				"Cache-Control":    "no-cache",
+				UserAgentHeader:    "Dalvik/2.1.0",
+				RadikoAreaIDHeader: "JP13",
+			},
+		},
+		{
+			EndpointPlaylist,
+			"FMT",
+			map[string]string{
+				"Cache-Control":    "no-cache",
+				RadikoAreaIDHeader: "JP13",
+			},
+		},
+		{
+			EndpointAuth1,
+			"FMT",
+			base,
+		},
+	}
+	for _, tt := range headertests {
+		got := a.GetHeaders(tt.endpoint, tt.stationID, base)
+		if len(got) != len(tt.out) {
+			t.Errorf("GetHeaders(%v, %v) => %v, want %v", tt.endpoint, tt.stationID, got, tt.out)
+		}
+		for k, v := range tt.out {
+			if got.Get(k) != v {
+				t.Errorf("GetHeaders(%v, %v)[%v] => %v, want %v", tt.endpoint, tt.stationID, k, got.Get(k), v)
+			}
+		}
+	}
+}
+
 func TestGetPartialKey(t *testing.T) {
 	client, err := radiko.New("")
 	if err != nil {
 		t.Error(err)
 	}
 
-	asset, _ := NewAsset(client)
+	asset, _ := NewAsset(context.Background(), client)
 	partialKey, err := asset.GetPartialKey(128, 16)
 	if err != nil {
 		t.Error(err)
@@ -197,8 +346,8 @@ func TestNewDevice(t *testing.T) {
 		t.Error(err)
 	}
 
-	a, _ := NewAsset(client)
-	device, err := a.NewDevice("JP13")
+	a, _ := NewAsset(context.Background(), client)
+	device, err := a.NewDevice(context.Background(), "JP13")
 
 	if err != nil {
 		t.Error(err)
@@ -0,0 +1,138 @@
+package radicron
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// postProcess is the backlog of the downloaded programs in the post-processing (trim, transcode, tag)
+var postProcess = newBacklog(DefaultPostProcessBacklog)
+
+// BacklogStats shows the backpressure on the segment downloads
+type BacklogStats struct {
+	// Limit of the programs in the post-processing to pause the segment downloads, 0 to disable
+	Limit int `json:"limit"`
+	// Pending programs in the post-processing
+	Pending int `json:"pending"`
+	// Paused is true while the segment downloads wait for the post-processing
+	Paused bool `json:"paused"`
+	// Waiting segment downloads
+	Waiting int `json:"waiting"`
+	// PausedSeconds in total
+	PausedSeconds float64 `json:"paused_seconds"`
+}
+
+// backlog pauses the new segment downloads while the post-processing lags behind,
+// not to pile up the concatenated files in the tmp dir
+type backlog struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	limit   int
+	pending int
+	waiting int
+	// when the downloads started to wait, zero if not paused
+	pausedAt time.Time
+	paused   time.Duration
+}
+
+func newBacklog(limit int) *backlog {
+	return &backlog{
+		changed: make(chan struct{}),
+		limit:   limit,
+	}
+}
+
+// SetPostProcessBacklog sets the limit of the programs in the post-processing, 0 to disable
+func SetPostProcessBacklog(limit int) {
+	postProcess.setLimit(limit)
+}
+
+// PostProcessStats returns the backpressure on the segment downloads
+func PostProcessStats() BacklogStats {
+	return postProcess.stats()
+}
+
+// enter counts the program in the post-processing
+func (b *backlog) enter() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending++
+}
+
+// exit counts the program out of the post-processing and resumes the downloads
+func (b *backlog) exit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending--
+	b.notify()
+}
+
+func (b *backlog) full() bool {
+	return b.limit > 0 && b.pending >= b.limit
+}
+
+// notify wakes up the waiters, the caller holds b.mu
+func (b *backlog) notify() {
+	if !b.full() && !b.pausedAt.IsZero() {
+		b.paused += time.Since(b.pausedAt)
+		b.pausedAt = time.Time{}
+		log.Printf("resuming the downloads after the post-processing caught up")
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *backlog) setLimit(limit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	b.notify()
+}
+
+func (b *backlog) stats() BacklogStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	paused := b.paused
+	if !b.pausedAt.IsZero() {
+		paused += time.Since(b.pausedAt)
+	}
+	return BacklogStats{
+		Limit:         b.limit,
+		Pending:       b.pending,
+		Paused:        !b.pausedAt.IsZero(),
+		Waiting:       b.waiting,
+		PausedSeconds: paused.Seconds(),
+	}
+}
+
+// wait blocks the segment download while the post-processing lags behind,
+// except for the programs about to expire
+func (b *backlog) wait(ctx context.Context, urgent bool) error {
+	if urgent {
+		return nil
+	}
+	b.mu.Lock()
+	for b.full() {
+		if b.pausedAt.IsZero() {
+			b.pausedAt = time.Now()
+			log.Printf("pausing the downloads until the post-processing catches up: %d programs", b.pending)
+		}
+		b.waiting++
+		changed := b.changed
+		b.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.waiting--
+			b.mu.Unlock()
+			return ctx.Err()
+		}
+		b.mu.Lock()
+		b.waiting--
+	}
+	b.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,69 @@
+package radicron
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBacklog(t *testing.T) {
+	b := newBacklog(2)
+	ctx := context.Background()
+
+	// under the limit
+	b.enter()
+	if err := b.wait(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the post-processing lags behind
+	b.enter()
+	resumed := make(chan error, 1)
+	go func() {
+		resumed <- b.wait(ctx, false)
+	}()
+	// the urgent ones don't wait
+	if err := b.wait(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for b.stats().Waiting == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := b.stats(); !stats.Paused || stats.Waiting != 1 || stats.Pending != 2 {
+		t.Errorf("stats => %+v, want paused with 1 waiting and 2 pending", stats)
+	}
+	select {
+	case err := <-resumed:
+		t.Fatalf("wait => %v while paused", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// caught up
+	b.exit()
+	select {
+	case err := <-resumed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait => blocked after the post-processing caught up")
+	}
+	if stats := b.stats(); stats.Paused || stats.Waiting != 0 || stats.PausedSeconds <= 0 {
+		t.Errorf("stats => %+v, want resumed with the paused seconds", stats)
+	}
+
+	// cancel while paused
+	b.enter()
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.wait(cctx, false); err != context.Canceled {
+		t.Errorf("wait => %v, want %v", err, context.Canceled)
+	}
+
+	// disabled
+	b.setLimit(0)
+	if err := b.wait(ctx, false); err != nil {
+		t.Error(err)
+	}
+}
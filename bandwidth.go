@@ -0,0 +1,91 @@
+package radicron
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// bandwidth is the low-bandwidth mode shared by the segment downloads
+var bandwidth = &bandwidthMode{rate: LowBandwidthRate}
+
+// bandwidthMode caps the concurrency and the rate for the metered links,
+// and defers the programs not about to expire
+type bandwidthMode struct {
+	mu      sync.Mutex
+	enabled bool
+	// bytes per second
+	rate float64
+	// when the bytes read so far are paid off
+	next time.Time
+}
+
+// LowBandwidth returns true if the low-bandwidth mode is on
+func LowBandwidth() bool {
+	bandwidth.mu.Lock()
+	defer bandwidth.mu.Unlock()
+	return bandwidth.enabled
+}
+
+// SetLowBandwidth toggles the low-bandwidth mode
+func SetLowBandwidth(enabled bool) {
+	bandwidth.mu.Lock()
+	defer bandwidth.mu.Unlock()
+	changed := bandwidth.enabled != enabled
+	bandwidth.enabled = enabled
+	bandwidth.next = time.Time{}
+	if !changed {
+		return
+	}
+	if enabled && concurrency.Max > LowBandwidthConcurrency {
+		slots.setMax(LowBandwidthConcurrency)
+	} else {
+		slots.setMax(concurrency.Max)
+	}
+	log.Printf("low-bandwidth mode: %v", enabled)
+}
+
+// reserve accounts n bytes read and returns how long to wait to stay within the rate
+func (b *bandwidthMode) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.enabled {
+		return 0
+	}
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	b.next = b.next.Add(time.Duration(float64(n) / b.rate * float64(time.Second)))
+	return b.next.Sub(now)
+}
+
+// throttledReader slows down the reads in the low-bandwidth mode
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// throttle wraps r to follow the low-bandwidth mode, toggled even in the middle of a read
+func throttle(ctx context.Context, r io.Reader) io.Reader {
+	return &throttledReader{ctx: ctx, r: r}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		usage.add(n, time.Now())
+		if wait := bandwidth.reserve(n); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-t.ctx.Done():
+				return n, t.ctx.Err()
+			}
+		}
+	}
+	return n, err
+}
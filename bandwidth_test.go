@@ -0,0 +1,50 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBandwidthModeReserve(t *testing.T) {
+	b := &bandwidthMode{rate: 1000}
+	if wait := b.reserve(500); wait != 0 {
+		t.Errorf("reserve while disabled => %v, want 0", wait)
+	}
+	b.enabled = true
+	var reservetests = []struct {
+		n    int
+		want time.Duration
+	}{
+		{500, 500 * time.Millisecond},
+		{500, time.Second},
+		{1000, 2 * time.Second},
+	}
+	for _, tt := range reservetests {
+		// allow the time passed between the calls
+		if wait := b.reserve(tt.n); wait > tt.want || wait < tt.want-100*time.Millisecond {
+			t.Errorf("reserve(%v) => %v, want %v", tt.n, wait, tt.want)
+		}
+	}
+}
+
+func TestSetLowBandwidth(t *testing.T) {
+	defer SetLowBandwidth(false)
+	SetLowBandwidth(true)
+	if !LowBandwidth() {
+		t.Error("LowBandwidth => false, want true")
+	}
+	if slots.Limit() > LowBandwidthConcurrency {
+		t.Errorf("Limit => %v, want up to %v", slots.Limit(), LowBandwidthConcurrency)
+	}
+
+	// the canceled read returns at once
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := throttle(ctx, bytes.NewReader(make([]byte, 10*LowBandwidthRate)))
+	if _, err := io.ReadAll(r); err != context.Canceled {
+		t.Errorf("ReadAll => %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,135 @@
+package radicron
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Blackout is a calendar period to pause the scheduling, e.g., on vacation,
+// while the guide is still fetched and archived to catch up after it
+type Blackout struct {
+	// From and To in BlackoutDateLayout for the whole days, or in BlackoutDatetimeLayout
+	From string `mapstructure:"from" json:"from"`
+	To   string `mapstructure:"to" json:"to"`
+}
+
+// Span returns the start and the end of the blackout, until the end of the day if To is a date
+func (b *Blackout) Span() (from, to time.Time, err error) {
+	if from, err = parseBlackoutTime(b.From, false); err != nil {
+		return from, to, fmt.Errorf("invalid from: %s", b.From)
+	}
+	if to, err = parseBlackoutTime(b.To, true); err != nil {
+		return from, to, fmt.Errorf("invalid to: %s", b.To)
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("invalid blackout: %s to %s", b.From, b.To)
+	}
+	return from, to, nil
+}
+
+// parseBlackoutTime parses the date or the time in Location, the next day of the date if end
+func parseBlackoutTime(s string, end bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(BlackoutDatetimeLayout, s, Location); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(BlackoutDateLayout, s, Location)
+	if err == nil && end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, err
+}
+
+// Blackouts are the periods to pause the scheduling
+type Blackouts []*Blackout
+
+// Validate returns the error of the first invalid blackout
+func (bs Blackouts) Validate() error {
+	for _, b := range bs {
+		if _, _, err := b.Span(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blackout is the state of the blackouts shared with the admin API
+var blackout = &blackoutState{changed: make(chan struct{})}
+
+// blackoutState holds the blackouts in the config and the ones paused or resumed on the admin API
+type blackoutState struct {
+	mu        sync.Mutex
+	blackouts Blackouts
+	// paused on the admin API until the time, or indefinitely if zero
+	paused      bool
+	pausedUntil time.Time
+	// the blackouts ending by the time are resumed on the admin API
+	resumed time.Time
+	// closed on the changes to wake up the scheduler
+	changed chan struct{}
+}
+
+// SetBlackouts replaces the blackouts, e.g., on reloading the config
+func SetBlackouts(bs Blackouts) {
+	blackout.mu.Lock()
+	defer blackout.mu.Unlock()
+	blackout.blackouts = bs
+}
+
+// BlackoutUntil returns the end of the blackout in effect at t, zero if paused indefinitely,
+// and false if none
+func BlackoutUntil(t time.Time) (time.Time, bool) {
+	blackout.mu.Lock()
+	defer blackout.mu.Unlock()
+	if blackout.paused && (blackout.pausedUntil.IsZero() || t.Before(blackout.pausedUntil)) {
+		return blackout.pausedUntil, true
+	}
+	var until time.Time
+	for _, b := range blackout.blackouts {
+		from, to, err := b.Span()
+		if err != nil || t.Before(from) || !t.Before(to) || !to.After(blackout.resumed) {
+			continue
+		}
+		if to.After(until) {
+			until = to
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// PauseScheduling starts a blackout at once until the time, or indefinitely if zero
+func PauseScheduling(until time.Time) {
+	blackout.mu.Lock()
+	defer blackout.mu.Unlock()
+	blackout.paused, blackout.pausedUntil = true, until
+	blackout.notify()
+	log.Printf("paused the scheduling until %v", until)
+}
+
+// ResumeScheduling ends the blackout in effect at t, keeping the later ones
+func ResumeScheduling(t time.Time) {
+	blackout.mu.Lock()
+	defer blackout.mu.Unlock()
+	blackout.paused, blackout.pausedUntil = false, time.Time{}
+	for _, b := range blackout.blackouts {
+		if from, to, err := b.Span(); err == nil && !t.Before(from) && t.Before(to) && to.After(blackout.resumed) {
+			blackout.resumed = to
+		}
+	}
+	blackout.notify()
+	log.Println("resumed the scheduling")
+}
+
+// BlackoutChanged returns a channel closed once the scheduling is paused or resumed on the admin API
+func BlackoutChanged() <-chan struct{} {
+	blackout.mu.Lock()
+	defer blackout.mu.Unlock()
+	return blackout.changed
+}
+
+// notify wakes up the waiters, the caller holds blackout.mu
+func (b *blackoutState) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,109 @@
+package radicron
+
+import (
+	"testing"
+	"time"
+)
+
+// resetBlackout restores the blackout state when the test ends
+func resetBlackout(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		blackout.mu.Lock()
+		defer blackout.mu.Unlock()
+		blackout.blackouts, blackout.paused, blackout.pausedUntil, blackout.resumed = nil, false, time.Time{}, time.Time{}
+	})
+}
+
+func TestBlackoutSpan(t *testing.T) {
+	var spantests = []struct {
+		from  string
+		to    string
+		start time.Time
+		end   time.Time
+		valid bool
+	}{
+		{"2024-08-10", "2024-08-18", time.Date(2024, 8, 10, 0, 0, 0, 0, Location), time.Date(2024, 8, 19, 0, 0, 0, 0, Location), true},
+		{"2024-08-10 18:00", "2024-08-11 09:30", time.Date(2024, 8, 10, 18, 0, 0, 0, Location), time.Date(2024, 8, 11, 9, 30, 0, 0, Location), true},
+		{"2024-08-10", "2024-08-10", time.Date(2024, 8, 10, 0, 0, 0, 0, Location), time.Date(2024, 8, 11, 0, 0, 0, 0, Location), true},
+		{"2024-08-18", "2024-08-10", time.Time{}, time.Time{}, false},
+		{"20240810", "2024-08-18", time.Time{}, time.Time{}, false},
+		{"2024-08-10", "", time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range spantests {
+		b := &Blackout{From: tt.from, To: tt.to}
+		start, end, err := b.Span()
+		if (err == nil) != tt.valid {
+			t.Errorf("Span(%s, %s) => %v, want valid %v", tt.from, tt.to, err, tt.valid)
+			continue
+		}
+		if tt.valid && (!start.Equal(tt.start) || !end.Equal(tt.end)) {
+			t.Errorf("Span(%s, %s) => %v-%v, want %v-%v", tt.from, tt.to, start, end, tt.start, tt.end)
+		}
+		if err = (Blackouts{b}).Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%s, %s) => %v, want valid %v", tt.from, tt.to, err, tt.valid)
+		}
+	}
+}
+
+func TestBlackoutUntil(t *testing.T) {
+	resetBlackout(t)
+	SetBlackouts(Blackouts{
+		{From: "2024-08-10", To: "2024-08-18"},
+		{From: "2024-08-17 12:00", To: "2024-08-20 09:00"},
+		{From: "2024-09-01", To: "2024-09-01"},
+	})
+	at := func(s string) time.Time {
+		t, _ := time.ParseInLocation(BlackoutDatetimeLayout, s, Location)
+		return t
+	}
+	var untiltests = []struct {
+		t      time.Time
+		until  time.Time
+		paused bool
+	}{
+		{at("2024-08-09 23:59"), time.Time{}, false},
+		{at("2024-08-10 00:00"), at("2024-08-19 00:00"), true},
+		{at("2024-08-18 06:00"), at("2024-08-20 09:00"), true},
+		{at("2024-08-20 09:00"), time.Time{}, false},
+		{at("2024-09-01 12:00"), at("2024-09-02 00:00"), true},
+	}
+	for _, tt := range untiltests {
+		until, paused := BlackoutUntil(tt.t)
+		if paused != tt.paused || !until.Equal(tt.until) {
+			t.Errorf("BlackoutUntil(%v) => %v, %v, want %v, %v", tt.t, until, paused, tt.until, tt.paused)
+		}
+	}
+
+	// resumed on the admin API, keeping the later blackouts
+	changed := BlackoutChanged()
+	ResumeScheduling(at("2024-08-18 06:00"))
+	select {
+	case <-changed:
+	default:
+		t.Error("ResumeScheduling() => not notified")
+	}
+	if until, paused := BlackoutUntil(at("2024-08-19 12:00")); paused {
+		t.Errorf("BlackoutUntil() after ResumeScheduling() => %v", until)
+	}
+	if _, paused := BlackoutUntil(at("2024-09-01 12:00")); !paused {
+		t.Error("BlackoutUntil() after ResumeScheduling() => the later blackout resumed")
+	}
+
+	// paused on the admin API
+	PauseScheduling(at("2024-08-25 00:00"))
+	if until, paused := BlackoutUntil(at("2024-08-21 00:00")); !paused || !until.Equal(at("2024-08-25 00:00")) {
+		t.Errorf("BlackoutUntil() after PauseScheduling() => %v, %v", until, paused)
+	}
+	if _, paused := BlackoutUntil(at("2024-08-26 00:00")); paused {
+		t.Error("BlackoutUntil() after the pause => paused")
+	}
+	PauseScheduling(time.Time{})
+	if until, paused := BlackoutUntil(at("2030-01-01 00:00")); !paused || !until.IsZero() {
+		t.Errorf("BlackoutUntil() paused indefinitely => %v, %v", until, paused)
+	}
+	ResumeScheduling(at("2030-01-01 00:00"))
+	if _, paused := BlackoutUntil(at("2030-01-01 00:00")); paused {
+		t.Error("BlackoutUntil() after ResumeScheduling() => paused")
+	}
+}
@@ -0,0 +1,73 @@
+package radicron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseBlockTime parses the time of a block in YYYYMMDDhhmm or YYYYMMDDhhmmss
+func ParseBlockTime(s string) (time.Time, error) {
+	layout := DatetimeLayout
+	if len(s) == len(OutputDatetimeLayout) {
+		layout = OutputDatetimeLayout
+	}
+	t, err := time.ParseInLocation(layout, s, Location)
+	if err != nil {
+		return t, fmt.Errorf("invalid time: %s", s)
+	}
+	return t, nil
+}
+
+// Between returns the programs on air in [ft, to)
+func (ps Progs) Between(ft, to string) Progs {
+	progs := Progs{}
+	for _, p := range ps {
+		if p.Ft < to && ft < p.To {
+			progs = append(progs, p)
+		}
+	}
+	return progs
+}
+
+// NewBlockProg returns the recording of the airtime from ft to to on the station
+// regardless of the program boundaries, with the metadata of the programs on air in it
+func NewBlockProg(stationID string, ft, to time.Time, progs Progs) (*Prog, error) {
+	if !ft.Before(to) {
+		return nil, fmt.Errorf("the end %v is not after the start %v", to, ft)
+	}
+	if to.Sub(ft) > BlockMaxHours*time.Hour {
+		return nil, fmt.Errorf("the block is over %d hours: %v", BlockMaxHours, to.Sub(ft))
+	}
+	p := &Prog{
+		ID:        fmt.Sprintf("%s_%s-%s", stationID, ft.Format(DatetimeLayout), to.Format(DatetimeLayout)),
+		StationID: stationID,
+		Ft:        ft.Format(DatetimeLayout),
+		To:        to.Format(DatetimeLayout),
+	}
+	titles := []string{}
+	pfms := []string{}
+	infos := []string{}
+	for _, part := range progs.Between(p.Ft, p.To) {
+		titles = appendUnique(titles, part.Title)
+		pfms = appendUnique(pfms, part.Pfm)
+		for _, tag := range part.Tags {
+			p.Tags = appendUnique(p.Tags, tag)
+		}
+		if p.Img == "" {
+			p.Img = part.Img
+		}
+		start := part.Ft
+		if pft, err := time.ParseInLocation(DatetimeLayout, part.Ft, Location); err == nil {
+			start = pft.Format("15:04")
+		}
+		infos = append(infos, fmt.Sprintf("%s %s", start, part.Title))
+	}
+	p.Title = strings.Join(titles, " / ")
+	if p.Title == "" {
+		p.Title = fmt.Sprintf("%s %s-%s", stationID, ft.Format("2006-01-02 15:04"), to.Format("15:04"))
+	}
+	p.Pfm = strings.Join(pfms, "、")
+	p.Info = strings.Join(infos, "\n")
+	return p, nil
+}
@@ -0,0 +1,56 @@
+package radicron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewBlockProg(t *testing.T) {
+	Location, _ = time.LoadLocation(TZTokyo)
+	progs := Progs{
+		{ID: "1", Ft: "20230605110000", To: "20230605130000", Title: "Morning", Pfm: "A"},
+		{ID: "2", Ft: "20230605130000", To: "20230605145500", Title: "Afternoon", Pfm: "B", Tags: []string{"music"}},
+		{ID: "3", Ft: "20230605145500", To: "20230605150000", Title: "News"},
+		{ID: "4", Ft: "20230605150000", To: "20230605170000", Title: "Evening", Pfm: "A"},
+	}
+	ft, _ := ParseBlockTime("202306051230")
+	to, _ := ParseBlockTime("20230605150000")
+	p, err := NewBlockProg("FMT", ft, to, progs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Prog{
+		ID:        "FMT_20230605123000-20230605150000",
+		StationID: "FMT",
+		Ft:        "20230605123000",
+		To:        "20230605150000",
+		Title:     "Morning / Afternoon / News",
+		Pfm:       "A、B",
+		Info:      "11:00 Morning\n13:00 Afternoon\n14:55 News",
+	}
+	if p.ID != want.ID || p.Ft != want.Ft || p.To != want.To || p.Title != want.Title ||
+		p.Pfm != want.Pfm || p.Info != want.Info || len(p.Tags) != 1 {
+		t.Errorf("NewBlockProg => %+v, want %+v", p, want)
+	}
+
+	// without the guide
+	if p, err = NewBlockProg("FMT", ft, to, nil); err != nil || p.Title != "FMT 2023-06-05 12:30-15:00" {
+		t.Errorf("NewBlockProg without the guide => %v, %v", p, err)
+	}
+
+	var invalidtests = []struct {
+		name string
+		to   time.Time
+	}{
+		{"reversed", ft.Add(-time.Hour)},
+		{"too long", ft.Add((BlockMaxHours + 1) * time.Hour)},
+	}
+	for _, tt := range invalidtests {
+		if _, err = NewBlockProg("FMT", ft, tt.to, progs); err == nil {
+			t.Errorf("NewBlockProg(%s) => nil, want error", tt.name)
+		}
+	}
+	if _, err = ParseBlockTime("2023-06-05"); err == nil {
+		t.Error("ParseBlockTime(2023-06-05) => nil, want error")
+	}
+}
@@ -0,0 +1,142 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// Bundle packages the episodes in dir into an M4B audiobook
+// at output with a chapter per episode
+func Bundle(ctx context.Context, dir string, episodes Episodes, title, output string) error {
+	if len(episodes) == 0 {
+		return fmt.Errorf("no episodes to bundle")
+	}
+	episodes = episodes.oldestFirst()
+	chapters, err := episodeChapters(ctx, dir, episodes)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bundle")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	list, err := writeConcatList(tmpDir, dir, episodes)
+	if err != nil {
+		return err
+	}
+	metadata := filepath.Join(tmpDir, "metadata.txt")
+	if err = os.WriteFile(metadata, []byte(ffmetadata(title, chapters)), 0o600); err != nil {
+		return err
+	}
+
+	args := []string{
+		"-f", "concat", "-safe", "0", "-i", list,
+		"-i", metadata,
+		"-map", "0:a", "-map_metadata", "1", "-map_chapters", "1",
+	}
+	if episodes.allAAC() {
+		args = append(args, "-c:a", "copy", "-bsf:a", "aac_adtstoasc")
+	} else {
+		args = append(args, "-c:a", "aac", "-b:a", BundleBitrate)
+	}
+	args = append(args, "-f", "mp4", "-y", output)
+	return runFFmpeg(ctx, nil, args...)
+}
+
+// episodeChapters returns the chapters of the concatenated episodes
+func episodeChapters(ctx context.Context, dir string, episodes Episodes) (Chapters, error) {
+	chapters := Chapters{}
+	var start time.Duration
+	for _, e := range episodes {
+		d, err := probeDuration(ctx, filepath.Join(dir, e.FileName))
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, &Chapter{
+			Start:   start,
+			End:     start + d,
+			Summary: e.chapterTitle(),
+		})
+		start += d
+	}
+	return chapters, nil
+}
+
+// ffmetadata returns the chapters in the FFMETADATA format
+func ffmetadata(title string, chapters Chapters) string {
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", `\`+"\n")
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	fmt.Fprintf(&b, "title=%s\n", escape.Replace(title))
+	fmt.Fprintf(&b, "album=%s\n", escape.Replace(title))
+	for _, c := range chapters {
+		b.WriteString("[CHAPTER]\nTIMEBASE=1/1000\n")
+		fmt.Fprintf(&b, "START=%d\nEND=%d\n", c.Start.Milliseconds(), c.End.Milliseconds())
+		fmt.Fprintf(&b, "title=%s\n", escape.Replace(c.Summary))
+	}
+	return b.String()
+}
+
+// writeConcatList writes the list of the episodes for the ffmpeg concat demuxer
+func writeConcatList(tmpDir, dir string, episodes Episodes) (string, error) {
+	var b strings.Builder
+	for _, e := range episodes {
+		path, err := filepath.Abs(filepath.Join(dir, e.FileName))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(path, "'", `'\''`))
+	}
+	list := filepath.Join(tmpDir, "list.txt")
+	return list, os.WriteFile(list, []byte(b.String()), 0o600)
+}
+
+// chapterTitle returns the date and the title of the episode
+func (e *Episode) chapterTitle() string {
+	if e.PubDate.IsZero() {
+		return e.Title
+	}
+	return fmt.Sprintf("%s %s", e.PubDate.Format("2006-01-02"), e.Title)
+}
+
+// Filter returns the episodes published in [from, to) with the title containing query
+func (es Episodes) Filter(from, to time.Time, stationID, query string) Episodes {
+	filtered := Episodes{}
+	for _, e := range es {
+		if e.PubDate.Before(from) || !e.PubDate.Before(to) ||
+			(stationID != "" && e.StationID != stationID) ||
+			!strings.Contains(e.Title, query) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
+// allAAC returns true if all the episodes are in aac
+func (es Episodes) allAAC() bool {
+	for _, e := range es {
+		if filepath.Ext(e.FileName) != "."+radigo.AudioFormatAAC {
+			return false
+		}
+	}
+	return true
+}
+
+// oldestFirst returns a copy of the episodes sorted by the oldest first
+func (es Episodes) oldestFirst() Episodes {
+	sorted := append(Episodes{}, es...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PubDate.Before(sorted[j].PubDate)
+	})
+	return sorted
+}
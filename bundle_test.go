@@ -0,0 +1,74 @@
+package radicron
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFfmetadata(t *testing.T) {
+	chapters := Chapters{
+		{Start: 0, End: 55 * time.Minute, Summary: "2023-06-05 THE TRAD"},
+		{Start: 55 * time.Minute, End: 110 * time.Minute, Summary: "2023-06-06 THE TRAD; #2"},
+	}
+	got := ffmetadata("THE TRAD=weekly", chapters)
+	for _, want := range []string{
+		";FFMETADATA1\n",
+		"title=THE TRAD\\=weekly\n",
+		"START=0\nEND=3300000\ntitle=2023-06-05 THE TRAD\n",
+		"START=3300000\nEND=6600000\ntitle=2023-06-06 THE TRAD\\; \\#2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ffmetadata => %q, want to contain %q", got, want)
+		}
+	}
+}
+
+func TestEpisodesFilter(t *testing.T) {
+	es := Episodes{
+		parseEpisodeFileName("202306071300_FMT_THE TRAD.aac"),
+		parseEpisodeFileName("202306061300_FMT_THE TRAD.aac"),
+		parseEpisodeFileName("202306061300_TBS_THE TRAD.mp3"),
+		parseEpisodeFileName("202306051300_FMT_Other.aac"),
+		parseEpisodeFileName("202306121300_FMT_THE TRAD.aac"),
+	}
+	from := time.Date(2023, 6, 5, 0, 0, 0, 0, Location)
+	to := from.AddDate(0, 0, 7)
+
+	got := es.Filter(from, to, "FMT", "TRAD").oldestFirst()
+	if len(got) != 2 {
+		t.Fatalf("Filter => %v episodes, want 2", len(got))
+	}
+	if got[0].FileName != "202306061300_FMT_THE TRAD.aac" {
+		t.Errorf("oldestFirst => %v, want 202306061300_FMT_THE TRAD.aac", got[0].FileName)
+	}
+	if !got.allAAC() {
+		t.Error("allAAC => false, want true")
+	}
+	if es.Filter(from, to, "", "").allAAC() {
+		t.Error("allAAC with mp3 => true, want false")
+	}
+	if want := "2023-06-06 THE TRAD"; got[0].chapterTitle() != want {
+		t.Errorf("chapterTitle => %v, want %v", got[0].chapterTitle(), want)
+	}
+}
+
+func TestWriteConcatList(t *testing.T) {
+	dir := t.TempDir()
+	list, err := writeConcatList(dir, "/downloads", Episodes{
+		{FileName: "a.aac"},
+		{FileName: "it's.aac"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.ReadFile(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "file '/downloads/a.aac'\nfile '/downloads/it'\\''s.aac'\n"
+	if string(blob) != want {
+		t.Errorf("writeConcatList => %q, want %q", blob, want)
+	}
+}
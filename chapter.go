@@ -0,0 +1,387 @@
+package radicron
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bogem/id3v2"
+)
+
+// timedTranscriptExts are the transcript files with the timestamps
+var timedTranscriptExts = []string{".vtt", ".srt"}
+
+// cueTimePattern matches "00:01:02.345 --> 00:01:05.678" in VTT and SRT
+var cueTimePattern = regexp.MustCompile(`^((?:\d+:)?\d{1,2}:\d{2}[.,]\d{3})\s+-->\s+((?:\d+:)?\d{1,2}:\d{2}[.,]\d{3})`)
+
+// Chapter is a topic segment of the audio
+type Chapter struct {
+	Start   time.Duration
+	End     time.Duration
+	Summary string
+}
+
+type Chapters []*Chapter
+
+// Cue is a timed text in the transcript
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type Cues []*Cue
+
+// ChapterFrame is the ID3v2 CHAP frame with a TIT2 sub-frame
+type ChapterFrame struct {
+	ElementID string
+	Start     time.Duration
+	End       time.Duration
+	Title     id3v2.TextFrame
+	SynchSafe bool
+}
+
+func (cf ChapterFrame) Size() int {
+	// element id + null + 4 times + sub-frame header + sub-frame body
+	return len(cf.ElementID) + 1 + 16 + 10 + cf.Title.Size()
+}
+
+func (cf ChapterFrame) UniqueIdentifier() string {
+	return cf.ElementID
+}
+
+func (cf ChapterFrame) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(cf.ElementID)
+	buf.WriteByte(0)
+	for _, v := range []uint32{
+		uint32(cf.Start.Milliseconds()),
+		uint32(cf.End.Milliseconds()),
+		math.MaxUint32, // no start offset
+		math.MaxUint32, // no end offset
+	} {
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+	buf.WriteString("TIT2")
+	_ = binary.Write(buf, binary.BigEndian, frameSize(cf.Title.Size(), cf.SynchSafe))
+	buf.Write([]byte{0, 0}) // flags
+	if _, err := cf.Title.WriteTo(buf); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// TOCFrame is the ID3v2 CTOC frame listing the chapters
+type TOCFrame struct {
+	ElementID string
+	ChildIDs  []string
+	TopLevel  bool
+	Ordered   bool
+}
+
+func (tf TOCFrame) Size() int {
+	size := len(tf.ElementID) + 1 + 2 // element id + null + flags + entry count
+	for _, id := range tf.ChildIDs {
+		size += len(id) + 1
+	}
+	return size
+}
+
+func (tf TOCFrame) UniqueIdentifier() string {
+	return tf.ElementID
+}
+
+func (tf TOCFrame) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(tf.ElementID)
+	buf.WriteByte(0)
+	var flags byte
+	if tf.TopLevel {
+		flags |= 0x02
+	}
+	if tf.Ordered {
+		flags |= 0x01
+	}
+	buf.WriteByte(flags)
+	buf.WriteByte(byte(len(tf.ChildIDs)))
+	for _, id := range tf.ChildIDs {
+		buf.WriteString(id)
+		buf.WriteByte(0)
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// ParseTranscript parses the cues in a VTT or SRT transcript
+func ParseTranscript(r io.Reader) (Cues, error) {
+	cues := Cues{}
+	var cue *Cue
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := cueTimePattern.FindStringSubmatch(line); m != nil {
+			start, err := parseCueTime(m[1])
+			if err != nil {
+				return cues, err
+			}
+			end, err := parseCueTime(m[2])
+			if err != nil {
+				return cues, err
+			}
+			cue = &Cue{Start: start, End: end}
+			cues = append(cues, cue)
+			continue
+		}
+		if line == "" {
+			cue = nil // end of the cue
+			continue
+		}
+		if cue != nil {
+			cue.Text = strings.TrimSpace(cue.Text + " " + line)
+		}
+	}
+	return cues, scanner.Err()
+}
+
+// SegmentTopics splits the cues into the chapters by the lexical cohesion
+// between the adjacent blocks (TextTiling), each at least minLength
+func SegmentTopics(cues Cues, minLength time.Duration) Chapters {
+	chapters := Chapters{}
+	if len(cues) == 0 {
+		return chapters
+	}
+
+	// group the cues into the blocks
+	blocks := []Cues{}
+	var block Cues
+	for _, c := range cues {
+		if len(block) > 0 && c.Start-block[0].Start >= ChapterBlockSeconds*time.Second {
+			blocks = append(blocks, block)
+			block = nil
+		}
+		block = append(block, c)
+	}
+	blocks = append(blocks, block)
+
+	// the similarity at each gap between the blocks
+	bags := make([]map[string]int, len(blocks))
+	for i, b := range blocks {
+		bags[i] = b.bag()
+	}
+	gaps := make([]float64, len(blocks)-1)
+	for i := range gaps {
+		left, right := map[string]int{}, map[string]int{}
+		for j := i; j >= 0 && j > i-ChapterWindowBlocks; j-- {
+			mergeBag(left, bags[j])
+		}
+		for j := i + 1; j < len(bags) && j <= i+ChapterWindowBlocks; j++ {
+			mergeBag(right, bags[j])
+		}
+		gaps[i] = cosine(left, right)
+	}
+
+	// the depth of the valley at each gap
+	depths := make([]float64, len(gaps))
+	var sum, sumSq float64
+	for i, g := range gaps {
+		lpeak, rpeak := g, g
+		for j := i - 1; j >= 0 && gaps[j] >= lpeak; j-- {
+			lpeak = gaps[j]
+		}
+		for j := i + 1; j < len(gaps) && gaps[j] >= rpeak; j++ {
+			rpeak = gaps[j]
+		}
+		depths[i] = (lpeak - g) + (rpeak - g)
+		sum += depths[i]
+		sumSq += depths[i] * depths[i]
+	}
+
+	// pick the deepest gaps above the threshold keeping minLength
+	boundaries := []time.Duration{}
+	if len(depths) > 0 {
+		mean := sum / float64(len(depths))
+		threshold := mean + math.Sqrt(math.Max(sumSq/float64(len(depths))-mean*mean, 0))/2
+		order := make([]int, len(depths))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return depths[order[a]] > depths[order[b]] })
+		end := cues[len(cues)-1].End
+		for _, i := range order {
+			if depths[i] <= threshold || depths[i] == 0 {
+				break
+			}
+			at := blocks[i+1][0].Start
+			ok := at >= minLength && end-at >= minLength
+			for _, b := range boundaries {
+				if (at - b).Abs() < minLength {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				boundaries = append(boundaries, at)
+			}
+		}
+	}
+	sort.Slice(boundaries, func(a, b int) bool { return boundaries[a] < boundaries[b] })
+
+	// build the chapters with the most representative cue as the summary
+	starts := append([]time.Duration{0}, boundaries...)
+	for i, start := range starts {
+		chapter := &Chapter{Start: start, End: cues[len(cues)-1].End}
+		if i+1 < len(starts) {
+			chapter.End = starts[i+1]
+		}
+		var chapterCues Cues
+		for _, c := range cues {
+			if c.Start >= chapter.Start && c.Start < chapter.End {
+				chapterCues = append(chapterCues, c)
+			}
+		}
+		chapter.Summary = chapterCues.summary()
+		chapters = append(chapters, chapter)
+	}
+	return chapters
+}
+
+// WriteChapters writes the chapters to the ID3v2 tag of the audio
+func WriteChapters(path string, chapters Chapters) error {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("error while opening the audio: %s", err)
+	}
+	defer tag.Close()
+
+	synchSafe := tag.Version() == 4
+	encoding := id3v2.EncodingUTF8
+	if !synchSafe {
+		encoding = id3v2.EncodingUTF16 // no UTF-8 in ID3v2.3
+	}
+
+	// replace the existing chapters
+	tag.DeleteFrames("CHAP")
+	tag.DeleteFrames("CTOC")
+	toc := TOCFrame{ElementID: "toc", TopLevel: true, Ordered: true}
+	for i, c := range chapters {
+		id := fmt.Sprintf("chp%d", i)
+		toc.ChildIDs = append(toc.ChildIDs, id)
+		tag.AddFrame("CHAP", ChapterFrame{
+			ElementID: id,
+			Start:     c.Start,
+			End:       c.End,
+			Title:     id3v2.TextFrame{Encoding: encoding, Text: c.Summary},
+			SynchSafe: synchSafe,
+		})
+	}
+	tag.AddFrame("CTOC", toc)
+	return tag.Save()
+}
+
+// ChaptersFromTranscript segments the timed transcript next to the audio
+func ChaptersFromTranscript(audioPath string, minLength time.Duration) (Chapters, error) {
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	for _, ext := range timedTranscriptExts {
+		f, err := os.Open(base + ext)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		cues, err := ParseTranscript(f)
+		if err != nil {
+			return nil, err
+		}
+		return SegmentTopics(cues, minLength), nil
+	}
+	return nil, errors.New("no timed transcript")
+}
+
+// bag returns the character bigram counts of the cues
+func (cs Cues) bag() map[string]int {
+	bag := map[string]int{}
+	for _, c := range cs {
+		runes := []rune(strings.ToLower(c.Text))
+		for i := 0; i+1 < len(runes); i++ {
+			if isWordRune(runes[i]) && isWordRune(runes[i+1]) {
+				bag[string(runes[i:i+2])]++
+			}
+		}
+	}
+	return bag
+}
+
+// summary returns the cue most similar to the whole cues
+func (cs Cues) summary() string {
+	all := cs.bag()
+	best, bestScore := "", -1.0
+	for _, c := range cs {
+		score := cosine(Cues{c}.bag(), all)
+		if score > bestScore {
+			best, bestScore = c.Text, score
+		}
+	}
+	runes := []rune(best)
+	if len(runes) > ChapterSummaryRunes {
+		return string(runes[:ChapterSummaryRunes]) + "…"
+	}
+	return best
+}
+
+func cosine(a, b map[string]int) float64 {
+	var dot, na, nb float64
+	for k, v := range a {
+		dot += float64(v * b[k])
+		na += float64(v * v)
+	}
+	for _, v := range b {
+		nb += float64(v * v)
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// frameSize encodes the frame size, synchsafe in ID3v2.4
+func frameSize(size int, synchSafe bool) uint32 {
+	if !synchSafe {
+		return uint32(size)
+	}
+	var s uint32
+	for i := 0; i < 4; i++ {
+		s |= uint32((size>>(7*i))&0x7f) << (8 * i)
+	}
+	return s
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func mergeBag(dst, src map[string]int) {
+	for k, v := range src {
+		dst[k] += v
+	}
+}
+
+// parseCueTime parses "01:02:03.456", "02:03.456", or "01:02:03,456"
+func parseCueTime(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+	return time.ParseDuration(fmt.Sprintf("%sh%sm%ss", parts[0], parts[1], parts[2]))
+}
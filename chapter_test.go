@@ -0,0 +1,113 @@
+package radicron
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bogem/id3v2"
+)
+
+func TestParseTranscript(t *testing.T) {
+	var transcripttests = []struct {
+		in  string
+		out Cues
+	}{
+		{
+			"WEBVTT\n\n00:00:01.000 --> 00:00:04.500\nこんにちは\n\n00:01:02.000 --> 01:00:03.000\nline 1\nline 2\n",
+			Cues{
+				{time.Second, 4500 * time.Millisecond, "こんにちは"},
+				{62 * time.Second, time.Hour + 3*time.Second, "line 1 line 2"},
+			},
+		},
+		{
+			"1\n00:00:01,000 --> 00:00:02,000\nhello\n\n2\n00:00:02,000 --> 00:00:03,000\nworld\n",
+			Cues{
+				{time.Second, 2 * time.Second, "hello"},
+				{2 * time.Second, 3 * time.Second, "world"},
+			},
+		},
+	}
+	for _, tt := range transcripttests {
+		got, err := ParseTranscript(strings.NewReader(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tt.out) {
+			t.Fatalf("ParseTranscript => %v cues, want %v", len(got), len(tt.out))
+		}
+		for i, c := range got {
+			if *c != *tt.out[i] {
+				t.Errorf("ParseTranscript[%v] => %v, want %v", i, c, tt.out[i])
+			}
+		}
+	}
+}
+
+func TestSegmentTopics(t *testing.T) {
+	cues := Cues{}
+	topics := []string{
+		"今日はシティポップの名曲を紹介します。山下達郎や竹内まりやのシティポップ",
+		"続いては天気予報です。明日の東京は晴れ、気温は二十五度の予報です",
+	}
+	for i := 0; i < 20; i++ {
+		cues = append(cues, &Cue{
+			Start: time.Duration(i) * time.Minute,
+			End:   time.Duration(i+1) * time.Minute,
+			Text:  topics[i/10],
+		})
+	}
+	chapters := SegmentTopics(cues, 5*time.Minute)
+	if len(chapters) != 2 {
+		t.Fatalf("SegmentTopics => %v chapters, want %v", len(chapters), 2)
+	}
+	if chapters[1].Start != 10*time.Minute {
+		t.Errorf("chapters[1].Start => %v, want %v", chapters[1].Start, 10*time.Minute)
+	}
+	if chapters[1].End != 20*time.Minute {
+		t.Errorf("chapters[1].End => %v, want %v", chapters[1].End, 20*time.Minute)
+	}
+	if !strings.HasPrefix(chapters[1].Summary, "続いては天気予報です") {
+		t.Errorf("chapters[1].Summary => %v", chapters[1].Summary)
+	}
+	if got := SegmentTopics(Cues{}, time.Minute); len(got) != 0 {
+		t.Errorf("SegmentTopics(empty) => %v", got)
+	}
+}
+
+func TestWriteChapters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.aac")
+	if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	chapters := Chapters{
+		{Start: 0, End: time.Minute, Summary: "first"},
+		{Start: time.Minute, End: 2 * time.Minute, Summary: "second"},
+	}
+	// write twice to replace the chapters
+	for i := 0; i < 2; i++ {
+		if err := WriteChapters(path, chapters); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tag.Close()
+	if got := len(tag.GetFrames("CHAP")); got != 2 {
+		t.Errorf("CHAP frames => %v, want %v", got, 2)
+	}
+	if got := len(tag.GetFrames("CTOC")); got != 1 {
+		t.Errorf("CTOC frames => %v, want %v", got, 1)
+	}
+	for _, f := range tag.GetFrames("CHAP") {
+		uf, ok := f.(id3v2.UnknownFrame)
+		if !ok || !bytes.HasPrefix(uf.Body, []byte("chp")) || !bytes.Contains(uf.Body, []byte("TIT2")) {
+			t.Errorf("CHAP frame => %v", f)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configFile is the config to use, set by the persistent flag
+var configFile string
+
+// rootOptions are the flags to record without a subcommand
+type rootOptions struct {
+	serveAddr      string
+	feedURL        string
+	adminAddr      string
+	addListener    string
+	revokeListener string
+	readOnly       bool
+	leaseTTL       time.Duration
+	dryRun         bool
+	daemon         bool
+	lowBandwidth   bool
+	serviceName    string
+}
+
+// newRootCommand returns the command to record, with the subcommands of the toolkit
+func newRootCommand() *cobra.Command {
+	var enableDebug, showSecrets bool
+	opts := &rootOptions{}
+	cmd := &cobra.Command{
+		Use:   "radicron",
+		Short: "Record the radiko timefree programs matching the rules",
+		Long: "radicron records the radiko timefree programs matching the rules in the config,\n" +
+			"and works as a toolkit with the subcommands.",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// to change the flags on the default logger
+			if enableDebug {
+				log.SetFlags(log.LstdFlags | log.Lshortfile)
+			}
+			// mask the secrets in the logs
+			radicron.LogRedactor.SetEnabled(!showSecrets)
+			log.SetOutput(radicron.LogRedactor)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRoot(opts)
+		},
+	}
+	// use the version from build
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		cmd.Version = bi.Main.Version
+	}
+	cmd.SetVersionTemplate("{{.Version}}\n")
+
+	pfs := cmd.PersistentFlags()
+	pfs.StringVarP(&configFile, "config", "c", "config.yml", "the config file (YAML or TOML) to use.")
+	pfs.BoolVarP(&enableDebug, "debug", "d", false, "enable debug mode.")
+	pfs.BoolVar(&showSecrets, "show-secrets", false, "do not redact the secrets in the logs (for debugging).")
+	_ = cmd.MarkPersistentFlagFilename("config", "yml", "yaml", "toml")
+
+	fs := cmd.Flags()
+	fs.StringVar(&opts.serveAddr, "serve", "", "serve the podcast feed on the address, e.g., :8080.")
+	fs.StringVar(&opts.feedURL, "feed-url", "", "the public base URL of the podcast feed.")
+	fs.StringVar(&opts.adminAddr, "admin", "", "serve the admin endpoints (pprof) on the address, e.g., localhost:6060.")
+	fs.StringVar(&opts.addListener, "add-listener", "", "generate a feed token for the listener and exit.")
+	fs.StringVar(&opts.revokeListener, "revoke-listener", "", "revoke the feed token of the listener and exit.")
+	fs.BoolVar(&opts.readOnly, "read-only", false, "only serve the library with --serve from the shared storage without scheduling or downloading (e.g., a secondary replica).")
+	fs.DurationVar(&opts.leaseTTL, "lease", 0, "hold a lease in RADICRON_HOME for the duration to record by only one of the instances sharing it, e.g., 1m.")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "report the programs to be downloaded with the output paths and the estimated sizes, and exit without downloading.")
+	fs.BoolVar(&opts.daemon, "daemon", true, "keep running to scan the guide periodically, --daemon=false to scan once and exit after the downloads (e.g., from cron).")
+	fs.BoolVar(&opts.lowBandwidth, "low-bandwidth", false, "cap the concurrency and the rate, and defer the programs not about to expire (toggled on the admin endpoint).")
+	fs.StringVar(&opts.serviceName, "service-name", "radicron", "the name of the Windows service (set by service install).")
+	tuning.register(fs)
+
+	cmd.AddCommand(
+		newBundleCommand(),
+		newChaptersCommand(),
+		newConfigCommand(),
+		newHistoryCommand(),
+		newInitCommand(),
+		newRecordCommand(),
+		newRulesCommand(),
+		newSearchCommand(),
+		newSearchArchiveCommand(),
+		newServeCommand(),
+		newServiceCommand(),
+		newStationsCommand(),
+		newStatsCommand(),
+		newWorkerCommand(),
+	)
+	return cmd
+}
+
+// runRoot records with the flags without a subcommand, serving the feed if set
+func runRoot(opts *rootOptions) error {
+	// manage the feed listeners
+	if opts.addListener != "" || opts.revokeListener != "" {
+		return manageListeners(opts.addListener, opts.revokeListener, opts.feedURL)
+	}
+
+	if opts.readOnly && opts.serveAddr == "" {
+		return errors.New("--read-only requires --serve")
+	}
+
+	// serve the podcast feed
+	if opts.serveAddr != "" {
+		// the episode title template is needed before recording
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		go serve(opts.serveAddr, opts.feedURL, viper.GetString("episode-title"))
+	}
+
+	// spare the metered link
+	radicron.SetLowBandwidth(opts.lowBandwidth)
+
+	// elect the recording instance
+	if opts.leaseTTL > 0 && !opts.readOnly {
+		if err := startLease(opts.leaseTTL); err != nil {
+			return err
+		}
+	}
+
+	// started by the Windows service control manager
+	if isWindowsService() {
+		if err := lockInstance(configFile); err != nil {
+			return err
+		}
+		return runWindowsService(opts.serviceName, configFile)
+	}
+
+	// serve the admin endpoints if opted in
+	if opts.adminAddr != "" {
+		go serveAdmin(opts.adminAddr)
+	}
+
+	// leave the scheduling and the downloads to the primary instance
+	if opts.readOnly {
+		log.Println("starting radicron in the read-only mode")
+		waitSignal()
+		log.Println("exiting radicron")
+		return nil
+	}
+
+	dryRun = opts.dryRun
+	if err := lockInstance(configFile); err != nil {
+		return err
+	}
+	record(configFile, opts.daemon && !dryRun)
+	return nil
+}
+
+// legacyArgs rewrites the long flags with a single dash, e.g., -dry-run, into --dry-run
+// to keep the command lines written for the flag package working
+func legacyArgs(args []string) []string {
+	rewritten := make([]string, len(args))
+	for i, arg := range args {
+		rewritten[i] = arg
+		if arg == "--" {
+			copy(rewritten[i:], args[i:])
+			break
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && len(name) > 1 &&
+			name[0] >= 'a' && name[0] <= 'z' {
+			rewritten[i] = "-" + arg
+		}
+	}
+	return rewritten
+}
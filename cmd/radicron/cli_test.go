@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLegacyArgs(t *testing.T) {
+	var legacytests = []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-c", "config.yml", "-dry-run"}, []string{"-c", "config.yml", "--dry-run"}},
+		{[]string{"record", "-daemon=false", "-concurrency", "4"}, []string{"record", "--daemon=false", "--concurrency", "4"}},
+		{[]string{"history", "--status", "saved", "-json"}, []string{"history", "--status", "saved", "--json"}},
+		{[]string{"bundle", "-q", "THE TRAD", "-from", "20230605"}, []string{"bundle", "-q", "THE TRAD", "--from", "20230605"}},
+		{[]string{"chapters", "-min", "-5m"}, []string{"chapters", "--min", "-5m"}},
+		{[]string{"search-archive", "--", "-keyword"}, []string{"search-archive", "--", "-keyword"}},
+	}
+	for _, tt := range legacytests {
+		if got := legacyArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("legacyArgs(%v) => %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRootCommand(t *testing.T) {
+	var commandtests = []struct {
+		args []string
+		want string
+	}{
+		{[]string{"history", "show"}, "show"},
+		{[]string{"rules", "test"}, "test"},
+		{[]string{"service", "install"}, "install"},
+		{[]string{"config", "validate"}, "validate"},
+		{[]string{"rules", "export"}, "export"},
+	}
+	root := newRootCommand()
+	for _, tt := range commandtests {
+		cmd, _, err := root.Find(tt.args)
+		if err != nil {
+			t.Errorf("Find(%v) => %v", tt.args, err)
+			continue
+		}
+		if cmd.Name() != tt.want {
+			t.Errorf("Find(%v) => %v, want %v", tt.args, cmd.Name(), tt.want)
+		}
+	}
+
+	// the completions of the flag values
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"__complete", "history", "--status", ""})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range historyStatuses {
+		if !strings.Contains(out.String(), status+"\n") {
+			t.Errorf("completions for --status => %q, want %s", out.String(), status)
+		}
+	}
+}
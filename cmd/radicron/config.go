@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/radigo"
+	"gopkg.in/yaml.v3"
+)
+
+// configKeys are the top-level keys of the config
+var configKeys = []string{
+	"archive-guide", "area-id", "availability-delay", "blacklist-expiry", "blacklist-threshold", "blackouts",
+	"concurrency", "direct-write", "emergency", "enrichers", "episode-title", "explicit-dir", "extra-stations",
+	"file-format", "gapless-priming", "header-profiles", "ignore-stations", "job-queue",
+	"keep-duplicates", "keep-failed-tmp", "lenient-playlist", "live-boot-ahead", "metadata-only", "minimum-output-size",
+	"output-dir", "plugins", "post-process-backlog", "premium", "radiru-area", "report", "retry", "rules", "scan-interval",
+	"script", "segment-failure-threshold", "stations", "strict-adts", "summarize", "summarize-api-key",
+	"summarize-endpoint", "summarize-model", "summarize-prompt",
+}
+
+// areaIDPattern for JP1 (Hokkaido) to JP47 (Okinawa)
+var areaIDPattern = regexp.MustCompile(`^JP([1-9]|[1-3][0-9]|4[0-7])$`)
+
+// configIssue is a problem of the key in the config
+type configIssue struct {
+	key    string
+	msg    string
+	line   int
+	column int
+}
+
+// configValidator collects the problems in the config read by viper
+type configValidator struct {
+	// root of the YAML config to locate the keys, nil for the other formats
+	root   *yaml.Node
+	issues []*configIssue
+}
+
+// newConfigCommand checks the config
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Check the config",
+	}
+	validate := &cobra.Command{
+		Use:   "validate",
+		Short: "Report the problems in the config at the line and the column",
+		Args:  cobra.NoArgs,
+	}
+	probe := validate.Flags().Bool("probe", false, "also connect to the job-queue, radiko premium, and the summarize-endpoint to check the credentials.")
+	asJSON := validate.Flags().Bool("json", false, "print the problems in JSON.")
+	validate.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		file := viper.ConfigFileUsed()
+		issues, err := validateConfig(context.Background(), file, *probe)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if err = printJSON(os.Stdout, newConfigReport(file, issues)); err != nil {
+				return err
+			}
+		} else {
+			printIssues(os.Stdout, file, issues)
+		}
+		if len(issues) > 0 {
+			return fmt.Errorf("%d problem(s) in %s", len(issues), file)
+		}
+		if !*asJSON {
+			fmt.Printf("%s is valid\n", file)
+		}
+		return nil
+	}
+	cmd.AddCommand(validate)
+	return cmd
+}
+
+// configReport is the result of config validate in JSON
+type configReport struct {
+	File   string             `json:"file"`
+	Valid  bool               `json:"valid"`
+	Issues []*configIssueJSON `json:"issues"`
+}
+
+// configIssueJSON is a problem in the config, with the line and the column 0 if unknown
+type configIssueJSON struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+// newConfigReport returns the report of the problems in the order of the lines
+func newConfigReport(file string, issues []*configIssue) *configReport {
+	sortIssues(issues)
+	report := &configReport{File: file, Valid: len(issues) == 0, Issues: []*configIssueJSON{}}
+	for _, issue := range issues {
+		report.Issues = append(report.Issues, &configIssueJSON{
+			Key:     issue.key,
+			Message: issue.msg,
+			Line:    issue.line,
+			Column:  issue.column,
+		})
+	}
+	return report
+}
+
+// sortIssues sorts the problems in the order of the lines
+func sortIssues(issues []*configIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].line != issues[j].line {
+			return issues[i].line < issues[j].line
+		}
+		return issues[i].column < issues[j].column
+	})
+}
+
+// printIssues writes the problems as file:line:column: key: message in the order of the lines
+func printIssues(w io.Writer, file string, issues []*configIssue) {
+	sortIssues(issues)
+	for _, issue := range issues {
+		pos := file
+		if issue.line > 0 {
+			pos = fmt.Sprintf("%s:%d:%d", file, issue.line, issue.column)
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", pos, issue.key, issue.msg)
+	}
+}
+
+// validateConfig returns the problems in the config loaded in viper from the file,
+// probing the job-queue, radiko premium, and the summarize-endpoint if probe
+func validateConfig(ctx context.Context, file string, probe bool) ([]*configIssue, error) {
+	cv := &configValidator{}
+	if ext := strings.ToLower(filepath.Ext(file)); ext == ".yml" || ext == ".yaml" {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		root := &yaml.Node{}
+		if err = yaml.Unmarshal(blob, root); err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		cv.root = root
+	}
+
+	cv.checkKeys("", viper.AllSettings(), configKeys)
+	cv.checkGeneral()
+	cv.checkTuning()
+	cv.checkStations()
+	cv.checkEmergency()
+	cv.checkRules()
+	cv.checkCommands()
+	cv.checkJobQueue(ctx, probe)
+	cv.checkPremium(ctx, probe)
+	cv.checkSummarize(ctx, probe)
+	return cv.issues, nil
+}
+
+// add the problem of the key
+func (cv *configValidator) add(key, format string, a ...any) {
+	line, column := cv.position(key)
+	cv.issues = append(cv.issues, &configIssue{key: key, msg: fmt.Sprintf(format, a...), line: line, column: column})
+}
+
+// position returns the line and the column of the key, or of the closest parent found
+func (cv *configValidator) position(key string) (line, column int) {
+	if cv.root == nil || len(cv.root.Content) == 0 {
+		return 0, 0
+	}
+	node := cv.root.Content[0]
+	for _, k := range strings.Split(key, ".") {
+		var next *yaml.Node
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if strings.EqualFold(node.Content[i].Value, k) {
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line, column
+}
+
+// checkKeys reports the keys unknown in the settings under the prefix
+func (cv *configValidator) checkKeys(prefix string, settings map[string]any, known []string) {
+	for key := range settings {
+		found := false
+		for _, k := range known {
+			if strings.EqualFold(key, k) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if hint := closestKey(key, known); hint != "" {
+			cv.add(prefix+key, "unknown key, did you mean %s?", hint)
+		} else {
+			cv.add(prefix+key, "unknown key")
+		}
+	}
+}
+
+// checkGeneral checks the top-level values
+func (cv *configValidator) checkGeneral() {
+	if viper.IsSet("area-id") && !areaIDPattern.MatchString(viper.GetString("area-id")) {
+		cv.add("area-id", "invalid area-id: %s (JP1 to JP47)", viper.GetString("area-id"))
+	}
+	if viper.IsSet("file-format") {
+		if f := viper.GetString("file-format"); f != radigo.AudioFormatAAC && f != radigo.AudioFormatMP3 {
+			cv.add("file-format", "unsupported audio format: %s (aac or mp3)", f)
+		}
+	}
+	for _, key := range []string{"availability-delay", "blacklist-expiry", "keep-failed-tmp", "live-boot-ahead", "scan-interval"} {
+		if !viper.IsSet(key) {
+			continue
+		}
+		d, err := time.ParseDuration(viper.GetString(key))
+		if err != nil {
+			cv.add(key, "invalid duration: %s", err)
+		} else if d < 0 || (d == 0 && key == "scan-interval") {
+			cv.add(key, "out of range: %v", d)
+		}
+	}
+	for _, key := range []string{"blacklist-threshold", "gapless-priming", "minimum-output-size", "post-process-backlog"} {
+		if !viper.IsSet(key) {
+			continue
+		}
+		if n, err := strconv.Atoi(fmt.Sprint(viper.Get(key))); err != nil {
+			cv.add(key, "not an integer: %v", viper.Get(key))
+		} else if n < 0 {
+			cv.add(key, "out of range: %d", n)
+		}
+	}
+	if viper.IsSet("segment-failure-threshold") {
+		if f, err := strconv.ParseFloat(fmt.Sprint(viper.Get("segment-failure-threshold")), 64); err != nil || f < 0 || f > 1 {
+			cv.add("segment-failure-threshold", "not a ratio from 0 to 1: %v", viper.Get("segment-failure-threshold"))
+		}
+	}
+	for _, key := range []string{"archive-guide", "direct-write", "keep-duplicates", "lenient-playlist", "metadata-only", "report", "strict-adts", "summarize"} {
+		if !viper.IsSet(key) {
+			continue
+		}
+		if _, err := strconv.ParseBool(fmt.Sprint(viper.Get(key))); err != nil {
+			cv.add(key, "not a boolean: %v", viper.Get(key))
+		}
+	}
+	if err := radicron.ValidateEpisodeTitle(viper.GetString("episode-title")); err != nil {
+		cv.add("episode-title", "%s", err)
+	}
+	if _, err := loadBlackouts(); err != nil {
+		cv.add("blackouts", "%s", err)
+	}
+}
+
+// checkTuning checks the retry policy and the concurrency
+func (cv *configValidator) checkTuning() {
+	retry := radicron.NewRetryPolicy()
+	if viper.IsSet("retry") {
+		cv.checkKeys("retry.", viper.GetStringMap("retry"), structKeys(retry))
+		if err := viper.UnmarshalKey("retry", retry); err != nil {
+			cv.add("retry", "%s", err)
+		} else if retry.Attempts < 1 {
+			cv.add("retry.attempts", "invalid retry attempts: %d", retry.Attempts)
+		}
+	}
+	concurrency := radicron.NewConcurrency()
+	if viper.IsSet("concurrency") {
+		cv.checkKeys("concurrency.", viper.GetStringMap("concurrency"), structKeys(concurrency))
+		if err := viper.UnmarshalKey("concurrency", concurrency); err != nil {
+			cv.add("concurrency", "%s", err)
+		} else if err = concurrency.Validate(); err != nil {
+			cv.add("concurrency", "%s", err)
+		}
+	}
+}
+
+// checkStations checks the settings of the stations and the header profiles
+func (cv *configValidator) checkStations() {
+	headerProfiles := radicron.HeaderProfiles{}
+	if err := viper.UnmarshalKey("header-profiles", &headerProfiles); err != nil {
+		cv.add("header-profiles", "%s", err)
+	}
+	for stationID := range viper.GetStringMap("stations") {
+		key := "stations." + stationID
+		cv.checkKeys(key+".", viper.GetStringMap(key), structKeys(&radicron.StationSetting{}))
+		setting := &radicron.StationSetting{}
+		if err := viper.UnmarshalKey(key, setting); err != nil {
+			cv.add(key, "%s", err)
+			continue
+		}
+		if p := strings.ToLower(setting.HeaderProfile); p != "" {
+			if _, ok := headerProfiles[p]; !ok {
+				cv.add(key+".header-profile", "unknown header-profile: %s", setting.HeaderProfile)
+			}
+		}
+		if setting.Simulcast != "" {
+			if u, err := url.Parse(setting.Simulcast); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				cv.add(key+".simulcast", "invalid playlist URL: %s", setting.Simulcast)
+			}
+		}
+	}
+}
+
+// checkEmergency checks the stations watched for the emergency programming
+func (cv *configValidator) checkEmergency() {
+	if !viper.IsSet("emergency") {
+		return
+	}
+	emergency := &radicron.Emergency{}
+	cv.checkKeys("emergency.", viper.GetStringMap("emergency"), structKeys(emergency))
+	if err := viper.UnmarshalKey("emergency", emergency); err != nil {
+		cv.add("emergency", "%s", err)
+	} else if len(emergency.Stations) == 0 {
+		cv.add("emergency.stations", "required to watch for the emergency programming")
+	}
+}
+
+// checkRules checks each rule
+func (cv *configValidator) checkRules() {
+	ruleKeys := structKeys(&radicron.Rule{})
+	for name := range viper.GetStringMap("rules") {
+		key := "rules." + name
+		cv.checkKeys(key+".", viper.GetStringMap(key), ruleKeys)
+		rule := &radicron.Rule{}
+		if err := viper.UnmarshalKey(key, rule); err != nil {
+			cv.add(key, "%s", err)
+			continue
+		}
+		var ruleErr *radicron.RuleError
+		if err := rule.Validate(); errors.As(err, &ruleErr) {
+			cv.add(key+"."+ruleErr.Field, "%s", ruleErr.Err)
+		}
+	}
+}
+
+// checkCommands checks the enrichers, the plugins, and the script
+func (cv *configValidator) checkCommands() {
+	for _, command := range viper.GetStringSlice("enrichers") {
+		if err := lookCommand(command); err != nil {
+			cv.add("enrichers", "%s", err)
+		}
+	}
+	plugins := radicron.Plugins{}
+	if err := viper.UnmarshalKey("plugins", &plugins); err != nil {
+		cv.add("plugins", "%s", err)
+	}
+	for name, p := range plugins {
+		key := "plugins." + name
+		cv.checkKeys(key+".", viper.GetStringMap(key), structKeys(p))
+		if err := (radicron.Plugins{name: p}).Validate(); err != nil {
+			cv.add(key, "%s", err)
+		} else if err = lookCommand(p.Command); err != nil {
+			cv.add(key+".command", "%s", err)
+		}
+	}
+	if path := viper.GetString("script"); path != "" {
+		if _, err := radicron.LoadScript(path); err != nil {
+			cv.add("script", "%s", err)
+		}
+	}
+}
+
+// checkJobQueue checks the job-queue, connecting to it if probe
+func (cv *configValidator) checkJobQueue(ctx context.Context, probe bool) {
+	if !viper.IsSet("job-queue") {
+		return
+	}
+	rawURL, err := radicron.LookupSecret(ctx, "job-queue", viper.GetString("job-queue"))
+	if err != nil {
+		cv.add("job-queue", "%s", err)
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" {
+		cv.add("job-queue", "unsupported job queue (redis://[:password@]host:port[/db])")
+		return
+	}
+	if probe {
+		if _, err = radicron.NewRedisJobQueue(u); err != nil {
+			cv.add("job-queue", "%s", err)
+		}
+	}
+}
+
+// checkPremium checks the radiko premium login, logging in if probe
+func (cv *configValidator) checkPremium(ctx context.Context, probe bool) {
+	if !viper.IsSet("premium") {
+		return
+	}
+	cv.checkKeys("premium.", viper.GetStringMap("premium"), []string{"favorite-rules", "favorites", "mail", "password"})
+	credentials := map[string]string{}
+	for _, key := range []string{"mail", "password"} {
+		if !viper.IsSet("premium." + key) {
+			cv.add("premium."+key, "required to log in to radiko premium")
+			continue
+		}
+		secret, err := radicron.LookupSecret(ctx, "premium-"+key, viper.GetString("premium."+key))
+		if err != nil {
+			cv.add("premium."+key, "%s", err)
+			continue
+		}
+		credentials[key] = secret
+	}
+	if probe && len(credentials) == 2 {
+		client, err := radiko.New("")
+		if err != nil {
+			cv.add("premium", "%s", err)
+			return
+		}
+		premium := &radicron.Premium{Mail: credentials["mail"], Password: credentials["password"]}
+		if err = premium.Login(ctx, client); err != nil {
+			cv.add("premium", "%s", err)
+			return
+		}
+		_ = premium.Logout(ctx, client)
+	}
+}
+
+// checkSummarize checks the summarizer if opted in, requesting the endpoint if probe
+func (cv *configValidator) checkSummarize(ctx context.Context, probe bool) {
+	if !viper.GetBool("summarize") {
+		return
+	}
+	endpoint := viper.GetString("summarize-endpoint")
+	if u, err := url.Parse(endpoint); endpoint == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		cv.add("summarize-endpoint", "invalid endpoint: %q", endpoint)
+		return
+	}
+	apiKey, err := radicron.LookupSecret(ctx, "summarize-api-key", viper.GetString("summarize-api-key"))
+	if err != nil {
+		cv.add("summarize-api-key", "%s", err)
+		return
+	}
+	if probe {
+		s := &radicron.Summarizer{APIKey: apiKey, Endpoint: endpoint}
+		if err = s.Probe(ctx); err != nil {
+			cv.add("summarize-endpoint", "%s", err)
+		}
+	}
+}
+
+// lookCommand returns an error if the executable of the command is not found
+func lookCommand(command string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("command not found: %s", args[0])
+	}
+	return nil
+}
+
+// structKeys returns the mapstructure keys of the struct v points to
+func structKeys(v any) []string {
+	keys := []string{}
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("mapstructure"), ","); tag != "" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
+
+// closestKey returns the known key within the edit distance of 2 for the typo, or empty
+func closestKey(key string, known []string) string {
+	closest, min := "", 3
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(key), k); d < min {
+			closest, min = k, d
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j] + 1
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateConfig(t *testing.T) {
+	defer viper.Reset()
+	if err := loadConfig("test/config-test.yml"); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := validateConfig(context.Background(), viper.ConfigFileUsed(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		t.Errorf("test/config-test.yml => %s: %s", issue.key, issue.msg)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	config := `area-id: JP99
+scan-intervl: 1h
+episode-title: "{title} {data}"
+blacklist-threshold: three
+retry:
+  attempts: 0
+stations:
+  FMT:
+    header-profile: unknown
+rules:
+  trad:
+    title: THE TRAD
+    windw: 48h
+  night:
+    slot: 27:00-25:00
+plugins:
+  s3:
+    command: radicron-no-such-plugin
+    kinds: [storage]
+premium:
+  mail: member@example.com
+emergency:
+  recrd: true
+blackouts:
+  - from: 2024-08-18
+    to: 2024-08-10
+`
+	if err = os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	if err = loadConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+	issues, err = validateConfig(context.Background(), configFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printIssues(&buf, "config.yml", issues)
+	want := `config.yml:1:1: area-id: invalid area-id: JP99 (JP1 to JP47)
+config.yml:2:1: scan-intervl: unknown key, did you mean scan-interval?
+config.yml:3:1: episode-title: unknown placeholder {data} ({title}, {station}, {date}, or {date:layout})
+config.yml:4:1: blacklist-threshold: not an integer: three
+config.yml:6:3: retry.attempts: invalid retry attempts: 0
+config.yml:9:5: stations.fmt.header-profile: unknown header-profile: unknown
+config.yml:13:5: rules.trad.windw: unknown key, did you mean window?
+config.yml:15:5: rules.night.slot: invalid slot: 27:00-25:00 (within 05:00-29:00)
+config.yml:18:5: plugins.s3.command: command not found: radicron-no-such-plugin
+config.yml:20:1: premium.password: required to log in to radiko premium
+config.yml:22:1: emergency.stations: required to watch for the emergency programming
+config.yml:23:3: emergency.recrd: unknown key, did you mean record?
+config.yml:24:1: blackouts: invalid blackout: 2024-08-18 to 2024-08-10
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printIssues =>\n%s\nwant\n%s", got, want)
+	}
+
+	report := newConfigReport("config.yml", issues)
+	if report.Valid || len(report.Issues) != len(issues) {
+		t.Fatalf("newConfigReport => valid %v with %d issues, want invalid with %d", report.Valid, len(report.Issues), len(issues))
+	}
+	if first := report.Issues[0]; first.Key != "area-id" || first.Line != 1 || first.Column != 1 {
+		t.Errorf("newConfigReport => %+v, want area-id at 1:1", first)
+	}
+	if report = newConfigReport("config.yml", nil); !report.Valid || report.Issues == nil {
+		t.Errorf("newConfigReport without issues => %+v, want valid with no issues", report)
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	var closestkeytests = []struct {
+		key  string
+		want string
+	}{
+		{"windw", "window"},
+		{"Station-ID", "station-id"},
+		{"stationid", "station-id"},
+		{"schedule", ""},
+	}
+	for _, tt := range closestkeytests {
+		if got := closestKey(tt.key, []string{"window", "station-id", "slot"}); got != tt.want {
+			t.Errorf("closestKey(%s) => %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+)
+
+// historyStatuses to list the programs in
+var historyStatuses = []string{"saved", "failed", "blacklisted", "expired", "pending"}
+
+// newHistoryCommand lists the programs in the history
+func newHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the programs in the history",
+		Args:  cobra.NoArgs,
+	}
+	status := cmd.Flags().String("status", "", "list only the programs in the status: "+strings.Join(historyStatuses, ", ")+".")
+	asJSON := cmd.Flags().Bool("json", false, "print the records in JSON.")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(historyStatuses, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		history, err := radicron.NewHistory()
+		if err != nil {
+			return err
+		}
+		records := filterHistory(history, *status, time.Now())
+		if *asJSON {
+			return printJSON(os.Stdout, records)
+		}
+		for _, r := range records {
+			fmt.Printf("[%s]%s (%s) %s %s\n", r.StationID, r.Title, r.Ft, historyStatus(r, time.Now()), r.Path)
+		}
+		return nil
+	}
+	cmd.AddCommand(newHistoryShowCommand(), newHistorySeriesCommand(), newHistoryMigrateCommand())
+	return cmd
+}
+
+// newHistoryShowCommand prints the record and the timeline of the program with the id
+func newHistoryShowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Print the record and the timeline of the program",
+		Args:  cobra.ExactArgs(1),
+	}
+	asJSON := cmd.Flags().Bool("json", false, "print the record and the events in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return showHistory(args[0], *asJSON)
+	}
+	return cmd
+}
+
+// showHistory prints the record and the timeline of the program with the id
+func showHistory(id string, asJSON bool) error {
+	if err := loadConfig(configFile); err != nil {
+		return err
+	}
+	history, err := radicron.NewHistory()
+	if err != nil {
+		return err
+	}
+	path, err := radicron.EventLogPath()
+	if err != nil {
+		return err
+	}
+	events, err := radicron.LoadEvents(path, id, 0)
+	if err != nil {
+		return err
+	}
+	r, ok := history.Records[id]
+	if !ok && len(events) == 0 {
+		return fmt.Errorf("no program %s in the history", id)
+	}
+	if asJSON {
+		return printJSON(os.Stdout, map[string]any{"record": r, "events": events})
+	}
+	printTimeline(os.Stdout, r, events, time.Now())
+	return nil
+}
+
+// newHistorySeriesCommand lists the episodes of the followed series
+func newHistorySeriesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "series",
+		Short: "List the episodes of the followed series",
+		Args:  cobra.NoArgs,
+	}
+	asJSON := cmd.Flags().Bool("json", false, "print the series in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		history, err := radicron.NewHistory()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(os.Stdout, history.Series)
+		}
+		printSeries(os.Stdout, history, time.Now())
+		return nil
+	}
+	return cmd
+}
+
+// printSeries writes each series with the status of the episodes
+func printSeries(w io.Writer, history *radicron.History, t time.Time) {
+	keys := make([]string, 0, len(history.Series))
+	for key := range history.Series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := history.Series[key]
+		fmt.Fprintf(w, "[%s]%s\n", s.StationID, s.Title)
+		for _, id := range s.IDs() {
+			status, ft, path := "pending", "", ""
+			if r, ok := history.Records[id]; ok {
+				status, ft, path = historyStatus(r, t), r.Ft, r.Path
+			}
+			fmt.Fprintf(w, "  #%d %s (%s) %s %s\n", s.Episodes[id], id, ft, status, path)
+		}
+	}
+}
+
+// printTimeline writes the record and its events
+func printTimeline(w io.Writer, r *radicron.HistoryRecord, events []*radicron.Event, t time.Time) {
+	if r != nil {
+		fmt.Fprintf(w, "[%s]%s (%s) %s %s\n", r.StationID, r.Title, r.Ft, historyStatus(r, t), r.Path)
+		if r.LastError != "" {
+			fmt.Fprintf(w, "last error (%d failures): %s\n", r.Failures, r.LastError)
+		}
+	}
+	for _, e := range events {
+		fmt.Fprintf(w, "%s %-9s %s\n", e.Time.Format(radicron.EventDatetimeLayout), e.Type, e.Message)
+	}
+}
+
+// newHistoryMigrateCommand upgrades the history to the format of this release
+func newHistoryMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the history to the format of this release",
+		Args:  cobra.NoArgs,
+	}
+	dryRun := cmd.Flags().Bool("dry-run", false, "print the pending migrations without applying them.")
+	asJSON := cmd.Flags().Bool("json", false, "print the migrations in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return migrateHistory(*dryRun, *asJSON)
+	}
+	return cmd
+}
+
+// historyMigrationJSON is a migration of the history in JSON
+type historyMigrationJSON struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+}
+
+// historyMigrationReport is the result of history migrate in JSON
+type historyMigrationReport struct {
+	// Version of the history in this release
+	Version    int                     `json:"version"`
+	Migrations []*historyMigrationJSON `json:"migrations"`
+	Applied    bool                    `json:"applied"`
+}
+
+// migrateHistory prints the pending migrations of the history, and applies them unless dryRun
+func migrateHistory(dryRun, asJSON bool) error {
+	if err := loadConfig(configFile); err != nil {
+		return err
+	}
+	path, err := radicron.HistoryPath()
+	if err != nil {
+		return err
+	}
+	pending, err := radicron.PendingHistoryMigrations(path)
+	if err != nil {
+		return err
+	}
+	report := &historyMigrationReport{Version: radicron.HistoryVersion, Migrations: []*historyMigrationJSON{}}
+	for _, m := range pending {
+		report.Migrations = append(report.Migrations, &historyMigrationJSON{Version: m.Version, Description: m.Description})
+	}
+	if len(pending) > 0 && !dryRun {
+		// migrate on loading
+		if _, err = radicron.LoadHistory(path); err != nil {
+			return err
+		}
+		report.Applied = true
+	}
+	if asJSON {
+		return printJSON(os.Stdout, report)
+	}
+
+	if len(pending) == 0 {
+		fmt.Printf("the history is up to date (v%d)\n", radicron.HistoryVersion)
+		return nil
+	}
+	for _, m := range report.Migrations {
+		fmt.Printf("v%d: %s\n", m.Version, m.Description)
+	}
+	if report.Applied {
+		fmt.Printf("migrated the history to v%d\n", radicron.HistoryVersion)
+	}
+	return nil
+}
+
+// filterHistory returns the records in the status, or all the records if empty, sorted by the start time
+func filterHistory(history *radicron.History, status string, t time.Time) []*radicron.HistoryRecord {
+	records := []*radicron.HistoryRecord{}
+	for _, r := range history.Records {
+		if status == "" || historyStatus(r, t) == status {
+			records = append(records, r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Ft != records[j].Ft {
+			return records[i].Ft < records[j].Ft
+		}
+		return records[i].StationID < records[j].StationID
+	})
+	return records
+}
+
+// historyStatus summarizes the record at t
+func historyStatus(r *radicron.HistoryRecord, t time.Time) string {
+	switch {
+	case r.Path != "":
+		return "saved"
+	case r.Expired:
+		return "expired"
+	case r.BlacklistedUntil != nil && r.BlacklistedUntil.After(t):
+		return "blacklisted"
+	case r.Failures > 0:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+// printJSON writes v in the indented JSON
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
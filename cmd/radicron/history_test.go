@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/iomz/radicron"
+)
+
+func TestFilterHistory(t *testing.T) {
+	now := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	history := &radicron.History{Records: map[string]*radicron.HistoryRecord{
+		"saved":       {ID: "saved", StationID: "FMT", Ft: "20230605130000", Path: "/downloads/saved.aac"},
+		"expired":     {ID: "expired", StationID: "FMT", Ft: "20230601130000", Expired: true},
+		"blacklisted": {ID: "blacklisted", StationID: "TBS", Ft: "20230605130000", Failures: 3, BlacklistedUntil: &later},
+		"failed":      {ID: "failed", StationID: "FMT", Ft: "20230606130000", Failures: 3, BlacklistedUntil: &earlier},
+		"pending":     {ID: "pending", StationID: "FMT", Ft: "20230607130000"},
+	}}
+
+	var historytests = []struct {
+		status string
+		want   []string
+	}{
+		{"", []string{"expired", "saved", "blacklisted", "failed", "pending"}},
+		{"saved", []string{"saved"}},
+		{"expired", []string{"expired"}},
+		{"blacklisted", []string{"blacklisted"}},
+		{"failed", []string{"failed"}},
+		{"pending", []string{"pending"}},
+		{"unknown", []string{}},
+	}
+	for _, tt := range historytests {
+		t.Run(tt.status, func(t *testing.T) {
+			records := filterHistory(history, tt.status, now)
+			got := []string{}
+			for _, r := range records {
+				got = append(got, r.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("filterHistory(%q) => %v, want %v", tt.status, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("filterHistory(%q) => %v, want %v", tt.status, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPrintTimeline(t *testing.T) {
+	now := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+	r := &radicron.HistoryRecord{ID: "12345", StationID: "FMT", Ft: "20230605130000", Title: "Title", Failures: 1, LastError: "lack of aac files"}
+	events := []*radicron.Event{
+		{Time: now, Type: radicron.EventStarted},
+		{Time: now.Add(time.Minute), Type: radicron.EventFailed, Message: "lack of aac files"},
+	}
+	var timelinetests = []struct {
+		name   string
+		record *radicron.HistoryRecord
+		want   string
+	}{
+		{"record", r, "[FMT]Title (20230605130000) failed \n" +
+			"last error (1 failures): lack of aac files\n" +
+			"2023-06-12 00:00:00 started   \n" +
+			"2023-06-12 00:01:00 failed    lack of aac files\n"},
+		{"events only", nil, "2023-06-12 00:00:00 started   \n" +
+			"2023-06-12 00:01:00 failed    lack of aac files\n"},
+	}
+	for _, tt := range timelinetests {
+		var buf bytes.Buffer
+		printTimeline(&buf, tt.record, events, now)
+		if buf.String() != tt.want {
+			t.Errorf("printTimeline(%s) => %q, want %q", tt.name, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestPrintSeries(t *testing.T) {
+	now := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+	history := &radicron.History{
+		Records: map[string]*radicron.HistoryRecord{
+			"1": {ID: "1", StationID: "FMT", Ft: "20230529130000", Path: "/downloads/ep001.aac"},
+		},
+		Series: map[string]*radicron.Series{
+			"FMT/Title": {StationID: "FMT", Title: "Title", Episodes: map[string]int{"2": 2, "1": 1}},
+		},
+	}
+	want := "[FMT]Title\n" +
+		"  #1 1 (20230529130000) saved /downloads/ep001.aac\n" +
+		"  #2 2 () pending \n"
+	var buf bytes.Buffer
+	printSeries(&buf, history, now)
+	if buf.String() != want {
+		t.Errorf("printSeries => %q, want %q", buf.String(), want)
+	}
+}
@@ -1,31 +1,84 @@
 package main
 
 import (
+	"bufio"
 	"context"
-	"flag"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"runtime/debug"
+	"reflect"
+	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/yyoshiki41/go-radiko"
 	"github.com/yyoshiki41/radigo"
 )
 
-// reload config to set a context and returns Rules
-func reload(ctx context.Context, filename string) (radicron.Rules, error) {
-	// update CurrentTime
-	radicron.CurrentTime = time.Now().In(radicron.Location)
+// lease to record by only one of the instances sharing RADICRON_HOME, if opted in
+var lease *radicron.Lease
 
-	// init Rules
-	rules := radicron.Rules{}
+// dryRun to report the programs to be downloaded without downloading or writing anything
+var dryRun bool
+
+// queue to leave the downloads to the workers if dispatching
+var queue radicron.JobQueue
+
+// instanceLock on the output dir not to run two instances on it, e.g., the overlapping cron jobs
+var instanceLock *radicron.FileLock
+
+// lockInstance takes the lock on the output dir unless sharing it under the lease or in the dry-run
+func lockInstance(conf string) error {
+	if lease != nil || dryRun || instanceLock != nil {
+		return nil
+	}
+	if err := loadConfig(conf); err != nil {
+		return err
+	}
+	dir, err := radicron.DownloadDir()
+	if err != nil {
+		return err
+	}
+	l, err := radicron.LockDir(dir)
+	if err != nil {
+		return err
+	}
+	instanceLock = l
+	return nil
+}
+
+// startLease acquires the lease and keeps renewing it
+func startLease(ttl time.Duration) error {
+	l, err := radicron.NewLease(ttl)
+	if err != nil {
+		return err
+	}
+	if _, err = l.Acquire(); err != nil {
+		return err
+	}
+	lease = l
+	go lease.Keep(context.Background())
+	return nil
+}
+
+// releaseLease lets the other instance take over right away
+func releaseLease() {
+	if lease == nil {
+		return
+	}
+	if err := lease.Release(); err != nil {
+		log.Printf("failed to release the lease: %s", err)
+	}
+}
+
+// loadConfig reads the config file into viper
+func loadConfig(filename string) error {
 	cwd, _ := os.Getwd()
 
 	// check ${RADICRON_HOME}
@@ -37,7 +90,7 @@ func reload(ctx context.Context, filename string) (radicron.Rules, error) {
 	if filename != "config.yml" && filename != "config.toml" {
 		configPath, err := filepath.Abs(filename)
 		if err != nil {
-			return rules, err
+			return err
 		}
 		viper.SetConfigFile(configPath)
 	} else {
@@ -47,15 +100,34 @@ func reload(ctx context.Context, filename string) (radicron.Rules, error) {
 
 	// read the config file
 	if err := viper.ReadInConfig(); err != nil {
-		return rules, fmt.Errorf("error reading config: %s", err)
+		return fmt.Errorf("error reading config: %s", err)
 	}
+	radicron.SetDownloadDir(viper.GetString("output-dir"))
+	return nil
+}
 
-	// set the default area_id
-	currentAreaID, err := radiko.AreaID()
-	if err != nil {
-		return rules, fmt.Errorf("error getting area-id: %s", err)
+// reload config to set a context and returns Rules
+func reload(ctx context.Context, filename string) (radicron.Rules, error) {
+	// update CurrentTime
+	radicron.CurrentTime = time.Now().In(radicron.Location)
+
+	// init Rules
+	rules := radicron.Rules{}
+
+	// read the config file
+	if err := loadConfig(filename); err != nil {
+		return rules, err
 	}
-	viper.SetDefault("area-id", currentAreaID)
+
+	// set the default availability-delay
+	viper.SetDefault("availability-delay", radicron.DefaultAvailabilityDelay)
+	// set the default live-boot-ahead
+	viper.SetDefault("live-boot-ahead", radicron.DefaultLiveBootAhead)
+	// set the default blacklist
+	viper.SetDefault("blacklist-expiry", radicron.DefaultBlacklistExpiry)
+	viper.SetDefault("blacklist-threshold", radicron.DefaultBlacklistThreshold)
+	// set the default scan-interval
+	viper.SetDefault("scan-interval", radicron.DefaultScanInterval)
 	// set the default extra stations
 	viper.SetDefault("extra-stations", []string{})
 	// set the default ignore stations
@@ -72,8 +144,11 @@ func reload(ctx context.Context, filename string) (radicron.Rules, error) {
 		fileFormat != radigo.AudioFormatMP3 {
 		return rules, fmt.Errorf("unsupported audio format: %s", fileFormat)
 	}
-	// load the available station for AreaID
-	areaID := viper.GetString("area-id")
+	// load the available station for AreaID, detected unless overridden
+	areaID, err := resolveAreaID(ctx, viper.GetString("area-id"))
+	if err != nil {
+		return rules, err
+	}
 
 	// extra/ignore stations
 	extraStations := viper.GetStringSlice("extra-stations")
@@ -81,24 +156,223 @@ func reload(ctx context.Context, filename string) (radicron.Rules, error) {
 
 	minimumOutputSize := viper.GetInt64("minimum-output-size")
 
+	// the delay until the timefree becomes available
+	availabilityDelay, err := time.ParseDuration(viper.GetString("availability-delay"))
+	if err != nil {
+		return rules, fmt.Errorf("invalid availability-delay: %s", err)
+	}
+
+	// prepare the live captures before the start
+	liveBootAhead, err := time.ParseDuration(viper.GetString("live-boot-ahead"))
+	if err != nil || liveBootAhead < 0 {
+		return rules, fmt.Errorf("invalid live-boot-ahead: %s", viper.GetString("live-boot-ahead"))
+	}
+
+	// blacklist the programs failed repeatedly
+	blacklistExpiry, err := time.ParseDuration(viper.GetString("blacklist-expiry"))
+	if err != nil {
+		return rules, fmt.Errorf("invalid blacklist-expiry: %s", err)
+	}
+	// scan the guide again at the latest
+	scanInterval, err := time.ParseDuration(viper.GetString("scan-interval"))
+	if err != nil || scanInterval <= 0 {
+		return rules, fmt.Errorf("invalid scan-interval: %s", viper.GetString("scan-interval"))
+	}
+
+	// keep the tmp files of the failed programs
+	keepFailedTmp := time.Duration(0)
+	if s := viper.GetString("keep-failed-tmp"); s != "" {
+		if keepFailedTmp, err = time.ParseDuration(s); err != nil || keepFailedTmp < 0 {
+			return rules, fmt.Errorf("invalid keep-failed-tmp: %s", s)
+		}
+	}
+
+	history, err := radicron.NewHistory()
+	if err != nil {
+		return rules, fmt.Errorf("error loading the history: %s", err)
+	}
+
+	// log the lifecycle of the programs for the timeline
+	events, err := radicron.NewEventLog()
+	if err != nil {
+		return rules, fmt.Errorf("error preparing the event log: %s", err)
+	}
+
+	// remember the matched programs until available
+	upcoming, err := radicron.NewUpcoming()
+	if err != nil {
+		return rules, fmt.Errorf("error loading the upcoming programs: %s", err)
+	}
+
+	// archive the fetched programs
+	var guideArchive *radicron.GuideArchive
+	if viper.GetBool("archive-guide") {
+		guideArchive, err = radicron.NewGuideArchive()
+		if err != nil {
+			return rules, fmt.Errorf("error preparing the guide archive: %s", err)
+		}
+	}
+
+	// hooks for the matching and the naming
+	var script *radicron.Script
+	if path := viper.GetString("script"); path != "" {
+		script, err = radicron.LoadScript(path)
+		if err != nil {
+			return rules, err
+		}
+	}
+
+	// retry policy for the segments
+	retry := radicron.NewRetryPolicy()
+	if err = viper.UnmarshalKey("retry", retry); err != nil {
+		return rules, fmt.Errorf("error reading the retry policy: %s", err)
+	}
+	if retry.Attempts < 1 {
+		return rules, fmt.Errorf("invalid retry attempts: %d", retry.Attempts)
+	}
+
+	// concurrency of the segment downloads
+	concurrency := radicron.NewConcurrency()
+	if err = viper.UnmarshalKey("concurrency", concurrency); err != nil {
+		return rules, fmt.Errorf("error reading the concurrency: %s", err)
+	}
+	// override with the environment variables and the flags
+	if err = tuning.apply(concurrency, retry); err != nil {
+		return rules, err
+	}
+	if err = concurrency.Validate(); err != nil {
+		return rules, err
+	}
+	radicron.SetConcurrency(concurrency)
+	// pause the segment downloads while the post-processing lags behind
+	viper.SetDefault("post-process-backlog", radicron.DefaultPostProcessBacklog)
+	postProcessBacklog := viper.GetInt("post-process-backlog")
+	if postProcessBacklog < 0 {
+		return rules, fmt.Errorf("invalid post-process-backlog: %d", postProcessBacklog)
+	}
+	radicron.SetPostProcessBacklog(postProcessBacklog)
+
+	// header profiles
+	headerProfiles := radicron.HeaderProfiles{}
+	if err = viper.UnmarshalKey("header-profiles", &headerProfiles); err != nil {
+		return rules, fmt.Errorf("error reading the header profiles: %s", err)
+	}
+
+	// the external plugins
+	plugins := radicron.Plugins{}
+	if err = viper.UnmarshalKey("plugins", &plugins); err != nil {
+		return rules, fmt.Errorf("error reading the plugins: %s", err)
+	}
+	if err = plugins.Validate(); err != nil {
+		return rules, err
+	}
+	providers := []radicron.Provider{}
+	for _, p := range plugins.Of(radicron.PluginProvider) {
+		providers = append(providers, p)
+	}
+	storages := []radicron.Storage{}
+	for _, p := range plugins.Of(radicron.PluginStorage) {
+		storages = append(storages, p)
+	}
+	for _, p := range plugins.Of(radicron.PluginNotifier) {
+		events.Notifiers = append(events.Notifiers, p)
+	}
+
+	// the enrichers of the Go packages, the commands, and the plugins
+	enrichers := radicron.RegisteredEnrichers()
+	for _, command := range viper.GetStringSlice("enrichers") {
+		enrichers = append(enrichers, &radicron.CommandEnricher{Command: command})
+	}
+	for _, p := range plugins.Of(radicron.PluginEnricher) {
+		enrichers = append(enrichers, p)
+	}
+
+	// per-station settings
+	stationSettings := radicron.StationSettings{}
+	for stationID := range viper.GetStringMap("stations") {
+		setting := &radicron.StationSetting{}
+		err = viper.UnmarshalKey(fmt.Sprintf("stations.%s", stationID), setting)
+		if err != nil {
+			return rules, fmt.Errorf("error reading the station setting: %s", err)
+		}
+		if setting.HeaderProfile != "" {
+			setting.HeaderProfile = strings.ToLower(setting.HeaderProfile)
+			if _, ok := headerProfiles[setting.HeaderProfile]; !ok {
+				return rules, fmt.Errorf("unknown header-profile for %s: %s", stationID, setting.HeaderProfile)
+			}
+		}
+		// viper keys are case-insensitive but station-ids are upper case
+		stationSettings[strings.ToUpper(stationID)] = setting
+	}
+
+	// pause the scheduling in the blackouts
+	blackouts, err := loadBlackouts()
+	if err != nil {
+		return rules, err
+	}
+	radicron.SetBlackouts(blackouts)
+
+	// watch the news stations for the emergency programming
+	emergency, err := loadEmergency()
+	if err != nil {
+		return rules, err
+	}
+
 	// save the asset in the current context
 	asset := radicron.GetAsset(ctx)
+	asset.AvailabilityDelay = availabilityDelay
+	asset.BlacklistExpiry = blacklistExpiry
+	asset.BlacklistThreshold = viper.GetInt("blacklist-threshold")
+	asset.DirectWrite = viper.GetBool("direct-write")
+	asset.Emergency = emergency
+	asset.Enrichers = enrichers
+	asset.EpisodeTitle = viper.GetString("episode-title")
+	asset.Events = events
+	asset.ExplicitDir = viper.GetString("explicit-dir")
+	asset.GaplessPriming = viper.GetInt("gapless-priming")
+	asset.GuideArchive = guideArchive
+	asset.History = history
+	asset.HeaderProfiles = headerProfiles
+	asset.KeepDuplicates = viper.GetBool("keep-duplicates")
+	asset.KeepFailedTmp = keepFailedTmp
+	asset.LenientPlaylist = viper.GetBool("lenient-playlist")
+	asset.LiveBootAhead = liveBootAhead
+	asset.MetadataOnly = viper.GetBool("metadata-only")
 	asset.OutputFormat = fileFormat
+	asset.Providers = providers
+	asset.Report = viper.GetBool("report")
+	asset.Retry = retry
+	asset.ScanInterval = scanInterval
+	asset.Script = script
+	asset.SegmentFailureThreshold = viper.GetFloat64("segment-failure-threshold")
+	asset.StationSettings = stationSettings
+	asset.Storages = storages
+	asset.StrictADTS = viper.GetBool("strict-adts")
+	asset.Upcoming = upcoming
 	asset.MinimumOutputSize = minimumOutputSize * radicron.Kilobytes * radicron.Kilobytes
+	// log in to radiko premium for the stations outside the area
+	if viper.IsSet("premium") {
+		if asset.Premium, err = loginPremium(ctx, asset.DefaultClient, areaID); err != nil {
+			return rules, err
+		}
+	}
 	asset.LoadAvailableStations(areaID)
 	asset.AddExtraStations(extraStations)
+	favoriteRules := syncFavorites(ctx, asset)
 	asset.RemoveIgnoreStations(ignoreStations)
+	if emergency != nil {
+		asset.AddExtraStations(emergency.Stations)
+	}
 
 	// load rules from the file
-	for name := range viper.GetStringMap("rules") {
-		rule := &radicron.Rule{}
-		err := viper.UnmarshalKey(fmt.Sprintf("rules.%s", name), rule)
-		if err != nil {
-			return rules, fmt.Errorf("error reading the rule: %s", err)
-		}
-		rule.SetName(name)
+	rules, err = loadRules()
+	if err != nil {
+		return rules, err
+	}
+	rules = appendFavoriteRules(rules, favoriteRules)
+	for _, rule := range rules {
 		// add the station-id to look up if not exists
-		if rule.HasStationID() {
+		if rule.HasStationID() && !rule.IsRadiru() {
 			isNewStation := true
 			for _, as := range asset.AvailableStations {
 				if as == rule.StationID {
@@ -110,104 +384,940 @@ func reload(ctx context.Context, filename string) (radicron.Rules, error) {
 				asset.AddExtraStations([]string{rule.StationID})
 			}
 		}
+	}
+
+	// the second source for the NHK programs
+	if len(rules.RadiruStations()) > 0 {
+		if err = loadRadiru(ctx, asset); err != nil {
+			return rules, err
+		}
+	}
+	return rules, nil
+}
+
+// loadRadiru loads radiru in the radiru-area for the asset
+func loadRadiru(ctx context.Context, asset *radicron.Asset) error {
+	viper.SetDefault("radiru-area", radicron.DefaultRadiruArea)
+	radiru, err := radicron.LoadRadiru(ctx, viper.GetString("radiru-area"))
+	if err != nil {
+		return fmt.Errorf("error loading radiru: %s", err)
+	}
+	asset.Radiru = radiru
+	return nil
+}
+
+// loadBlackouts returns the blackouts in the config
+func loadBlackouts() (radicron.Blackouts, error) {
+	blackouts := radicron.Blackouts{}
+	if err := viper.UnmarshalKey("blackouts", &blackouts, viper.DecodeHook(timeToString)); err != nil {
+		return nil, fmt.Errorf("error reading the blackouts: %s", err)
+	}
+	return blackouts, blackouts.Validate()
+}
+
+// timeToString decodes the dates and the times parsed by YAML back to strings
+func timeToString(_, to reflect.Type, data any) (any, error) {
+	t, ok := data.(time.Time)
+	if !ok || to.Kind() != reflect.String {
+		return data, nil
+	}
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format(radicron.BlackoutDateLayout), nil
+	}
+	return t.Format(radicron.BlackoutDatetimeLayout), nil
+}
+
+// loadEmergency returns the news stations to watch for the emergency programming, or nil if not configured
+func loadEmergency() (*radicron.Emergency, error) {
+	if !viper.IsSet("emergency") {
+		return nil, nil
+	}
+	emergency := &radicron.Emergency{}
+	if err := viper.UnmarshalKey("emergency", emergency); err != nil {
+		return nil, fmt.Errorf("error reading the emergency: %s", err)
+	}
+	if len(emergency.Stations) == 0 {
+		return nil, errors.New("emergency.stations is required to watch for the emergency programming")
+	}
+	for i, s := range emergency.Stations {
+		emergency.Stations[i] = strings.ToUpper(s)
+	}
+	return emergency, nil
+}
+
+// loadRules returns the rules in the config
+func loadRules() (radicron.Rules, error) {
+	rules := radicron.Rules{}
+	for name := range viper.GetStringMap("rules") {
+		rule := &radicron.Rule{}
+		err := viper.UnmarshalKey(fmt.Sprintf("rules.%s", name), rule)
+		if err != nil {
+			return rules, fmt.Errorf("error reading the rule: %s", err)
+		}
+		rule.SetName(name)
 		rules = append(rules, rule)
 	}
 	return rules, nil
 }
 
+// syncFavorites adds the favorite stations of the premium member if premium.favorites,
+// and returns the rules of the favorite programs if premium.favorite-rules
+func syncFavorites(ctx context.Context, asset *radicron.Asset) radicron.Rules {
+	stations, generate := viper.GetBool("premium.favorites"), viper.GetBool("premium.favorite-rules")
+	if asset.Premium == nil || (!stations && !generate) {
+		return nil
+	}
+	favorites, err := asset.Premium.Favorites(ctx, asset.DefaultClient)
+	if err != nil {
+		log.Printf("failed to sync the favorites: %s", err)
+		return nil
+	}
+	if stations {
+		asset.AddExtraStations(favorites.StationIDs())
+	}
+	if !generate {
+		return nil
+	}
+	return favorites.Rules()
+}
+
+// appendFavoriteRules appends the rules of the favorites not named in the config
+func appendFavoriteRules(rules, favoriteRules radicron.Rules) radicron.Rules {
+	names := map[string]bool{}
+	for _, r := range rules {
+		names[r.Name] = true
+	}
+	added := 0
+	for _, r := range favoriteRules {
+		if names[r.Name] {
+			continue
+		}
+		rules = append(rules, r)
+		added++
+	}
+	if added > 0 {
+		log.Printf("added %d rules from the favorites", added)
+	}
+	return rules
+}
+
+// resolveAreaID returns the override if any, or the area of this host detected by radiko
+func resolveAreaID(ctx context.Context, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	areaID, err := radicron.DetectAreaID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect the area, set area-id to override: %s", err)
+	}
+	return areaID, nil
+}
+
+// loginPremium logs in to radiko premium with the mail and the password in the config
+func loginPremium(ctx context.Context, client *radiko.Client, areaID string) (*radicron.Premium, error) {
+	mail, err := radicron.LookupSecret(ctx, "premium-mail", viper.GetString("premium.mail"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading premium.mail: %s", err)
+	}
+	password, err := radicron.LookupSecret(ctx, "premium-password", viper.GetString("premium.password"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading premium.password: %s", err)
+	}
+	if mail == "" || password == "" {
+		return nil, errors.New("premium.mail and premium.password are required to log in to radiko premium")
+	}
+	premium := &radicron.Premium{AreaID: areaID, Mail: mail, Password: password}
+	if err = premium.Login(ctx, client); err != nil {
+		return nil, fmt.Errorf("error logging in to radiko premium: %s", err)
+	}
+	log.Printf("logged in to radiko premium (area-free: %v)", premium.AreaFree)
+	return premium, nil
+}
+
+// newSummarizer returns the summarizer if opted in
+func newSummarizer(ctx context.Context) (*radicron.Summarizer, error) {
+	if !viper.GetBool("summarize") {
+		return nil, nil
+	}
+	viper.SetDefault("summarize-model", radicron.DefaultSummarizeModel)
+	viper.SetDefault("summarize-prompt", radicron.DefaultSummarizePrompt)
+	endpoint := viper.GetString("summarize-endpoint")
+	if endpoint == "" {
+		return nil, errors.New("summarize-endpoint is required to summarize")
+	}
+	apiKey, err := radicron.LookupSecret(ctx, "summarize-api-key", viper.GetString("summarize-api-key"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading summarize-api-key: %s", err)
+	}
+	radicron.RegisterSecret(apiKey)
+	return &radicron.Summarizer{
+		APIKey:   apiKey,
+		Endpoint: endpoint,
+		Model:    viper.GetString("summarize-model"),
+		Prompt:   viper.GetString("summarize-prompt"),
+	}, nil
+}
+
+// manageListeners adds or revokes a listener of the podcast feed
+func manageListeners(add, revoke, feedURL string) error {
+	server, err := radicron.NewServer(radicron.DefaultFeedTitle, feedURL)
+	if err != nil {
+		return err
+	}
+	if revoke != "" {
+		if err = server.Listeners.Revoke(revoke); err != nil {
+			return err
+		}
+		fmt.Printf("revoked the feed token for %s\n", revoke)
+	}
+	if add != "" {
+		token, err := server.Listeners.Add(add)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("feed token for %s: %s\n", add, token)
+		if feedURL != "" {
+			fmt.Printf("%s/feed.xml?token=%s\n", server.BaseURL, token)
+			fmt.Printf("%s/feeds.opml?token=%s\n", server.BaseURL, token)
+		}
+	}
+	return nil
+}
+
+// serve the podcast feed
+func serve(addr, feedURL, episodeTitle string) {
+	server, err := radicron.NewServer(radicron.DefaultFeedTitle, feedURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.EpisodeTitle = episodeTitle
+	log.Printf("serving the podcast feed on %s", addr)
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: radicron.ReadHeaderTimeoutSeconds * time.Second,
+	}
+	log.Fatal(httpServer.ListenAndServe())
+}
+
+// newServeCommand only serves the library, leaving the recording to another process
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the podcast feed of the library without recording",
+		Args:  cobra.NoArgs,
+	}
+	fs := cmd.Flags()
+	addr := fs.String("addr", ":8080", "serve the podcast feed on the address.")
+	feedURL := fs.String("feed-url", "", "the public base URL of the podcast feed.")
+	adminAddr := fs.String("admin", "", "serve the admin endpoints (pprof) on the address, e.g., localhost:6060.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		go serve(*addr, *feedURL, viper.GetString("episode-title"))
+		if *adminAddr != "" {
+			go serveAdmin(*adminAddr)
+		}
+		waitSignal()
+		log.Println("exiting radicron")
+		return nil
+	}
+	return cmd
+}
+
+// serveAdmin serves the profiling endpoints
+func serveAdmin(addr string) {
+	log.Printf("serving the admin endpoints on %s", addr)
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           radicron.AdminHandler(),
+		ReadHeaderTimeout: radicron.ReadHeaderTimeoutSeconds * time.Second,
+	}
+	log.Fatal(httpServer.ListenAndServe())
+}
+
+// newBundleCommand packages the episodes over a date range into an M4B audiobook
+func newBundleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Package the episodes over a date range into an M4B audiobook",
+		Args:  cobra.NoArgs,
+	}
+	fs := cmd.Flags()
+	query := fs.StringP("query", "q", "", "bundle only the episodes with the title containing this.")
+	stationID := fs.String("station", "", "bundle only the episodes of this station.")
+	from := fs.String("from", "", "bundle the episodes from this date (e.g., 20230605).")
+	to := fs.String("to", "", "bundle the episodes until this date (e.g., 20230611).")
+	output := fs.StringP("output", "o", "", "the M4B file to write (default to <title>_<from>-<to>.m4b).")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	_ = cmd.MarkFlagFilename("output", "m4b")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return bundle(*query, *stationID, *from, *to, *output)
+	}
+	return cmd
+}
+
+// bundle packages the episodes of the station with the title containing query from the date to the date
+func bundle(query, stationID, from, to, output string) error {
+	fromTime, err := time.ParseInLocation(radicron.ArchiveDayLayout, from, radicron.Location)
+	if err != nil {
+		return fmt.Errorf("invalid --from: %s", err)
+	}
+	toTime, err := time.ParseInLocation(radicron.ArchiveDayLayout, to, radicron.Location)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %s", err)
+	}
+	if err = loadConfig(configFile); err != nil {
+		return err
+	}
+
+	dir, err := radicron.DownloadDir()
+	if err != nil {
+		return err
+	}
+	episodes, err := radicron.LoadEpisodes(dir)
+	if err != nil {
+		return err
+	}
+	// include the last day
+	episodes = episodes.Filter(fromTime, toTime.AddDate(0, 0, 1), stationID, query)
+	if len(episodes) == 0 {
+		return errors.New("no episodes found")
+	}
+
+	title := query
+	if title == "" {
+		title = episodes[len(episodes)-1].Title
+	}
+	if output == "" {
+		output = fmt.Sprintf("%s_%s-%s.m4b", title, from, to)
+	}
+	if err = radicron.Bundle(context.Background(), dir, episodes, title, output); err != nil {
+		return err
+	}
+	log.Printf("+%d episodes bundled: %s", len(episodes), output)
+	return nil
+}
+
+// newChaptersCommand writes the chapters derived from the transcripts
+func newChaptersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chapters [file...]",
+		Short: "Write the chapters derived from the transcripts, to all the recorded programs by default",
+	}
+	minLength := cmd.Flags().Duration("min", 0, "the minimum length of a chapter (default "+radicron.DefaultChapterLength+").")
+	asJSON := cmd.Flags().Bool("json", false, "print the chapters written to the files in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return writeChapters(args, *minLength, *asJSON)
+	}
+	return cmd
+}
+
+// chapterJSON is a chapter in JSON, with the start and the end in seconds
+type chapterJSON struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Summary string  `json:"summary"`
+}
+
+// chaptersJSON is the chapters written to the file in JSON
+type chaptersJSON struct {
+	Path     string         `json:"path"`
+	Chapters []*chapterJSON `json:"chapters"`
+}
+
+// writeChapters writes the chapters to the files, or all the recorded programs if none
+func writeChapters(paths []string, minLength time.Duration, asJSON bool) error {
+	if minLength == 0 {
+		minLength, _ = time.ParseDuration(radicron.DefaultChapterLength)
+	}
+
+	// default to all the recorded programs
+	if len(paths) == 0 {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		history, err := radicron.NewHistory()
+		if err != nil {
+			return err
+		}
+		for _, r := range history.Records {
+			if r.Path != "" {
+				paths = append(paths, r.Path)
+			}
+		}
+	}
+
+	written := []*chaptersJSON{}
+	for _, path := range paths {
+		chapters, err := radicron.ChaptersFromTranscript(path, minLength)
+		if err != nil {
+			continue // no transcript yet
+		}
+		if err = radicron.WriteChapters(path, chapters); err != nil {
+			log.Printf("failed to write the chapters to %s: %s", path, err)
+			continue
+		}
+		log.Printf("+%d chapters: %s", len(chapters), path)
+		if asJSON {
+			cj := &chaptersJSON{Path: path, Chapters: []*chapterJSON{}}
+			for _, c := range chapters {
+				cj.Chapters = append(cj.Chapters, &chapterJSON{Start: c.Start.Seconds(), End: c.End.Seconds(), Summary: c.Summary})
+			}
+			written = append(written, cj)
+			continue
+		}
+		for _, c := range chapters {
+			fmt.Printf("  %v %s\n", c.Start, c.Summary)
+		}
+	}
+	if asJSON {
+		return printJSON(os.Stdout, written)
+	}
+	return nil
+}
+
+// newInitCommand writes a starter config and creates ${RADICRON_HOME}
+func newInitCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a starter config and create ${RADICRON_HOME}",
+		Args:  cobra.NoArgs,
+	}
+	force := cmd.Flags().Bool("force", false, "overwrite the existing config.")
+	defaults := cmd.Flags().Bool("defaults", false, "write the starter config without the questions, as when the stdin is not a terminal.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return initHome(configFile, *force, *defaults)
+	}
+	return cmd
+}
+
+// initHome writes the config at conf by the wizard or from the template, and creates ${RADICRON_HOME}
+func initHome(conf string, force, defaults bool) error {
+	if defaults || !isTerminal(os.Stdin) {
+		if err := writeStarterConfig(conf, force); err != nil {
+			return err
+		}
+		log.Printf("wrote the starter config to %s", conf)
+	} else {
+		if err := initWizard(conf, force); err != nil {
+			return err
+		}
+	}
+
+	if os.Getenv(radicron.EnvRadicronHome) == "" {
+		cwd, _ := os.Getwd()
+		os.Setenv(radicron.EnvRadicronHome, filepath.Join(cwd, "radiko"))
+	}
+	downloadDir, err := radicron.DownloadDir()
+	if err != nil {
+		return err
+	}
+	home := os.Getenv(radicron.EnvRadicronHome)
+	for _, dir := range []string{downloadDir, filepath.Join(home, "tmp")} {
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	log.Printf("created %s", home)
+
+	// ffmpeg is required to save the audio
+	if version, err := checkFFmpeg(context.Background()); err != nil {
+		log.Printf("warning: %s", err)
+	} else {
+		log.Printf("found %s", version)
+	}
+	return nil
+}
+
+// initWizard asks the area, the stations, the output, and the first rule to write the config
+func initWizard(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite", path)
+	}
+	detectedArea, err := resolveAreaID(context.Background(), "")
+	if err != nil {
+		log.Printf("failed to detect the area: %s", err)
+		detectedArea = "JP13"
+	}
+	wz := &wizard{r: bufio.NewReader(os.Stdin), w: os.Stdout}
+	answers, err := wz.run(detectedArea, func(areaID string) ([]*stationEntry, error) {
+		client, err := radiko.New("")
+		if err != nil {
+			return nil, err
+		}
+		asset, err := radicron.NewAsset(context.Background(), client)
+		if err != nil {
+			return nil, err
+		}
+		return listStations(asset.Stations, areaID), nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = writeConfigFile(path, answers.render(), force); err != nil {
+		return err
+	}
+	radicron.SetDownloadDir(answers.OutputDir)
+	fmt.Printf("\nWrote %s; check it with `radicron -c %s config validate` and try `radicron -c %s --dry-run`.\n", path, path, path)
+	return nil
+}
+
+// writeStarterConfig writes the embedded config template to path
+func writeStarterConfig(path string, force bool) error {
+	return writeConfigFile(path, radicron.ConfigTemplate, force)
+}
+
+// writeConfigFile writes the config to path, unless it exists without force
+func writeConfigFile(path string, blob []byte, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite", path)
+	} else if err != nil {
+		return err
+	}
+	if _, err = f.Write(blob); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newSearchArchiveCommand searches the recorded programs and the transcripts
+func newSearchArchiveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search-archive <query>",
+		Short: "Search the recorded programs and the transcripts",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	asJSON := cmd.Flags().Bool("json", false, "print the results in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		history, err := radicron.NewHistory()
+		if err != nil {
+			return err
+		}
+		results := history.Search(strings.Join(args, " "))
+		if *asJSON {
+			return printJSON(os.Stdout, results)
+		}
+		for _, r := range results {
+			fmt.Printf("[%s]%s (%s) %s: %s\n  %s\n", r.Record.StationID, r.Record.Title, r.Record.Ft, r.Field, r.Snippet, r.Record.Path)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// applyRules sets the options of the rules matching the program
+func applyRules(matching radicron.Rules, p *radicron.Prog) {
+	p.SkipRerun = matching.SkipRerun()
+	p.Omnibus = matching.Omnibus()
+	p.Artwork = matching.Artwork()
+	p.Explicit = matching.Explicit()
+	p.ID3Version = matching.ID3Version()
+	p.Follow = matching.Follow()
+	p.LiveFallback = matching.LiveFallback()
+	p.Podcast = matching.Podcast()
+	p.PadBefore, p.PadAfter = matching.Padding()
+}
+
 // run forever
-func run(wg *sync.WaitGroup, configFileName string) {
+// run scans the guide and downloads the matched programs,
+// again and again in the daemon mode, reloading the config on SIGHUP
+func run(wg *sync.WaitGroup, configFileName string, daemon bool) {
 	client, err := radiko.New("")
 	if err != nil {
 		log.Fatal(err)
 	}
-	ck := radicron.ContextKey("asset")
+	var hup <-chan os.Signal
+	if daemon {
+		hup = notifyReload()
+	}
+	var reloaded context.Context
+	var reloadedRules radicron.Rules
+	var sessions *radicron.AuthSessions
 	for {
-		// replenish asset
-		asset, err := radicron.NewAsset(client)
-		if err != nil {
-			log.Fatal(err)
+		// stand by while the other instance records
+		if lease != nil && !lease.Held() {
+			if !daemon {
+				log.Println("the lease is held by another instance – skipping the scan")
+				return
+			}
+			log.Println("standing by while the lease is held by another instance")
+			if err := lease.Wait(context.Background()); err != nil {
+				log.Fatal(err)
+			}
+			log.Println("acquired the lease")
 		}
-		// new context with the asset
-		ctx := context.WithValue(context.Background(), ck, asset)
-		// reload config params
-		rules, err := reload(ctx, configFileName)
-		if err != nil {
-			log.Fatal(err)
+
+		// the config reloaded while sleeping
+		ctx, rules := reloaded, reloadedRules
+		reloaded = nil
+		if ctx == nil {
+			if ctx, rules, err = newScan(client, configFileName); err != nil {
+				log.Fatal(err)
+			}
+		}
+		asset := radicron.GetAsset(ctx)
+		// keep the tokens of the areas across the scans
+		if sessions != nil {
+			asset.AuthSessions = sessions
 		}
+		sessions = asset.AuthSessions
 
-		// check the weekly program for each station
-		for _, stationID := range asset.AvailableStations {
-			if !rules.HasRuleWithoutStationID() && // search all stations
-				!rules.HasRuleForStationID(stationID) { // search this station
-				continue
+		// remove the tmp files of the failed programs after the retention
+		if asset.KeepFailedTmp > 0 && !asset.DryRun {
+			if n, err := radicron.CleanFailedDirs(asset.KeepFailedTmp); err != nil {
+				log.Printf("failed to clean up the failed tmp files: %s", err)
+			} else if n > 0 {
+				log.Printf("removed the tmp files of %d failed programs", n)
 			}
+		}
 
-			// fetch the weekly program
-			weeklyPrograms, err := radicron.FetchWeeklyPrograms(stationID)
-			if err != nil {
-				log.Printf("failed to fetch the %s program: %v", stationID, err)
-				continue
+		// give the jobs of the workers gone away to the others
+		if queue != nil && !asset.DryRun {
+			if n, err := queue.Requeue(radicron.JobClaimTimeoutHours * time.Hour); err != nil {
+				log.Printf("failed to requeue the stale jobs: %s", err)
+			} else if n > 0 {
+				log.Printf("requeued %d stale jobs", n)
 			}
-			log.Printf("checking the %s program", stationID)
+		}
 
-			// check each program
-			for _, p := range weeklyPrograms {
-				if rules.HasMatch(stationID, p) {
-					err = radicron.Download(ctx, wg, p)
-					if err != nil {
-						log.Printf("downlod faild: %s", err)
-					}
-				}
-			} // weeklyPrograms for stationID
-		} // stations
+		// keep the programs due in the blackout to catch up after it
+		if until, ok := radicron.BlackoutUntil(time.Now()); ok {
+			log.Printf("in the blackout until %v – archiving the guide only", until)
+			scan(ctx, wg, rules)
+		} else {
+			scan(ctx, wg, rules, append(resumePending(ctx), dueUpcoming(ctx)...)...)
+		}
+		// the interrupted programs not to download again, e.g., saved meanwhile
+		if asset.Pending != nil {
+			asset.Pending.Discard()
+		}
 
 		// wait for all the downloading jobs
 		log.Println("waiting for all the downloads to complete")
-		wg.Wait()
+		replaced := []*radicron.Asset{}
+		// one waiter for the scan, not to leave one behind on each reload
+		done := waitDone(wg)
+		for waiting := true; waiting; {
+			select {
+			case <-done:
+				waiting = false
+			case <-hup:
+				// scan again with the new rules while the downloads are in flight
+				if ctx, rules, err = rescan(client, configFileName, ctx); err != nil {
+					log.Printf("failed to reload the config: %s", err)
+					continue
+				}
+				replaced = append(replaced, asset)
+				asset = radicron.GetAsset(ctx)
+				scan(ctx, wg, rules)
+				// wait again for the downloads added after all done
+				select {
+				case <-done:
+					done = waitDone(wg)
+				default:
+				}
+			}
+		}
+		// the downloads of the replaced assets may retry later
+		for _, a := range replaced {
+			if a.NextFetchTime != nil && (asset.NextFetchTime == nil || asset.NextFetchTime.After(*a.NextFetchTime)) {
+				asset.NextFetchTime = a.NextFetchTime
+			}
+		}
+		// nothing to post-process
+		if asset.DryRun {
+			return
+		}
+		// leave the rest to the instance taking over
+		if lease != nil && !lease.Held() {
+			continue
+		}
+
+		// summarize the new transcripts if opted in
+		summarizer, err := newSummarizer(ctx)
+		if err != nil {
+			log.Printf("summarization disabled: %s", err)
+		} else if summarizer != nil {
+			n, err := summarizer.SummarizePending(ctx, asset.History)
+			if err != nil {
+				log.Printf("summarization failed: %s", err)
+			}
+			if n > 0 {
+				log.Printf("summarized %d programs", n)
+			}
+		}
+
+		// merge the weekly omnibus
+		if n, err := radicron.MergeOmnibus(ctx, asset.History, radicron.CurrentTime); err != nil {
+			log.Printf("failed to merge the omnibus: %s", err)
+		} else if n > 0 {
+			log.Printf("merged %d omnibus", n)
+		}
+
+		// scan only once
+		if !daemon {
+			return
+		}
 
-		// if the next program is not found, check again 24 hours later
-		if asset.NextFetchTime == nil {
-			oneDayLater := radicron.CurrentTime.Add(radicron.OneDay * time.Hour)
-			asset.NextFetchTime = &oneDayLater
+		// scan again when the next program is available, or at the scan-interval for the guide updates
+		nextScan := radicron.CurrentTime.Add(asset.ScanInterval)
+		if next := asset.Upcoming.Next(); next != nil && (asset.NextFetchTime == nil || asset.NextFetchTime.After(*next)) {
+			asset.NextFetchTime = next
+		}
+		// or at the end of the blackout
+		if until, ok := radicron.BlackoutUntil(time.Now()); ok {
+			asset.NextFetchTime = nil
+			if !until.IsZero() {
+				asset.NextFetchTime = &until
+			}
+		}
+		if asset.NextFetchTime == nil || asset.NextFetchTime.After(nextScan) {
+			asset.NextFetchTime = &nextScan
 		}
 		// sleep
 		log.Printf("fetching completed – sleeping until %v", asset.NextFetchTime)
-		// sleep until the next earliest program to be available
+		// sleep until the next earliest program to be available, or scan again with the new config
 		fetchTimer := time.NewTimer(time.Until(*asset.NextFetchTime))
-		<-fetchTimer.C
+		for sleeping := true; sleeping; {
+			select {
+			case <-fetchTimer.C:
+				sleeping = false
+			case <-radicron.BlackoutChanged():
+				// paused or resumed on the admin API
+				fetchTimer.Stop()
+				sleeping = false
+			case <-hup:
+				log.Println("reloading the config")
+				if reloaded, reloadedRules, err = newScan(client, configFileName); err != nil {
+					log.Printf("failed to reload the config: %s", err)
+					continue
+				}
+				fetchTimer.Stop()
+				sleeping = false
+			}
+		}
+	}
+}
+
+// newScan returns the context with a new asset and the rules from the config
+func newScan(client *radiko.Client, configFileName string) (context.Context, radicron.Rules, error) {
+	asset, err := radicron.NewAsset(context.Background(), client)
+	if err != nil {
+		return nil, nil, err
+	}
+	asset.DryRun = dryRun
+	asset.Queue = queue
+	// journal the downloads unless left to the workers
+	if queue == nil {
+		if asset.Pending, err = radicron.NewPending(); err != nil {
+			return nil, nil, fmt.Errorf("error loading the pending downloads: %s", err)
+		}
 	}
+	// new context with the asset
+	ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+	// reload config params
+	rules, err := reload(ctx, configFileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, rules, nil
+}
+
+// rescan reloads the config for another scan while the downloads of the last one are in flight,
+// sharing the history and the schedules not to download the same programs again
+func rescan(client *radiko.Client, configFileName string, last context.Context) (context.Context, radicron.Rules, error) {
+	log.Println("reloading the config")
+	ctx, rules, err := newScan(client, configFileName)
+	if err != nil {
+		return last, nil, err
+	}
+	asset, lastAsset := radicron.GetAsset(ctx), radicron.GetAsset(last)
+	asset.AuthSessions = lastAsset.AuthSessions
+	asset.History = lastAsset.History
+	asset.Upcoming = lastAsset.Upcoming
+	asset.Pending = lastAsset.Pending
+	asset.Emergency.Inherit(lastAsset.Emergency)
+	asset.Schedules = append(asset.Schedules, lastAsset.Schedules...)
+	return ctx, rules, nil
+}
+
+// dueUpcoming returns the programs remembered until available
+func dueUpcoming(ctx context.Context) []*radicron.Prog {
+	asset := radicron.GetAsset(ctx)
+	if asset.DryRun {
+		return nil
+	}
+	progs, err := asset.Upcoming.Due(radicron.CurrentTime)
+	if err != nil {
+		log.Printf("failed to save the upcoming programs: %s", err)
+	}
+	return progs
+}
+
+// resumePending returns the programs interrupted by a crash or a restart to download again
+func resumePending(ctx context.Context) []*radicron.Prog {
+	asset := radicron.GetAsset(ctx)
+	if asset.Pending == nil || asset.DryRun {
+		return nil
+	}
+	progs, err := asset.Pending.Resume()
+	if err != nil {
+		log.Printf("failed to save the pending downloads: %s", err)
+	}
+	for _, p := range progs {
+		log.Printf("resuming [%s]%s (%s)", p.StationID, p.Title, p.Ft)
+	}
+	return progs
+}
+
+// scan checks the weekly program for each station,
+// and downloads the matched programs with the due ones, expiring first
+func scan(ctx context.Context, wg *sync.WaitGroup, rules radicron.Rules, due ...*radicron.Prog) {
+	asset := radicron.GetAsset(ctx)
+	matched := radicron.Progs(due)
+	for _, stationID := range asset.AvailableStations {
+		if lease != nil && !lease.Held() {
+			break
+		}
+		if !rules.HasRuleWithoutStationID() && !asset.Script.HasMatch() && // search all stations
+			!rules.HasRuleForStationID(stationID) && // search this station
+			!asset.Emergency.Watches(stationID) { // watch this station
+			continue
+		}
+
+		// fetch the weekly program
+		weeklyPrograms, err := radicron.FetchWeeklyPrograms(ctx, stationID)
+		if err != nil {
+			log.Printf("failed to fetch the %s program: %v", stationID, err)
+			continue
+		}
+		matched = append(matched, matchPrograms(asset, rules, stationID, weeklyPrograms)...)
+	} // stations
+
+	// the programs on radiru
+	for _, stationID := range rules.RadiruStations() {
+		if asset.Radiru == nil || (lease != nil && !lease.Held()) {
+			break
+		}
+		progs, err := asset.Radiru.FetchPrograms(ctx, stationID)
+		if err != nil {
+			log.Printf("failed to fetch the %s program: %v", stationID, err)
+			continue
+		}
+		matched = append(matched, matchPrograms(asset, rules, stationID, progs)...)
+	}
+
+	// the same broadcast on the affiliate stations
+	if !asset.KeepDuplicates {
+		matched = matched.Dedup()
+	}
+	// the timefree expires in the order of the start
+	matched.SortByExpiry()
+	// authorize the areas of the programs in parallel, not one by one in the downloads
+	if _, paused := radicron.BlackoutUntil(radicron.CurrentTime); !paused && !asset.DryRun && asset.Queue == nil {
+		if err := asset.AuthSessions.AuthorizeAll(ctx, asset, asset.AuthAreaIDs(matched, radicron.CurrentTime)); err != nil {
+			log.Printf("warning: %s", err)
+		}
+	}
+	for _, p := range matched {
+		if lease != nil && !lease.Held() {
+			log.Println("lost the lease – leaving the rest to another instance")
+			break
+		}
+		if err := radicron.Download(ctx, wg, p); err != nil {
+			log.Printf("downlod faild: %s", err)
+		}
+	}
+}
+
+// matchPrograms returns the programs in the guide of the station matching the rules
+func matchPrograms(asset *radicron.Asset, rules radicron.Rules, stationID string, guide radicron.Progs) radicron.Progs {
+	log.Printf("checking the %s program", stationID)
+	if asset.GuideArchive != nil && !asset.DryRun {
+		if err := asset.GuideArchive.Add(guide); err != nil {
+			log.Printf("failed to archive the %s program: %v", stationID, err)
+		}
+	}
+
+	// warn of the upcoming programs moved or dropped in the guide
+	reportGuideChanges(asset, stationID, guide)
+
+	// check each program
+	matched := radicron.Progs{}
+	for _, p := range rules.MergeConsecutive(stationID, guide) {
+		// match once to read the options from the rules matched
+		matching := rules.Matching(stationID, p)
+		if asset.Script.Match(stationID, p, len(matching) > 0) {
+			// guard against the unexpectedly long programs
+			if skip, warn := matching.Oversized(p); skip {
+				log.Printf("-skip oversized [%s]%s (%s): %v", stationID, p.Title, p.Ft, p.Duration())
+				continue
+			} else if warn {
+				log.Printf("warning: [%s]%s (%s) is %v, over the max-duration", stationID, p.Title, p.Ft, p.Duration())
+			}
+			applyRules(matching, p)
+			matched = append(matched, p)
+		}
+	}
+
+	// the emergency or special programming interrupting the regular programs
+	for _, p := range asset.Emergency.Detect(stationID, guide) {
+		log.Printf("emergency programming [%s]%s (%s)", stationID, p.Title, p.Ft)
+		asset.Events.Add(radicron.EventEmergency, p, "emergency programming in the guide")
+		if !asset.Emergency.Record || matched.At(p.Ft) != nil {
+			continue
+		}
+		matched = append(matched, p)
+	}
+	return matched
+}
+
+// reportGuideChanges logs and notifies the upcoming programs moved or dropped in the guide
+func reportGuideChanges(asset *radicron.Asset, stationID string, guide radicron.Progs) {
+	if asset.Upcoming == nil || asset.DryRun {
+		return
+	}
+	changes, err := asset.Upcoming.Diff(stationID, guide, radicron.CurrentTime)
+	if err != nil {
+		log.Printf("failed to save the upcoming programs: %s", err)
+	}
+	for _, c := range changes {
+		if c.Moved == nil {
+			log.Printf("warning: [%s]%s (%s) is dropped from the guide", c.Prog.StationID, c.Prog.Title, c.Prog.Ft)
+			asset.Events.Add(radicron.EventDropped, c.Prog, "dropped from the guide")
+			continue
+		}
+		log.Printf("warning: [%s]%s (%s) is moved to %s-%s", c.Prog.StationID, c.Prog.Title, c.Prog.Ft, c.Moved.Ft, c.Moved.To)
+		asset.Events.Add(radicron.EventMoved, c.Prog, fmt.Sprintf("moved to %s-%s", c.Moved.Ft, c.Moved.To))
+	}
+}
+
+// waitDone returns a channel closed once the downloads complete
+func waitDone(wg *sync.WaitGroup) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
 }
 
 func main() {
-	// Set the config location
-	conf := flag.String("c", "config.yml", "the config.yml to use.")
-	enableDebug := flag.Bool("d", false, "enable debug mode.")
-	version := flag.Bool("v", false, "print version.")
-	flag.Parse()
-
-	// use the version from build
-	if *version {
-		bi, _ := debug.ReadBuildInfo()
-		fmt.Printf("%v\n", bi.Main.Version)
-		os.Exit(0)
-	}
-
-	// to change the flags on the default logger
-	if *enableDebug {
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
-	}
-
-	log.Println("starting radicron")
-	wg := sync.WaitGroup{}
-	run(&wg, *conf)
-
-	// listen for SIGINT/SIGTERM
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	// finish the downloading in progress
-	log.Println("exit once all the downloads complete")
-	wg.Wait()
-	log.Println("exiting radicron")
+	cmd := newRootCommand()
+	cmd.SetArgs(legacyArgs(os.Args[1:]))
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
 }
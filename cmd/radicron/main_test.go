@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"log"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/iomz/radicron"
+	"github.com/spf13/viper"
 	"github.com/yyoshiki41/go-radiko"
 	"github.com/yyoshiki41/radigo"
 )
@@ -22,7 +24,7 @@ func TestConfig(t *testing.T) {
 		log.Fatal(err)
 	}
 	ck := radicron.ContextKey("asset")
-	asset, err := radicron.NewAsset(client)
+	asset, err := radicron.NewAsset(context.Background(), client)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -40,9 +42,91 @@ func TestConfig(t *testing.T) {
 		t.Error("error parsing the rules")
 	}
 
+	if asset.GetAvailabilityDelay("TBS") != 10*time.Minute {
+		t.Errorf("asset.GetAvailabilityDelay(TBS): %v => want %v", asset.GetAvailabilityDelay("TBS"), 10*time.Minute)
+	}
+	if asset.GetAvailabilityDelay("FMT") != 15*time.Minute {
+		t.Errorf("asset.GetAvailabilityDelay(FMT): %v => want %v", asset.GetAvailabilityDelay("FMT"), 15*time.Minute)
+	}
+	if asset.GetAvailabilityDelay("LFR") != 0 {
+		t.Errorf("asset.GetAvailabilityDelay(LFR): %v => want %v", asset.GetAvailabilityDelay("LFR"), 0)
+	}
+
+	if asset.Retry.InitialDelay != time.Second || asset.Retry.Attempts != radicron.MaxRetryAttempts {
+		t.Errorf("asset.Retry: %+v => want 1s initial delay and %v attempts", asset.Retry, radicron.MaxRetryAttempts)
+	}
+
 	got := len(asset.AvailableStations)
 	nStations := 12
 	if got != nStations {
 		t.Errorf("asset.AvailableStations: %v => want %v", got, nStations)
 	}
 }
+
+func TestWriteStarterConfig(t *testing.T) {
+	t.Setenv(radicron.EnvRadicronHome, t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := writeStarterConfig(path, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStarterConfig(path, false); err == nil {
+		t.Error("writeStarterConfig without -force => nil, want error")
+	}
+	if err := writeStarterConfig(path, true); err != nil {
+		t.Errorf("writeStarterConfig with -force => %v, want nil", err)
+	}
+
+	// the starter config is valid
+	if err := loadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(viper.GetStringMap("rules")) == 0 {
+		t.Error("the starter config has no rules")
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	defer viper.Reset()
+	defer radicron.SetDownloadDir("")
+	home := t.TempDir()
+	t.Setenv(radicron.EnvRadicronHome, home)
+	if err := loadConfig("test/config-test.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if got := viper.GetString("file-format"); got != radigo.AudioFormatMP3 {
+		t.Errorf("file-format => %v, want %v", got, radigo.AudioFormatMP3)
+	}
+	if dir, err := radicron.DownloadDir(); err != nil || dir != filepath.Join(home, "recordings") {
+		t.Errorf("DownloadDir => %v, %v, want %v", dir, err, filepath.Join(home, "recordings"))
+	}
+
+	concurrency := radicron.NewConcurrency()
+	if err := viper.UnmarshalKey("concurrency", concurrency); err != nil {
+		t.Fatal(err)
+	}
+	if want := (radicron.Concurrency{Initial: 4, Min: 1, Max: 8}); *concurrency != want {
+		t.Errorf("concurrency => %+v, want %+v", *concurrency, want)
+	}
+	retry := radicron.NewRetryPolicy()
+	if err := viper.UnmarshalKey("retry", retry); err != nil {
+		t.Fatal(err)
+	}
+	if retry.Attempts != 3 || retry.InitialDelay != 2*time.Second {
+		t.Errorf("retry => %+v, want 3 attempts with 2s initial delay", retry)
+	}
+	if got := len(viper.GetStringMap("rules")); got != 1 {
+		t.Errorf("len(rules) => %v, want 1", got)
+	}
+}
+
+func TestAppendFavoriteRules(t *testing.T) {
+	rules := radicron.Rules{&radicron.Rule{Name: "favorite-fmt-thetrad", StationID: "FMT", Title: "THE TRAD", Follow: true}}
+	favorites := radicron.Rules{
+		&radicron.Rule{Name: "favorite-fmt-thetrad", StationID: "FMT", Title: "THE TRAD"},
+		&radicron.Rule{Name: "favorite-tbs-junk", StationID: "TBS", Title: "JUNK"},
+	}
+	got := appendFavoriteRules(rules, favorites)
+	if len(got) != 2 || !got[0].Follow || got[1].Name != "favorite-tbs-junk" {
+		t.Errorf("appendFavoriteRules() => %v, want the rule in the config kept and favorite-tbs-junk added", got)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// newRecordCommand runs the recorder with its own flags
+func newRecordCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record [share-url]",
+		Short: "Record the programs matching the rules, or the program in the share URL once",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	fs := cmd.Flags()
+	daemon := fs.Bool("daemon", true, "keep running to scan the guide periodically, --daemon=false to scan once and exit after the downloads (e.g., from cron).")
+	leaseTTL := fs.Duration("lease", 0, "hold a lease in RADICRON_HOME for the duration to record by only one of the instances sharing it, e.g., 1m.")
+	lowBandwidth := fs.Bool("low-bandwidth", false, "cap the concurrency and the rate, and defer the programs not about to expire (toggled on the admin endpoint).")
+	adminAddr := fs.String("admin", "", "serve the admin endpoints (pprof) on the address, e.g., localhost:6060.")
+	dryRunFlag := fs.Bool("dry-run", false, "report the programs to be downloaded with the output paths and the estimated sizes, and exit without downloading.")
+	dispatch := fs.Bool("dispatch", false, "only schedule and queue the downloads in the job-queue for the workers.")
+	station := fs.String("station", "", "the station-id to record the airtime from --from to --to regardless of the programs.")
+	from := fs.String("from", "", "the start of the airtime to record in YYYYMMDDhhmm, with --station and --to.")
+	to := fs.String("to", "", "the end of the airtime to record in YYYYMMDDhhmm, with --station and --from.")
+	hls := fs.String("hls", "", "record the audio in the HLS playlist at the URL once, any m3u8 stream not only of radiko.")
+	name := fs.String("name", "", "the output name of --hls (default to <YYYYMMDDhhmm>_<host>).")
+	live := fs.Bool("live", false, "record the live stream of --station now until --to or the end of the program on air; stop with Ctrl-C to save what is captured.")
+	tuning.register(fs)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		dryRun = *dryRunFlag
+		conf := configFile
+
+		// record a program once
+		if len(args) > 0 {
+			return recordURL(conf, args[0])
+		}
+		// record an HLS stream once
+		if *hls != "" {
+			return recordHLS(conf, *hls, *name)
+		}
+		// record the live stream once
+		if *live {
+			return recordLiveStream(conf, *station, *to)
+		}
+		// record a block of airtime once
+		if *station != "" || *from != "" || *to != "" {
+			return recordBlock(conf, *station, *from, *to)
+		}
+
+		if *dispatch {
+			if err := loadConfig(conf); err != nil {
+				return err
+			}
+			q, err := newJobQueue(context.Background())
+			if err != nil {
+				return err
+			}
+			queue = q
+		}
+
+		radicron.SetLowBandwidth(*lowBandwidth)
+		if *leaseTTL > 0 {
+			if err := startLease(*leaseTTL); err != nil {
+				return err
+			}
+		}
+		if err := lockInstance(conf); err != nil {
+			return err
+		}
+		if *adminAddr != "" {
+			go serveAdmin(*adminAddr)
+		}
+		record(conf, *daemon && !dryRun)
+		return nil
+	}
+	return cmd
+}
+
+// recordURL downloads the program in the radiko share or timefree URL
+func recordURL(conf, rawURL string) error {
+	stationID, t, err := radicron.ParseShareURL(rawURL)
+	if err != nil {
+		return err
+	}
+	client, err := radiko.New("")
+	if err != nil {
+		return err
+	}
+	asset, err := radicron.NewAsset(context.Background(), client)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+	rules, err := reload(ctx, conf)
+	if err != nil {
+		return err
+	}
+	asset.DryRun = dryRun
+
+	progs, err := radicron.FetchWeeklyPrograms(ctx, stationID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the %s program: %s", stationID, err)
+	}
+	p := progs.At(t)
+	if p == nil {
+		return fmt.Errorf("no program on %s at %s in the guide", stationID, t)
+	}
+	applyRules(rules.Matching(p.StationID, p), p)
+	return downloadOnce(ctx, p)
+}
+
+// recordBlock downloads the airtime on the station from the start to the end regardless of the programs
+func recordBlock(conf, stationID, from, to string) error {
+	if stationID == "" || from == "" || to == "" {
+		return errors.New("usage: radicron record --station <station-id> --from YYYYMMDDhhmm --to YYYYMMDDhhmm")
+	}
+	client, err := radiko.New("")
+	if err != nil {
+		return err
+	}
+	asset, err := radicron.NewAsset(context.Background(), client)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+	if _, err = reload(ctx, conf); err != nil {
+		return err
+	}
+	asset.DryRun = dryRun
+
+	ft, err := radicron.ParseBlockTime(from)
+	if err != nil {
+		return err
+	}
+	tt, err := radicron.ParseBlockTime(to)
+	if err != nil {
+		return err
+	}
+	// the metadata of the programs in the block
+	progs, err := radicron.FetchWeeklyPrograms(ctx, stationID)
+	if err != nil {
+		log.Printf("failed to fetch the %s program: %s", stationID, err)
+	}
+	p, err := radicron.NewBlockProg(stationID, ft, tt, progs)
+	if err != nil {
+		return err
+	}
+	return downloadOnce(ctx, p)
+}
+
+// recordLiveStream records the live stream of the station from now until the end, or until interrupted
+func recordLiveStream(conf, stationID, to string) error {
+	if stationID == "" {
+		return errors.New("usage: radicron record --live --station <station-id> [--to YYYYMMDDhhmm]")
+	}
+	client, err := radiko.New("")
+	if err != nil {
+		return err
+	}
+	asset, err := radicron.NewAsset(context.Background(), client)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+	rules, err := reload(ctx, conf)
+	if err != nil {
+		return err
+	}
+	asset.DryRun = dryRun
+
+	// the program on air, or the block until the end
+	var progs radicron.Progs
+	if radicron.IsRadiruStation(stationID) {
+		if asset.Radiru == nil {
+			if err = loadRadiru(ctx, asset); err != nil {
+				return err
+			}
+		}
+		progs, err = asset.Radiru.FetchPrograms(ctx, stationID)
+	} else {
+		progs, err = radicron.FetchWeeklyPrograms(ctx, stationID)
+	}
+	if err != nil {
+		log.Printf("failed to fetch the %s program: %s", stationID, err)
+	}
+	now := time.Now().In(radicron.Location).Truncate(time.Minute)
+	var p *radicron.Prog
+	if to == "" {
+		if p = progs.At(now.Format(radicron.DatetimeLayout)); p == nil {
+			return fmt.Errorf("no program on %s now in the guide, set the end with --to", stationID)
+		}
+		applyRules(rules.Matching(p.StationID, p), p)
+	} else {
+		tt, err := radicron.ParseBlockTime(to)
+		if err != nil {
+			return err
+		}
+		if p, err = radicron.NewBlockProg(stationID, now, tt, progs); err != nil {
+			return err
+		}
+	}
+	if asset.DryRun {
+		log.Printf("would record [%s]%s live until %s", p.StationID, p.Title, p.To)
+		return nil
+	}
+
+	// stop with the signal to save what is captured
+	captureCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	err = radicron.CaptureLive(captureCtx, p)
+	stop()
+	if err != nil {
+		return fmt.Errorf("failed to record [%s]%s live: %s", p.StationID, p.Title, err)
+	}
+	if asset.StationSettings[stationID] == nil {
+		asset.StationSettings[stationID] = &radicron.StationSetting{}
+	}
+	asset.StationSettings[stationID].Live = true
+	return downloadOnce(ctx, p)
+}
+
+// recordHLS downloads the audio in the HLS playlist at uri to the output name
+func recordHLS(conf, uri, name string) error {
+	client, err := radiko.New("")
+	if err != nil {
+		return err
+	}
+	asset, err := radicron.NewAsset(context.Background(), client)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+	if _, err = reload(ctx, conf); err != nil {
+		return err
+	}
+	asset.DryRun = dryRun
+
+	output, err := radicron.RecordHLS(ctx, uri, name)
+	if err != nil {
+		return err
+	}
+	if !asset.DryRun {
+		log.Printf("+saved %s", output)
+	}
+	return nil
+}
+
+// downloadOnce downloads the program and waits for it
+func downloadOnce(ctx context.Context, p *radicron.Prog) error {
+	wg := sync.WaitGroup{}
+	if err := radicron.Download(ctx, &wg, p); err != nil {
+		return err
+	}
+	wg.Wait()
+	if asset := radicron.GetAsset(ctx); asset.NextFetchTime != nil {
+		return fmt.Errorf("the timefree of [%s]%s (%s) is not available until %v", p.StationID, p.Title, p.Ft, asset.NextFetchTime)
+	}
+	return nil
+}
+
+// newJobQueue returns the job-queue in the config, or the jobs in RADICRON_HOME if not set
+func newJobQueue(ctx context.Context) (radicron.JobQueue, error) {
+	rawURL, err := radicron.LookupSecret(ctx, "job-queue", viper.GetString("job-queue"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading job-queue: %s", err)
+	}
+	return radicron.NewJobQueue(rawURL)
+}
+
+// record runs the recorder until SIGINT/SIGTERM, or scans once without the daemon mode
+func record(conf string, daemon bool) {
+	log.Println("starting radicron")
+	wg := sync.WaitGroup{}
+	if !daemon {
+		run(&wg, conf, false)
+		releaseLease()
+		log.Println("exiting radicron")
+		return
+	}
+	go run(&wg, conf, true)
+	waitSignal()
+	// finish the downloading in progress
+	log.Println("exit once all the downloads complete")
+	wg.Wait()
+	releaseLease()
+	log.Println("exiting radicron")
+}
+
+// waitSignal blocks until SIGINT/SIGTERM
+func waitSignal() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	signal.Stop(quit)
+}
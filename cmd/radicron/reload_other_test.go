@@ -0,0 +1,35 @@
+//go:build !windows
+
+package main
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyReload(t *testing.T) {
+	hup := notifyReload()
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	done := waitDone(&wg)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// reload while the downloads are in flight
+	select {
+	case <-hup:
+	case <-done:
+		t.Fatal("waitDone before the downloads complete")
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP not received")
+	}
+	wg.Done()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitDone not closed after the downloads complete")
+	}
+}
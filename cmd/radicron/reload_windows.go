@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// notifyReload returns nil without SIGHUP on Windows, restart the service to reload the config
+func notifyReload() <-chan os.Signal {
+	return nil
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yyoshiki41/go-radiko"
+	"gopkg.in/yaml.v3"
+)
+
+// newRulesCommand manages the rules in the config
+func newRulesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Manage the rules in the config",
+	}
+	cmd.AddCommand(newRulesExportCommand(), newRulesImportCommand(), newRulesSuggestCommand(), newRulesTestCommand())
+	return cmd
+}
+
+// newRulesExportCommand writes the rules in the config as a portable YAML
+func newRulesExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the rules in the config as a portable YAML",
+		Args:  cobra.NoArgs,
+	}
+	output := cmd.Flags().StringP("output", "o", "", "the file to write the rules (default to stdout).")
+	_ = cmd.MarkFlagFilename("output", "yml", "yaml")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		w := io.Writer(os.Stdout)
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return exportRules(w)
+	}
+	return cmd
+}
+
+// newRulesImportCommand merges the rules in the file into the config
+func newRulesImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <rules.yml>",
+		Short: "Merge the rules in the file into the config",
+		Args:  cobra.ExactArgs(1),
+	}
+	overwrite := cmd.Flags().Bool("overwrite", false, "overwrite the existing rules with the same name.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		n, err := importRules(args[0], *overwrite)
+		if err != nil {
+			return err
+		}
+		log.Printf("imported %d rules to %s", n, viper.ConfigFileUsed())
+		return nil
+	}
+	return cmd
+}
+
+// newRulesSuggestCommand writes the rules for the shows frequently saved without any rule
+func newRulesSuggestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest the rules for the shows frequently saved without any rule",
+		Args:  cobra.NoArgs,
+	}
+	min := cmd.Flags().Int("min", 3, "suggest the shows saved at least this many times.")
+	output := cmd.Flags().StringP("output", "o", "", "the file to write the suggested rules (default to stdout).")
+	_ = cmd.MarkFlagFilename("output", "yml", "yaml")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		w := io.Writer(os.Stdout)
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return suggestRules(w, *min)
+	}
+	return cmd
+}
+
+// newRulesTestCommand explains which rules match the programs
+func newRulesTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Explain which rules match the programs",
+		Args:  cobra.NoArgs,
+	}
+	fs := cmd.Flags()
+	query := fs.StringP("query", "q", "", "test only the programs with the title containing this.")
+	guide := fs.String("guide", "", "test the programs in the guide snapshot (weekly program XML) instead of fetching.")
+	archive := fs.String("archive", "", "test the programs in the guide archive of the day (e.g., 20230605) instead of fetching.")
+	stationID := fs.String("station", "", "test only the programs of this station.")
+	from := fs.String("from", "", "test only the programs starting from this date (e.g., 20230605).")
+	to := fs.String("to", "", "test only the programs starting until this date (e.g., 20230611).")
+	asJSON := fs.Bool("json", false, "print the rules matched to the programs in JSON.")
+	_ = cmd.MarkFlagFilename("guide", "xml")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		radicron.CurrentTime = time.Now().In(radicron.Location)
+		rules, err := loadRules()
+		if err != nil {
+			return err
+		}
+		// the script decides the matching as in the scan
+		var script *radicron.Script
+		if path := viper.GetString("script"); path != "" {
+			if script, err = radicron.LoadScript(path); err != nil {
+				return err
+			}
+		}
+
+		filter := func(p *radicron.Prog) bool {
+			return strings.Contains(p.Title, *query) &&
+				(*stationID == "" || p.StationID == *stationID) &&
+				(*from == "" || p.Ft >= *from) &&
+				(*to == "" || p.Ft < *to || strings.HasPrefix(p.Ft, *to))
+		}
+		explanations := []*programExplanation{}
+		explain := func(progs radicron.Progs) {
+			if *asJSON {
+				explanations = append(explanations, explainRules(rules, script, progs, filter)...)
+				return
+			}
+			explainPrograms(os.Stdout, rules, script, progs, filter)
+		}
+		done := func() error {
+			if *asJSON {
+				return printJSON(os.Stdout, explanations)
+			}
+			return nil
+		}
+
+		// use the guide snapshot
+		if *guide != "" {
+			progs, err := radicron.LoadWeeklyPrograms(*guide)
+			if err != nil {
+				return err
+			}
+			explain(progs)
+			return done()
+		}
+
+		// use the guide archive
+		if *archive != "" {
+			ga, err := radicron.NewGuideArchive()
+			if err != nil {
+				return err
+			}
+			progs, err := ga.Load(*archive)
+			if err != nil {
+				return err
+			}
+			explain(progs)
+			return done()
+		}
+
+		// fetch the weekly programs
+		stations := []string{*stationID}
+		if *stationID == "" {
+			client, err := radiko.New("")
+			if err != nil {
+				return err
+			}
+			asset, err := radicron.NewAsset(cmd.Context(), client)
+			if err != nil {
+				return err
+			}
+			ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+			if rules, err = reload(ctx, configFile); err != nil {
+				return err
+			}
+			stations = asset.AvailableStations
+		}
+		for _, s := range stations {
+			progs, err := radicron.FetchWeeklyPrograms(cmd.Context(), s)
+			if err != nil {
+				log.Printf("failed to fetch the %s program: %v", s, err)
+				continue
+			}
+			explain(progs)
+		}
+		return done()
+	}
+	return cmd
+}
+
+// ruleMatch is whether the rule matches the program and why
+type ruleMatch struct {
+	Rule    string   `json:"rule"`
+	Matched bool     `json:"matched"`
+	Reasons []string `json:"reasons"`
+}
+
+// programExplanation is the rules tested against the program
+type programExplanation struct {
+	Program *radicron.Prog `json:"program"`
+	Rules   []*ruleMatch   `json:"rules"`
+	// Oversized is "skipped" or "warning" if the program is over the max-duration
+	Oversized string `json:"oversized,omitempty"`
+	// Script is "record", "skip", or "rules" if left to the rules, with the match hook
+	Script string `json:"script,omitempty"`
+	// Record is true if the scan records the program
+	Record bool `json:"record"`
+}
+
+// explainRules returns which rules match the programs and why, and whether the scan records them
+func explainRules(rules radicron.Rules, script *radicron.Script, progs radicron.Progs, filter func(*radicron.Prog) bool) []*programExplanation {
+	explanations := []*programExplanation{}
+	for _, p := range progs {
+		if !filter(p) {
+			continue
+		}
+		pe := &programExplanation{Program: p, Rules: []*ruleMatch{}}
+		for _, r := range rules {
+			matched, reasons := r.Explain(p.StationID, p)
+			if len(reasons) == 0 {
+				reasons = append(reasons, "no criteria")
+			}
+			pe.Rules = append(pe.Rules, &ruleMatch{Rule: r.Name, Matched: matched, Reasons: reasons})
+		}
+		matching := rules.Matching(p.StationID, p)
+		pe.Record = len(matching) > 0
+		if script.HasMatch() {
+			pe.Script = "rules"
+			if record, decided := script.Decide(p.StationID, p); decided {
+				pe.Record = record
+				pe.Script = "skip"
+				if record {
+					pe.Script = "record"
+				}
+			}
+		}
+		if !pe.Record {
+			explanations = append(explanations, pe)
+			continue
+		}
+		if skip, warn := matching.Oversized(p); skip {
+			pe.Oversized = "skipped"
+			pe.Record = false
+		} else if warn {
+			pe.Oversized = "warning"
+		}
+		explanations = append(explanations, pe)
+	}
+	return explanations
+}
+
+// explainPrograms writes which rules match the programs and why
+func explainPrograms(w io.Writer, rules radicron.Rules, script *radicron.Script, progs radicron.Progs, filter func(*radicron.Prog) bool) {
+	for _, pe := range explainRules(rules, script, progs, filter) {
+		p := pe.Program
+		fmt.Fprintf(w, "[%s]%s (%s)\n", p.StationID, p.Title, p.Ft)
+		for _, m := range pe.Rules {
+			mark := "-"
+			if m.Matched {
+				mark = "+"
+			}
+			fmt.Fprintf(w, "  %s rule[%s]: %s\n", mark, m.Rule, strings.Join(m.Reasons, ", "))
+		}
+		switch pe.Script {
+		case "record", "skip":
+			fmt.Fprintf(w, "  = script: %s\n", pe.Script)
+		case "rules":
+			fmt.Fprintf(w, "  = script: left to the rules\n")
+		}
+		if pe.Oversized != "" {
+			fmt.Fprintf(w, "  ! %s: %v is over the max-duration\n", pe.Oversized, p.Duration())
+		}
+	}
+}
+
+// exportRules writes the rules in the config as a portable YAML,
+// keeping the names and the comments as in the YAML config
+func exportRules(w io.Writer) error {
+	var rules any = viper.GetStringMap("rules")
+	if isYAML(viper.ConfigFileUsed()) {
+		doc, err := readYAMLNode(viper.ConfigFileUsed())
+		if err != nil {
+			return err
+		}
+		node := yamlValue(yamlRoot(doc), "rules")
+		if node == nil {
+			node = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		rules = node
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(map[string]any{"rules": rules})
+}
+
+// suggestRules writes the rules for the shows frequently saved without any rule,
+// in the format for rules import
+func suggestRules(w io.Writer, min int) error {
+	rules, err := loadRules()
+	if err != nil {
+		return err
+	}
+	history, err := radicron.NewHistory()
+	if err != nil {
+		return err
+	}
+	suggestions := history.SuggestRules(rules, min)
+	if len(suggestions) == 0 {
+		log.Printf("no show saved %d times or more without a rule", min)
+		return nil
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range suggestions {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: s.Rule.Name, HeadComment: fmt.Sprintf("saved %d times", s.Count)}
+		value := &yaml.Node{}
+		if err = value.Encode(map[string]string{
+			"station-id": s.Rule.StationID,
+			"title":      s.Rule.Title,
+		}); err != nil {
+			return err
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(map[string]*yaml.Node{"rules": node})
+}
+
+// importRules merges the rules from the file into the rules of the YAML config file,
+// leaving the rest of the file as is
+func importRules(filename string, overwrite bool) (int, error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return 0, fmt.Errorf("error reading the rules: %s", err)
+	}
+	src, err := readYAMLNode(filename)
+	if err != nil {
+		return 0, fmt.Errorf("error reading the rules: %s", err)
+	}
+	srcRules := yamlValue(yamlRoot(src), "rules")
+	if srcRules == nil || srcRules.Kind != yaml.MappingNode {
+		return 0, nil
+	}
+
+	configFile := viper.ConfigFileUsed()
+	if !isYAML(configFile) {
+		return 0, fmt.Errorf("rules import supports only the YAML config: %s", configFile)
+	}
+	doc, err := readYAMLNode(configFile)
+	if err != nil {
+		return 0, err
+	}
+	root := yamlRoot(doc)
+	rules := yamlValue(root, "rules")
+	if rules == nil {
+		rules = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "rules"}, rules)
+	} else if rules.Kind != yaml.MappingNode {
+		// e.g., `rules:` without a value in the starter config
+		*rules = yaml.Node{Kind: yaml.MappingNode, LineComment: rules.LineComment}
+	}
+
+	imported := 0
+	for i := 0; i+1 < len(srcRules.Content); i += 2 {
+		name, value := srcRules.Content[i], srcRules.Content[i+1]
+		// validate the rule
+		rule := &radicron.Rule{}
+		if err = v.UnmarshalKey(fmt.Sprintf("rules.%s", name.Value), rule); err != nil {
+			return 0, fmt.Errorf("error reading the rule %s: %s", name.Value, err)
+		}
+		if j := yamlIndex(rules, name.Value); j >= 0 {
+			if !overwrite {
+				log.Printf("-skip the existing rule: %s", name.Value)
+				continue
+			}
+			rules.Content[j+1] = value
+		} else {
+			rules.Content = append(rules.Content, name, value)
+		}
+		imported++
+	}
+	if imported == 0 {
+		return 0, nil
+	}
+	return imported, writeYAMLNode(configFile, doc)
+}
+
+// isYAML returns true if the file is in YAML by the extension
+func isYAML(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// readYAMLNode reads the YAML file as a node to edit without losing the comments and the order
+func readYAMLNode(filename string) (*yaml.Node, error) {
+	blob, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{}
+	if err = yaml.Unmarshal(blob, doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	return doc, nil
+}
+
+// writeYAMLNode writes the node to the YAML file, keeping the permission
+func writeYAMLNode(filename string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(filename, buf.Bytes(), mode)
+}
+
+// yamlRoot returns the top-level mapping of the document
+func yamlRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// yamlIndex returns the index of the key in the mapping node ignoring the case as viper does, or -1
+func yamlIndex(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlValue returns the value of the key in the mapping node, or nil
+func yamlValue(node *yaml.Node, key string) *yaml.Node {
+	if i := yamlIndex(node, key); i >= 0 {
+		return node.Content[i+1]
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/viper"
+)
+
+func TestExportImportRules(t *testing.T) {
+	defer viper.Reset()
+	if err := loadConfig("test/config-test.yml"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := exportRules(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"airship", "citypop", "hiccorohee", "watchman"} {
+		if !strings.Contains(buf.String(), name+":") {
+			t.Errorf("exportRules => missing %v", name)
+		}
+	}
+
+	// import the exported rules to another config
+	dir := t.TempDir()
+	rulesFile := filepath.Join(dir, "rules.yml")
+	if err := os.WriteFile(rulesFile, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.yml")
+	config := "# my radicron\narea-id: JP13 # Tokyo\nrules:\n  airship:\n    title: AIRSHIP\n  LateNight:\n    title: JUNK # the comment kept\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	if err := loadConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+	n, err := importRules(rulesFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("importRules => %v, want %v", n, 3)
+	}
+
+	// reload the imported config
+	viper.Reset()
+	if err = loadConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(viper.GetStringMap("rules")); got != 5 {
+		t.Errorf("len(rules) => %v, want %v", got, 5)
+	}
+	if got := viper.GetString("rules.airship.title"); got != "AIRSHIP" {
+		t.Errorf("rules.airship.title => %v, want %v", got, "AIRSHIP")
+	}
+	if got := viper.GetString("rules.watchman.station-id"); got != "LTBS" {
+		t.Errorf("rules.watchman.station-id => %v, want %v", got, "LTBS")
+	}
+
+	// only the rules edited in the config file
+	blob, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(blob), "# my radicron\narea-id: JP13 # Tokyo\nrules:\n  airship:\n    title: AIRSHIP\n  LateNight:\n    title: JUNK # the comment kept\n") {
+		t.Errorf("importRules => %v, want the config as is before the imported rules", string(blob))
+	}
+	for _, key := range []string{"availability-delay", "file-format"} {
+		if strings.Contains(string(blob), key) {
+			t.Errorf("importRules => %v written", key)
+		}
+	}
+
+	// the names as in the config
+	buf.Reset()
+	if err = exportRules(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "LateNight:") || !strings.Contains(buf.String(), "# the comment kept") {
+		t.Errorf("exportRules => %v, want LateNight with the comment", buf.String())
+	}
+}
+
+func TestImportRulesEmpty(t *testing.T) {
+	defer viper.Reset()
+	dir := t.TempDir()
+	rulesFile := filepath.Join(dir, "rules.yml")
+	if err := os.WriteFile(rulesFile, []byte("rules:\n  airship:\n    title: AIRSHIP\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(configFile, []byte("area-id: JP13\nrules: # add the rules here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+	n, err := importRules(rulesFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("importRules => %v, want %v", n, 1)
+	}
+	blob, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "area-id: JP13\nrules: # add the rules here\n  airship:\n    title: AIRSHIP\n"; string(blob) != want {
+		t.Errorf("importRules => %q, want %q", string(blob), want)
+	}
+	viper.Reset()
+	if err = loadConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := viper.GetString("rules.airship.title"); got != "AIRSHIP" {
+		t.Errorf("rules.airship.title => %v, want %v", got, "AIRSHIP")
+	}
+}
+
+func TestExplainPrograms(t *testing.T) {
+	progs, err := radicron.LoadWeeklyPrograms("../../test/weekly-program-test.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := radicron.Rules{
+		&radicron.Rule{Name: "reina", Pfm: "山崎怜奈"},
+		&radicron.Rule{Name: "tbs", StationID: "TBS"},
+	}
+	var buf bytes.Buffer
+	explainPrograms(&buf, rules, nil, progs, func(p *radicron.Prog) bool { return true })
+	want := `[FMT]山崎怜奈の誰かに話したかったこと。 (20230605130000)
+  + rule[reina]: the pfm '山崎怜奈' contains '山崎怜奈'
+  - rule[tbs]: the station is not TBS
+`
+	if buf.String() != want {
+		t.Errorf("explainPrograms => %v, want %v", buf.String(), want)
+	}
+
+	buf.Reset()
+	explainPrograms(&buf, rules, nil, progs, func(p *radicron.Prog) bool { return false })
+	if buf.Len() != 0 {
+		t.Errorf("explainPrograms with no programs => %v", buf.String())
+	}
+
+	// for --json
+	buf.Reset()
+	if err = printJSON(&buf, explainRules(rules, nil, progs, func(p *radicron.Prog) bool { return true })); err != nil {
+		t.Fatal(err)
+	}
+	explanations := []*programExplanation{}
+	if err = json.Unmarshal(buf.Bytes(), &explanations); err != nil {
+		t.Fatal(err)
+	}
+	if len(explanations) != 1 || len(explanations[0].Rules) != 2 {
+		t.Fatalf("explainRules => %s", buf.String())
+	}
+	var ruletests = []struct {
+		rule    string
+		matched bool
+		reason  string
+	}{
+		{"reina", true, "the pfm '山崎怜奈' contains '山崎怜奈'"},
+		{"tbs", false, "the station is not TBS"},
+	}
+	for i, tt := range ruletests {
+		m := explanations[0].Rules[i]
+		if m.Rule != tt.rule || m.Matched != tt.matched || strings.Join(m.Reasons, ", ") != tt.reason {
+			t.Errorf("explainRules => %+v, want %+v", m, tt)
+		}
+	}
+	if got := explanations[0].Program.ID; got != progs[0].ID {
+		t.Errorf("explainRules program => %v, want %v", got, progs[0].ID)
+	}
+	if !explanations[0].Record || explanations[0].Script != "" {
+		t.Errorf("explainRules => record %v, script %q, want true without the script", explanations[0].Record, explanations[0].Script)
+	}
+}
+
+func TestExplainProgramsScript(t *testing.T) {
+	progs, err := radicron.LoadWeeklyPrograms("../../test/weekly-program-test.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := radicron.Rules{&radicron.Rule{Name: "reina", Pfm: "山崎怜奈"}}
+	var scripttests = []struct {
+		src    string
+		script string
+		record bool
+	}{
+		{"def match(station_id, prog):\n    return station_id != \"FMT\"\n", "skip", false},
+		{"def match(station_id, prog):\n    return None\n", "rules", true},
+		{"def filename(prog):\n    return None\n", "", true},
+	}
+	for _, tt := range scripttests {
+		path := filepath.Join(t.TempDir(), "hooks.star")
+		if err = os.WriteFile(path, []byte(tt.src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		script, err := radicron.LoadScript(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		explanations := explainRules(rules, script, progs, func(p *radicron.Prog) bool { return true })
+		if len(explanations) != 1 {
+			t.Fatalf("explainRules => %v programs, want 1", len(explanations))
+		}
+		if pe := explanations[0]; pe.Script != tt.script || pe.Record != tt.record {
+			t.Errorf("explainRules => script %q, record %v, want %q, %v", pe.Script, pe.Record, tt.script, tt.record)
+		}
+	}
+
+	var buf bytes.Buffer
+	path := filepath.Join(t.TempDir(), "hooks.star")
+	if err = os.WriteFile(path, []byte(scripttests[0].src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	script, err := radicron.LoadScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explainPrograms(&buf, rules, script, progs, func(p *radicron.Prog) bool { return true })
+	if !strings.Contains(buf.String(), "  = script: skip\n") {
+		t.Errorf("explainPrograms => %v, want the script verdict", buf.String())
+	}
+}
+
+func TestSuggestRules(t *testing.T) {
+	defer viper.Reset()
+	if err := loadConfig("test/config-test.yml"); err != nil {
+		t.Fatal(err)
+	}
+	home := t.TempDir()
+	t.Setenv(radicron.EnvRadicronHome, home)
+	history, err := radicron.LoadHistory(filepath.Join(home, radicron.HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ft := range []string{"20230605050000", "20230612050000", "20230619050000"} {
+		p := &radicron.Prog{ID: ft, StationID: "FMT", Ft: ft, Title: "Morning Show"}
+		if err = history.RecordSuccess(p, ft+".aac"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = suggestRules(&buf, 3); err != nil {
+		t.Fatal(err)
+	}
+	want := `rules:
+    # saved 3 times
+    fmt-morning-show:
+        station-id: FMT
+        title: Morning Show
+`
+	if buf.String() != want {
+		t.Errorf("suggestRules => %v, want %v", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err = suggestRules(&buf, 4); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("suggestRules with min 4 => %v", buf.String())
+	}
+}
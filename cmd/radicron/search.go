@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// newSearchCommand searches the program guide to discover the programs before setting up the rules
+func newSearchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search [keyword...]",
+		Short: "Search the program guide to discover the programs before setting up the rules",
+	}
+	fs := cmd.Flags()
+	stationID := fs.String("station", "", "search only the programs of this station.")
+	from := fs.String("from", "", "search only the programs starting from this date (e.g., 20230605).")
+	to := fs.String("to", "", "search only the programs starting until this date (e.g., 20230611).")
+	now := fs.Bool("now", false, "search the programs on air in the area instead of the weekly programs.")
+	area := fs.String("area", "", "the area to search (default to the area of this host).")
+	asJSON := fs.Bool("json", false, "print the programs in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		keyword := strings.Join(args, " ")
+
+		filter := func(p *radicron.Prog) bool {
+			return matchesKeyword(p, keyword) &&
+				(*stationID == "" || p.StationID == *stationID) &&
+				(*from == "" || p.Ft >= *from) &&
+				(*to == "" || p.Ft < *to || strings.HasPrefix(p.Ft, *to))
+		}
+
+		// the area of this host
+		if *area == "" && (*now || *stationID == "") {
+			areaID, err := resolveAreaID(cmd.Context(), "")
+			if err != nil {
+				return err
+			}
+			*area = areaID
+		}
+
+		progs := radicron.Progs{}
+		stations := []string{*stationID}
+		if !*now && *stationID == "" {
+			client, err := radiko.New("")
+			if err != nil {
+				return err
+			}
+			asset, err := radicron.NewAsset(cmd.Context(), client)
+			if err != nil {
+				return err
+			}
+			asset.LoadAvailableStations(*area)
+			stations = asset.AvailableStations
+		}
+		if *now {
+			ps, err := radicron.FetchNowPrograms(cmd.Context(), *area)
+			if err != nil {
+				return fmt.Errorf("failed to fetch the %s programs on air: %s", *area, err)
+			}
+			progs = ps
+		} else {
+			for _, s := range stations {
+				ps, err := radicron.FetchWeeklyPrograms(cmd.Context(), s)
+				if err != nil {
+					log.Printf("failed to fetch the %s program: %v", s, err)
+					continue
+				}
+				progs = append(progs, ps...)
+			}
+		}
+
+		found := radicron.Progs{}
+		for _, p := range progs {
+			if filter(p) {
+				found = append(found, p)
+			}
+		}
+		sort.SliceStable(found, func(i, j int) bool {
+			if found[i].Ft != found[j].Ft {
+				return found[i].Ft < found[j].Ft
+			}
+			return found[i].StationID < found[j].StationID
+		})
+		if *asJSON {
+			return printJSON(os.Stdout, found)
+		}
+		printPrograms(os.Stdout, found)
+		return nil
+	}
+	return cmd
+}
+
+// matchesKeyword returns true if the title, pfm, desc, or info contains the keyword
+func matchesKeyword(p *radicron.Prog, keyword string) bool {
+	for _, s := range []string{p.Title, p.Pfm, p.Desc, p.Info} {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// printPrograms writes the programs with the ft and to for the rules
+func printPrograms(w io.Writer, progs radicron.Progs) {
+	for _, p := range progs {
+		fmt.Fprintf(w, "[%s]%s ft=%s to=%s", p.StationID, p.Title, p.Ft, p.To)
+		if p.Pfm != "" {
+			fmt.Fprintf(w, " pfm=%s", p.Pfm)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iomz/radicron"
+)
+
+func TestMatchesKeyword(t *testing.T) {
+	p := &radicron.Prog{Title: "THE TRAD", Pfm: "稲垣吾郎", Desc: "音楽", Info: "<b>info</b>"}
+	var keywordtests = []struct {
+		keyword string
+		want    bool
+	}{
+		{"", true},
+		{"TRAD", true},
+		{"稲垣", true},
+		{"音楽", true},
+		{"info", true},
+		{"trad", false},
+		{"Session", false},
+	}
+	for _, tt := range keywordtests {
+		if got := matchesKeyword(p, tt.keyword); got != tt.want {
+			t.Errorf("matchesKeyword(%q) => %v, want %v", tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestPrintPrograms(t *testing.T) {
+	var buf bytes.Buffer
+	printPrograms(&buf, radicron.Progs{
+		{StationID: "FMT", Title: "THE TRAD", Ft: "20230612180000", To: "20230612185500", Pfm: "稲垣吾郎"},
+		{StationID: "TBS", Title: "Session", Ft: "20230612180000", To: "20230612200000"},
+	})
+	want := "[FMT]THE TRAD ft=20230612180000 to=20230612185500 pfm=稲垣吾郎\n" +
+		"[TBS]Session ft=20230612180000 to=20230612200000\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printPrograms => %q, want %q", got, want)
+	}
+}
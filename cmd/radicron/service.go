@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+)
+
+// serviceSpec describes the service to register
+type serviceSpec struct {
+	Name       string
+	Executable string
+	Config     string
+	Home       string
+	User       bool
+}
+
+// runServiceCommand runs the service manager, replaced in the tests
+var runServiceCommand = func(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %s: %s", name, strings.Join(args, " "), err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// newServiceCommand installs or uninstalls radicron as a service
+func newServiceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Install or uninstall radicron as a service",
+	}
+	for _, action := range []string{"install", "uninstall"} {
+		action := action
+		sub := &cobra.Command{
+			Use:   action,
+			Short: strings.ToUpper(action[:1]) + action[1:] + " radicron as a service (systemd, launchd, or Windows)",
+			Args:  cobra.NoArgs,
+		}
+		name := sub.Flags().String("name", "radicron", "the name of the service.")
+		user := sub.Flags().Bool("user", false, "register as a per-user service (systemd --user or LaunchAgents).")
+		dryRun := sub.Flags().Bool("dry-run", false, "print the service definition without registering it.")
+		sub.RunE = func(cmd *cobra.Command, args []string) error {
+			return manageService(action, *name, *user, *dryRun)
+		}
+		cmd.AddCommand(sub)
+	}
+	return cmd
+}
+
+// manageService installs or uninstalls the service with the name for the config
+func manageService(action, name string, user, dryRun bool) error {
+	spec, err := newServiceSpec(name, configFile, user)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "install":
+		if dryRun {
+			def, err := spec.definition(runtime.GOOS)
+			if err != nil {
+				return err
+			}
+			fmt.Print(def)
+			return nil
+		}
+		return spec.install(runtime.GOOS)
+	case "uninstall":
+		return spec.uninstall(runtime.GOOS)
+	default:
+		return fmt.Errorf("unknown service command: %s", action)
+	}
+}
+
+// newServiceSpec resolves the absolute paths for the service
+func newServiceSpec(name, conf string, user bool) (*serviceSpec, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, err
+	}
+	config, err := filepath.Abs(conf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(config); err != nil {
+		return nil, fmt.Errorf("config not found: %s", err)
+	}
+	if os.Getenv(radicron.EnvRadicronHome) == "" {
+		cwd, _ := os.Getwd()
+		os.Setenv(radicron.EnvRadicronHome, filepath.Join(cwd, "radiko"))
+	}
+	downloadDir, err := radicron.DownloadDir()
+	if err != nil {
+		return nil, err
+	}
+	return &serviceSpec{
+		Name:       name,
+		Executable: exe,
+		Config:     config,
+		Home:       filepath.Dir(downloadDir),
+		User:       user,
+	}, nil
+}
+
+// label returns the launchd label
+func (s *serviceSpec) label() string {
+	return "com.github.iomz." + s.Name
+}
+
+// path returns the file to write the service definition
+func (s *serviceSpec) path(goos string) (string, error) {
+	switch goos {
+	case "linux":
+		if s.User {
+			dir, err := os.UserConfigDir()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(dir, "systemd", "user", s.Name+".service"), nil
+		}
+		return filepath.Join("/etc/systemd/system", s.Name+".service"), nil
+	case "darwin":
+		if s.User {
+			dir, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(dir, "Library", "LaunchAgents", s.label()+".plist"), nil
+		}
+		return filepath.Join("/Library/LaunchDaemons", s.label()+".plist"), nil
+	case "windows":
+		// registered in the service control manager
+		return "", nil
+	default:
+		return "", fmt.Errorf("service is not supported on %s", goos)
+	}
+}
+
+// definition returns the service definition for the OS
+func (s *serviceSpec) definition(goos string) (string, error) {
+	switch goos {
+	case "linux":
+		return s.systemdUnit(), nil
+	case "darwin":
+		return s.launchdPlist(), nil
+	case "windows":
+		return strings.Join(s.scArgs(), " ") + "\n", nil
+	default:
+		return "", fmt.Errorf("service is not supported on %s", goos)
+	}
+}
+
+// systemdUnit returns the systemd unit
+func (s *serviceSpec) systemdUnit() string {
+	wantedBy := "multi-user.target"
+	if s.User {
+		wantedBy = "default.target"
+	}
+	return fmt.Sprintf(`[Unit]
+Description=radicron
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+Environment=%s
+ExecStart=%s -c %s
+Restart=on-failure
+RestartSec=30
+
+[Install]
+WantedBy=%s
+`, systemdQuote(radicron.EnvRadicronHome+"="+s.Home), systemdQuote(s.Executable), systemdQuote(s.Config), wantedBy)
+}
+
+// systemdQuote quotes the word for the systemd unit
+func systemdQuote(word string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%")
+	return `"` + r.Replace(word) + `"`
+}
+
+// launchdPlist returns the launchd property list
+func (s *serviceSpec) launchdPlist() string {
+	esc := func(v string) string {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(v))
+		return buf.String()
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>-c</string>
+		<string>%s</string>
+	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>%s</key>
+		<string>%s</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, esc(s.label()), esc(s.Executable), esc(s.Config), radicron.EnvRadicronHome, esc(s.Home),
+		esc(filepath.Join(s.Home, s.Name+".log")), esc(filepath.Join(s.Home, s.Name+".log")))
+}
+
+// scArgs returns the arguments for sc.exe to create the Windows service
+func (s *serviceSpec) scArgs() []string {
+	binPath := fmt.Sprintf(`"%s" -c "%s" -service-name %s`, s.Executable, s.Config, s.Name)
+	return []string{"create", s.Name, "binPath=", binPath, "start=", "auto", "DisplayName=", "radicron"}
+}
+
+// install writes and registers the service
+func (s *serviceSpec) install(goos string) error {
+	if goos == "windows" {
+		if err := runServiceCommand("sc.exe", s.scArgs()...); err != nil {
+			return err
+		}
+		// the service reads RADICRON_HOME from its own environment
+		key := `HKLM\SYSTEM\CurrentControlSet\Services\` + s.Name
+		env := radicron.EnvRadicronHome + "=" + s.Home
+		if err := runServiceCommand("reg.exe", "add", key, "/v", "Environment", "/t", "REG_MULTI_SZ", "/d", env, "/f"); err != nil {
+			return err
+		}
+		if err := installEventSource(s.Name); err != nil {
+			log.Printf("failed to register the event source: %s", err)
+		}
+		log.Printf("installed the service %s", s.Name)
+		return runServiceCommand("sc.exe", "start", s.Name)
+	}
+
+	path, err := s.path(goos)
+	if err != nil {
+		return err
+	}
+	def, err := s.definition(goos)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, []byte(def), 0o644); err != nil {
+		return err
+	}
+	log.Printf("installed the service to %s", path)
+
+	switch goos {
+	case "linux":
+		if err = runServiceCommand("systemctl", s.systemctlArgs("daemon-reload")...); err != nil {
+			return err
+		}
+		return runServiceCommand("systemctl", s.systemctlArgs("enable", "--now", s.Name)...)
+	default:
+		return runServiceCommand("launchctl", "load", "-w", path)
+	}
+}
+
+// uninstall stops and removes the service
+func (s *serviceSpec) uninstall(goos string) error {
+	if goos == "windows" {
+		// the service may not be running
+		if err := runServiceCommand("sc.exe", "stop", s.Name); err != nil {
+			log.Println(err)
+		}
+		if err := runServiceCommand("sc.exe", "delete", s.Name); err != nil {
+			return err
+		}
+		if err := removeEventSource(s.Name); err != nil {
+			log.Printf("failed to remove the event source: %s", err)
+		}
+		log.Printf("uninstalled the service %s", s.Name)
+		return nil
+	}
+
+	path, err := s.path(goos)
+	if err != nil {
+		return err
+	}
+	switch goos {
+	case "linux":
+		err = runServiceCommand("systemctl", s.systemctlArgs("disable", "--now", s.Name)...)
+	default:
+		err = runServiceCommand("launchctl", "unload", "-w", path)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if goos == "linux" {
+		if err = runServiceCommand("systemctl", s.systemctlArgs("daemon-reload")...); err != nil {
+			return err
+		}
+	}
+	log.Printf("uninstalled the service from %s", path)
+	return nil
+}
+
+// systemctlArgs prepends --user for the per-user service
+func (s *serviceSpec) systemctlArgs(args ...string) []string {
+	if s.User {
+		return append([]string{"--user"}, args...)
+	}
+	return args
+}
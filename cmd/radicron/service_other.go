@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// isWindowsService is always false but on Windows
+func isWindowsService() bool {
+	return false
+}
+
+// runWindowsService is only available on Windows
+func runWindowsService(name, conf string) error {
+	return errors.New("not a Windows service")
+}
+
+// installEventSource is only for Windows
+func installEventSource(name string) error {
+	return nil
+}
+
+// removeEventSource is only for Windows
+func removeEventSource(name string) error {
+	return nil
+}
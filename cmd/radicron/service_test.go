@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestServiceDefinition(t *testing.T) {
+	spec := &serviceSpec{
+		Name:       "radicron",
+		Executable: "/usr/local/bin/radicron",
+		Config:     "/srv/radicron/config 100%.yml",
+		Home:       "/srv/radicron/radiko",
+	}
+
+	definitiontests := []struct {
+		goos string
+		want []string
+	}{
+		{
+			"linux",
+			[]string{
+				`Environment="RADICRON_HOME=/srv/radicron/radiko"`,
+				`ExecStart="/usr/local/bin/radicron" -c "/srv/radicron/config 100%%.yml"`,
+				"WantedBy=multi-user.target",
+			},
+		},
+		{
+			"darwin",
+			[]string{
+				"<string>com.github.iomz.radicron</string>",
+				"<string>/srv/radicron/config 100%.yml</string>",
+				"<key>RADICRON_HOME</key>",
+			},
+		},
+		{
+			"windows",
+			[]string{`binPath= "/usr/local/bin/radicron" -c "/srv/radicron/config 100%.yml" -service-name radicron start= auto`},
+		},
+	}
+	for _, tt := range definitiontests {
+		def, err := spec.definition(tt.goos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(def, want) {
+				t.Errorf("definition(%s) => %s\nwant %s", tt.goos, def, want)
+			}
+		}
+	}
+
+	if _, err := spec.definition("plan9"); err == nil {
+		t.Error("definition(plan9) => nil, want error")
+	}
+}
+
+func TestServiceInstallUninstall(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("systemd is only on Linux")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var commands []string
+	orig := runServiceCommand
+	runServiceCommand = func(name string, args ...string) error {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runServiceCommand = orig }()
+
+	spec := &serviceSpec{Name: "radicron", Executable: "/bin/radicron", Config: "/etc/radicron.yml", Home: "/var/lib/radiko", User: true}
+	if err := spec.install("linux"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "systemd", "user", "radicron.service")
+	unit, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(unit), "WantedBy=default.target") {
+		t.Errorf("the user unit => %s, want default.target", unit)
+	}
+	if got := commands[len(commands)-1]; got != "systemctl --user enable --now radicron" {
+		t.Errorf("install => %s, want systemctl --user enable --now radicron", got)
+	}
+
+	if err = spec.uninstall("linux"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the unit remains after uninstall: %v", err)
+	}
+}
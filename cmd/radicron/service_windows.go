@@ -0,0 +1,101 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/iomz/radicron"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventLogID for the messages from radicron
+const eventLogID = 1
+
+// windowsService handles the requests from the service control manager
+type windowsService struct {
+	conf string
+}
+
+// Execute runs radicron until the service is stopped
+func (ws *windowsService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	wg := sync.WaitGroup{}
+	go run(&wg, ws.conf, true)
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			changes <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			changes <- svc.Status{State: svc.StopPending}
+			// finish the downloading in progress
+			log.Println("exit once all the downloads complete")
+			wg.Wait()
+			releaseLease()
+			log.Println("exiting radicron")
+			return false, 0
+		default:
+			log.Printf("unexpected service control request #%d", c.Cmd)
+		}
+	}
+	return false, 0
+}
+
+// eventLogWriter writes the logs to the Windows event log
+type eventLogWriter struct {
+	el *eventlog.Log
+}
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	var err error
+	if strings.Contains(msg, "failed") || strings.Contains(msg, "error") {
+		err = w.el.Error(eventLogID, msg)
+	} else {
+		err = w.el.Info(eventLogID, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// isWindowsService reports whether radicron is started by the service control manager
+func isWindowsService() bool {
+	ok, err := svc.IsWindowsService()
+	return err == nil && ok
+}
+
+// runWindowsService runs radicron as the Windows service
+func runWindowsService(name, conf string) error {
+	el, err := eventlog.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open the event log: %s", err)
+	}
+	defer el.Close()
+	radicron.LogRedactor.SetOutput(&eventLogWriter{el})
+	log.SetFlags(0)
+
+	log.Println("starting radicron")
+	if err = svc.Run(name, &windowsService{conf}); err != nil {
+		_ = el.Error(eventLogID, err.Error())
+		return err
+	}
+	return nil
+}
+
+// installEventSource registers radicron as an event log source
+func installEventSource(name string) error {
+	return eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+}
+
+// removeEventSource removes the event log source
+func removeEventSource(name string) error {
+	return eventlog.Remove(name)
+}
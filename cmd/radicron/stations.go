@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// stationEntry is a station in the list
+type stationEntry struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Ruby  string   `json:"ruby,omitempty"`
+	Areas []string `json:"areas"`
+}
+
+// newStationsCommand lists the stations in the area to configure the rules
+func newStationsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stations",
+		Short: "List the stations in the area to configure the rules",
+		Args:  cobra.NoArgs,
+	}
+	fs := cmd.Flags()
+	area := fs.String("area", "", "list the stations in the area, e.g., JP13 (default to the area of this host).")
+	asJSON := fs.Bool("json", false, "print the stations in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *area == "" {
+			areaID, err := resolveAreaID(cmd.Context(), "")
+			if err != nil {
+				return err
+			}
+			*area = areaID
+		}
+		client, err := radiko.New("")
+		if err != nil {
+			return err
+		}
+		asset, err := radicron.NewAsset(cmd.Context(), client)
+		if err != nil {
+			return err
+		}
+		entries := listStations(asset.Stations, *area)
+		if len(entries) == 0 {
+			return fmt.Errorf("no station in the area: %s", *area)
+		}
+		if *asJSON {
+			return printJSON(os.Stdout, entries)
+		}
+		printStations(os.Stdout, entries)
+		return nil
+	}
+	return cmd
+}
+
+// listStations returns the stations in the area sorted by the ID
+func listStations(stations radicron.Stations, areaID string) []*stationEntry {
+	entries := []*stationEntry{}
+	for id, s := range stations {
+		for _, a := range s.Areas {
+			if a == areaID {
+				entries = append(entries, &stationEntry{ID: id, Name: s.Name, Ruby: s.Ruby, Areas: s.Areas})
+				break
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// printStations writes the station IDs and the names
+func printStations(w io.Writer, entries []*stationEntry) {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\n", e.ID, e.Name)
+	}
+}
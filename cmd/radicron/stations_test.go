@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iomz/radicron"
+)
+
+func TestListStations(t *testing.T) {
+	stations := radicron.Stations{
+		"TBS":   {Areas: []string{"JP13", "JP14"}, Name: "TBSラジオ"},
+		"FMT":   {Areas: []string{"JP13"}, Name: "TOKYO FM"},
+		"ABC":   {Areas: []string{"JP27"}, Name: "ABCラジオ"},
+		"RN1":   {Areas: []string{"JP1", "JP13", "JP27"}, Name: "ラジオNIKKEI第1"},
+		"HBC":   {Areas: []string{"JP1"}, Name: "HBCラジオ"},
+		"OTHER": {Areas: []string{}, Name: "other"},
+	}
+	var stationstests = []struct {
+		area string
+		want string
+	}{
+		{"JP13", "FMT\tTOKYO FM\nRN1\tラジオNIKKEI第1\nTBS\tTBSラジオ\n"},
+		{"JP1", "HBC\tHBCラジオ\nRN1\tラジオNIKKEI第1\n"},
+		{"JP47", ""},
+	}
+	for _, tt := range stationstests {
+		var buf bytes.Buffer
+		printStations(&buf, listStations(stations, tt.area))
+		if got := buf.String(); got != tt.want {
+			t.Errorf("stations in %s => %q, want %q", tt.area, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+)
+
+// newStatsCommand prints the bytes downloaded per month, or per day of the month
+func newStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Print the bytes downloaded per month, or per day of the month",
+		Args:  cobra.NoArgs,
+	}
+	fs := cmd.Flags()
+	month := fs.String("month", "", "print the usage per day of the month, e.g., 2023-06.")
+	asJSON := fs.Bool("json", false, "print the usage per day and month in JSON.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		history, err := radicron.NewHistory()
+		if err != nil {
+			return err
+		}
+		stats := history.UsageStats()
+		if *asJSON {
+			return printJSON(os.Stdout, stats)
+		}
+		printUsage(os.Stdout, stats, *month)
+		return nil
+	}
+	return cmd
+}
+
+// printUsage writes the bytes per month, or per day of the month with the total
+func printUsage(w io.Writer, stats *radicron.UsageStats, month string) {
+	if month == "" {
+		for _, m := range stats.Months() {
+			fmt.Fprintf(w, "%s %s\n", m, formatBytes(stats.Monthly[m]))
+		}
+		return
+	}
+	for _, d := range stats.Days(month) {
+		fmt.Fprintf(w, "%s %s\n", d, formatBytes(stats.Daily[d]))
+	}
+	fmt.Fprintf(w, "total %s\n", formatBytes(stats.Monthly[month]))
+}
+
+// formatBytes returns the bytes in MB, or GB from 1024 MB
+func formatBytes(n int64) string {
+	mb := float64(n) / radicron.Kilobytes / radicron.Kilobytes
+	if mb >= radicron.Kilobytes {
+		return fmt.Sprintf("%.2f GB", mb/radicron.Kilobytes)
+	}
+	return fmt.Sprintf("%.1f MB", mb)
+}
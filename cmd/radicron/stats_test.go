@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iomz/radicron"
+)
+
+func TestPrintUsage(t *testing.T) {
+	stats := &radicron.UsageStats{
+		Daily: map[string]int64{
+			"2023-05-31": 3 << 30,
+			"2023-06-01": 1 << 20,
+			"2023-06-02": 512 << 10,
+		},
+		Monthly: map[string]int64{
+			"2023-05": 3 << 30,
+			"2023-06": 3 << 19,
+		},
+	}
+	var usagetests = []struct {
+		month string
+		want  string
+	}{
+		{"", "2023-05 3.00 GB\n2023-06 1.5 MB\n"},
+		{"2023-06", "2023-06-01 1.0 MB\n2023-06-02 0.5 MB\ntotal 1.5 MB\n"},
+		{"2023-07", "total 0.0 MB\n"},
+	}
+	for _, tt := range usagetests {
+		var buf bytes.Buffer
+		printUsage(&buf, stats, tt.month)
+		if buf.String() != tt.want {
+			t.Errorf("printUsage(%q) => %q, want %q", tt.month, buf.String(), tt.want)
+		}
+	}
+}
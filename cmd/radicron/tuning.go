@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/pflag"
+)
+
+// tuning overrides the concurrency and the retry policy in the config, set by the flags
+var tuning tuningFlags
+
+// tuningFlags to tune the throughput without editing the config, zero to keep the config
+type tuningFlags struct {
+	concurrency   int
+	retryAttempts int
+	retryDelay    time.Duration
+}
+
+// register adds the flags to the flag set
+func (t *tuningFlags) register(fs *pflag.FlagSet) {
+	fs.IntVar(&t.concurrency, "concurrency", 0, "the max concurrent segment downloads, overrides concurrency.max in the config and $"+radicron.EnvConcurrency+".")
+	fs.IntVar(&t.retryAttempts, "retry-attempts", 0, "the attempts for each segment, overrides retry.attempts in the config and $"+radicron.EnvRetryAttempts+".")
+	fs.DurationVar(&t.retryDelay, "retry-delay", 0, "the initial backoff before retrying a segment, overrides retry.initial-delay in the config and $"+radicron.EnvRetryInitialDelay+".")
+}
+
+// apply overrides the config with the environment variables, and then the flags
+func (t *tuningFlags) apply(c *radicron.Concurrency, rp *radicron.RetryPolicy) error {
+	max, attempts, delay := t.concurrency, t.retryAttempts, t.retryDelay
+	var err error
+	if s := os.Getenv(radicron.EnvConcurrency); s != "" && max == 0 {
+		if max, err = strconv.Atoi(s); err != nil || max < 1 {
+			return fmt.Errorf("invalid %s: %s", radicron.EnvConcurrency, s)
+		}
+	}
+	if s := os.Getenv(radicron.EnvRetryAttempts); s != "" && attempts == 0 {
+		if attempts, err = strconv.Atoi(s); err != nil || attempts < 1 {
+			return fmt.Errorf("invalid %s: %s", radicron.EnvRetryAttempts, s)
+		}
+	}
+	if s := os.Getenv(radicron.EnvRetryInitialDelay); s != "" && delay == 0 {
+		if delay, err = time.ParseDuration(s); err != nil || delay <= 0 {
+			return fmt.Errorf("invalid %s: %s", radicron.EnvRetryInitialDelay, s)
+		}
+	}
+
+	if max < 0 || attempts < 0 || delay < 0 {
+		return fmt.Errorf("invalid tuning: concurrency=%d, retry-attempts=%d, retry-delay=%v", max, attempts, delay)
+	}
+	if max > 0 {
+		// scale within the new max
+		c.Max = max
+		if c.Initial > max {
+			c.Initial = max
+		}
+		if c.Min > max {
+			c.Min = max
+		}
+	}
+	if attempts > 0 {
+		rp.Attempts = attempts
+	}
+	if delay > 0 {
+		rp.InitialDelay = delay
+		if rp.MaxDelay > 0 && rp.MaxDelay < delay {
+			rp.MaxDelay = delay
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iomz/radicron"
+)
+
+func TestTuningApply(t *testing.T) {
+	var tuningtests = []struct {
+		name     string
+		flags    tuningFlags
+		env      map[string]string
+		max      int
+		initial  int
+		attempts int
+		delay    time.Duration
+		ok       bool
+	}{
+		{"config", tuningFlags{}, nil, radicron.MaxConcurrency, radicron.InitialConcurrency, radicron.MaxRetryAttempts, 500 * time.Millisecond, true},
+		{"env", tuningFlags{}, map[string]string{radicron.EnvConcurrency: "8", radicron.EnvRetryAttempts: "3", radicron.EnvRetryInitialDelay: "2s"}, 8, 8, 3, 2 * time.Second, true},
+		{"flags over env", tuningFlags{4, 12, time.Second}, map[string]string{radicron.EnvConcurrency: "8", radicron.EnvRetryAttempts: "3"}, 4, 4, 12, time.Second, true},
+		{"invalid env", tuningFlags{}, map[string]string{radicron.EnvRetryAttempts: "many"}, 0, 0, 0, 0, false},
+		{"invalid flag", tuningFlags{-1, 0, 0}, nil, 0, 0, 0, 0, false},
+	}
+	for _, tt := range tuningtests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{radicron.EnvConcurrency, radicron.EnvRetryAttempts, radicron.EnvRetryInitialDelay} {
+				t.Setenv(key, tt.env[key])
+			}
+			c := radicron.NewConcurrency()
+			rp := radicron.NewRetryPolicy()
+			err := tt.flags.apply(c, rp)
+			if (err == nil) != tt.ok {
+				t.Fatalf("apply => %v, want ok %v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			if c.Max != tt.max || c.Initial != tt.initial || rp.Attempts != tt.attempts || rp.InitialDelay != tt.delay {
+				t.Errorf("apply => %+v, %+v, want max %v, initial %v, attempts %v, delay %v", c, rp, tt.max, tt.initial, tt.attempts, tt.delay)
+			}
+			if err = c.Validate(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
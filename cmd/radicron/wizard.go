@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// ffmpegCheckTimeout to run ffmpeg -version
+const ffmpegCheckTimeout = 10 * time.Second
+
+// wizardAnswers are the choices in the init wizard
+type wizardAnswers struct {
+	AreaID      string
+	Stations    []string
+	Ignored     []string
+	OutputDir   string
+	FileFormat  string
+	RuleName    string
+	RuleKey     string
+	RuleValue   string
+	RuleStation string
+}
+
+// wizard asks the questions on the terminal
+type wizard struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// isTerminal returns true if f is a terminal to ask the questions
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ask returns the answer to the question, or def if empty
+func (wz *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(wz.w, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(wz.w, "%s: ", question)
+	}
+	line, err := wz.r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// choose asks until the answer is one of the choices
+func (wz *wizard) choose(question, def string, choices ...string) (string, error) {
+	for {
+		answer, err := wz.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, "/")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range choices {
+			if strings.EqualFold(answer, c) {
+				return c, nil
+			}
+		}
+		fmt.Fprintf(wz.w, "  choose one of %s\n", strings.Join(choices, ", "))
+	}
+}
+
+// run asks the area in the detected one, the stations in the area, the output, and the first rule
+func (wz *wizard) run(detectedArea string, listArea func(areaID string) ([]*stationEntry, error)) (*wizardAnswers, error) {
+	a := &wizardAnswers{}
+	var err error
+	fmt.Fprintln(wz.w, "Answer the questions to write the config; press Enter for the default in [brackets].")
+
+	// the area
+	for {
+		if a.AreaID, err = wz.ask("Area to record from, JP1 (Hokkaido) to JP47 (Okinawa)", detectedArea); err != nil {
+			return nil, err
+		}
+		a.AreaID = strings.ToUpper(a.AreaID)
+		if areaIDPattern.MatchString(a.AreaID) {
+			break
+		}
+		fmt.Fprintf(wz.w, "  invalid area: %s\n", a.AreaID)
+	}
+
+	// the stations in the area
+	entries, err := listArea(a.AreaID)
+	if err != nil {
+		fmt.Fprintf(wz.w, "  failed to list the stations: %s\n", err)
+	}
+	if len(entries) > 0 {
+		fmt.Fprintf(wz.w, "Stations in %s:\n", a.AreaID)
+		for i, e := range entries {
+			fmt.Fprintf(wz.w, "  %2d. %s\t%s\n", i+1, e.ID, e.Name)
+		}
+		for {
+			answer, err := wz.ask("Stations to scan, the numbers or IDs separated by commas", "all")
+			if err != nil {
+				return nil, err
+			}
+			if a.Stations, a.Ignored, err = pickStations(entries, answer); err == nil {
+				break
+			}
+			fmt.Fprintf(wz.w, "  %s\n", err)
+		}
+	}
+
+	// the output
+	if a.OutputDir, err = wz.ask("Directory to save the recordings, empty for ${RADICRON_HOME}/downloads", ""); err != nil {
+		return nil, err
+	}
+	if a.FileFormat, err = wz.choose("Audio format", radigo.AudioFormatAAC, radigo.AudioFormatAAC, radigo.AudioFormatMP3); err != nil {
+		return nil, err
+	}
+
+	// the first rule
+	fmt.Fprintln(wz.w, "Add a rule to record the programs:")
+	if a.RuleKey, err = wz.choose("  Match the programs by", "title", "title", "pfm", "keyword"); err != nil {
+		return nil, err
+	}
+	for a.RuleValue == "" {
+		if a.RuleValue, err = wz.ask(fmt.Sprintf("  The %s contains", a.RuleKey), ""); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.Stations) > 0 {
+		for {
+			if a.RuleStation, err = wz.ask("  On the station, empty for any", ""); err != nil {
+				return nil, err
+			}
+			if a.RuleStation == "" {
+				break
+			}
+			picked, _, err := pickStations(entries, a.RuleStation)
+			if err == nil && len(picked) == 1 && contains(a.Stations, picked[0]) {
+				a.RuleStation = picked[0]
+				break
+			}
+			fmt.Fprintf(wz.w, "  unknown station: %s\n", a.RuleStation)
+		}
+	}
+	if a.RuleName, err = wz.ask("  Name of the rule", "my-rule"); err != nil {
+		return nil, err
+	}
+	a.RuleName = strings.ReplaceAll(strings.ToLower(a.RuleName), " ", "-")
+	return a, nil
+}
+
+// pickStations returns the stations picked by the numbers or the IDs, and the rest; all for "all"
+func pickStations(entries []*stationEntry, answer string) (picked, rest []string, err error) {
+	if strings.EqualFold(strings.TrimSpace(answer), "all") {
+		for _, e := range entries {
+			picked = append(picked, e.ID)
+		}
+		return picked, nil, nil
+	}
+	chosen := map[string]bool{}
+	for _, s := range strings.Split(answer, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		found := false
+		for i, e := range entries {
+			if strings.EqualFold(s, e.ID) || s == strconv.Itoa(i+1) {
+				chosen[e.ID] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown station: %s", s)
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, nil, errors.New("no station picked")
+	}
+	for _, e := range entries {
+		if chosen[e.ID] {
+			picked = append(picked, e.ID)
+		} else {
+			rest = append(rest, e.ID)
+		}
+	}
+	return picked, rest, nil
+}
+
+// contains returns true if ss has s
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// render returns the config for the answers
+func (a *wizardAnswers) render() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# written by radicron init; see https://github.com/iomz/radicron#configuration for all the options")
+	fmt.Fprintf(&buf, "area-id: %s\n", a.AreaID)
+	fmt.Fprintf(&buf, "file-format: %s\n", a.FileFormat)
+	if a.OutputDir != "" {
+		fmt.Fprintf(&buf, "output-dir: %s\n", strconv.Quote(a.OutputDir))
+	}
+	if len(a.Ignored) > 0 {
+		fmt.Fprintf(&buf, "ignore-stations: # not to scan, only %s\n", strings.Join(a.Stations, ", "))
+		for _, id := range a.Ignored {
+			fmt.Fprintf(&buf, "  - %s\n", id)
+		}
+	}
+	fmt.Fprintln(&buf, "rules:")
+	fmt.Fprintf(&buf, "  %s:\n", strconv.Quote(a.RuleName))
+	if a.RuleStation != "" {
+		fmt.Fprintf(&buf, "    station-id: %s\n", a.RuleStation)
+	}
+	fmt.Fprintf(&buf, "    %s: %s\n", a.RuleKey, strconv.Quote(a.RuleValue))
+	return buf.Bytes()
+}
+
+// checkFFmpeg returns the version line of ffmpeg, or an error if not working
+func checkFFmpeg(ctx context.Context) (string, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", errors.New("ffmpeg is not found in the PATH, install it from https://ffmpeg.org/download.html")
+	}
+	ctx, cancel := context.WithTimeout(ctx, ffmpegCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg does not run: %s", err)
+	}
+	version, _, _ := strings.Cut(string(out), "\n")
+	return version, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+var wizardStations = []*stationEntry{
+	{ID: "FMJ", Name: "J-WAVE"},
+	{ID: "FMT", Name: "TOKYO FM"},
+	{ID: "TBS", Name: "TBSラジオ"},
+}
+
+func TestPickStations(t *testing.T) {
+	var pickstationstests = []struct {
+		answer string
+		picked string
+		rest   string
+		err    bool
+	}{
+		{"all", "FMJ,FMT,TBS", "", false},
+		{"2, tbs", "FMT,TBS", "FMJ", false},
+		{"FMT", "FMT", "FMJ,TBS", false},
+		{"4", "", "", true},
+		{" , ", "", "", true},
+	}
+	for _, tt := range pickstationstests {
+		picked, rest, err := pickStations(wizardStations, tt.answer)
+		if (err != nil) != tt.err || strings.Join(picked, ",") != tt.picked || strings.Join(rest, ",") != tt.rest {
+			t.Errorf("pickStations(%q) => %v, %v, %v, want %v, %v", tt.answer, picked, rest, err, tt.picked, tt.rest)
+		}
+	}
+}
+
+func TestWizard(t *testing.T) {
+	defer viper.Reset()
+	// retry the invalid answers
+	input := strings.Join([]string{
+		"JP99", "",
+		"fmt, 3",
+		"/srv/radiko",
+		"wav", "mp3",
+		"", "", "THE TRAD",
+		"FMJ", "3",
+		"The Trad",
+	}, "\n") + "\n"
+	var out strings.Builder
+	wz := &wizard{r: bufio.NewReader(strings.NewReader(input)), w: &out}
+	a, err := wz.run("JP13", func(areaID string) ([]*stationEntry, error) {
+		return wizardStations, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"invalid area: JP99", " 2. FMT\tTOKYO FM", "choose one of aac, mp3", "unknown station: FMJ"} {
+		if !strings.Contains(out.String(), prompt) {
+			t.Errorf("wizard output => missing %q", prompt)
+		}
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	if err = writeConfigFile(configFile, a.render(), false); err != nil {
+		t.Fatal(err)
+	}
+	want := `# written by radicron init; see https://github.com/iomz/radicron#configuration for all the options
+area-id: JP13
+file-format: mp3
+output-dir: "/srv/radiko"
+ignore-stations: # not to scan, only FMT, TBS
+  - FMJ
+rules:
+  "the-trad":
+    station-id: TBS
+    title: "THE TRAD"
+`
+	if got, _ := os.ReadFile(configFile); string(got) != want {
+		t.Errorf("render =>\n%s\nwant\n%s", got, want)
+	}
+
+	// the written config is valid
+	if err = loadConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := validateConfig(context.Background(), configFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		t.Errorf("the wizard config => %s: %s", issue.key, issue.msg)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iomz/radicron"
+	"github.com/spf13/cobra"
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// newWorkerCommand downloads the programs queued by the scheduler (record --dispatch)
+func newWorkerCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Download the programs queued by the scheduler (record --dispatch)",
+		Args:  cobra.NoArgs,
+	}
+	fs := cmd.Flags()
+	interval := fs.Duration("interval", time.Minute, "check the queue at the interval while empty.")
+	once := fs.Bool("once", false, "exit once the queue is empty.")
+	tuning.register(fs)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *interval <= 0 {
+			return fmt.Errorf("invalid interval: %v", *interval)
+		}
+
+		if err := loadConfig(configFile); err != nil {
+			return err
+		}
+		q, err := newJobQueue(context.Background())
+		if err != nil {
+			return err
+		}
+		client, err := radiko.New("")
+		if err != nil {
+			return err
+		}
+
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(quit)
+
+		log.Println("starting the worker")
+		for {
+			// finish the job in progress before exiting
+			select {
+			case <-quit:
+				log.Println("exiting the worker")
+				return nil
+			default:
+			}
+
+			job, err := q.Claim()
+			if err != nil {
+				log.Printf("failed to claim a job: %s", err)
+			}
+			if job == nil {
+				if *once {
+					log.Println("the queue is empty – exiting the worker")
+					return nil
+				}
+				select {
+				case <-quit:
+					log.Println("exiting the worker")
+					return nil
+				case <-time.After(*interval):
+				}
+				continue
+			}
+			if err = work(client, configFile, job.Prog); err != nil {
+				log.Printf("job failed: %s", err)
+			}
+			if err = q.Done(job); err != nil {
+				log.Printf("failed to remove the job: %s", err)
+			}
+		}
+	}
+	return cmd
+}
+
+// work downloads the program with the fresh asset and the config
+func work(client *radiko.Client, conf string, prog *radicron.Prog) error {
+	asset, err := radicron.NewAsset(context.Background(), client)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(context.Background(), radicron.ContextKey("asset"), asset)
+	if _, err = reload(ctx, conf); err != nil {
+		return err
+	}
+	wg := sync.WaitGroup{}
+	err = radicron.Download(ctx, &wg, prog)
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,190 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// slots limits the concurrent segment downloads across the programs
+var slots = newLimiter(InitialConcurrency, MinConcurrency, MaxConcurrency)
+
+// concurrency configured for the slots, guarded by bandwidth.mu with the low-bandwidth mode
+var concurrency = NewConcurrency()
+
+// Concurrency of the adaptive segment downloads
+type Concurrency struct {
+	Initial int `mapstructure:"initial"`
+	Min     int `mapstructure:"min"`
+	Max     int `mapstructure:"max"`
+}
+
+// Validate returns an error unless 1 <= min <= initial <= max
+func (c *Concurrency) Validate() error {
+	if c.Min < 1 || c.Min > c.Initial || c.Initial > c.Max {
+		return fmt.Errorf("invalid concurrency: initial=%d, min=%d, max=%d", c.Initial, c.Min, c.Max)
+	}
+	return nil
+}
+
+// NewConcurrency returns the default Concurrency
+func NewConcurrency() *Concurrency {
+	return &Concurrency{
+		Initial: InitialConcurrency,
+		Min:     MinConcurrency,
+		Max:     MaxConcurrency,
+	}
+}
+
+// SetConcurrency applies the concurrency to the segment downloads,
+// starting over from the initial only if changed
+func SetConcurrency(c *Concurrency) {
+	bandwidth.mu.Lock()
+	defer bandwidth.mu.Unlock()
+	if *c == *concurrency {
+		return
+	}
+	concurrency = c
+	max := c.Max
+	if bandwidth.enabled && max > LowBandwidthConcurrency {
+		max = LowBandwidthConcurrency
+	}
+	slots.configure(c.Initial, c.Min, max)
+}
+
+// limiter scales the concurrency with AIMD:
+// it adds a slot after each window of successes unless the throughput dropped,
+// and halves the slots on an error
+type limiter struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	limit   float64
+	min     float64
+	max     float64
+	inUse   int
+	// the current window
+	start time.Time
+	done  int
+	// the throughput of the last window in segments per second
+	throughput float64
+}
+
+func newLimiter(initial, min, max int) *limiter {
+	return &limiter{
+		changed: make(chan struct{}),
+		limit:   float64(initial),
+		min:     float64(min),
+		max:     float64(max),
+		start:   time.Now(),
+	}
+}
+
+// capacity returns the slots available, leaving the reserved ones for the urgent
+func (l *limiter) capacity(urgent bool) int {
+	n := int(l.limit)
+	if urgent {
+		return n
+	}
+	// the reserved at the configured max, up to the half
+	atMax := math.Min(UrgentReservedSlots, math.Floor(l.max/2))
+	reserved := int(math.Ceil(l.limit * atMax / l.max))
+	if n-reserved < 1 {
+		return 1
+	}
+	return n - reserved
+}
+
+// Limit returns the current number of the slots
+func (l *limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// acquire waits for a slot
+func (l *limiter) acquire(ctx context.Context, urgent bool) error {
+	for {
+		l.mu.Lock()
+		if l.inUse < l.capacity(urgent) {
+			l.inUse++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees the slot and adjusts the limit with the result
+func (l *limiter) release(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inUse--
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// not the link's fault
+	case err != nil:
+		l.decrease()
+	default:
+		l.done++
+		if float64(l.done) >= l.limit {
+			elapsed := time.Since(l.start).Seconds()
+			throughput := float64(l.done) / math.Max(elapsed, 1e-9)
+			if l.throughput > 0 && throughput < l.throughput*ThroughputDropRatio {
+				l.decrease()
+			} else {
+				l.limit = math.Min(l.max, l.limit+1)
+				l.reset()
+			}
+			l.throughput = throughput
+		}
+	}
+	// wake up the waiters
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// configure starts over with the new bounds
+func (l *limiter) configure(initial, min, max int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.min = float64(min)
+	l.max = float64(max)
+	l.limit = math.Min(float64(initial), l.max)
+	l.throughput = 0
+	l.reset()
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// setMax changes the upper bound of the slots, lowering the current limit if needed
+func (l *limiter) setMax(max int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.max = float64(max)
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+	l.reset()
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// decrease halves the limit and starts a new window
+func (l *limiter) decrease() {
+	l.limit = math.Min(l.max, math.Max(l.min, l.limit/2))
+	l.reset()
+}
+
+func (l *limiter) reset() {
+	l.start = time.Now()
+	l.done = 0
+}
@@ -0,0 +1,136 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(4, 2, 8)
+	ctx := context.Background()
+
+	// a window of successes adds a slot
+	for i := 0; i < 4; i++ {
+		if err := l.acquire(ctx, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		l.release(nil)
+	}
+	if l.Limit() != 5 {
+		t.Errorf("Limit after a window => %v, want 5", l.Limit())
+	}
+
+	// an error halves the slots down to the minimum
+	for _, want := range []int{2, 2} {
+		if err := l.acquire(ctx, false); err != nil {
+			t.Fatal(err)
+		}
+		l.release(errors.New("503 Service Unavailable"))
+		if l.Limit() != want {
+			t.Errorf("Limit after an error => %v, want %v", l.Limit(), want)
+		}
+	}
+
+	// the cancellation does not count
+	_ = l.acquire(ctx, false)
+	l.release(context.Canceled)
+	if l.Limit() != 2 {
+		t.Errorf("Limit after the cancellation => %v, want 2", l.Limit())
+	}
+}
+
+func TestLimiterUrgent(t *testing.T) {
+	l := newLimiter(MaxConcurrency, MinConcurrency, MaxConcurrency)
+	ctx := context.Background()
+	for i := 0; i < MaxConcurrency-UrgentReservedSlots; i++ {
+		if err := l.acquire(ctx, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the normal slots are exhausted
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(timeout, false); err == nil {
+		t.Error("acquire(normal) => nil, want the deadline exceeded")
+	}
+
+	// the reserved slots are left for the urgent ones
+	if err := l.acquire(ctx, true); err != nil {
+		t.Errorf("acquire(urgent) => %v, want nil", err)
+	}
+
+	// a released slot wakes up the waiter
+	done := make(chan error)
+	go func() { done <- l.acquire(ctx, false) }()
+	l.release(nil)
+	l.release(nil)
+	if err := <-done; err != nil {
+		t.Errorf("acquire after release => %v, want nil", err)
+	}
+}
+
+func TestLimiterCapacity(t *testing.T) {
+	var capacitytests = []struct {
+		limit, max int
+		want       int
+	}{
+		{MaxConcurrency, MaxConcurrency, MaxConcurrency - UrgentReservedSlots},
+		{MaxConcurrency / 2, MaxConcurrency, MaxConcurrency/2 - UrgentReservedSlots/2},
+		{8, 8, 4},
+		{4, 8, 2},
+		{1, 1, 1},
+	}
+	for _, tt := range capacitytests {
+		l := newLimiter(tt.limit, 1, tt.max)
+		if got := l.capacity(false); got != tt.want {
+			t.Errorf("capacity(limit=%v, max=%v) => %v, want %v", tt.limit, tt.max, got, tt.want)
+		}
+		if got := l.capacity(true); got != tt.limit {
+			t.Errorf("capacity(limit=%v, max=%v, urgent) => %v, want %v", tt.limit, tt.max, got, tt.limit)
+		}
+	}
+}
+
+func TestConcurrencyValidate(t *testing.T) {
+	var concurrencytests = []struct {
+		c       Concurrency
+		wantErr bool
+	}{
+		{*NewConcurrency(), false},
+		{Concurrency{Initial: 1, Min: 1, Max: 1}, false},
+		{Concurrency{Initial: 4, Min: 0, Max: 8}, true},
+		{Concurrency{Initial: 1, Min: 2, Max: 8}, true},
+		{Concurrency{Initial: 16, Min: 2, Max: 8}, true},
+	}
+	for _, tt := range concurrencytests {
+		if err := tt.c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) => %v, want error %v", tt.c, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSetConcurrency(t *testing.T) {
+	defer SetConcurrency(NewConcurrency())
+	SetConcurrency(&Concurrency{Initial: 3, Min: 1, Max: 4})
+	if slots.Limit() != 3 {
+		t.Errorf("Limit => %v, want 3", slots.Limit())
+	}
+
+	// the low-bandwidth mode caps and restores the configured max
+	SetLowBandwidth(true)
+	if slots.Limit() != LowBandwidthConcurrency {
+		t.Errorf("Limit in the low-bandwidth mode => %v, want %v", slots.Limit(), LowBandwidthConcurrency)
+	}
+	SetLowBandwidth(false)
+	slots.mu.Lock()
+	max := slots.max
+	slots.mu.Unlock()
+	if max != 4 {
+		t.Errorf("max after the low-bandwidth mode => %v, want 4", max)
+	}
+}
@@ -1,42 +1,335 @@
 package radicron
 
 const (
+	// ADTSHeaderLength without the CRC
+	ADTSHeaderLength = 7
+	// ArchiveCorruptSuffix for the corrupted guide archive moved aside
+	ArchiveCorruptSuffix = ".corrupt"
+	// ArchiveDayLayout for the guide archive files
+	ArchiveDayLayout = "20060102"
+	// AuthTimeoutSeconds to authorize for an area
+	AuthTimeoutSeconds = 30
+	// BlackoutDateLayout for the whole days of the blackouts
+	BlackoutDateLayout = "2006-01-02"
+	// BlackoutDatetimeLayout for the blackouts from or to the time
+	BlackoutDatetimeLayout = "2006-01-02 15:04"
+	// BlockMaxHours of the airtime to record regardless of the programs
+	BlockMaxHours = 24
 	// BufferMinutes for fetching the playlist.m3u8 chunks
 	BufferMinutes = 5
+	// BundleBitrate to encode the audiobook if not in aac
+	BundleBitrate = "64k"
+	// ChapterBlockSeconds to group the transcript cues for the topic segmentation
+	ChapterBlockSeconds = 60
+	// ChapterSummaryRunes for the chapter titles
+	ChapterSummaryRunes = 40
+	// ChapterWindowBlocks to compare at each gap for the topic segmentation
+	ChapterWindowBlocks = 2
+	// CopyBufferSize of the pooled buffers to copy the segments
+	CopyBufferSize = 32 * Kilobytes
 	// DatetimeLayout for time strings from radiko
 	DatetimeLayout = "20060102150405"
+	// DebugDirName to dump the invalid playlists in RADICRON_HOME
+	DebugDirName = "debug"
 	// DefaultArea for radiko are
 	DefaultArea = "JP13"
+	// DefaultAvailabilityDelay after the program ends until the timefree is available
+	DefaultAvailabilityDelay = "5m"
 	// RetryDelaySecond for initial delay
 	DefaultInitialDelaySeconds = 60
+	// DefaultLiveBootAhead of the start to prepare the live capture
+	DefaultLiveBootAhead = "1m"
+	// DefaultRadiruArea of the radiru streams and programs
+	DefaultRadiruArea = "tokyo"
 	// DefaultInterval to fetch the programs
 	DefaultInterval = "168h"
 	// DefaultMinimumOutputSize
 	DefaultMinimumOutputSize = 1
+	// DefaultBlacklistExpiry for the programs failed repeatedly
+	DefaultBlacklistExpiry = "168h"
+	// DefaultBlacklistThreshold of the failures to blacklist a program
+	DefaultBlacklistThreshold = 3
+	// DefaultChapterLength is the minimum length of a chapter
+	DefaultChapterLength = "5m"
+	// DefaultPostProcessBacklog of the programs in the post-processing to pause the segment downloads
+	DefaultPostProcessBacklog = 4
+	// DefaultRetryInitialDelay before retrying a segment
+	DefaultRetryInitialDelay = "500ms"
+	// DefaultRetryMaxDelay caps the backoff
+	DefaultRetryMaxDelay = "30s"
+	// DefaultScanInterval to scan the guide again in the daemon mode
+	DefaultScanInterval = "24h"
+	// DefaultSummarizeModel for the summarization
+	DefaultSummarizeModel = "gpt-4o-mini"
+	// DefaultSummarizePrompt for the summarization
+	DefaultSummarizePrompt = "Summarize this radio program transcript in a few sentences in its language."
+	// DefaultFeedTitle for the podcast feed
+	DefaultFeedTitle = "radicron"
+	// DockerSecretsDir to look up the secrets
+	DockerSecretsDir = "/run/secrets"
+	// EnricherTimeoutSeconds for an external enricher command
+	EnricherTimeoutSeconds = 60
+	// EnvConcurrency overrides concurrency.max in the config
+	EnvConcurrency = "RADICRON_CONCURRENCY"
 	// Environment Variable for RADICRON_HOME
 	EnvRadicronHome = "RADICRON_HOME"
+	// EnvRetryAttempts overrides retry.attempts in the config
+	EnvRetryAttempts = "RADICRON_RETRY_ATTEMPTS"
+	// EnvRetryInitialDelay overrides retry.initial-delay in the config
+	EnvRetryInitialDelay = "RADICRON_RETRY_INITIAL_DELAY"
+	// EpisodeTitleDateLayout for {date} in the episode title template
+	EpisodeTitleDateLayout = "2006-01-02"
+	// EventCompleted when the program is saved
+	EventCompleted = "completed"
+	// EventDropped when an upcoming program is dropped from the guide
+	EventDropped = "dropped"
+	// EventEmergency when the emergency or special programming is found in the guide of a watched station
+	EventEmergency = "emergency"
+	// EventDatetimeLayout to print the events in the timeline
+	EventDatetimeLayout = "2006-01-02 15:04:05"
+	// EventExpiring when the program is scheduled within UrgentHours before the expiry
+	EventExpiring = "expiring"
+	// EventFailed when the program failed with the cause
+	EventFailed = "failed"
+	// EventLogFileName to store the lifecycle events in RADICRON_HOME
+	EventLogFileName = "events.jsonl"
+	// EventMoved when an upcoming program is moved to another time in the guide
+	EventMoved = "moved"
+	// EventProgress at the milestones of the download
+	EventProgress = "progress"
+	// EventScheduled when the program is matched to be downloaded
+	EventScheduled = "scheduled"
+	// EventStarted when the download starts
+	EventStarted = "started"
+	// EventsAPILimit of the latest events returned by the API
+	EventsAPILimit = 100
+	// FailureAuth when the authorization to the station failed
+	FailureAuth = "auth"
+	// FailureDiskFull when no space is left to save the program
+	FailureDiskFull = "disk-full"
+	// FailureEncoder when ffmpeg failed to concat, trim, or transcode the audio
+	FailureEncoder = "encoder"
+	// FailureExpired when the timefree of the program expired
+	FailureExpired = "expired"
+	// FailurePlaylist when the playlist of the program is not available
+	FailurePlaylist = "playlist"
+	// FailureSegments when too many segments of the program failed
+	FailureSegments = "segments"
+	// FavoriteRulePrefix for the names of the rules generated from the favorites
+	FavoriteRulePrefix = "favorite-"
+	// ID3v2AdvisoryExplicit for the explicit programs in ID3v2DescAdvisory
+	ID3v2AdvisoryExplicit = "1"
+	// ID3v2DescAdvisory of the TXXX frame for the content advisory (iTunes)
+	ID3v2DescAdvisory = "ITUNESADVISORY"
 	// Language for ID3v2 tags
 	ID3v2LangJPN = "jpn"
+	// EstimatedAACKbps of the timefree aac to estimate the size in the dry-run
+	EstimatedAACKbps = 48.0
+	// EstimatedMP3Kbps of the transcoded mp3 (VBR -q:a 2) to estimate the size in the dry-run
+	EstimatedMP3Kbps = 190.0
+	// FailedDirName to keep the tmp files of the failed programs in RADICRON_HOME
+	FailedDirName = "failed"
+	// FailedReportFileName with the error in the kept tmp files
+	FailedReportFileName = "error.txt"
+	// FingerprintFrameSamples per frame (100ms) for the rerun detection
+	FingerprintFrameSamples = 800
+	// FingerprintMaxShift in frames to align the fingerprints
+	FingerprintMaxShift = 300
+	// FingerprintOffsetSeconds to skip the opening theme, the jingle, or the sponsor block shared by the programs
+	FingerprintOffsetSeconds = 60
+	// FingerprintSampleRate to decode the audio for the fingerprint
+	FingerprintSampleRate = 8000
+	// FingerprintSeconds after the opening of a program for the fingerprint
+	FingerprintSeconds = 180
+	// HistoryBackupGenerations to keep the backups of the history
+	HistoryBackupGenerations = 7
+	// HistoryBackupHours to back up the history
+	HistoryBackupHours = 24
+	// HistoryBackupSuffix for the backups of the history
+	HistoryBackupSuffix = ".bak"
+	// HistoryCorruptSuffix for the corrupted history moved aside
+	HistoryCorruptSuffix = ".corrupt"
+	// HistoryFileName to store the history in RADICRON_HOME
+	HistoryFileName = "history.json"
+	// HistoryVersion of the history format, the last of the migrations
+	HistoryVersion = 1
+	// InitialConcurrency of the adaptive segment downloads
+	InitialConcurrency = 16
+	// InstanceLockFileName in the output dir to run one instance at a time on it
+	InstanceLockFileName = ".radicron.lock"
+	// KeyMethodAES128 for the encrypted segments
+	KeyMethodAES128 = "AES-128"
+	// KeyMethodNone for the unencrypted segments
+	KeyMethodNone = "NONE"
+	// JobClaimTimeoutHours to give the job of a worker gone away to another
+	JobClaimTimeoutHours = 6
+	// JobsDirName for the job queue in RADICRON_HOME
+	JobsDirName = "jobs"
 	// Kilobytes for the metric bytes
 	Kilobytes = 1024
-	// DefaultMaxConcurrents
+	// LeaseFileName to elect the recording instance in RADICRON_HOME
+	LeaseFileName = "lease.json"
+	// LeaseRenewDivisor of the lease duration to renew the lease
+	LeaseRenewDivisor = 3
+	// ListenersFileName to store the feed tokens in RADICRON_HOME
+	ListenersFileName = "listeners.json"
+	// ListenerTokenLength for the feed tokens
+	ListenerTokenLength = 16
+	// LockFileSuffix for the advisory lock next to the file or the output
+	LockFileSuffix = ".lock"
+	// LowBandwidthConcurrency caps the segment downloads in the low-bandwidth mode
+	LowBandwidthConcurrency = 2
+	// LowBandwidthRate in bytes per second across the segment downloads in the low-bandwidth mode
+	LowBandwidthRate = 64 * Kilobytes
+	// LowBandwidthRecheckHours to check the deferred programs again in the low-bandwidth mode
+	LowBandwidthRecheckHours = 1
+	// MaxConcurrency of the adaptive segment downloads
 	MaxConcurrency = 64
+	// MaxPaddingMinutes around the recording
+	MaxPaddingMinutes = 30
 	// MaxRetryAttempts for BackOffDelay
 	MaxRetryAttempts = 8
+	// MaxPooledBufferSize not to keep the large segment buffers in the pool
+	MaxPooledBufferSize = Kilobytes * Kilobytes
+	// MinConcurrency of the adaptive segment downloads
+	MinConcurrency = 2
+	// MinSecretLength to register for the redaction
+	MinSecretLength = 4
 	// OneDay is 24 hours
 	OneDay = 24
 	// OutputDatetimeLayout for downloaded files
 	OutputDatetimeLayout = "200601021504"
+	// OversizeWarn to record the programs over the max-duration with a warning
+	OversizeWarn = "warn"
+	// SourceRadiko for the rules matching the radiko programs (default)
+	SourceRadiko = "radiko"
+	// SourceRadiru for the rules matching the NHK radiru programs, recorded from the live streams
+	SourceRadiru = "radiru"
+	// PartialFileSuffix of the segments in the download
+	PartialFileSuffix = ".part"
+	// PendingFileName to journal the downloads in progress in RADICRON_HOME
+	PendingFileName = "pending.json"
+	// PlaylistPreviewBytes to log the invalid playlist
+	PlaylistPreviewBytes = 200
+	// PlaylistTimeoutSeconds to get a playlist
+	PlaylistTimeoutSeconds = 60
+	// PluginEnricher to improve the metadata before tagging
+	PluginEnricher = "enricher"
+	// PluginNotifier to be told of the events
+	PluginNotifier = "notifier"
+	// PluginProvider to fetch the audio from another source than the timefree
+	PluginProvider = "provider"
+	// PluginStorage to keep the saved recording elsewhere
+	PluginStorage = "storage"
+	// PluginTimeoutSeconds for a call to a plugin
+	PluginTimeoutSeconds = 600
+	// PodcastMatchHours between the broadcast and the publication of the official podcast episode
+	PodcastMatchHours = 72
+	// RadikoChunkSeconds is the length of an aac chunk in the playlist
+	RadikoChunkSeconds = 5
+	// RadikoSessionKey of the premium session in the cookie and the query
+	RadikoSessionKey = "radiko_session"
+	// RadiruDateLayout for the day of the radiru programs
+	RadiruDateLayout = "2006-01-02"
+	// RadiruGuideDays to fetch the radiru programs from today
+	RadiruGuideDays = 7
+	// RadioDayStartHour when the radio day starts, e.g., 25:00 is 01:00 on the next day
+	RadioDayStartHour = 5
+	// ReadHeaderTimeoutSeconds for the feed server
+	ReadHeaderTimeoutSeconds = 10
+	// ReportFileSuffix for the report of the recording next to the audio
+	ReportFileSuffix = ".report.json"
+	// RedactedMask replaces the secrets in the logs
+	RedactedMask = "[REDACTED]"
+	// RedisDefaultPort for the job queue without the port
+	RedisDefaultPort = "6379"
+	// RedisKeyPrefix for the keys of the job queue
+	RedisKeyPrefix = "radicron:"
+	// RedisTimeoutSeconds to connect and wait for a reply
+	RedisTimeoutSeconds = 10
+	// ReencodeBitrate to join the segments across the discontinuities
+	ReencodeBitrate = "64k"
+	// RerunLookbackDays to compare the fingerprints with the recordings
+	RerunLookbackDays = 90
+	// RerunSimilarity of the fingerprints to consider a program as a rerun
+	RerunSimilarity = 0.8
+	// ScriptMaxSteps for a hook in the script not to hang the scan
+	ScriptMaxSteps = 1_000_000
+	// SearchIndexFileName to store the search index next to the history
+	SearchIndexFileName = "search.json"
+	// SegmentTimeoutSeconds to get a segment
+	SegmentTimeoutSeconds = 30
+	// SimulcastDirName for the live captures of the simulcasts in RADICRON_HOME
+	SimulcastDirName = "simulcast"
+	// SimulcastPollSeconds to reload the live playlist of the simulcast
+	SimulcastPollSeconds = 5
+	// SnippetRunes around the term in the search results
+	SnippetRunes = 30
+	// StationListConcurrency to fetch the station lists of the areas in parallel
+	StationListConcurrency = 8
+	// StationsCacheHours to reuse the stations fetched from radiko
+	StationsCacheHours = 24
+	// StationsFileName to cache the stations with the areas in RADICRON_HOME
+	StationsFileName = "stations.json"
+	// SummaryFileSuffix for the summary next to the audio
+	SummaryFileSuffix = ".summary.txt"
+	// SummaryInputRunes to send for the summarization
+	SummaryInputRunes = 20000
+	// TokenLifetimeMinutes for reusing the auth token
+	TokenLifetimeMinutes = 60
+	// TokenRefreshMinutes before the expiry to refresh the auth token, not to expire in the middle of a download
+	TokenRefreshMinutes = 5
+	// ThroughputDropRatio of the last window to scale down the concurrency
+	ThroughputDropRatio = 0.8
+	// TimefreeExpiryDays after the program starts until the timefree expires
+	TimefreeExpiryDays = 7
+	// TranscodeMinTimeoutSeconds to transcode however short the audio is
+	TranscodeMinTimeoutSeconds = 60
+	// TranscodeTimeoutFactor of the audio duration to transcode it
+	TranscodeTimeoutFactor = 2
 	// TZTokyo for time location
 	TZTokyo = "Asia/Tokyo"
+	// UpcomingFileName to remember the programs until available in RADICRON_HOME
+	UpcomingFileName = "upcoming.json"
+	// UrgentBackoffDivisor shortens the backoff for the programs about to expire
+	UrgentBackoffDivisor = 4
+	// UrgentHours before the expiry to escalate the program
+	UrgentHours = 6
+	// UrgentReservedSlots at the max concurrency, up to the half, for the programs about to expire
+	UrgentReservedSlots = 16
+	// UsageDayLayout for the bytes downloaded per day
+	UsageDayLayout = "2006-01-02"
+	// UsageMonthLayout for the bytes downloaded per month
+	UsageMonthLayout = "2006-01"
 	// UserIDLength for user-id
 	UserIDLength = 16
 
 	// API endpoints
 	// region full
+	APIArea          = "https://radiko.jp/area"
 	APIRegionFull    = "https://radiko.jp/v3/station/region/full.xml"
+	APIStationList   = "https://radiko.jp/v3/station/list/%s.xml"
 	APIPlaylistM3U8  = "https://radiko.jp/v2/api/ts/playlist.m3u8"
 	APIWeeklyProgram = "https://radiko.jp/v3/program/station/weekly/%s.xml"
+	APINowProgram    = "https://radiko.jp/v3/program/now/%s.xml"
+	APILiveM3U8      = "https://f-radiko.smartstream.ne.jp/%s/_definst_/simul-stream.stream/playlist.m3u8"
+	// radiko premium login and logout with the mail and the password
+	APIPremiumLogin  = "https://radiko.jp/v4/api/member/login"
+	APIPremiumLogout = "https://radiko.jp/v4/api/member/logout"
+	// radiko premium favorites (マイリスト) of the member logged in
+	APIPremiumFavorites = "https://radiko.jp/v4/api/member/favorite/list"
+	// NHK radiru config with the areas and the live streams
+	APIRadiruConfig = "https://www.nhk.or.jp/radio/config/config_web.xml"
+	// NHK radiru programs of the area key, the service, and the date (2006-01-02)
+	APIRadiruProgramDay = "https://api.nhk.or.jp/r5/pg2/list/4/%s/%s/%s.json"
+
+	// Endpoint names for the header profiles
+	EndpointAuth1    = "auth1"
+	EndpointAuth2    = "auth2"
+	EndpointPlaylist = "playlist"
+	EndpointLive     = "live"
+	// DefaultHeaderProfile applies to all the stations
+	DefaultHeaderProfile = "default"
 
 	// HTTP Headers
 	// auth1 req
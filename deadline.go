@@ -0,0 +1,27 @@
+package radicron
+
+import (
+	"context"
+	"time"
+)
+
+// the deadlines of the stages of a download, shortened in the tests
+var (
+	authTimeout         = AuthTimeoutSeconds * time.Second
+	playlistTimeout     = PlaylistTimeoutSeconds * time.Second
+	segmentTimeout      = SegmentTimeoutSeconds * time.Second
+	transcodeMinTimeout = TranscodeMinTimeoutSeconds * time.Second
+)
+
+// transcodeTimeout returns the deadline to transcode the audio of the duration
+func transcodeTimeout(d time.Duration) time.Duration {
+	if timeout := TranscodeTimeoutFactor * d; timeout > transcodeMinTimeout {
+		return timeout
+	}
+	return transcodeMinTimeout
+}
+
+// transcodeContext returns the context with the deadline to transcode the audio of the duration
+func transcodeContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, transcodeTimeout(d))
+}
@@ -0,0 +1,131 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// stallTransport holds the requests until canceled
+type stallTransport struct{}
+
+func (stallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+// shortenTimeouts shortens the deadlines of the stages until the test ends
+func shortenTimeouts(t *testing.T, d time.Duration) {
+	t.Helper()
+	auth, playlist, segment := authTimeout, playlistTimeout, segmentTimeout
+	authTimeout, playlistTimeout, segmentTimeout = d, d, d
+	t.Cleanup(func() {
+		authTimeout, playlistTimeout, segmentTimeout = auth, playlist, segment
+	})
+}
+
+func TestStageTimeouts(t *testing.T) {
+	shortenTimeouts(t, 50*time.Millisecond)
+	// the server never responds in time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	var stagetests = []struct {
+		stage string
+		fetch func(ctx context.Context) error
+	}{
+		{"playlist", func(ctx context.Context) error {
+			_, err := getChunklistFromM3U8(ctx, ts.URL+"/chunklist.m3u8", true)
+			return err
+		}},
+		{"live playlist", func(ctx context.Context) error {
+			_, err := liveChunklist(ctx, ts.URL+"/live.m3u8")
+			return err
+		}},
+		{"podcast feed", func(ctx context.Context) error {
+			_, err := fetchPodcastFeed(ctx, ts.URL+"/feed.xml")
+			return err
+		}},
+		{"segment", func(ctx context.Context) error {
+			return downloadSegment(ctx, &Segment{URI: ts.URL + "/segment.aac"}, newSegmentKeys(), t.TempDir())
+		}},
+		{"segment size", func(ctx context.Context) error {
+			_, err := contentLength(ctx, ts.URL+"/segment.aac")
+			return err
+		}},
+	}
+	for _, tt := range stagetests {
+		start := time.Now()
+		err := tt.fetch(context.Background())
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s => %v, want %v", tt.stage, err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s => timed out in %v", tt.stage, elapsed)
+		}
+	}
+}
+
+func TestAuthTimeout(t *testing.T) {
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset := &Asset{AuthSessions: NewAuthSessions(), DefaultClient: client}
+	blob, err := VersionsJSON.ReadFile("assets/versions.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(blob, &asset.Versions); err != nil {
+		t.Fatal(err)
+	}
+
+	shortenTimeouts(t, 50*time.Millisecond)
+	transport := http.DefaultTransport
+	http.DefaultTransport = stallTransport{}
+	defer func() { http.DefaultTransport = transport }()
+
+	start := time.Now()
+	_, err = asset.AuthSessions.Get("JP13").Authorize(context.Background(), asset)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Authorize() => %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Authorize() => timed out in %v", elapsed)
+	}
+}
+
+func TestTranscodeTimeout(t *testing.T) {
+	var transcodetests = []struct {
+		duration time.Duration
+		want     time.Duration
+	}{
+		{0, TranscodeMinTimeoutSeconds * time.Second},
+		{10 * time.Second, TranscodeMinTimeoutSeconds * time.Second},
+		{30 * time.Minute, time.Hour},
+		{2 * time.Hour, 4 * time.Hour},
+	}
+	for _, tt := range transcodetests {
+		if got := transcodeTimeout(tt.duration); got != tt.want {
+			t.Errorf("transcodeTimeout(%v) => %v, want %v", tt.duration, got, tt.want)
+		}
+	}
+
+	ctx, cancel := transcodeContext(context.Background(), time.Hour)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) <= time.Hour || time.Until(deadline) > 2*time.Hour {
+		t.Errorf("transcodeContext(1h) => deadline in %v, want 2h", time.Until(deadline))
+	}
+}
@@ -0,0 +1,59 @@
+package radicron
+
+import (
+	"log"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DedupKey returns the key of the broadcast shared by the simulcasts on the affiliate stations,
+// i.e., the title ignoring the case, the width, the spaces, and the punctuation, with the start
+func DedupKey(title, ft string) string {
+	var sb strings.Builder
+	for _, r := range title {
+		// the fullwidth ASCII variants
+		if r >= '！' && r <= '～' {
+			r -= '！' - '!'
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String() + "@" + ft
+}
+
+// Dedup returns the programs without the simulcasts of the earlier ones on the other stations
+func (ps Progs) Dedup() Progs {
+	seen := map[string]*Prog{}
+	progs := Progs{}
+	for _, p := range ps {
+		key := DedupKey(p.Title, p.Ft)
+		if q, ok := seen[key]; ok && q.StationID != p.StationID {
+			log.Printf("-skip simulcast [%s]%s (%s) of %s", p.StationID, p.Title, p.Ft, q.StationID)
+			continue
+		}
+		seen[key] = p
+		progs = append(progs, p)
+	}
+	return progs
+}
+
+// FindDuplicate returns the record of the same broadcast saved from another station if any
+func (h *History) FindDuplicate(prog *Prog) *HistoryRecord {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := DedupKey(prog.Title, prog.Ft)
+	for _, r := range h.Records {
+		if r.StationID == prog.StationID || r.Path == "" || r.Ft != prog.Ft || DedupKey(r.Title, r.Ft) != key {
+			continue
+		}
+		if _, err := os.Stat(r.Path); err == nil {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupKey(t *testing.T) {
+	var dedupkeytests = []struct {
+		a, b string
+		same bool
+	}{
+		{"ＴＨＥ ＴＲＡＤ", "THE TRAD", true},
+		{"オールナイトニッポン", "オールナイトニッポン！", true},
+		{"Tokyo Hot 100", "TOKYO HOT100", true},
+		{"THE TRAD", "THE TRAD 2", false},
+	}
+	for _, tt := range dedupkeytests {
+		if got := DedupKey(tt.a, "20230605130000") == DedupKey(tt.b, "20230605130000"); got != tt.same {
+			t.Errorf("DedupKey(%q) == DedupKey(%q) => %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+	if DedupKey("THE TRAD", "20230605130000") == DedupKey("THE TRAD", "20230606130000") {
+		t.Error("DedupKey ignores the start")
+	}
+}
+
+func TestProgsDedup(t *testing.T) {
+	ps := Progs{
+		{ID: "1", StationID: "LFR", Title: "オールナイトニッポン", Ft: "20230605010000"},
+		{ID: "2", StationID: "HBC", Title: "オールナイトニッポン", Ft: "20230605010000"},
+		{ID: "3", StationID: "LFR", Title: "オールナイトニッポン", Ft: "20230606010000"},
+		{ID: "4", StationID: "STV", Title: "ＴＨＥ ＴＲＡＤ", Ft: "20230605130000"},
+		{ID: "5", StationID: "FMT", Title: "THE TRAD", Ft: "20230605130000"},
+		{ID: "6", StationID: "FMT", Title: "THE TRAD", Ft: "20230605130000"},
+	}
+	got := ""
+	for _, p := range ps.Dedup() {
+		got += p.ID
+	}
+	if got != "134" {
+		t.Errorf("Dedup() => %s, want 134", got)
+	}
+}
+
+func TestHistoryFindDuplicate(t *testing.T) {
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "saved.aac")
+	if err := os.WriteFile(saved, []byte("aac"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := &History{Records: map[string]*HistoryRecord{
+		"1": {ID: "1", StationID: "LFR", Title: "オールナイトニッポン", Ft: "20230605010000", Path: saved},
+		"2": {ID: "2", StationID: "LFR", Title: "THE TRAD", Ft: "20230605130000", Path: filepath.Join(dir, "removed.aac")},
+	}}
+	var findduplicatetests = []struct {
+		prog *Prog
+		want string
+	}{
+		{&Prog{ID: "3", StationID: "HBC", Title: "オールナイトニッポン", Ft: "20230605010000"}, "1"},
+		{&Prog{ID: "1", StationID: "LFR", Title: "オールナイトニッポン", Ft: "20230605010000"}, ""},
+		{&Prog{ID: "4", StationID: "HBC", Title: "オールナイトニッポン", Ft: "20230606010000"}, ""},
+		{&Prog{ID: "5", StationID: "FMT", Title: "THE TRAD", Ft: "20230605130000"}, ""},
+	}
+	for _, tt := range findduplicatetests {
+		got := ""
+		if r := h.FindDuplicate(tt.prog); r != nil {
+			got = r.ID
+		}
+		if got != tt.want {
+			t.Errorf("FindDuplicate(%s) => %q, want %q", tt.prog.ID, got, tt.want)
+		}
+	}
+	if (*History)(nil).FindDuplicate(&Prog{}) != nil {
+		t.Error("FindDuplicate on nil history")
+	}
+}
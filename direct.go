@@ -0,0 +1,170 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// errNotDirect is returned when the segments cannot be written straight to the output
+var errNotDirect = errors.New("not directly writable")
+
+// directDownload writes the segments at their offsets in the preallocated file in dir,
+// without the separate concat pass, if all the sizes are known from Content-Length
+func directDownload(ctx context.Context, segments Segments, dir string) (string, error) {
+	if !segments.directWritable() {
+		return "", errNotDirect
+	}
+	unique, dups := segments.dedupe()
+	sizes, err := segmentSizes(ctx, unique)
+	if err != nil {
+		return "", err
+	}
+	for orig, ss := range dups {
+		for _, dup := range ss {
+			sizes[dup] = sizes[orig]
+		}
+	}
+	offsets := make(map[*Segment]int64, len(segments))
+	var total int64
+	for _, s := range segments {
+		offsets[s] = total
+		total += sizes[s]
+	}
+
+	output := filepath.Join(dir, "concated.aac")
+	f, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	if err = preallocate(f, total); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to preallocate %d bytes: %s", total, err)
+	}
+	_, err = bulkFetch(ctx, unique, func(ctx context.Context, segment *Segment) error {
+		at := []int64{offsets[segment]}
+		for _, dup := range dups[segment] {
+			at = append(at, offsets[dup])
+		}
+		return writeSegmentAt(ctx, segment, f, at, sizes[segment])
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = validateDirect(output)
+	}
+	if err != nil {
+		os.Remove(output)
+		return "", err
+	}
+	return output, nil
+}
+
+// segmentSizes returns the sizes of the segments from Content-Length
+func segmentSizes(ctx context.Context, segments Segments) (map[*Segment]int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sizes := make(map[*Segment]int64, len(segments))
+	var firstErr error
+	for _, v := range segments {
+		wg.Add(1)
+		go func(segment *Segment) {
+			defer wg.Done()
+			size, err := contentLength(ctx, segment.URI)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			sizes[segment] = size
+		}(v)
+	}
+	wg.Wait()
+	return sizes, firstErr
+}
+
+// contentLength sends a HEAD request for the size of uri
+func contentLength(ctx context.Context, uri string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, segmentTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uri, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	if err = acquireSlot(ctx, false); err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	releaseSlot(err)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, fmt.Errorf("%w: unknown size of %s (%s)", errNotDirect, uri, resp.Status)
+	}
+	return resp.ContentLength, nil
+}
+
+// writeSegmentAt downloads the segment and writes it at the offsets
+func writeSegmentAt(ctx context.Context, segment *Segment, w io.WriterAt, offsets []int64, size int64) error {
+	ctx, cancel := context.WithTimeout(ctx, segmentTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, segment.URI, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", segment.URI, resp.Status)
+	}
+
+	// read one more byte to detect the size change
+	buf := getSegmentBuffer()
+	defer putSegmentBuffer(buf)
+	if _, err = buf.ReadFrom(io.LimitReader(throttle(ctx, resp.Body), size+1)); err != nil {
+		return err
+	}
+	if int64(buf.Len()) != size {
+		return fmt.Errorf("the size of %s changed: %d => %d bytes", segment.URI, size, buf.Len())
+	}
+	for _, offset := range offsets {
+		if _, err = w.WriteAt(buf.Bytes(), offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDirect checks the ADTS frames in the output,
+// the invalid frames or the format change need the segment files to handle
+func validateDirect(output string) error {
+	f, err := os.Open(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = ValidateADTS(f); err != nil {
+		return fmt.Errorf("%w: %s", errNotDirect, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDirectDownload(t *testing.T) {
+	frames := [][]byte{}
+	for i := 0; i < 5; i++ {
+		frames = append(frames, adtsFrame(3, 2, bytes.Repeat([]byte{byte(i)}, 100+i)))
+	}
+	noHead := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && noHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		i, _ := strconv.Atoi(filepath.Base(r.URL.Path[:len(r.URL.Path)-len(".aac")]))
+		w.Header().Set("Content-Length", strconv.Itoa(len(frames[i])))
+		_, _ = w.Write(frames[i])
+	}))
+	defer ts.Close()
+
+	asset := &Asset{Retry: &RetryPolicy{Attempts: 1, InitialDelay: time.Millisecond}}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	segments := Segments{}
+	for i := range frames {
+		segments = append(segments, &Segment{Index: i, URI: fmt.Sprintf("%s/%d.aac", ts.URL, i)})
+	}
+
+	// repeat the second segment at the end
+	segments = append(segments, &Segment{Index: len(frames), URI: segments[1].URI})
+	want := append(bytes.Join(frames, nil), frames[1]...)
+
+	output, err := directDownload(ctx, segments, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("directDownload => %d bytes, want the %d bytes of the segments in order", len(got), len(want))
+	}
+
+	// the sizes are unknown
+	noHead = true
+	if _, err = directDownload(ctx, segments, t.TempDir()); !errors.Is(err, errNotDirect) {
+		t.Errorf("directDownload without HEAD => %v, want %v", err, errNotDirect)
+	}
+
+	// the segments need the segment files
+	encrypted := Segments{{URI: ts.URL + "/0.aac", Key: &SegmentKey{Method: KeyMethodAES128}}}
+	if _, err = directDownload(ctx, encrypted, t.TempDir()); !errors.Is(err, errNotDirect) {
+		t.Errorf("directDownload(encrypted) => %v, want %v", err, errNotDirect)
+	}
+}
+
+func TestPreallocate(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "concated.aac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = preallocate(f, 4096); err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 4096 {
+		t.Errorf("preallocate => %v bytes, want 4096", info.Size())
+	}
+}
@@ -1,6 +1,7 @@
 package radicron
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bogem/id3v2"
@@ -18,7 +20,14 @@ import (
 	"github.com/yyoshiki41/radigo"
 )
 
-var sem = make(chan struct{}, MaxConcurrency)
+// ErrExpired is returned when the timefree of the program has expired
+var ErrExpired = errors.New("expired")
+
+// ErrRerun is returned when the program is a rerun of a recording
+var ErrRerun = errors.New("rerun")
+
+// outputDir overrides the downloads dir if set
+var outputDir atomic.Value
 
 func Download(
 	ctx context.Context,
@@ -28,24 +37,138 @@ func Download(
 	asset := GetAsset(ctx)
 	title := prog.Title
 	start := prog.Ft
-	var startTime, nextEndTime time.Time
+	var startTime, endTime time.Time
 
 	startTime, err = time.ParseInLocation(DatetimeLayout, start, Location)
 	if err != nil {
 		return fmt.Errorf("invalid start time format '%s': %s", start, err)
 	}
 
-	// the program is in the future
-	if startTime.After(CurrentTime) {
-		nextEndTime, err = time.ParseInLocation(DatetimeLayout, prog.To, Location)
+	endTime, err = time.ParseInLocation(DatetimeLayout, prog.To, Location)
+	if err != nil {
+		return fmt.Errorf("invalid end time format '%s': %s", prog.To, err)
+	}
+
+	fileBaseName := fmt.Sprintf(
+		"%s_%s_%s",
+		startTime.In(Location).Format(OutputDatetimeLayout),
+		prog.StationID,
+		sanitizeFileName(title),
+	)
+	if name, err := asset.Script.FileName(prog); err != nil {
+		log.Printf("script filename failed for [%s]%s (%s): %s", prog.StationID, title, start, err)
+	} else if name != "" {
+		fileBaseName = name
+	}
+
+	// save only the metadata as soon as the program is in the guide
+	if asset.MetadataOnly && !asset.DryRun {
+		path, err := saveMetadata(ctx, prog, fileBaseName)
 		if err != nil {
-			return fmt.Errorf("invalid end time format '%s': %s", start, err)
+			return fmt.Errorf("failed to save the metadata [%s]%s (%s): %s", prog.StationID, title, start, err)
+		}
+		log.Printf("+saved the metadata: %s", path)
+		return nil
+	}
+
+	// pause the scheduling in the blackout to catch up after it
+	if until, ok := BlackoutUntil(CurrentTime); ok {
+		if !until.IsZero() && (asset.NextFetchTime == nil || asset.NextFetchTime.After(until)) {
+			asset.NextFetchTime = &until
 		}
+		log.Printf("-defer in the blackout [%s]%s (%s)", prog.StationID, title, start)
+		return nil
+	}
+
+	// the live capture is ready without waiting for the timefree
+	live := asset.IsLive(prog.StationID) || asset.IsLiveFallback(prog)
+	captured := live && simulcastCapture(prog) != ""
+
+	// the program is in the future or the timefree is not yet available
+	availableTime := endTime.Add(prog.PadAfter + asset.GetAvailabilityDelay(prog.StationID))
+	if availableTime.After(CurrentTime) && !captured {
 		// update the next fetching time
-		if asset.NextFetchTime == nil || asset.NextFetchTime.After(nextEndTime) {
-			next := nextEndTime.Add(BufferMinutes * time.Minute)
-			asset.NextFetchTime = &next
+		if asset.NextFetchTime == nil || asset.NextFetchTime.After(availableTime) {
+			asset.NextFetchTime = &availableTime
+		}
+		// capture the station-owned simulcast live in case the timefree fails
+		if uri := asset.GetSimulcast(prog.StationID); uri != "" && !asset.DryRun && endTime.After(CurrentTime) {
+			go captureSimulcast(ctx, prog, uri)
+		} else if live && !asset.DryRun && endTime.After(CurrentTime) {
+			if liveCtx, uri, err := liveStream(ctx, prog.StationID); err != nil {
+				log.Printf("failed to schedule the live capture [%s]%s (%s): %s", prog.StationID, title, start, err)
+			} else {
+				go captureSimulcast(liveCtx, prog, uri)
+			}
+		}
+		// record it once available even if it drops out of the guide
+		if asset.Upcoming != nil && !asset.DryRun {
+			added, err := asset.Upcoming.Add(prog, availableTime)
+			if err != nil {
+				return fmt.Errorf("failed to schedule [%s]%s (%s): %s", prog.StationID, title, start, err)
+			}
+			if added {
+				log.Printf("+scheduled [%s]%s (%s) at %v", prog.StationID, title, start, availableTime)
+			}
+		}
+		return nil
+	}
+
+	// the timefree is no longer available
+	expiry := startTime.AddDate(0, 0, TimefreeExpiryDays)
+	if !CurrentTime.Before(expiry) {
+		if !asset.DryRun {
+			if err = asset.History.RecordExpired(prog); err != nil {
+				log.Printf("failed to save the history: %s", err)
+			}
+		}
+		return fmt.Errorf("%w at %v [%s]%s (%s)", ErrExpired, expiry, prog.StationID, title, start)
+	}
+
+	// leave the metered link for the programs about to expire
+	if LowBandwidth() {
+		if urgentTime := expiry.Add(-UrgentHours * time.Hour); CurrentTime.Before(urgentTime) {
+			recheckTime := CurrentTime.Add(LowBandwidthRecheckHours * time.Hour)
+			if urgentTime.Before(recheckTime) {
+				recheckTime = urgentTime
+			}
+			if asset.NextFetchTime == nil || asset.NextFetchTime.After(recheckTime) {
+				asset.NextFetchTime = &recheckTime
+			}
+			log.Printf("-defer in the low-bandwidth mode [%s]%s (%s)", prog.StationID, title, start)
+			return nil
+		}
+	}
+
+	// the program failed repeatedly
+	if asset.History.IsBlacklisted(prog, CurrentTime) {
+		log.Printf("-skip blacklisted [%s]%s (%s)", prog.StationID, title, start)
+		return nil
+	}
+
+	// the program is known as a rerun
+	if prog.SkipRerun && asset.History.IsRerun(prog) {
+		log.Printf("-skip rerun [%s]%s (%s)", prog.StationID, title, start)
+		return nil
+	}
+
+	// the program was saved in an earlier scan
+	if asset.History.IsSaved(prog) {
+		log.Printf("-skip already saved [%s]%s (%s)", prog.StationID, title, start)
+		return nil
+	}
+
+	// the same broadcast was saved from an affiliate station
+	if !asset.KeepDuplicates {
+		if r := asset.History.FindDuplicate(prog); r != nil {
+			log.Printf("-skip simulcast [%s]%s (%s) saved from %s", prog.StationID, title, start, r.StationID)
+			return nil
 		}
+	}
+
+	// the program is already merged into the omnibus
+	if asset.History.IsMerged(prog) {
+		log.Printf("-skip merged into the omnibus [%s]%s (%s)", prog.StationID, title, start)
 		return nil
 	}
 
@@ -55,31 +178,128 @@ func Download(
 		return nil
 	}
 	asset.Schedules = append(asset.Schedules, prog)
+	if !asset.DryRun {
+		emit(ctx, EventScheduled, prog, "")
+	}
+
+	// number the episode of the followed series
+	if prog.Follow && prog.Episode == 0 {
+		if prog.Episode, err = asset.History.Episode(prog, asset.DryRun); err != nil {
+			log.Printf("failed to number the episode [%s]%s (%s): %s", prog.StationID, title, start, err)
+		}
+	}
+	if prog.Episode > 0 {
+		fileBaseName = fmt.Sprintf("%s_ep%03d", fileBaseName, prog.Episode)
+	}
+
+	// alert the program about to fall out of the timefree
+	if left := expiry.Sub(CurrentTime); left < UrgentHours*time.Hour {
+		log.Printf("warning: [%s]%s (%s) expires in %v", prog.StationID, title, start, left.Round(time.Minute))
+		if !asset.DryRun {
+			emit(ctx, EventExpiring, prog, fmt.Sprintf("expires at %v", expiry))
+		}
+	}
+
+	// leave the download to the workers
+	if asset.Queue != nil && !asset.DryRun {
+		queued, err := asset.Queue.Enqueue(prog)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue [%s]%s (%s): %s", prog.StationID, title, start, err)
+		}
+		if queued {
+			log.Printf("+queued [%s]%s (%s)", prog.StationID, title, start)
+		}
+		return nil
+	}
 
 	// the output config
-	output, err := newOutputConfig(
-		fmt.Sprintf(
-			"%s_%s_%s",
-			startTime.In(Location).Format(OutputDatetimeLayout),
-			prog.StationID,
-			title,
-		),
-		asset.OutputFormat,
-	)
+	output, err := newOutputConfig(fileBaseName, asset.OutputFormat)
 	if err != nil {
 		return fmt.Errorf("failed to configure output: %s", err)
 	}
+	// keep the explicit programs apart
+	if prog.Explicit && asset.ExplicitDir != "" {
+		if output.DirFullPath, err = radicronDir(asset.ExplicitDir); err != nil {
+			return fmt.Errorf("failed to configure output: %s", err)
+		}
+	}
+	if asset.DryRun {
+		if output.IsExist() {
+			log.Printf("-skip already exists: %s", output.AbsPath())
+		} else {
+			reportDryRun(prog, output)
+		}
+		return nil
+	}
 	if err = output.SetupDir(); err != nil {
 		return fmt.Errorf("failed to setup the output dir: %s", err)
 	}
+	// another instance on the output dir is downloading the program
+	locked, err := lockProgram(prog, output)
+	if err != nil {
+		return fmt.Errorf("failed to lock the output: %s", err)
+	}
+	if !locked {
+		log.Printf("-skip being downloaded by another instance [%s]%s (%s)", prog.StationID, title, start)
+		return nil
+	}
+	// unlocked once downloaded, or here if not started
+	started := false
+	defer func() {
+		if !started {
+			unlockProgram(prog)
+		}
+	}()
+	// the partial output of the download interrupted by the last run
+	if asset.Pending.Interrupted(prog) && output.IsExist() {
+		log.Printf("removing the partial output: %s", output.AbsPath())
+		if err = os.Remove(output.AbsPath()); err != nil {
+			return fmt.Errorf("failed to remove the partial output: %s", err)
+		}
+	}
 	if output.IsExist() {
 		log.Printf("-skip already exists: %s", output.AbsPath())
 		return nil
 	}
 
+	// save the live capture in place of the timefree
+	if captured {
+		log.Printf("start saving the live capture [%s]%s (%s)", prog.StationID, title, start)
+		emit(ctx, EventStarted, prog, "live")
+		journal(asset, prog, output)
+		started = true
+		wg.Add(1)
+		go downloadProgram(ctx, wg, prog, output)
+		return nil
+	}
+
+	// no timefree on radiru
+	if IsRadiruStation(prog.StationID) {
+		err = fmt.Errorf("no live capture of [%s]%s (%s) on radiru", prog.StationID, title, start)
+		recordFailure(ctx, prog, err)
+		return err
+	}
+
 	// fetch the recording m3u8 uri
 	uri, err := timeshiftProgM3U8(ctx, prog)
+	if err != nil && simulcastCapture(prog) != "" {
+		log.Printf("playlist.m3u8 not available [%s]%s (%s): %s", prog.StationID, title, start, err)
+		journal(asset, prog, output)
+		started = true
+		wg.Add(1)
+		go downloadProgram(ctx, wg, prog, output)
+		return nil
+	}
 	if err != nil {
+		exhausted := recordFailure(ctx, prog, err) || asset.BlacklistThreshold <= 0
+		// capture the next airing live instead once the failures reach the threshold
+		if prog.LiveFallback && exhausted && FailureReason(err) == FailurePlaylist {
+			if err := asset.History.RecordNoTimefree(prog); err != nil {
+				log.Printf("failed to save the history: %s", err)
+			} else {
+				log.Printf("+live fallback for the next airing of [%s]%s", prog.StationID, title)
+			}
+		}
 		return fmt.Errorf(
 			"playlist.m3u8 not available [%s]%s (%s): %s",
 			prog.StationID,
@@ -89,7 +309,10 @@ func Download(
 		)
 	}
 	log.Printf("start downloading [%s]%s (%s): %s", prog.StationID, title, start, uri)
+	emit(ctx, EventStarted, prog, uri)
 	prog.M3U8 = uri
+	journal(asset, prog, output)
+	started = true
 	wg.Add(1)
 	go downloadProgram(ctx, wg, prog, output)
 	return nil
@@ -100,12 +323,17 @@ func buildM3U8RequestURI(prog *Prog) string {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// widen the recording with the padding
+	ft, to := prog.Ft, prog.To
+	if start, end, err := prog.Span(); err == nil {
+		ft, to = start.Format(DatetimeLayout), end.Format(DatetimeLayout)
+	}
 	// set query parameters
 	urlQuery := u.Query()
 	params := map[string]string{
 		"station_id": prog.StationID,
-		"ft":         prog.Ft,
-		"to":         prog.To,
+		"ft":         ft,
+		"to":         to,
 		"l":          "15", // required?
 	}
 	for k, v := range params {
@@ -116,58 +344,236 @@ func buildM3U8RequestURI(prog *Prog) string {
 	return u.String()
 }
 
-func bulkDownload(list []string, output string) error {
-	var errFlag bool
-	var wg sync.WaitGroup
+// bulkDownload downloads the segments to the files in output,
+// fetching the repeated segments only once
+func bulkDownload(ctx context.Context, segments Segments, output string) (Segments, error) {
+	keys := newSegmentKeys()
+	unique, dups := segments.dedupe()
+	if len(unique) < len(segments) {
+		log.Printf("reusing %d repeated segments", len(segments)-len(unique))
+	}
+	failed, err := bulkFetch(ctx, unique, func(ctx context.Context, segment *Segment) error {
+		// downloaded before the interruption
+		if _, err := os.Stat(filepath.Join(output, segment.FileName())); err == nil {
+			return copyDuplicates(output, segment, dups[segment])
+		}
+		if err := downloadSegment(ctx, segment, keys, output); err != nil {
+			return err
+		}
+		return copyDuplicates(output, segment, dups[segment])
+	})
+	for _, s := range failed {
+		failed = append(failed, dups[s]...)
+	}
+	return failed, err
+}
+
+// copyDuplicates links or copies the downloaded segment to the positions repeating it
+func copyDuplicates(dir string, segment *Segment, dups Segments) error {
+	src := filepath.Join(dir, segment.FileName())
+	for _, dup := range dups {
+		dst := filepath.Join(dir, dup.FileName())
+		if err := os.Link(src, dst); err == nil {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	for _, v := range list {
+// bulkFetch fetches the segments concurrently and returns the failed ones,
+// canceling the rest once the failures exceed the threshold
+func bulkFetch(ctx context.Context, segments Segments, fetch func(context.Context, *Segment) error) (Segments, error) {
+	asset := GetAsset(ctx)
+	tolerance := int(float64(len(segments)) * asset.SegmentFailureThreshold)
+	dlCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// escalate the program about to expire
+	retry := asset.Retry
+	urgent := false
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < UrgentHours*time.Hour {
+		log.Printf("urgent: %v until the expiry", time.Until(deadline).Round(time.Minute))
+		retry = retry.Urgent()
+		urgent = true
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := Segments{}
+	doomed := false
+	retried := 0
+	done := 0
+	for _, v := range segments {
 		wg.Add(1)
-		go func(link string) {
+		go func(segment *Segment) {
 			defer wg.Done()
 
-			var err error
-			for i := 0; i < MaxRetryAttempts; i++ {
-				sem <- struct{}{}
-				err = downloadLink(link, output)
-				<-sem
+			attempts := 0
+			err := downloadWithRetry(dlCtx, func(ctx context.Context) error {
+				attempts++
+				return fetch(ctx, segment)
+			}, retry, urgent)
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts > 1 {
+				getReport(ctx).addRetries(attempts - 1)
 				if err == nil {
-					break
+					retried++
 				}
 			}
-			if err != nil {
-				log.Printf("failed to download: %s", err)
-				errFlag = true
+			if err == nil {
+				done++
+				reportProgress(ctx, done, len(segments))
+				return
+			}
+			if doomed {
+				return // canceled
+			}
+			log.Printf("failed to download: %s", err)
+			failed = append(failed, segment)
+			if len(failed) > tolerance {
+				// the program is doomed, cancel the rest
+				doomed = true
+				cancel()
 			}
 		}(v)
 	}
 	wg.Wait()
+	getReport(ctx).addSegments(len(segments), len(failed), retried)
 
-	if errFlag {
-		return errors.New("lack of aac files")
+	if err := ctx.Err(); err != nil {
+		return failed, err
 	}
-	return nil
+	if doomed {
+		return failed, &SegmentsError{Failed: len(failed), Total: len(segments)}
+	}
+	if len(failed) > 0 {
+		log.Printf("missing %d/%d segments within the threshold", len(failed), len(segments))
+	}
+	return failed, nil
+}
+
+// downloadWithRetry fetches the segment backing off between the attempts
+func downloadWithRetry(
+	ctx context.Context,
+	fetch func(context.Context) error,
+	retry *RetryPolicy,
+	urgent bool,
+) error {
+	var err error
+	for attempt := 1; attempt <= retry.Attempts; attempt++ {
+		if err = acquireSlot(ctx, urgent); err != nil {
+			return err
+		}
+		err = fetch(ctx)
+		releaseSlot(err)
+		if err == nil || attempt == retry.Attempts {
+			break
+		}
+		// back off before the next attempt
+		if err = retry.Wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	return err
 }
 
-func downloadLink(link, output string) error {
-	resp, err := http.Get(link) //nolint:gosec,noctx
+// acquireSlot waits for a download slot, the urgent ones can use the reserved slots
+func acquireSlot(ctx context.Context, urgent bool) error {
+	if err := postProcess.wait(ctx, urgent); err != nil {
+		return err
+	}
+	return slots.acquire(ctx, urgent)
+}
+
+// releaseSlot releases the slot acquired by acquireSlot with the result
+func releaseSlot(err error) {
+	slots.release(err)
+}
+
+func downloadSegment(ctx context.Context, segment *Segment, keys *segmentKeys, output string) error {
+	ctx, cancel := context.WithTimeout(ctx, segmentTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, segment.URI, http.NoBody)
+	if err != nil {
+		return err
+	}
+	if header := requestHeader(ctx); header != nil {
+		req.Header = header
+	}
+	if r := segment.Range(); r != "" {
+		req.Header.Set("Range", r)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	_, fileName := filepath.Split(link)
-	file, err := os.Create(filepath.Join(output, fileName))
+	var body io.Reader = throttle(ctx, resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusOK && segment.Limit > 0:
+		// the server ignored the range
+		if _, err = io.CopyN(io.Discard, body, segment.Offset); err != nil {
+			return err
+		}
+		body = io.LimitReader(body, segment.Limit)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("failed to get %s: %s", segment.URI, resp.Status)
+	}
+	if segment.Map != nil {
+		initSection, err := fetchRange(ctx, segment.Map.URI, segment.Map.Range())
+		if err != nil {
+			return fmt.Errorf("failed to get the init section %s: %s", segment.Map.URI, err)
+		}
+		body = io.MultiReader(bytes.NewReader(initSection), body)
+	}
+	if segment.IsEncrypted() {
+		buf := getSegmentBuffer()
+		defer putSegmentBuffer(buf)
+		if _, err = buf.ReadFrom(body); err != nil {
+			return err
+		}
+		data, err := keys.Decrypt(ctx, segment, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %s", segment.URI, err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// complete only once renamed, to resume the download
+	path := filepath.Join(output, segment.FileName())
+	file, err := os.Create(path + PartialFileSuffix)
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(file, resp.Body)
+	_, err = copySegment(file, body)
 	if closeErr := file.Close(); err == nil {
 		err = closeErr
 	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		// do not leave the partial segment
+		os.Remove(file.Name())
+	}
 	return err
 }
 
+// journal remembers the program in the download to resume it after a crash or a restart
+func journal(asset *Asset, prog *Prog, output *radigo.OutputConfig) {
+	if err := asset.Pending.Add(prog, output.AbsPath()); err != nil {
+		log.Printf("failed to save the pending downloads: %s", err)
+	}
+}
+
 // downloadProgram manages the download for the given program
 // in a go routine and notify the wg when finished
 func downloadProgram(
@@ -177,102 +583,400 @@ func downloadProgram(
 	output *radigo.OutputConfig, // the file configuration
 ) {
 	defer wg.Done()
-	var err error
+	defer unlockProgram(prog)
+	asset := GetAsset(ctx)
+	// done with the program whether saved or failed, the history tells which
+	defer func() {
+		if err := asset.Pending.Remove(prog); err != nil {
+			log.Printf("failed to save the pending downloads: %s", err)
+		}
+	}()
+	// save the bytes downloaded for the usage stats
+	defer saveUsage(asset.History)
+
+	// the hard deadline at the timefree expiry
+	if ft, err := time.ParseInLocation(DatetimeLayout, prog.Ft, Location); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, ft.AddDate(0, 0, TimefreeExpiryDays))
+		defer cancel()
+	}
+	// collect the stats for the report
+	var report *RecordingReport
+	if asset.Report {
+		report = newRecordingReport(prog)
+		ctx = context.WithValue(ctx, ContextKey("report"), report)
+	}
+
+	// report the segments downloaded to the EventFunc if any
+	ctx = withProgress(ctx, prog)
 
-	chunklist, err := getChunklistFromM3U8(prog.M3U8)
+	// improve the metadata for the tag
+	enrich(ctx, asset.Enrichers, prog)
+
+	err := saveProgram(ctx, prog, output)
+	// fall back to the live capture of the station-owned simulcast
+	if capture := simulcastCapture(prog); err != nil && !errors.Is(err, ErrRerun) && capture != "" {
+		log.Printf("falling back to the simulcast [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		err = saveSimulcast(ctx, prog, capture, output)
+	}
+	if errors.Is(err, ErrRerun) {
+		log.Printf("-skip rerun [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		emit(ctx, EventCompleted, prog, "skipped: "+err.Error())
+		removeSimulcast(prog)
+		return
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("failed to save [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, ErrExpired)
+		emitFailure(ctx, prog, ErrExpired)
+		if err = asset.History.RecordExpired(prog); err != nil {
+			log.Printf("failed to save the history: %s", err)
+		}
+		return
+	}
 	if err != nil {
-		log.Printf("failed to get chunklist: %s", err)
+		log.Printf("failed to save [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		recordFailure(ctx, prog, err)
 		return
 	}
+	if err := asset.History.RecordSuccess(prog, output.AbsPath()); err != nil {
+		log.Printf("failed to save the history: %s", err)
+	}
+	removeSimulcast(prog)
+
+	// finish downloading the file
+	log.Printf("+file saved: %s", output.AbsPath())
+	emit(ctx, EventCompleted, prog, output.AbsPath())
+	// keep it elsewhere
+	for _, s := range asset.Storages {
+		if err = s.Store(ctx, prog, output.AbsPath()); err != nil {
+			log.Printf("failed to store [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		}
+	}
+	if report != nil {
+		if err = report.write(output.AbsPath()); err != nil {
+			log.Printf("failed to write the report: %s", err)
+		}
+	}
+}
 
-	aacDir, err := tempAACDir()
+// recordFailure counts the failure of the program in the history,
+// and returns true if it is blacklisted as the failures reach the threshold
+func recordFailure(ctx context.Context, prog *Prog, cause error) bool {
+	asset := GetAsset(ctx)
+	emitFailure(ctx, prog, cause)
+	blacklisted, err := asset.History.RecordFailure(prog, cause, asset.BlacklistThreshold, asset.BlacklistExpiry)
 	if err != nil {
-		log.Printf("failed to create the aac dir: %s", err)
-		return
+		log.Printf("failed to save the history: %s", err)
+		return false
 	}
-	defer os.RemoveAll(aacDir) // clean up
+	if blacklisted {
+		log.Printf("blacklisted [%s]%s (%s) for %v", prog.StationID, prog.Title, prog.Ft, asset.BlacklistExpiry)
+	}
+	return blacklisted
+}
 
-	if err = bulkDownload(chunklist, aacDir); err != nil {
-		log.Printf("failed to download aac files: %s", err)
-		return
+// checkRerun fingerprints the chunks after the opening of the program
+// and returns ErrRerun if a similar recording exists
+func checkRerun(ctx context.Context, prog *Prog, aacDir string, segments Segments) error {
+	asset := GetAsset(ctx)
+	fp, err := fingerprintChunks(ctx, aacDir, segments)
+	if err != nil {
+		// record the program anyway
+		log.Printf("failed to fingerprint [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		return nil
+	}
+	since := CurrentTime.AddDate(0, 0, -RerunLookbackDays)
+	if r := asset.History.FindRerun(prog, fp, since); r != nil {
+		if err = asset.History.RecordRerun(prog, r.ID); err != nil {
+			log.Printf("failed to save the history: %s", err)
+		}
+		return fmt.Errorf("%w of [%s]%s (%s)", ErrRerun, r.StationID, r.Title, r.Ft)
+	}
+	return asset.History.RecordFingerprint(prog, fp)
+}
+
+// downloadSegments downloads the chunks to aacDir and concatenates them,
+// returning the concatenated file and the segments in it
+func downloadSegments(ctx context.Context, prog *Prog, chunklist Segments, aacDir string) (string, Segments, error) {
+	asset := GetAsset(ctx)
+
+	// check the first few minutes after the opening before downloading the rest
+	remaining := chunklist
+	if prog.SkipRerun {
+		n := (FingerprintOffsetSeconds + FingerprintSeconds) / RadikoChunkSeconds
+		if n > len(chunklist) {
+			n = len(chunklist)
+		}
+		failed, err := bulkDownload(ctx, chunklist[:n], aacDir)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to download aac files: %w", err)
+		}
+		if err = checkRerun(ctx, prog, aacDir, chunklist[:n].Without(failed)); err != nil {
+			return "", nil, err
+		}
+		remaining = chunklist[n:]
+		chunklist = chunklist.Without(failed)
 	}
 
-	concatedFile, err := radigo.ConcatAACFilesFromList(ctx, aacDir)
+	failed, err := bulkDownload(ctx, remaining, aacDir)
 	if err != nil {
-		log.Printf("failed to concat aac files: %s", err)
-		return
+		return "", nil, fmt.Errorf("failed to download aac files: %w", err)
+	}
+	chunklist = chunklist.Without(failed)
+	if err = validateSegments(aacDir, chunklist, asset.StrictADTS); err != nil {
+		return "", nil, err
+	}
+
+	ctx, cancel := transcodeContext(ctx, chunklist.Duration())
+	defer cancel()
+	concatedFile, err := concatSegments(ctx, aacDir, chunklist, asset.GaplessPriming)
+	if err != nil {
+		return "", nil, withReason(FailureEncoder, fmt.Errorf("failed to concat aac files: %w", err))
+	}
+	return concatedFile, chunklist, nil
+}
+
+// saveProgram downloads the chunks, concatenates them to the output, and writes the tag
+func saveProgram(
+	ctx context.Context, // the context for the request
+	prog *Prog, // the program metadata
+	output *radigo.OutputConfig, // the file configuration
+) (err error) {
+	asset := GetAsset(ctx)
+	report := getReport(ctx)
+	stageStart := time.Now()
+
+	// prefer the audio from the providers if any
+	if len(asset.Providers) > 0 {
+		if err = provide(ctx, asset.Providers, prog, output); err == nil {
+			report.stage("provider", stageStart)
+			stageStart = time.Now()
+			defer report.stage("tag", stageStart)
+			return finishOutput(ctx, asset, prog, output)
+		}
+		log.Printf("falling back to the timefree [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		stageStart = time.Now()
+	}
+
+	// prefer the official podcast episode if any
+	if prog.Podcast != "" {
+		emit(ctx, EventProgress, prog, "fetching the podcast")
+		if err = savePodcast(ctx, prog, output); err == nil {
+			report.stage("podcast", stageStart)
+			stageStart = time.Now()
+			defer report.stage("tag", stageStart)
+			return finishOutput(ctx, asset, prog, output)
+		}
+		log.Printf("falling back to the timefree [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+		stageStart = time.Now()
+	}
+
+	// the timefree is not available but the simulcast
+	if prog.M3U8 == "" {
+		return withReason(FailurePlaylist, errors.New("no playlist.m3u8"))
+	}
+	chunklist, err := getChunklistFromM3U8(ctx, prog.M3U8, !asset.LenientPlaylist)
+	if err != nil {
+		return withReason(FailurePlaylist, fmt.Errorf("failed to get chunklist: %w", err))
+	}
+	report.stage("chunklist", stageStart)
+
+	// drop the spillover from the adjacent programs beyond the padding
+	ft, to, _ := prog.Span()
+	chunklist, offset, length := chunklist.Trim(ft, to)
+	emit(ctx, EventProgress, prog, fmt.Sprintf("downloading %d segments", len(chunklist)))
+
+	aacDir, err := asset.Pending.TmpDir(prog)
+	if err != nil {
+		return fmt.Errorf("failed to create the aac dir: %w", err)
+	}
+	// clean up, or keep the partial audio of the failure
+	defer func() {
+		cleanupAACDir(aacDir, prog, err, asset.KeepFailedTmp)
+	}()
+
+	// transcode to mp3 while downloading the segments
+	if output.AudioFormat() == radigo.AudioFormatMP3 && !prog.SkipRerun && asset.GaplessPriming == 0 &&
+		chunklist.directWritable() {
+		stageStart = time.Now()
+		transcodedFile, err := pipelineTranscode(ctx, chunklist, aacDir, offset, length)
+		if err != nil {
+			return fmt.Errorf("failed to transcode aac files: %w", err)
+		}
+		if err = moveFile(transcodedFile, output.AbsPath()); err != nil {
+			return fmt.Errorf("failed to write the output file: %w", err)
+		}
+		report.stage("download+transcode", stageStart)
+		stageStart = time.Now()
+		defer report.stage("tag", stageStart)
+		return finishOutput(ctx, asset, prog, output)
+	}
+
+	// write the segments straight to the file if possible
+	stageStart = time.Now()
+	var concatedFile string
+	if asset.DirectWrite && !prog.SkipRerun && asset.GaplessPriming == 0 && asset.SegmentFailureThreshold == 0 {
+		concatedFile, err = directDownload(ctx, chunklist, aacDir)
+		if errors.Is(err, errNotDirect) {
+			log.Printf("falling back to the segment files: %s", err)
+		} else if err != nil {
+			return fmt.Errorf("failed to download aac files: %w", err)
+		}
+	}
+	if concatedFile == "" {
+		if concatedFile, chunklist, err = downloadSegments(ctx, prog, chunklist, aacDir); err != nil {
+			return err
+		}
+	}
+	report.stage("download", stageStart)
+	emit(ctx, EventProgress, prog, "post-processing")
+	stageStart = time.Now()
+	// pause the other downloads if the post-processing lags behind
+	postProcess.enter()
+	defer postProcess.exit()
+	tctx, cancel := transcodeContext(ctx, chunklist.Duration())
+	defer cancel()
+
+	if offset > 0 || length < chunklist.Duration() {
+		if concatedFile, err = trimAudio(tctx, concatedFile, offset, length); err != nil {
+			return withReason(FailureEncoder, fmt.Errorf("failed to trim the aac file: %w", err))
+		}
 	}
 
 	switch output.AudioFormat() {
 	case radigo.AudioFormatAAC:
-		err = os.Rename(concatedFile, output.AbsPath())
+		err = moveFile(concatedFile, output.AbsPath())
 	case radigo.AudioFormatMP3:
-		err = radigo.ConvertAACtoMP3(ctx, concatedFile, output.AbsPath())
+		err = withReason(FailureEncoder, radigo.ConvertAACtoMP3(tctx, concatedFile, output.AbsPath()))
 	default:
 		err = fmt.Errorf("invalid file format")
 	}
 
 	if err != nil {
-		log.Printf("failed to write the output file: %s", err)
-		return
+		return fmt.Errorf("failed to write the output file: %w", err)
 	}
+	report.stage("post-process", stageStart)
+	stageStart = time.Now()
+	defer report.stage("tag", stageStart)
+	return finishOutput(ctx, asset, prog, output)
+}
 
+// finishOutput checks the size of the output and writes the tag
+func finishOutput(ctx context.Context, asset *Asset, prog *Prog, output *radigo.OutputConfig) error {
 	info, err := os.Stat(output.AbsPath())
 	if err != nil {
-		log.Printf("failed to stat the output file: %s", err)
-		return
+		return fmt.Errorf("failed to stat the output file: %s", err)
 	}
 
-	asset := GetAsset(ctx)
 	if info.Size() < asset.MinimumOutputSize {
-		log.Printf("the output file is too small: %v MB", float32(info.Size())/Kilobytes/Kilobytes)
 		err = os.Remove(output.AbsPath())
 		if err != nil {
-			log.Printf("failed to remove the file: %v", err)
-			return
+			return fmt.Errorf("failed to remove the file: %v", err)
 		}
 		next := time.Now().In(Location).Add(BufferMinutes * time.Minute)
 		asset.NextFetchTime = &next
-		log.Printf("removed the file, retry downloading at %v", next)
-		return
+		return fmt.Errorf(
+			"the output file is too small: %v MB, retry downloading at %v",
+			float32(info.Size())/Kilobytes/Kilobytes,
+			next,
+		)
 	}
 
-	err = writeID3Tag(output, prog)
+	err = writeID3Tag(ctx, output, prog, asset.EpisodeTitle)
 	if err != nil {
-		log.Printf("ID3v2: %v", err)
-		return
+		return fmt.Errorf("ID3v2: %v", err)
 	}
 
-	// finish downloading the file
-	log.Printf("+file saved: %s", output.AbsPath())
+	return nil
 }
 
-// getChunklist returns a slice of uri string.
-func getChunklist(input io.Reader) ([]string, error) {
-	playlist, listType, err := m3u8.DecodeFrom(input, true)
-	if err != nil || listType != m3u8.MEDIA {
+// getChunklist returns the media segments with the URIs resolved against base
+func getChunklist(input io.Reader, base string, strict bool) (Segments, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	playlist, err := decodePlaylist(input, m3u8.MEDIA, strict)
+	if err != nil {
 		return nil, err
 	}
 	p := playlist.(*m3u8.MediaPlaylist)
 
-	var chunklist []string
+	var chunklist Segments
+	key := p.Key
+	var initMap *m3u8.Map
 	for _, v := range p.Segments {
-		if v != nil {
-			chunklist = append(chunklist, v.URI)
+		if v == nil {
+			continue
+		}
+		// the key applies until the next EXT-X-KEY
+		if v.Key != nil {
+			key = v.Key
+		}
+		segment := &Segment{
+			Index:           len(chunklist),
+			SeqID:           v.SeqId,
+			URI:             resolveURI(baseURL, v.URI),
+			Duration:        time.Duration(v.Duration * float64(time.Second)),
+			ProgramDateTime: v.ProgramDateTime,
+			Discontinuity:   v.Discontinuity,
+		}
+		if key != nil {
+			segment.Key = &SegmentKey{Method: key.Method, URI: resolveURI(baseURL, key.URI), IV: key.IV}
+		}
+		// prepend the init section when it changes or after a discontinuity
+		if v.Map != nil || (segment.Discontinuity && initMap != nil) {
+			if v.Map != nil {
+				initMap = v.Map
+			}
+			segment.Map = &SegmentMap{URI: resolveURI(baseURL, initMap.URI), Limit: initMap.Limit, Offset: initMap.Offset}
 		}
+		if v.Limit > 0 {
+			segment.Limit = v.Limit
+			segment.Offset = v.Offset
+			// without the offset, the range follows the previous one of the same resource
+			if prev := len(chunklist) - 1; v.Offset == 0 && prev >= 0 &&
+				chunklist[prev].URI == segment.URI && chunklist[prev].Limit > 0 {
+				segment.Offset = chunklist[prev].Offset + chunklist[prev].Limit
+			}
+		}
+		chunklist = append(chunklist, segment)
 	}
 	return chunklist, nil
 }
 
-// getChunklistFromM3U8 returns a slice of url.
-func getChunklistFromM3U8(uri string) ([]string, error) {
-	resp, err := http.Get(uri) //nolint:gosec,noctx
+// getChunklistFromM3U8 returns the media segments in the chunklist within playlistTimeout
+func getChunklistFromM3U8(ctx context.Context, uri string, strict bool) (Segments, error) {
+	ctx, cancel := context.WithTimeout(ctx, playlistTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", uri, resp.Status)
+	}
+
+	return getChunklist(resp.Body, uri, strict)
+}
 
-	return getChunklist(resp.Body)
+// DownloadDir returns the dir to save the programs
+func DownloadDir() (string, error) {
+	if dir, _ := outputDir.Load().(string); dir != "" {
+		return radicronDir(dir)
+	}
+	return getRadicronPath("downloads")
+}
+
+// SetDownloadDir overrides the downloads in RADICRON_HOME, relative to RADICRON_HOME if not absolute
+func SetDownloadDir(dir string) {
+	outputDir.Store(dir)
 }
 
 // getRadicronPath gets the RADICRON_HOME path
@@ -299,23 +1003,46 @@ func getRadicronPath(sub string) (string, error) {
 	return filepath.Clean(fullPath), nil
 }
 
-// getURI returns uri generated by parsing m3u8.
-func getURI(input io.Reader) (string, error) {
-	playlist, listType, err := m3u8.DecodeFrom(input, true)
-	if err != nil || listType != m3u8.MASTER {
+// getURI returns uri generated by parsing m3u8, resolved against base
+// the lenient mode takes the first variant if there are many
+func getURI(input io.Reader, base string, strict bool) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	playlist, err := decodePlaylist(input, m3u8.MASTER, strict)
+	if err != nil {
 		return "", err
 	}
 	p := playlist.(*m3u8.MasterPlaylist)
 
-	if p == nil || len(p.Variants) != 1 || p.Variants[0] == nil {
+	if p == nil || len(p.Variants) == 0 || p.Variants[0] == nil ||
+		(strict && len(p.Variants) != 1) {
 		return "", errors.New("invalid m3u8 format")
 	}
-	return p.Variants[0].URI, nil
+	return resolveURI(baseURL, p.Variants[0].URI), nil
+}
+
+// resolveURI resolves the relative reference in the playlist against the base
+func resolveURI(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil || ref == "" {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// radicronDir returns the dir, relative to RADICRON_HOME if not absolute
+func radicronDir(dir string) (string, error) {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir), nil
+	}
+	return getRadicronPath(dir)
 }
 
 // newOutputConfig prepares the outputdir
 func newOutputConfig(fileBaseName, fileFormat string) (*radigo.OutputConfig, error) {
-	fullPath, err := getRadicronPath("downloads")
+	fullPath, err := DownloadDir()
 	if err != nil {
 		return nil, err
 	}
@@ -350,39 +1077,41 @@ func timeshiftProgM3U8(
 	asset := GetAsset(ctx)
 	client := asset.DefaultClient
 	var req *http.Request
-	var err error
 
-	areaID := asset.GetAreaIDByStationID(prog.StationID)
+	areaID := asset.AuthAreaID(prog.StationID)
 
-	device, ok := asset.AreaDevices[areaID]
-	if !ok {
-		device, err = asset.NewDevice(areaID)
-		if err != nil {
-			return "", err
-		}
+	session := asset.AuthSessions.Get(areaID)
+	device, err := session.Authorize(ctx, asset)
+	if err != nil {
+		return "", withReason(FailureAuth, err)
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, playlistTimeout)
+	defer cancel()
 	uri := buildM3U8RequestURI(prog)
-	req, _ = http.NewRequest("POST", uri, http.NoBody)
-	req = req.WithContext(ctx)
-	headers := map[string]string{
+	req, _ = http.NewRequestWithContext(ctx, "POST", uri, http.NoBody)
+	req.Header = asset.GetHeaders(EndpointPlaylist, prog.StationID, map[string]string{
 		UserAgentHeader:       device.UserAgent,
 		RadikoAreaIDHeader:    areaID,
 		RadikoAuthTokenHeader: device.AuthToken,
-	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
+	})
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", withReason(FailurePlaylist, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		// the token is no longer valid, authorize again on the next attempt
+		session.Invalidate()
+		return "", withReason(FailureAuth, fmt.Errorf("unauthorized for %s: %s", areaID, resp.Status))
+	}
 
-	return getURI(resp.Body)
+	uri, err = getURI(resp.Body, uri, !asset.LenientPlaylist)
+	return uri, withReason(FailurePlaylist, err)
 }
 
-func writeID3Tag(output *radigo.OutputConfig, prog *Prog) error {
+// writeID3Tag tags the output with the program, titled with the template if any
+func writeID3Tag(ctx context.Context, output *radigo.OutputConfig, prog *Prog, titleTemplate string) error {
 	tag, err := id3v2.Open(output.AbsPath(), id3v2.Options{Parse: true})
 	if err != nil {
 		return fmt.Errorf("error while opening the output file: %s", err)
@@ -390,15 +1119,13 @@ func writeID3Tag(output *radigo.OutputConfig, prog *Prog) error {
 	defer tag.Close()
 
 	// Set tags
-	tag.SetTitle(output.FileBaseName)
-	tag.SetArtist(prog.Pfm)
-	tag.SetAlbum(prog.Title)
-	tag.SetYear(prog.Ft[:4])
-	tag.AddCommentFrame(id3v2.CommentFrame{
-		Encoding:    id3v2.EncodingUTF8,
-		Language:    ID3v2LangJPN,
-		Description: prog.Info,
-	})
+	title := output.FileBaseName
+	if titleTemplate != "" {
+		if ft, err := time.ParseInLocation(DatetimeLayout, prog.Ft, Location); err == nil {
+			title = FormatEpisodeTitle(titleTemplate, prog.Title, prog.StationID, ft)
+		}
+	}
+	tagProgram(ctx, tag, title, prog)
 
 	// write tag to the aac
 	if err = tag.Save(); err != nil {
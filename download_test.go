@@ -1,13 +1,28 @@
 package radicron
 
 import (
+	"bytes"
+	"context"
 	"embed"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/bogem/id3v2"
+	"github.com/yyoshiki41/radigo"
 )
 
 var (
 	//go:embed test/playlist-test.m3u8
 	PlaylistTestM3U8 embed.FS
+	//go:embed test/chunklist-test.m3u8 test/chunklist-encrypted-test.m3u8 test/chunklist-byterange-test.m3u8 test/chunklist-relative-test.m3u8
+	ChunklistTestM3U8 embed.FS
 )
 
 func TestBuildM3U8RequestURI(t *testing.T) {
@@ -21,6 +36,14 @@ func TestBuildM3U8RequestURI(t *testing.T) {
 	if uri != want {
 		t.Errorf("buildM3U8RequestURI => %v, want %v", uri, want)
 	}
+
+	// widened with the padding
+	prog.PadBefore, prog.PadAfter = time.Minute, 2*time.Minute
+	uri = buildM3U8RequestURI(prog)
+	want = "https://radiko.jp/v2/api/ts/playlist.m3u8?ft=20230605125900&l=15&station_id=FMT&to=20230605145700"
+	if uri != want {
+		t.Errorf("buildM3U8RequestURI with the padding => %v, want %v", uri, want)
+	}
 }
 
 func TestGetURI(t *testing.T) {
@@ -28,7 +51,7 @@ func TestGetURI(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	uri, err := getURI(m3u8)
+	uri, err := getURI(m3u8, APIPlaylistM3U8, true)
 	if err != nil {
 		t.Error(err)
 	}
@@ -37,3 +60,314 @@ func TestGetURI(t *testing.T) {
 		t.Errorf("getURI => %v, want %v", uri, want)
 	}
 }
+
+func TestGetChunklist(t *testing.T) {
+	m3u8, err := ChunklistTestM3U8.Open("test/chunklist-test.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunklist, err := getChunklist(m3u8, "https://radiko.jp/v2/api/ts/chunklist/FsNE6Bt0.m3u8", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunklist) != 4 {
+		t.Fatalf("getChunklist => %v segments, want 4", len(chunklist))
+	}
+	if chunklist[1].FileName() != "000001.aac" {
+		t.Errorf("FileName => %v, want 000001.aac", chunklist[1].FileName())
+	}
+	if chunklist.Duration() != 20*time.Second {
+		t.Errorf("Duration => %v, want 20s", chunklist.Duration())
+	}
+	want := time.Date(2023, 6, 5, 13, 0, 0, 0, Location)
+	if !chunklist[1].ProgramDateTime.Equal(want) {
+		t.Errorf("ProgramDateTime => %v, want %v", chunklist[1].ProgramDateTime, want)
+	}
+}
+
+func TestGetChunklistEncrypted(t *testing.T) {
+	m3u8, err := ChunklistTestM3U8.Open("test/chunklist-encrypted-test.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunklist, err := getChunklist(m3u8, "https://radiko.jp/v2/api/ts/chunklist/FsNE6Bt0.m3u8", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunklist) != 4 {
+		t.Fatalf("getChunklist => %v segments, want 4", len(chunklist))
+	}
+	var keytests = []struct {
+		encrypted bool
+		keyURI    string
+		seqID     uint64
+	}{
+		{true, "https://radiko.jp/key/1", 100},
+		{true, "https://radiko.jp/key/1", 101},
+		{true, "https://radiko.jp/key/2", 102},
+		{false, "", 103},
+	}
+	for i, tt := range keytests {
+		s := chunklist[i]
+		if s.IsEncrypted() != tt.encrypted || (tt.encrypted && s.Key.URI != tt.keyURI) || s.SeqID != tt.seqID {
+			t.Errorf("chunklist[%v] => %v, %+v, %v, want %v, %v, %v", i, s.IsEncrypted(), s.Key, s.SeqID, tt.encrypted, tt.keyURI, tt.seqID)
+		}
+	}
+	if groups := chunklist.Groups(); len(groups) != 2 || len(groups[1]) != 2 {
+		t.Errorf("Groups => %v, want 2 groups", len(groups))
+	}
+}
+
+func TestGetChunklistByteRange(t *testing.T) {
+	m3u8, err := ChunklistTestM3U8.Open("test/chunklist-byterange-test.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunklist, err := getChunklist(m3u8, "https://radiko.jp/v2/api/ts/chunklist/FsNE6Bt0.m3u8", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"bytes=0-999", "bytes=1000-2199", "bytes=5000-5799"}
+	if len(chunklist) != len(want) {
+		t.Fatalf("getChunklist => %v segments, want %v", len(chunklist), len(want))
+	}
+	for i, w := range want {
+		if got := chunklist[i].Range(); got != w {
+			t.Errorf("chunklist[%v].Range() => %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestDownloadSegment(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	var downloadtests = []struct {
+		ignoreRange bool
+		segment     *Segment
+		out         string
+	}{
+		{false, &Segment{Index: 0}, string(content)},
+		{false, &Segment{Index: 1, Limit: 5, Offset: 10}, "abcde"},
+		{true, &Segment{Index: 2, Limit: 5, Offset: 10}, "abcde"},
+		{false, &Segment{Index: 3, Limit: 5, Offset: 10, Map: &SegmentMap{Limit: 3}}, "012abcde"},
+	}
+	for _, tt := range downloadtests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.ignoreRange {
+				_, _ = w.Write(content)
+				return
+			}
+			http.ServeContent(w, r, "program.aac", time.Time{}, bytes.NewReader(content))
+		}))
+		tt.segment.URI = ts.URL + "/program.aac"
+		if tt.segment.Map != nil {
+			tt.segment.Map.URI = ts.URL + "/init.mp4"
+		}
+		dir := t.TempDir()
+		if err := downloadSegment(context.Background(), tt.segment, newSegmentKeys(), dir); err != nil {
+			t.Fatal(err)
+		}
+		ts.Close()
+		got, err := os.ReadFile(filepath.Join(dir, tt.segment.FileName()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.out {
+			t.Errorf("downloadSegment(%v) => %q, want %q", tt.segment.Range(), got, tt.out)
+		}
+	}
+}
+
+func TestGetChunklistRelative(t *testing.T) {
+	m3u8, err := ChunklistTestM3U8.Open("test/chunklist-relative-test.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunklist, err := getChunklist(m3u8, "https://radiko.jp/sound/a/chunklist.m3u8?token=abc", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://radiko.jp/sound/a/0001.m4s",
+		"https://radiko.jp/sound/b/0002.m4s",
+		"https://cdn.example.com/0003.m4s",
+	}
+	if len(chunklist) != len(want) {
+		t.Fatalf("getChunklist => %v segments, want %v", len(chunklist), len(want))
+	}
+	for i, w := range want {
+		if chunklist[i].URI != w {
+			t.Errorf("chunklist[%v].URI => %v, want %v", i, chunklist[i].URI, w)
+		}
+	}
+	if chunklist[0].Key.URI != "https://radiko.jp/key/1" {
+		t.Errorf("Key.URI => %v, want https://radiko.jp/key/1", chunklist[0].Key.URI)
+	}
+	// the init section at the start and after the discontinuity
+	for i, hasMap := range []bool{true, false, true} {
+		if (chunklist[i].Map != nil) != hasMap {
+			t.Errorf("chunklist[%v].Map => %v, want %v", i, chunklist[i].Map, hasMap)
+		} else if hasMap && chunklist[i].Map.URI != "https://radiko.jp/sound/a/init.mp4" {
+			t.Errorf("chunklist[%v].Map.URI => %v", i, chunklist[i].Map.URI)
+		}
+	}
+}
+
+func TestBulkDownload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/broken") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("aac"))
+	}))
+	defer ts.Close()
+
+	asset := &Asset{Retry: &RetryPolicy{Attempts: 2, InitialDelay: time.Millisecond}}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	segments := Segments{}
+	for i := 0; i < 20; i++ {
+		uri := fmt.Sprintf("%s/%d.aac", ts.URL, i)
+		if i == 3 {
+			uri = ts.URL + "/broken.aac"
+		}
+		segments = append(segments, &Segment{Index: i, URI: uri})
+	}
+
+	// within the threshold
+	asset.SegmentFailureThreshold = 0.05
+	failed, err := bulkDownload(ctx, segments, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || len(segments.Without(failed)) != 19 {
+		t.Errorf("bulkDownload => %v failed, want 1", len(failed))
+	}
+
+	// doomed
+	asset.SegmentFailureThreshold = 0
+	if _, err = bulkDownload(ctx, segments, t.TempDir()); err == nil {
+		t.Error("bulkDownload over the threshold => nil, want error")
+	}
+}
+
+func TestBulkDownloadRepeated(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/broken") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	asset := &Asset{Retry: &RetryPolicy{Attempts: 1}, SegmentFailureThreshold: 0.5}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	segments := Segments{}
+	for i, name := range []string{"a", "b", "a", "broken", "a", "broken"} {
+		segments = append(segments, &Segment{Index: i, URI: ts.URL + "/" + name + ".aac"})
+	}
+	dir := t.TempDir()
+	failed, err := bulkDownload(ctx, segments, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests["/a.aac"] != 1 || requests["/broken.aac"] != 1 {
+		t.Errorf("bulkDownload => %v, want a request per unique segment", requests)
+	}
+	if len(failed) != 2 {
+		t.Errorf("bulkDownload => %v failed, want both the broken ones", len(failed))
+	}
+	for _, i := range []int{0, 2, 4} {
+		if b, err := os.ReadFile(filepath.Join(dir, segments[i].FileName())); err != nil || string(b) != "/a.aac" {
+			t.Errorf("segment #%d => %q, %v, want /a.aac", i, b, err)
+		}
+	}
+}
+
+func BenchmarkBulkDownload(b *testing.B) {
+	payload := bytes.Repeat([]byte{0x21}, 16*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer ts.Close()
+
+	asset := &Asset{Retry: &RetryPolicy{Attempts: 1}}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	segments := Segments{}
+	for i := 0; i < 720; i++ { // an hour of 5s chunks
+		segments = append(segments, &Segment{Index: i, URI: fmt.Sprintf("%s/%d.aac", ts.URL, i)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := bulkDownload(ctx, segments, b.TempDir()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWriteID3Tag(t *testing.T) {
+	output := &radigo.OutputConfig{
+		DirFullPath:  t.TempDir(),
+		FileBaseName: "202306051300_FMT_Title",
+		FileFormat:   radigo.AudioFormatAAC,
+	}
+	prog := &Prog{StationID: "FMT", Ft: "20230605130000", Title: "Title", Pfm: "Pfm"}
+	var id3tests = []struct {
+		template string
+		want     string
+	}{
+		{"", "202306051300_FMT_Title"},
+		{"{title} {date}", "Title 2023-06-05"},
+	}
+	for _, tt := range id3tests {
+		if err := os.WriteFile(output.AbsPath(), make([]byte, 64), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeID3Tag(context.Background(), output, prog, tt.template); err != nil {
+			t.Fatal(err)
+		}
+		tag, err := id3v2.Open(output.AbsPath(), id3v2.Options{Parse: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tag.Title() != tt.want || tag.Album() != prog.Title {
+			t.Errorf("writeID3Tag(%q) => %v / %v, want %v / %v", tt.template, tag.Title(), tag.Album(), tt.want, prog.Title)
+		}
+		tag.Close()
+	}
+
+	// the explicit program is marked for the feeds
+	prog.Explicit = true
+	if err := writeID3Tag(context.Background(), output, prog, ""); err != nil {
+		t.Fatal(err)
+	}
+	episode := &Episode{}
+	readEpisodeTag(output.AbsPath(), episode)
+	if !episode.Explicit {
+		t.Error("readEpisodeTag => not explicit, want explicit")
+	}
+}
+
+func TestRadicronDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	abs := filepath.Join(t.TempDir(), "explicit")
+	var radicrondirtests = []struct {
+		dir  string
+		want string
+	}{
+		{"explicit", filepath.Join(home, "explicit")},
+		{abs, abs},
+	}
+	for _, tt := range radicrondirtests {
+		got, err := radicronDir(tt.dir)
+		if err != nil || got != tt.want {
+			t.Errorf("radicronDir(%v) => %v, %v, want %v", tt.dir, got, err, tt.want)
+		}
+	}
+}
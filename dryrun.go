@@ -0,0 +1,30 @@
+package radicron
+
+import (
+	"log"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// EstimateSize returns the approximate bytes of the program saved in the format
+func EstimateSize(d time.Duration, format string) int64 {
+	kbps := EstimatedAACKbps
+	if format == radigo.AudioFormatMP3 {
+		kbps = EstimatedMP3Kbps
+	}
+	return int64(d.Seconds() * kbps * 1000 / 8)
+}
+
+// reportDryRun logs the program to be downloaded without downloading
+func reportDryRun(prog *Prog, output *radigo.OutputConfig) {
+	size := EstimateSize(prog.Duration(), output.AudioFormat())
+	log.Printf(
+		"+would download [%s]%s (%s): %s (~%.1f MB)",
+		prog.StationID,
+		prog.Title,
+		prog.Ft,
+		output.AbsPath(),
+		float64(size)/Kilobytes/Kilobytes,
+	)
+}
@@ -0,0 +1,62 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+func TestEstimateSize(t *testing.T) {
+	var sizetests = []struct {
+		d      time.Duration
+		format string
+		want   int64
+	}{
+		{time.Hour, radigo.AudioFormatAAC, 21600000},
+		{time.Hour, radigo.AudioFormatMP3, 85500000},
+		{0, radigo.AudioFormatAAC, 0},
+	}
+	for _, tt := range sizetests {
+		if got := EstimateSize(tt.d, tt.format); got != tt.want {
+			t.Errorf("EstimateSize(%v, %v) => %v, want %v", tt.d, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadDryRun(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	prog := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", To: "20230625060000", Title: "Title"}
+	CurrentTime = time.Date(2023, 6, 26, 0, 0, 0, 0, Location)
+	asset := &Asset{DryRun: true, MetadataOnly: true, OutputFormat: radigo.AudioFormatAAC}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	var wg sync.WaitGroup
+	if err := Download(ctx, &wg, prog); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if !strings.Contains(buf.String(), "+would download [FMT]Title (20230625050000)") ||
+		!strings.Contains(buf.String(), "202306250500_FMT_Title.aac (~20.6 MB)") {
+		t.Errorf("log => %v", buf.String())
+	}
+	// nothing written
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("RADICRON_HOME => %v, want empty", entries)
+	}
+}
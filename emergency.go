@@ -0,0 +1,92 @@
+package radicron
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultEmergencyMarkers in the titles of the emergency or special programming
+var DefaultEmergencyMarkers = []string{"緊急", "臨時ニュース", "報道特別番組", "ニュース速報", "災害情報", "地震情報", "津波情報"}
+
+// Emergency watches the guides of the news stations for the emergency or special programming
+// interrupting the regular programs, and optionally records them
+type Emergency struct {
+	// Stations to watch, e.g., the news stations
+	Stations []string `mapstructure:"stations"`
+	// Markers in the titles, DefaultEmergencyMarkers if empty
+	Markers []string `mapstructure:"markers"`
+	// Record the programs detected besides the event
+	Record bool `mapstructure:"record"`
+	seen   map[string]bool
+	mu     sync.Mutex
+}
+
+// Watches returns true if the station is watched
+func (e *Emergency) Watches(stationID string) bool {
+	if e == nil {
+		return false
+	}
+	for _, s := range e.Stations {
+		if strings.EqualFold(s, stationID) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmergency returns true if the title of the program has any of the markers
+func (e *Emergency) IsEmergency(p *Prog) bool {
+	markers := e.Markers
+	if len(markers) == 0 {
+		markers = DefaultEmergencyMarkers
+	}
+	for _, m := range markers {
+		if m != "" && strings.Contains(p.Title, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the emergency programs in the guide of the station not detected before
+func (e *Emergency) Detect(stationID string, guide Progs) Progs {
+	detected := Progs{}
+	if !e.Watches(stationID) {
+		return detected
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.seen == nil {
+		e.seen = map[string]bool{}
+	}
+	for _, p := range guide {
+		if e.seen[p.ID] || !e.IsEmergency(p) {
+			continue
+		}
+		e.seen[p.ID] = true
+		detected = append(detected, p)
+	}
+	return detected
+}
+
+// Inherit takes over the programs detected by the last one, e.g., across the reloads of the config
+func (e *Emergency) Inherit(last *Emergency) {
+	if e == nil || last == nil || e == last {
+		return
+	}
+	last.mu.Lock()
+	seen := make(map[string]bool, len(last.seen))
+	for id := range last.seen {
+		seen[id] = true
+	}
+	last.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.seen == nil {
+		e.seen = map[string]bool{}
+	}
+	for id := range seen {
+		e.seen[id] = true
+	}
+}
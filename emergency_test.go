@@ -0,0 +1,54 @@
+package radicron
+
+import (
+	"testing"
+)
+
+func TestEmergency(t *testing.T) {
+	guide := Progs{
+		{ID: "1", StationID: "TBS", Ft: "20240101160000", To: "20240101170000", Title: "ニュース"},
+		{ID: "2", StationID: "TBS", Ft: "20240101161000", To: "20240101180000", Title: "報道特別番組 能登半島地震"},
+		{ID: "3", StationID: "TBS", Ft: "20240101180000", To: "20240101181000", Title: "臨時ニュース"},
+	}
+	var emergencytests = []struct {
+		emergency *Emergency
+		stationID string
+		detected  []string
+	}{
+		{nil, "TBS", []string{}},
+		{&Emergency{Stations: []string{"QRR"}}, "TBS", []string{}},
+		{&Emergency{Stations: []string{"tbs"}}, "TBS", []string{"2", "3"}},
+		{&Emergency{Stations: []string{"TBS"}, Markers: []string{"地震"}}, "TBS", []string{"2"}},
+		{&Emergency{Stations: []string{"TBS"}, Markers: []string{"選挙"}}, "TBS", []string{}},
+	}
+	for _, tt := range emergencytests {
+		detected := tt.emergency.Detect(tt.stationID, guide)
+		got := []string{}
+		for _, p := range detected {
+			got = append(got, p.ID)
+		}
+		if len(got) != len(tt.detected) {
+			t.Errorf("Detect(%s) with %+v => %v, want %v", tt.stationID, tt.emergency, got, tt.detected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.detected[i] {
+				t.Errorf("Detect(%s) with %+v => %v, want %v", tt.stationID, tt.emergency, got, tt.detected)
+				break
+			}
+		}
+		// detected once
+		if again := tt.emergency.Detect(tt.stationID, guide); len(again) != 0 {
+			t.Errorf("Detect(%s) again => %v, want none", tt.stationID, again)
+		}
+	}
+
+	// across the reloads
+	last := &Emergency{Stations: []string{"TBS"}}
+	last.Detect("TBS", guide[:2])
+	e := &Emergency{Stations: []string{"TBS"}}
+	e.Inherit(last)
+	if detected := e.Detect("TBS", guide); len(detected) != 1 || detected[0].ID != "3" {
+		t.Errorf("Detect() after Inherit() => %v, want only 3", detected)
+	}
+}
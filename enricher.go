@@ -0,0 +1,105 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Enricher improves the metadata of the program before tagging,
+// e.g., looking up the performers or the episode title on the show website
+type Enricher interface {
+	// Name of the enricher for the logs
+	Name() string
+	// Enrich updates the metadata of the program
+	Enrich(ctx context.Context, prog *Prog) error
+}
+
+var (
+	enrichersMu sync.Mutex
+	// enrichers registered by the plugins
+	enrichers []Enricher
+)
+
+// RegisterEnricher adds the enricher run before tagging in the order of registration,
+// typically in the init of a plugin package imported by a custom build
+func RegisterEnricher(e Enricher) {
+	enrichersMu.Lock()
+	defer enrichersMu.Unlock()
+	enrichers = append(enrichers, e)
+}
+
+// RegisteredEnrichers returns the enrichers registered by the plugins
+func RegisteredEnrichers() []Enricher {
+	enrichersMu.Lock()
+	defer enrichersMu.Unlock()
+	return append([]Enricher{}, enrichers...)
+}
+
+// CommandEnricher runs an external command reading the program in JSON from the stdin
+// and writing the updated one to the stdout
+type CommandEnricher struct {
+	Command string
+}
+
+// Name implements Enricher
+func (ce *CommandEnricher) Name() string {
+	return ce.Command
+}
+
+// Enrich implements Enricher, updating only the metadata and not the identity of the program
+func (ce *CommandEnricher) Enrich(ctx context.Context, prog *Prog) error {
+	args := strings.Fields(ce.Command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	input, err := json.Marshal(prog)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, EnricherTimeoutSeconds*time.Second)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %s", err, lastLine(stderr.String()))
+	}
+
+	enriched := &Prog{}
+	if err = json.Unmarshal(stdout.Bytes(), enriched); err != nil {
+		return fmt.Errorf("invalid output: %s", err)
+	}
+	updateMetadata(prog, enriched)
+	return nil
+}
+
+// updateMetadata copies the metadata but not the identity of the enriched program
+func updateMetadata(prog, enriched *Prog) {
+	if enriched.Title != "" {
+		prog.Title = enriched.Title
+	}
+	prog.Desc = enriched.Desc
+	prog.Info = enriched.Info
+	prog.Pfm = enriched.Pfm
+	prog.Tags = enriched.Tags
+	prog.Genre = enriched.Genre
+	prog.Img = enriched.Img
+}
+
+// enrich runs the enrichers on the program, logging the failures not to fail the recording
+func enrich(ctx context.Context, es []Enricher, prog *Prog) {
+	for _, e := range es {
+		if err := e.Enrich(ctx, prog); err != nil {
+			log.Printf("enricher %s failed for [%s]%s (%s): %s", e.Name(), prog.StationID, prog.Title, prog.Ft, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+// testEnricher appends the suffix to the info, or fails if empty
+type testEnricher struct {
+	suffix string
+}
+
+func (te *testEnricher) Name() string {
+	return "test"
+}
+
+func (te *testEnricher) Enrich(ctx context.Context, prog *Prog) error {
+	if te.suffix == "" {
+		return errors.New("no suffix")
+	}
+	prog.Info += te.suffix
+	return nil
+}
+
+func TestEnrich(t *testing.T) {
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230605130000", Title: "Title", Info: "info"}
+	enrich(context.Background(), []Enricher{&testEnricher{" 1"}, &testEnricher{}, &testEnricher{" 2"}}, prog)
+	if prog.Info != "info 1 2" {
+		t.Errorf("enrich => %q, want %q", prog.Info, "info 1 2")
+	}
+
+	// the plugins
+	n := len(RegisteredEnrichers())
+	RegisterEnricher(&testEnricher{" 3"})
+	if got := RegisteredEnrichers(); len(got) != n+1 || got[n].Name() != "test" {
+		t.Errorf("RegisteredEnrichers => %v, want the test enricher at %d", got, n)
+	}
+}
+
+func TestCommandEnricher(t *testing.T) {
+	if _, err := exec.LookPath("sed"); err != nil {
+		t.Skip("sed not found")
+	}
+	var commandtests = []struct {
+		command string
+		title   string
+		pfm     string
+		wantErr bool
+	}{
+		{"sed s/Before/After/g", "After", "After", false},
+		{"sed s/12345/67890/", "Before", "Before", false},
+		{"sed s/{.*/invalid/", "Before", "Before", true},
+		{"", "Before", "Before", true},
+	}
+	for _, tt := range commandtests {
+		prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230605130000", Title: "Before", Pfm: "Before"}
+		err := (&CommandEnricher{Command: tt.command}).Enrich(context.Background(), prog)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Enrich(%s) => %v, want error %v", tt.command, err, tt.wantErr)
+		}
+		// only the metadata
+		if prog.Title != tt.title || prog.Pfm != tt.pfm || prog.ID != "12345" {
+			t.Errorf("Enrich(%s) => %+v", tt.command, prog)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package radicron
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event is a step in the lifecycle of a program:
+// scheduled, moved, dropped, emergency, expiring, started, progress, completed, or failed
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	StationID string    `json:"station_id"`
+	Ft        string    `json:"ft"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message,omitempty"`
+	// Reason of the failure, e.g., FailureAuth, if classified
+	Reason string `json:"reason,omitempty"`
+	// Done and Total segments in the progress of the download, only to the EventFunc
+	Done  int `json:"done,omitempty"`
+	Total int `json:"total,omitempty"`
+}
+
+// EventFunc is called with the events of the programs in the download,
+// e.g., to show the progress in the UI of the application embedding radicron;
+// it is called synchronously and should return quickly
+type EventFunc func(Event)
+
+// WithEventFunc returns ctx calling fn with the events of the downloads with it,
+// e.g., Download and the jobs of the workers, besides the event log
+func WithEventFunc(ctx context.Context, fn EventFunc) context.Context {
+	return context.WithValue(ctx, ContextKey("events"), fn)
+}
+
+// emit adds the event of the program to the event log of the asset in ctx and calls the EventFunc in ctx
+func emit(ctx context.Context, eventType string, prog *Prog, message string) {
+	dispatch(ctx, newEvent(eventType, prog, message))
+}
+
+// emitFailure emits EventFailed of the program with the reason and the cause of err
+func emitFailure(ctx context.Context, prog *Prog, err error) {
+	e := newEvent(EventFailed, prog, failureMessage(err))
+	e.Reason = FailureReason(err)
+	dispatch(ctx, e)
+}
+
+// dispatch adds the event to the event log of the asset in ctx and calls the EventFunc in ctx
+func dispatch(ctx context.Context, e *Event) {
+	if fn, ok := ctx.Value(ContextKey("events")).(EventFunc); ok && fn != nil {
+		fn(*e)
+	}
+	if asset := GetAsset(ctx); asset != nil {
+		asset.Events.add(e)
+	}
+}
+
+// withProgress returns ctx to report the segments downloaded of the program to the EventFunc in ctx
+func withProgress(ctx context.Context, prog *Prog) context.Context {
+	fn, ok := ctx.Value(ContextKey("events")).(EventFunc)
+	if !ok || fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ContextKey("progress"), func(done, total int) {
+		e := newEvent(EventProgress, prog, fmt.Sprintf("downloaded %d/%d segments", done, total))
+		e.Done, e.Total = done, total
+		fn(*e)
+	})
+}
+
+// reportProgress reports the segments downloaded to the EventFunc in ctx if any
+func reportProgress(ctx context.Context, done, total int) {
+	if progress, ok := ctx.Value(ContextKey("progress")).(func(done, total int)); ok {
+		progress(done, total)
+	}
+}
+
+// newEvent returns the event of the program now
+func newEvent(eventType string, prog *Prog, message string) *Event {
+	return &Event{
+		Time:      time.Now(),
+		Type:      eventType,
+		ID:        prog.ID,
+		StationID: prog.StationID,
+		Ft:        prog.Ft,
+		Title:     prog.Title,
+		Message:   message,
+	}
+}
+
+// EventLog appends the events to a JSON lines file for the timeline
+type EventLog struct {
+	// Notifiers to be told of each event
+	Notifiers []Notifier
+	path      string
+	mu        sync.Mutex
+}
+
+// Add appends the event of the program, logging the error not to fail the download
+func (el *EventLog) Add(eventType string, prog *Prog, message string) {
+	el.add(newEvent(eventType, prog, message))
+}
+
+// add appends the event and tells the notifiers
+func (el *EventLog) add(e *Event) {
+	if el == nil {
+		return
+	}
+	// not to block the download
+	for _, n := range el.Notifiers {
+		go func(n Notifier) {
+			if err := n.Notify(context.Background(), e); err != nil {
+				log.Printf("failed to notify the event: %s", err)
+			}
+		}(n)
+	}
+	blob, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("failed to encode the event: %s", err)
+		return
+	}
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	f, err := os.OpenFile(el.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("failed to open the event log: %s", err)
+		return
+	}
+	defer f.Close()
+	if _, err = f.Write(append(blob, '\n')); err != nil {
+		log.Printf("failed to write the event log: %s", err)
+	}
+}
+
+// LoadEvents returns the last n events of the program with the id in the order of time,
+// or of all the programs if the id is empty, and all the events if n is 0
+func LoadEvents(path, id string, n int) ([]*Event, error) {
+	events := []*Event{}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return events, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := &Event{}
+		// skip the line torn by a crash
+		if err = json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		if id != "" && e.ID != id {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the event log: %s", err)
+	}
+	if n > 0 && len(events) > n {
+		events = events[len(events)-n:]
+	}
+	return events, nil
+}
+
+// EventLogPath returns the event log in ${RADICRON_HOME}
+func EventLogPath() (string, error) {
+	return getRadicronPath(EventLogFileName)
+}
+
+// NewEventLog returns the EventLog in ${RADICRON_HOME}
+func NewEventLog() (*EventLog, error) {
+	path, err := EventLogPath()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return &EventLog{path: path}, nil
+}
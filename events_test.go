@@ -0,0 +1,118 @@
+package radicron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), EventLogFileName)
+	el := &EventLog{path: path}
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	other := &Prog{ID: "67890", StationID: "TBS", Ft: "20230625060000", Title: "Other"}
+	el.Add(EventScheduled, prog, "")
+	el.Add(EventScheduled, other, "")
+	el.Add(EventStarted, prog, "")
+	el.Add(EventFailed, prog, "lack of aac files")
+	// nil-safe without the event log
+	(*EventLog)(nil).Add(EventStarted, prog, "")
+
+	// a line torn by a crash
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"time":`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var eventtests = []struct {
+		id   string
+		n    int
+		want []string
+	}{
+		{"12345", 0, []string{EventScheduled, EventStarted, EventFailed}},
+		{"12345", 2, []string{EventStarted, EventFailed}},
+		{"67890", 0, []string{EventScheduled}},
+		{"", 0, []string{EventScheduled, EventScheduled, EventStarted, EventFailed}},
+		{"missing", 0, []string{}},
+	}
+	for _, tt := range eventtests {
+		events, err := LoadEvents(path, tt.id, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, e := range events {
+			got = append(got, e.Type)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("LoadEvents(%q, %d) => %v, want %v", tt.id, tt.n, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("LoadEvents(%q, %d) => %v, want %v", tt.id, tt.n, got, tt.want)
+				break
+			}
+		}
+	}
+	if events, _ := LoadEvents(path, "12345", 0); events[2].Message != "lack of aac files" || events[2].Title != "Title" {
+		t.Errorf("the failed event => %+v", events[2])
+	}
+
+	if events, err := LoadEvents(filepath.Join(t.TempDir(), "missing.jsonl"), "", 0); err != nil || len(events) != 0 {
+		t.Errorf("LoadEvents without the log => %v, %v, want none", events, err)
+	}
+}
+
+func TestEventFunc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), EventLogFileName)
+	asset := &Asset{Events: &EventLog{path: path}}
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	got := []Event{}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	ctx = WithEventFunc(ctx, func(e Event) {
+		got = append(got, e)
+	})
+
+	emit(ctx, EventStarted, prog, "")
+	progressCtx := withProgress(ctx, prog)
+	reportProgress(progressCtx, 1, 2)
+	reportProgress(progressCtx, 2, 2)
+	emit(ctx, EventCompleted, prog, "done.aac")
+	// without the EventFunc
+	reportProgress(context.Background(), 1, 2)
+	emit(context.WithValue(context.Background(), ContextKey("asset"), asset), EventFailed, prog, "")
+
+	var functests = []struct {
+		eventType string
+		done      int
+		total     int
+	}{
+		{EventStarted, 0, 0},
+		{EventProgress, 1, 2},
+		{EventProgress, 2, 2},
+		{EventCompleted, 0, 0},
+	}
+	if len(got) != len(functests) {
+		t.Fatalf("EventFunc => %+v, want %d events", got, len(functests))
+	}
+	for i, tt := range functests {
+		if e := got[i]; e.Type != tt.eventType || e.Done != tt.done || e.Total != tt.total || e.ID != prog.ID {
+			t.Errorf("EventFunc => %+v, want %+v", e, tt)
+		}
+	}
+
+	// the progress of the segments is not logged
+	events, err := LoadEvents(path, prog.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].Type != EventStarted || events[1].Type != EventCompleted || events[2].Type != EventFailed {
+		t.Errorf("LoadEvents => %+v, want started, completed, and failed", events)
+	}
+}
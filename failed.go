@@ -0,0 +1,80 @@
+package radicron
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FailedDir returns the dir to keep the tmp files of the failed programs in RADICRON_HOME
+func FailedDir() (string, error) {
+	return getRadicronPath(FailedDirName)
+}
+
+// keepFailedDir moves the aac dir of the failed program to the FailedDir with the error,
+// to salvage the partial audio or attach it to a bug report
+func keepFailedDir(aacDir string, prog *Prog, cause error) (string, error) {
+	dir, err := FailedDir()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	kept := filepath.Join(dir, fmt.Sprintf(
+		"%s_%s_%s",
+		time.Now().In(Location).Format(DatetimeLayout),
+		prog.StationID,
+		sanitizeFileName(prog.Title),
+	))
+	if err = os.Rename(aacDir, kept); err != nil {
+		return "", err
+	}
+	report := fmt.Sprintf("[%s]%s (%s-%s)\n%s\n%s\n", prog.StationID, prog.Title, prog.Ft, prog.To, prog.M3U8, cause)
+	if err = os.WriteFile(filepath.Join(kept, FailedReportFileName), []byte(report), 0o644); err != nil {
+		return kept, err
+	}
+	return kept, nil
+}
+
+// cleanupAACDir removes the aac dir, or keeps it for the retention if the program failed
+func cleanupAACDir(aacDir string, prog *Prog, cause error, retention time.Duration) {
+	if cause != nil && retention > 0 && !errors.Is(cause, ErrRerun) {
+		kept, err := keepFailedDir(aacDir, prog, cause)
+		if err == nil {
+			log.Printf("kept the tmp files of [%s]%s (%s) for %v: %s", prog.StationID, prog.Title, prog.Ft, retention, kept)
+			return
+		}
+		log.Printf("failed to keep the tmp files: %s", err)
+	}
+	os.RemoveAll(aacDir)
+}
+
+// CleanFailedDirs removes the tmp files of the failed programs kept longer than the retention
+func CleanFailedDirs(retention time.Duration) (int, error) {
+	dir, err := FailedDir()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < retention {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
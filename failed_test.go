@@ -0,0 +1,92 @@
+package radicron
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanupAACDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{StationID: "FMT", Ft: "20230605130000", To: "20230605145500", Title: "Title/1"}
+	var cleanuptests = []struct {
+		name      string
+		cause     error
+		retention time.Duration
+		kept      bool
+	}{
+		{"saved", nil, time.Hour, false},
+		{"failed without the retention", errors.New("failed"), 0, false},
+		{"rerun", fmt.Errorf("%w of another", ErrRerun), time.Hour, false},
+		{"failed", errors.New("too many segments missing"), time.Hour, true},
+	}
+	for _, tt := range cleanuptests {
+		t.Run(tt.name, func(t *testing.T) {
+			aacDir, err := tempAACDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(filepath.Join(aacDir, "0.aac"), []byte("aac"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cleanupAACDir(aacDir, prog, tt.cause, tt.retention)
+			if _, err = os.Stat(aacDir); !os.IsNotExist(err) {
+				t.Errorf("aac dir => %v, want removed or moved", err)
+			}
+
+			dir, _ := FailedDir()
+			entries, _ := os.ReadDir(dir)
+			if got := len(entries) > 0; got != tt.kept {
+				t.Fatalf("kept => %v, want %v", got, tt.kept)
+			}
+			if !tt.kept {
+				return
+			}
+			kept := filepath.Join(dir, entries[0].Name())
+			if !strings.HasSuffix(kept, "_FMT_Title_1") {
+				t.Errorf("kept => %v, want *_FMT_Title_1", kept)
+			}
+			if _, err = os.Stat(filepath.Join(kept, "0.aac")); err != nil {
+				t.Error(err)
+			}
+			report, err := os.ReadFile(filepath.Join(kept, FailedReportFileName))
+			if err != nil || !strings.Contains(string(report), tt.cause.Error()) {
+				t.Errorf("report => %q, %v, want the cause", report, err)
+			}
+		})
+	}
+}
+
+func TestCleanFailedDirs(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	if n, err := CleanFailedDirs(time.Hour); err != nil || n != 0 {
+		t.Errorf("CleanFailedDirs without the dir => %v, %v, want 0", n, err)
+	}
+	dir, err := FailedDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"old", "new"} {
+		if err = os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.Chtimes(filepath.Join(dir, "old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := CleanFailedDirs(time.Hour); err != nil || n != 1 {
+		t.Errorf("CleanFailedDirs => %v, %v, want 1", n, err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "new")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,96 @@
+package radicron
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// SegmentsError is returned when too many segments of the program failed to download
+type SegmentsError struct {
+	Failed int
+	Total  int
+}
+
+// Error implements error
+func (e *SegmentsError) Error() string {
+	return fmt.Sprintf("lack of aac files: %d/%d segments failed", e.Failed, e.Total)
+}
+
+// failure marks the error with the reason of the failed recording, keeping the message
+type failure struct {
+	reason string
+	err    error
+}
+
+// Error implements error
+func (f *failure) Error() string {
+	return f.err.Error()
+}
+
+// Unwrap returns the marked error
+func (f *failure) Unwrap() error {
+	return f.err
+}
+
+// withReason marks err with the reason, e.g., FailureAuth, or returns nil if err is nil
+func withReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &failure{reason: reason, err: err}
+}
+
+// FailureReason classifies why the recording failed by the error chain:
+// FailureAuth, FailurePlaylist, FailureSegments, FailureEncoder, FailureDiskFull, FailureExpired,
+// or empty if unknown
+func FailureReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	// the disk full fails any stage, even ffmpeg
+	if errors.Is(err, syscall.ENOSPC) || isDiskFull(err.Error()) {
+		return FailureDiskFull
+	}
+	if errors.Is(err, ErrExpired) {
+		return FailureExpired
+	}
+	var se *SegmentsError
+	if errors.As(err, &se) {
+		return FailureSegments
+	}
+	var f *failure
+	if errors.As(err, &f) {
+		return f.reason
+	}
+	return ""
+}
+
+// failureMessage returns the reason and the cause of the failure for the notifications
+func failureMessage(err error) string {
+	var se *SegmentsError
+	switch FailureReason(err) {
+	case FailureAuth:
+		return fmt.Sprintf("auth failed: %s", err)
+	case FailurePlaylist:
+		return fmt.Sprintf("playlist not available: %s", err)
+	case FailureSegments:
+		errors.As(err, &se)
+		return fmt.Sprintf("%d/%d segments missing: %s", se.Failed, se.Total, err)
+	case FailureEncoder:
+		return fmt.Sprintf("encoder error: %s", err)
+	case FailureDiskFull:
+		return fmt.Sprintf("disk full: %s", err)
+	case FailureExpired:
+		return fmt.Sprintf("timefree expired: %s", err)
+	}
+	return err.Error()
+}
+
+// isDiskFull returns true if the message, e.g., the stderr of ffmpeg, tells the disk is full
+func isDiskFull(msg string) bool {
+	msg = strings.ToLower(msg)
+	// ENOSPC, or ERROR_DISK_FULL on Windows
+	return strings.Contains(msg, "no space left on device") || strings.Contains(msg, "not enough space on the disk")
+}
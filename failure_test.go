@@ -0,0 +1,77 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestFailureReason(t *testing.T) {
+	var failuretests = []struct {
+		err     error
+		reason  string
+		message string
+	}{
+		{
+			withReason(FailureAuth, errors.New("unauthorized for JP13: 403 Forbidden")),
+			FailureAuth,
+			"auth failed: unauthorized for JP13: 403 Forbidden",
+		},
+		{
+			withReason(FailurePlaylist, fmt.Errorf("failed to get chunklist: %w", errors.New("failed to get playlist.m3u8: 404 Not Found"))),
+			FailurePlaylist,
+			"playlist not available: failed to get chunklist: failed to get playlist.m3u8: 404 Not Found",
+		},
+		{
+			fmt.Errorf("failed to download aac files: %w", &SegmentsError{Failed: 12, Total: 360}),
+			FailureSegments,
+			"12/360 segments missing: failed to download aac files: lack of aac files: 12/360 segments failed",
+		},
+		{
+			withReason(FailureEncoder, fmt.Errorf("failed to trim the aac file: %w", errors.New("ffmpeg: exit status 1: Invalid data found"))),
+			FailureEncoder,
+			"encoder error: failed to trim the aac file: ffmpeg: exit status 1: Invalid data found",
+		},
+		{
+			fmt.Errorf("failed to write the output file: %w", &os.PathError{Op: "write", Path: "out.aac", Err: syscall.ENOSPC}),
+			FailureDiskFull,
+			"disk full: failed to write the output file: write out.aac: no space left on device",
+		},
+		{
+			// ffmpeg only tells in the stderr
+			withReason(FailureEncoder, errors.New("ffmpeg: exit status 1: No space left on device")),
+			FailureDiskFull,
+			"disk full: ffmpeg: exit status 1: No space left on device",
+		},
+		{ErrExpired, FailureExpired, "timefree expired: expired"},
+		{errors.New("invalid file format"), "", "invalid file format"},
+		{withReason(FailureAuth, nil), "", ""},
+	}
+	for _, tt := range failuretests {
+		if got := FailureReason(tt.err); got != tt.reason {
+			t.Errorf("FailureReason(%v) => %q, want %q", tt.err, got, tt.reason)
+		}
+		if tt.err == nil {
+			continue
+		}
+		if got := failureMessage(tt.err); got != tt.message {
+			t.Errorf("failureMessage(%v) => %q, want %q", tt.err, got, tt.message)
+		}
+	}
+}
+
+func TestEmitFailure(t *testing.T) {
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	var got Event
+	ctx := WithEventFunc(context.Background(), func(e Event) {
+		got = e
+	})
+	emitFailure(ctx, prog, fmt.Errorf("failed to download aac files: %w", &SegmentsError{Failed: 3, Total: 10}))
+	if got.Type != EventFailed || got.Reason != FailureSegments || got.ID != prog.ID ||
+		got.Message != "3/10 segments missing: failed to download aac files: lack of aac files: 3/10 segments failed" {
+		t.Errorf("emitFailure() => %+v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// Favorites are the stations and the programs in the マイリスト of the radiko premium member
+type Favorites struct {
+	Stations []FavoriteStation `json:"stations"`
+	Programs []FavoriteProgram `json:"programs"`
+}
+
+// FavoriteStation is a station in the favorites
+type FavoriteStation struct {
+	StationID string `json:"station_id"`
+}
+
+// FavoriteProgram is a program in the favorites
+type FavoriteProgram struct {
+	StationID string `json:"station_id"`
+	Title     string `json:"title"`
+}
+
+// Favorites returns the favorites of the member logged in
+func (p *Premium) Favorites(ctx context.Context, client *radiko.Client) (*Favorites, error) {
+	session := p.session()
+	if session == "" {
+		return nil, fmt.Errorf("not logged in to radiko premium")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	u, err := url.Parse(APIPremiumFavorites)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set(RadikoSessionKey, session)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get the radiko premium favorites: %s", resp.Status)
+	}
+	favorites := &Favorites{}
+	if err = json.NewDecoder(resp.Body).Decode(favorites); err != nil {
+		return nil, fmt.Errorf("invalid radiko premium favorites: %s", err)
+	}
+	return favorites, nil
+}
+
+// StationIDs returns the favorite stations and the stations of the favorite programs
+func (f *Favorites) StationIDs() []string {
+	seen := map[string]bool{}
+	sids := []string{}
+	add := func(sid string) {
+		sid = strings.ToUpper(strings.TrimSpace(sid))
+		if sid != "" && !seen[sid] {
+			seen[sid] = true
+			sids = append(sids, sid)
+		}
+	}
+	for _, s := range f.Stations {
+		add(s.StationID)
+	}
+	for _, p := range f.Programs {
+		add(p.StationID)
+	}
+	sort.Strings(sids)
+	return sids
+}
+
+// Rules returns a rule for each favorite program by the title on the station
+func (f *Favorites) Rules() Rules {
+	rules := Rules{}
+	seen := map[string]bool{}
+	for _, p := range f.Programs {
+		sid := strings.ToUpper(strings.TrimSpace(p.StationID))
+		title := strings.TrimSpace(p.Title)
+		if sid == "" || title == "" {
+			continue
+		}
+		name := FavoriteRulePrefix + strings.ToLower(sid) + "-" + strings.TrimSuffix(DedupKey(title, ""), "@")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		rules = append(rules, &Rule{Name: name, StationID: sid, Title: title})
+	}
+	return rules
+}
@@ -0,0 +1,69 @@
+package radicron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/yyoshiki41/go-radiko"
+)
+
+func TestFavorites(t *testing.T) {
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/api/member/login":
+			w.Write([]byte(`{"radiko_session":"s3ss10n","paid_member":"1","areafree":"1"}`))
+		case "/v4/api/member/favorite/list":
+			if r.URL.Query().Get(RadikoSessionKey) != "s3ss10n" {
+				http.Error(w, `{"status":"401"}`, http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{
+"stations":[{"station_id":"tbs"},{"station_id":"FMT"}],
+"programs":[
+  {"station_id":"FMT","title":"THE TRAD"},
+  {"station_id":"QRR","title":"ＴＨＥ ＴＲＡＤ"},
+  {"station_id":"FMT","title":"THE TRAD"},
+  {"station_id":"LFR","title":""}
+]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	server, _ := url.Parse(ts.URL)
+	transport := http.DefaultTransport
+	http.DefaultTransport = rewriteTransport{server: server, next: transport}
+	defer func() { http.DefaultTransport = transport }()
+
+	p := &Premium{AreaID: "JP13", Mail: "member@example.com", Password: "secret"}
+	if _, err = p.Favorites(context.Background(), client); err == nil {
+		t.Error("Favorites() before Login => nil, want the error")
+	}
+	if err = p.Login(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	favorites, err := p.Favorites(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := favorites.StationIDs(), []string{"FMT", "LFR", "QRR", "TBS"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("StationIDs() => %v, want %v", got, want)
+	}
+	want := Rules{
+		{Name: "favorite-fmt-thetrad", StationID: "FMT", Title: "THE TRAD"},
+		{Name: "favorite-qrr-thetrad", StationID: "QRR", Title: "ＴＨＥ ＴＲＡＤ"},
+	}
+	if got := favorites.Rules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rules() => %v, want %v", got, want)
+	}
+}
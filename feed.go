@@ -0,0 +1,237 @@
+package radicron
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogem/id3v2"
+	"github.com/yyoshiki41/radigo"
+)
+
+// Episode contains the metadata of a downloaded file
+type Episode struct {
+	Artwork     bool
+	Author      string
+	Description string
+	Explicit    bool
+	FileName    string
+	PubDate     time.Time
+	Size        int64
+	StationID   string
+	Title       string
+}
+
+// MIMEType returns the MIME type of the audio
+func (e *Episode) MIMEType() string {
+	if strings.HasSuffix(e.FileName, "."+radigo.AudioFormatMP3) {
+		return "audio/mpeg"
+	}
+	return "audio/aac"
+}
+
+type Episodes []*Episode
+
+// Shows returns the titles of the episodes in order
+func (es Episodes) Shows() []string {
+	seen := map[string]bool{}
+	shows := []string{}
+	for _, e := range es {
+		if !seen[e.Title] {
+			seen[e.Title] = true
+			shows = append(shows, e.Title)
+		}
+	}
+	sort.Strings(shows)
+	return shows
+}
+
+// Clean returns the episodes not marked as explicit
+func (es Episodes) Clean() Episodes {
+	episodes := Episodes{}
+	for _, e := range es {
+		if !e.Explicit {
+			episodes = append(episodes, e)
+		}
+	}
+	return episodes
+}
+
+// Show returns the episodes of the show
+func (es Episodes) Show(title string) Episodes {
+	episodes := Episodes{}
+	for _, e := range es {
+		if e.Title == title {
+			episodes = append(episodes, e)
+		}
+	}
+	return episodes
+}
+
+// RSS is the podcast feed
+type RSS struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	ITunesNS string     `xml:"xmlns:itunes,attr"`
+	Channel  RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []RSSItem `xml:"item"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type RSSImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type RSSItem struct {
+	Title       string       `xml:"title"`
+	Description string       `xml:"description"`
+	Author      string       `xml:"itunes:author,omitempty"`
+	Image       *RSSImage    `xml:"itunes:image,omitempty"`
+	Explicit    string       `xml:"itunes:explicit,omitempty"`
+	GUID        string       `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Enclosure   RSSEnclosure `xml:"enclosure"`
+}
+
+// LoadEpisodes returns the episodes in the dir sorted by the newest first
+func LoadEpisodes(dir string) (Episodes, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := Episodes{}
+	for _, entry := range entries {
+		ext := strings.TrimPrefix(filepath.Ext(entry.Name()), ".")
+		if entry.IsDir() || (ext != radigo.AudioFormatAAC && ext != radigo.AudioFormatMP3) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		episode := parseEpisodeFileName(entry.Name())
+		episode.Size = info.Size()
+		if episode.PubDate.IsZero() {
+			episode.PubDate = info.ModTime()
+		}
+		readEpisodeTag(filepath.Join(dir, entry.Name()), episode)
+		episodes = append(episodes, episode)
+	}
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].PubDate.After(episodes[j].PubDate)
+	})
+	return episodes, nil
+}
+
+// NewPodcastFeed returns the RSS for the episodes with the audio URLs under baseURL
+func NewPodcastFeed(title, baseURL, token string, episodes Episodes) *RSS {
+	rss := &RSS{
+		Version:  "2.0",
+		ITunesNS: "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: RSSChannel{
+			Title:       title,
+			Link:        baseURL,
+			Description: title,
+			Items:       []RSSItem{},
+		},
+	}
+	for _, e := range episodes {
+		query := ""
+		if token != "" {
+			query = "?token=" + url.QueryEscape(token)
+		}
+		audioURL := fmt.Sprintf("%s/audio/%s%s", strings.TrimSuffix(baseURL, "/"), url.PathEscape(e.FileName), query)
+		var image *RSSImage
+		if e.Artwork {
+			image = &RSSImage{
+				Href: fmt.Sprintf("%s/artwork/%s%s", strings.TrimSuffix(baseURL, "/"), url.PathEscape(e.FileName), query),
+			}
+		}
+		explicit := ""
+		if e.Explicit {
+			explicit = "true"
+		}
+		rss.Channel.Items = append(rss.Channel.Items, RSSItem{
+			Title:       e.Title,
+			Description: e.Description,
+			Author:      e.Author,
+			Image:       image,
+			Explicit:    explicit,
+			GUID:        e.FileName,
+			PubDate:     e.PubDate.Format(time.RFC1123Z),
+			Enclosure: RSSEnclosure{
+				URL:    audioURL,
+				Length: e.Size,
+				Type:   e.MIMEType(),
+			},
+		})
+	}
+	return rss
+}
+
+// parseEpisodeFileName parses the file name e.g., "202306051300_FMT_title.aac"
+func parseEpisodeFileName(fileName string) *Episode {
+	episode := &Episode{
+		FileName: fileName,
+		Title:    strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+	}
+	parts := strings.SplitN(episode.Title, "_", 3)
+	if len(parts) != 3 {
+		return episode
+	}
+	pubDate, err := time.ParseInLocation(OutputDatetimeLayout, parts[0], Location)
+	if err != nil {
+		return episode
+	}
+	episode.PubDate = pubDate
+	episode.StationID = parts[1]
+	episode.Title = parts[2]
+	return episode
+}
+
+// readEpisodeTag fills the episode with the ID3v2 tag if available
+func readEpisodeTag(path string, episode *Episode) {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return
+	}
+	defer tag.Close()
+
+	if tag.Album() != "" {
+		episode.Title = tag.Album()
+	}
+	episode.Author = tag.Artist()
+	episode.Artwork = len(tag.GetFrames(tag.CommonID("Attached picture"))) > 0
+	for _, f := range tag.GetFrames("TXXX") {
+		if udtf, ok := f.(id3v2.UserDefinedTextFrame); ok && udtf.Description == ID3v2DescAdvisory {
+			episode.Explicit = udtf.Value == ID3v2AdvisoryExplicit
+		}
+	}
+	for _, f := range tag.GetFrames(tag.CommonID("Comments")) {
+		if cf, ok := f.(id3v2.CommentFrame); ok {
+			episode.Description = cf.Description
+			break
+		}
+	}
+	// prefer the summary if available
+	if summary := readSummary(path); summary != "" {
+		episode.Description = summary
+	}
+}
@@ -0,0 +1,148 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEpisodeFileName(t *testing.T) {
+	var episodetests = []struct {
+		in        string
+		title     string
+		stationID string
+		pubDate   time.Time
+	}{
+		{
+			"202306051300_FMT_山崎怜奈の誰かに話したかったこと。.aac",
+			"山崎怜奈の誰かに話したかったこと。",
+			"FMT",
+			time.Date(2023, 6, 5, 13, 0, 0, 0, Location),
+		},
+		{
+			"202306051300_JOAK-FM_a_b.mp3",
+			"a_b",
+			"JOAK-FM",
+			time.Date(2023, 6, 5, 13, 0, 0, 0, Location),
+		},
+		{
+			"unknown.aac",
+			"unknown",
+			"",
+			time.Time{},
+		},
+	}
+	for _, tt := range episodetests {
+		got := parseEpisodeFileName(tt.in)
+		if got.Title != tt.title {
+			t.Errorf("parseEpisodeFileName(%v).Title => %v, want %v", tt.in, got.Title, tt.title)
+		}
+		if got.StationID != tt.stationID {
+			t.Errorf("parseEpisodeFileName(%v).StationID => %v, want %v", tt.in, got.StationID, tt.stationID)
+		}
+		if !got.PubDate.Equal(tt.pubDate) {
+			t.Errorf("parseEpisodeFileName(%v).PubDate => %v, want %v", tt.in, got.PubDate, tt.pubDate)
+		}
+	}
+}
+
+func TestLoadEpisodes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"202306051300_FMT_old.aac",
+		"202306061300_FMT_new.mp3",
+		"ignored.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("audio"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	episodes, err := LoadEpisodes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("LoadEpisodes => %v episodes, want %v", len(episodes), 2)
+	}
+	if episodes[0].Title != "new" || episodes[1].Title != "old" {
+		t.Errorf("LoadEpisodes => [%v, %v], want [new, old]", episodes[0].Title, episodes[1].Title)
+	}
+	if episodes[0].MIMEType() != "audio/mpeg" || episodes[1].MIMEType() != "audio/aac" {
+		t.Errorf("MIMEType => [%v, %v]", episodes[0].MIMEType(), episodes[1].MIMEType())
+	}
+}
+
+func TestNewPodcastFeed(t *testing.T) {
+	episodes := Episodes{
+		&Episode{
+			FileName: "202306051300_FMT_title.aac",
+			PubDate:  time.Date(2023, 6, 5, 13, 0, 0, 0, Location),
+			Size:     1024,
+			Title:    "title",
+		},
+	}
+	rss := NewPodcastFeed("radicron", "http://localhost:8080/", "secret", episodes)
+	if len(rss.Channel.Items) != 1 {
+		t.Fatalf("NewPodcastFeed => %v items, want %v", len(rss.Channel.Items), 1)
+	}
+	got := rss.Channel.Items[0].Enclosure.URL
+	want := "http://localhost:8080/audio/202306051300_FMT_title.aac?token=secret"
+	if got != want {
+		t.Errorf("enclosure url => %v, want %v", got, want)
+	}
+	if rss.Channel.Items[0].Image != nil {
+		t.Errorf("image => %v, want none", rss.Channel.Items[0].Image)
+	}
+
+	episodes[0].Artwork = true
+	rss = NewPodcastFeed("radicron", "http://localhost:8080/", "secret", episodes)
+	got = rss.Channel.Items[0].Image.Href
+	want = "http://localhost:8080/artwork/202306051300_FMT_title.aac?token=secret"
+	if got != want {
+		t.Errorf("image href => %v, want %v", got, want)
+	}
+}
+
+func TestEpisodesShows(t *testing.T) {
+	episodes := Episodes{
+		&Episode{FileName: "202306121300_FMT_b.aac", Title: "b"},
+		&Episode{FileName: "202306051300_FMT_b.aac", Title: "b"},
+		&Episode{FileName: "202306051000_TBS_a.aac", Title: "a"},
+	}
+	if got, want := episodes.Shows(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Shows => %v, want %v", got, want)
+	}
+	var showtests = []struct {
+		title string
+		want  int
+	}{
+		{"a", 1},
+		{"b", 2},
+		{"c", 0},
+	}
+	for _, tt := range showtests {
+		if got := len(episodes.Show(tt.title)); got != tt.want {
+			t.Errorf("Show(%v) => %v episodes, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestEpisodesClean(t *testing.T) {
+	episodes := Episodes{
+		&Episode{FileName: "202306121300_FMT_b.aac", Title: "b", Explicit: true},
+		&Episode{FileName: "202306051000_TBS_a.aac", Title: "a"},
+	}
+	clean := episodes.Clean()
+	if len(clean) != 1 || clean[0].Title != "a" {
+		t.Errorf("Clean => %v, want only a", clean)
+	}
+	rss := NewPodcastFeed("radicron", "http://localhost:8080", "", episodes)
+	if got := rss.Channel.Items[0].Explicit; got != "true" {
+		t.Errorf("explicit => %v, want true", got)
+	}
+	if got := rss.Channel.Items[1].Explicit; got != "" {
+		t.Errorf("explicit => %v, want none", got)
+	}
+}
@@ -0,0 +1,155 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// runFFmpeg runs ffmpeg with the args and writes the stdout to w if not nil
+func runFFmpeg(ctx context.Context, w io.Writer, args ...string) error {
+	return execFFmpeg(ctx, nil, w, append([]string{"-nostdin"}, args...))
+}
+
+// execFFmpeg runs ffmpeg reading the stdin from r if not nil
+func execFFmpeg(ctx context.Context, r io.Reader, w io.Writer, args []string) error {
+	cmdPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdPath, append([]string{"-hide_banner"}, args...)...) //nolint:gosec
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return withReason(FailureEncoder, fmt.Errorf("ffmpeg: %s: %s", err, lastLine(stderr.String())))
+	}
+	return nil
+}
+
+// concatSegments concatenates the segments saved in dir,
+// re-encoding at the discontinuities where the encoder settings may change
+// or to drop the priming samples of each segment if priming > 0
+func concatSegments(ctx context.Context, dir string, segments Segments, priming int) (string, error) {
+	if priming > 0 {
+		return concatGapless(ctx, dir, segments, priming)
+	}
+	groups := segments.Groups()
+	if len(groups) <= 1 {
+		return radigo.ConcatAACFilesFromList(ctx, dir)
+	}
+
+	args := []string{}
+	filter := ""
+	for i, group := range groups {
+		list := filepath.Join(dir, fmt.Sprintf("group%d.txt", i))
+		var b strings.Builder
+		for _, segment := range group {
+			fmt.Fprintf(&b, "file '%s'\n", segment.FileName())
+		}
+		if err := os.WriteFile(list, []byte(b.String()), 0o600); err != nil {
+			return "", err
+		}
+		args = append(args, "-f", "concat", "-safe", "0", "-i", list)
+		filter += fmt.Sprintf("[%d:a]", i)
+	}
+	filter += fmt.Sprintf("concat=n=%d:v=0:a=1", len(groups))
+	output := filepath.Join(dir, "concated.aac")
+	args = append(args, "-filter_complex", filter, "-c:a", "aac", "-b:a", ReencodeBitrate, "-y", output)
+	return output, runFFmpeg(ctx, nil, args...)
+}
+
+// concatGapless re-encodes the segments skipping the priming samples at each join
+func concatGapless(ctx context.Context, dir string, segments Segments, priming int) (string, error) {
+	list, err := gaplessList(dir, segments, priming)
+	if err != nil {
+		return "", err
+	}
+	listPath := filepath.Join(dir, "gapless.txt")
+	if err = os.WriteFile(listPath, []byte(list), 0o600); err != nil {
+		return "", err
+	}
+	output := filepath.Join(dir, "concated.aac")
+	return output, runFFmpeg(ctx, nil,
+		"-f", "concat", "-safe", "0", "-i", listPath,
+		"-c:a", "aac", "-b:a", ReencodeBitrate,
+		"-y", output,
+	)
+}
+
+// gaplessList returns the concat list with the inpoint after the priming samples
+// of each segment except the first one
+func gaplessList(dir string, segments Segments, priming int) (string, error) {
+	var b strings.Builder
+	for i, segment := range segments {
+		fmt.Fprintf(&b, "file '%s'\n", segment.FileName())
+		if i == 0 {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, segment.FileName()))
+		if err != nil {
+			return "", err
+		}
+		info, err := ValidateADTS(f)
+		f.Close()
+		if info == nil {
+			return "", fmt.Errorf("failed to read %s: %s", segment.FileName(), err)
+		}
+		fmt.Fprintf(&b, "inpoint %.6f\n", float64(priming)/float64(info.SampleRate))
+	}
+	return b.String(), nil
+}
+
+// trimAudio cuts the audio to length from offset and returns the trimmed file next to it
+func trimAudio(ctx context.Context, input string, offset, length time.Duration) (string, error) {
+	output := strings.TrimSuffix(input, filepath.Ext(input)) + "-trimmed" + filepath.Ext(input)
+	err := runFFmpeg(ctx, nil,
+		"-ss", fmt.Sprintf("%.3f", offset.Seconds()),
+		"-i", input,
+		"-t", fmt.Sprintf("%.3f", length.Seconds()),
+		"-c", "copy",
+		"-y", output,
+	)
+	if err != nil {
+		return "", err
+	}
+	return output, os.Remove(input)
+}
+
+// probeDuration returns the duration of the audio with ffprobe
+func probeDuration(ctx context.Context, path string) (time.Duration, error) {
+	cmdPath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return 0, err
+	}
+	out, err := exec.CommandContext(ctx, cmdPath, //nolint:gosec
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe %s: %s", path, err)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration of %s: %s", path, err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// lastLine returns the last non-empty line of the output
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
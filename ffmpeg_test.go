@@ -0,0 +1,63 @@
+package radicron
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGaplessList(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x21}, 100)
+	dir := t.TempDir()
+	segments := Segments{}
+	for i, rateIndex := range []int{3, 3, 6} { // 48k, 48k, 24k
+		s := &Segment{Index: i, URI: "https://radiko.jp/a.aac"}
+		if err := os.WriteFile(filepath.Join(dir, s.FileName()), adtsFrame(rateIndex, 2, payload), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, s)
+	}
+
+	got, err := gaplessList(dir, segments, 2112)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "file '000000.aac'\n" +
+		"file '000001.aac'\ninpoint 0.044000\n" +
+		"file '000002.aac'\ninpoint 0.088000\n"
+	if got != want {
+		t.Errorf("gaplessList => %q, want %q", got, want)
+	}
+
+	// missing segment
+	segments = append(segments, &Segment{Index: 3, URI: "https://radiko.jp/a.aac"})
+	if _, err = gaplessList(dir, segments, 2112); err == nil {
+		t.Error("gaplessList with a missing segment => nil, want error")
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	if got := lastLine("line 1\nline 2\n\n"); got != "line 2" {
+		t.Errorf("lastLine => %q, want %q", got, "line 2")
+	}
+}
+
+func BenchmarkGaplessList(b *testing.B) {
+	dir := b.TempDir()
+	frame := adtsFrame(3, 2, bytes.Repeat([]byte{0x21}, 300))
+	segments := Segments{}
+	for i := 0; i < 720; i++ {
+		s := &Segment{Index: i, URI: "https://radiko.jp/a.aac"}
+		if err := os.WriteFile(filepath.Join(dir, s.FileName()), frame, 0o600); err != nil {
+			b.Fatal(err)
+		}
+		segments = append(segments, s)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gaplessList(dir, segments, 2112); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
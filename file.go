@@ -0,0 +1,54 @@
+package radicron
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// moveFile renames src to dst, or copies it when they are on different drives
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	var linkErr *os.LinkError
+	if err == nil || !errors.As(err, &linkErr) {
+		return err
+	}
+	if err = copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies src to dst, removing dst on error
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err = out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// sanitizeFileName replaces the characters not allowed in the file name
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(invalidFileNameChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
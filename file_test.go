@@ -0,0 +1,57 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.aac")
+	dst := filepath.Join(dir, "dst.aac")
+	if err := os.WriteFile(src, []byte("aac"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("src remains after moveFile: %v", err)
+	}
+	if b, err := os.ReadFile(dst); err != nil || string(b) != "aac" {
+		t.Errorf("dst => %q, %v, want aac", b, err)
+	}
+	if err := moveFile(src, dst); err == nil {
+		t.Error("moveFile(missing) => nil, want error")
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.aac")
+	if err := os.WriteFile(src, []byte("aac"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst.aac")
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(dst); err != nil || string(b) != "aac" {
+		t.Errorf("dst => %q, %v, want aac", b, err)
+	}
+	if err := copyFile(src, filepath.Join(dir, "missing", "dst.aac")); err == nil {
+		t.Error("copyFile(missing dir) => nil, want error")
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	name := sanitizeFileName("AC/DC\tの" + invalidFileNameChars + "ロック")
+	if strings.ContainsAny(name, invalidFileNameChars+"\t") {
+		t.Errorf("sanitizeFileName => %s, want without %q", name, invalidFileNameChars)
+	}
+	if !strings.HasPrefix(name, "AC_DC_の") || !strings.HasSuffix(name, "ロック") {
+		t.Errorf("sanitizeFileName => %s, want AC_DC_の...ロック", name)
+	}
+}
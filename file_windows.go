@@ -0,0 +1,6 @@
+//go:build windows
+
+package radicron
+
+// invalidFileNameChars are reserved in the file names on Windows
+const invalidFileNameChars = `<>:"/\|?*`
@@ -0,0 +1,140 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Fingerprint contains the energy and zero-crossing changes of each frame
+// in a few minutes after the opening of a program, 2 bits per frame
+type Fingerprint []byte
+
+// NewFingerprint returns the fingerprint of the mono PCM samples
+func NewFingerprint(samples []int16) Fingerprint {
+	frames := len(samples) / FingerprintFrameSamples
+	if frames < 2 {
+		return Fingerprint{}
+	}
+	energies := make([]float64, frames)
+	crossings := make([]int, frames)
+	for i := 0; i < frames; i++ {
+		frame := samples[i*FingerprintFrameSamples : (i+1)*FingerprintFrameSamples]
+		for j, s := range frame {
+			energies[i] += float64(s) * float64(s)
+			if j > 0 && (frame[j-1] < 0) != (s < 0) {
+				crossings[i]++
+			}
+		}
+	}
+
+	fp := make(Fingerprint, frames-1)
+	for i := 1; i < frames; i++ {
+		if energies[i] > energies[i-1] {
+			fp[i-1] |= 1
+		}
+		if crossings[i] > crossings[i-1] {
+			fp[i-1] |= 2
+		}
+	}
+	return fp
+}
+
+// Similarity returns the ratio of the matching bits at the best alignment
+// within FingerprintMaxShift frames, 0.5 is expected for unrelated programs
+func (fp Fingerprint) Similarity(other Fingerprint) float64 {
+	best := 0.0
+	for shift := -FingerprintMaxShift; shift <= FingerprintMaxShift; shift++ {
+		matches, total := 0, 0
+		for i := range fp {
+			j := i + shift
+			if j < 0 || j >= len(other) {
+				continue
+			}
+			for bit := byte(1); bit <= 2; bit <<= 1 {
+				if fp[i]&bit == other[j]&bit {
+					matches++
+				}
+				total++
+			}
+		}
+		// require at least a half overlap
+		if total == 0 || total < len(fp) {
+			continue
+		}
+		best = math.Max(best, float64(matches)/float64(total))
+	}
+	return best
+}
+
+// FindRerun returns the recent record of the same program with a similar fingerprint if any,
+// i.e., the same title on the station or the same broadcast on an affiliate station
+func (h *History) FindRerun(prog *Prog, fp Fingerprint, since time.Time) *HistoryRecord {
+	if h == nil || len(fp) == 0 {
+		return nil
+	}
+	// compare outside the lock not to block the other history calls
+	for _, r := range h.rerunCandidates(prog, since) {
+		if fp.Similarity(r.Fingerprint) >= RerunSimilarity {
+			return r
+		}
+	}
+	return nil
+}
+
+// rerunCandidates returns the copies of the recent records of the same program with the fingerprint
+func (h *History) rerunCandidates(prog *Prog, since time.Time) []*HistoryRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	title := DedupKey(prog.Title, "")
+	broadcast := DedupKey(prog.Title, prog.Ft)
+	candidates := []*HistoryRecord{}
+	for _, r := range h.Records {
+		if r.ID == prog.ID || r.Path == "" || len(r.Fingerprint) == 0 {
+			continue
+		}
+		if (r.StationID != prog.StationID || DedupKey(r.Title, "") != title) && DedupKey(r.Title, r.Ft) != broadcast {
+			continue
+		}
+		ft, err := time.ParseInLocation(DatetimeLayout, r.Ft, Location)
+		if err != nil || ft.Before(since) {
+			continue
+		}
+		c := *r
+		candidates = append(candidates, &c)
+	}
+	return candidates
+}
+
+// fingerprintChunks decodes the aac chunks in dir with ffmpeg and returns the fingerprint
+func fingerprintChunks(ctx context.Context, dir string, segments Segments) (Fingerprint, error) {
+	inputs := make([]string, len(segments))
+	for i, segment := range segments {
+		inputs[i] = filepath.Join(dir, segment.FileName())
+	}
+
+	var stdout bytes.Buffer
+	err := runFFmpeg(ctx, &stdout,
+		"-i", "concat:"+strings.Join(inputs, "|"),
+		"-ss", fmt.Sprint(FingerprintOffsetSeconds),
+		"-t", fmt.Sprint(FingerprintSeconds),
+		"-ac", "1",
+		"-ar", fmt.Sprint(FingerprintSampleRate),
+		"-f", "s16le",
+		"pipe:1",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]int16, stdout.Len()/2)
+	if err = binary.Read(&stdout, binary.LittleEndian, samples); err != nil {
+		return nil, err
+	}
+	return NewFingerprint(samples), nil
+}
@@ -0,0 +1,96 @@
+package radicron
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testSamples returns a noise modulated by a random envelope
+func testSamples(seed int64, seconds int) []int16 {
+	r := rand.New(rand.NewSource(seed)) //nolint:gosec
+	samples := make([]int16, seconds*FingerprintSampleRate)
+	envelope := 0.0
+	for i := range samples {
+		if i%FingerprintFrameSamples == 0 {
+			envelope = r.Float64()
+		}
+		samples[i] = int16(envelope * math.MaxInt16 * (r.Float64()*2 - 1))
+	}
+	return samples
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	original := NewFingerprint(testSamples(1, 60))
+	// the same audio starting 3 seconds later
+	shifted := NewFingerprint(testSamples(1, 63)[3*FingerprintSampleRate:])
+	other := NewFingerprint(testSamples(2, 60))
+
+	if got := original.Similarity(original); got != 1 {
+		t.Errorf("Similarity(itself) => %v, want 1", got)
+	}
+	if got := original.Similarity(shifted); got < RerunSimilarity {
+		t.Errorf("Similarity(shifted) => %v, want >= %v", got, RerunSimilarity)
+	}
+	if got := original.Similarity(other); got >= RerunSimilarity {
+		t.Errorf("Similarity(other) => %v, want < %v", got, RerunSimilarity)
+	}
+	if got := original.Similarity(Fingerprint{}); got != 0 {
+		t.Errorf("Similarity(empty) => %v, want 0", got)
+	}
+}
+
+func TestFindRerun(t *testing.T) {
+	h, err := LoadHistory(filepath.Join(t.TempDir(), HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := &Prog{ID: "1", StationID: "FMT", Ft: "20230605130000", Title: "original"}
+	if err = h.RecordFingerprint(original, NewFingerprint(testSamples(1, 60))); err != nil {
+		t.Fatal(err)
+	}
+	rerun := &Prog{ID: "2", StationID: "FMT", Ft: "20230612130000", Title: "original"}
+	fp := NewFingerprint(testSamples(1, 60))
+	since := time.Date(2023, 6, 1, 0, 0, 0, 0, Location)
+
+	// not yet saved
+	if r := h.FindRerun(rerun, fp, since); r != nil {
+		t.Errorf("FindRerun before saved => %v, want nil", r.ID)
+	}
+	if err = h.RecordSuccess(original, "original.aac"); err != nil {
+		t.Fatal(err)
+	}
+	if r := h.FindRerun(rerun, fp, since); r == nil || r.ID != "1" {
+		t.Errorf("FindRerun => %v, want 1", r)
+	}
+	// too old
+	if r := h.FindRerun(rerun, fp, since.AddDate(0, 0, 7)); r != nil {
+		t.Errorf("FindRerun since the next week => %v, want nil", r.ID)
+	}
+	// unrelated
+	if r := h.FindRerun(rerun, NewFingerprint(testSamples(2, 60)), since); r != nil {
+		t.Errorf("FindRerun unrelated => %v, want nil", r.ID)
+	}
+	// another program sharing the opening
+	other := &Prog{ID: "3", StationID: "FMT", Ft: "20230612150000", Title: "other"}
+	if r := h.FindRerun(other, fp, since); r != nil {
+		t.Errorf("FindRerun of another program => %v, want nil", r.ID)
+	}
+	// the same broadcast on an affiliate station
+	affiliate := &Prog{ID: "4", StationID: "FMO", Ft: "20230605130000", Title: "ORIGINAL"}
+	if r := h.FindRerun(affiliate, fp, since); r == nil || r.ID != "1" {
+		t.Errorf("FindRerun of the affiliate => %v, want 1", r)
+	}
+
+	if err = h.RecordRerun(rerun, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if !h.IsRerun(rerun) {
+		t.Error("IsRerun => false, want true")
+	}
+	if h.IsRerun(original) {
+		t.Error("IsRerun(original) => true, want false")
+	}
+}
@@ -0,0 +1,450 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// History keeps the records of the programs across the runs
+type History struct {
+	Version int                       `json:"version"`
+	Records map[string]*HistoryRecord `json:"records"`
+	// Usage in bytes downloaded per day
+	Usage map[string]int64 `json:"usage,omitempty"`
+	// Series followed by the key of the station and the title
+	Series map[string]*Series `json:"series,omitempty"`
+	path   string
+	mu     sync.Mutex
+	// index to search the saved records, loaded on demand
+	index *SearchIndex
+}
+
+// HistoryRecord contains the status of a program
+type HistoryRecord struct {
+	ID               string     `json:"id"`
+	StationID        string     `json:"station_id"`
+	Ft               string     `json:"ft"`
+	To               string     `json:"to"`
+	Title            string     `json:"title"`
+	Pfm              string     `json:"pfm,omitempty"`
+	Desc             string     `json:"desc,omitempty"`
+	Info             string     `json:"info,omitempty"`
+	Path             string     `json:"path,omitempty"`
+	Summary          string     `json:"summary,omitempty"`
+	Fingerprint      []byte     `json:"fingerprint,omitempty"`
+	RerunOf          string     `json:"rerun_of,omitempty"`
+	Omnibus          bool       `json:"omnibus,omitempty"`
+	MergedInto       string     `json:"merged_into,omitempty"`
+	Failures         int        `json:"failures"`
+	LastError        string     `json:"last_error,omitempty"`
+	BlacklistedUntil *time.Time `json:"blacklisted_until,omitempty"`
+	Expired          bool       `json:"expired,omitempty"`
+	NoTimefree       bool       `json:"no_timefree,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// IsBlacklisted returns true if the program is blacklisted at t
+func (h *History) IsBlacklisted(prog *Prog, t time.Time) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.Records[prog.ID]
+	return ok && r.BlacklistedUntil != nil && t.Before(*r.BlacklistedUntil)
+}
+
+// IsSaved returns true if the program was saved and the file still exists
+func (h *History) IsSaved(prog *Prog) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.Records[prog.ID]
+	if !ok || r.Path == "" {
+		return false
+	}
+	_, err := os.Stat(r.Path)
+	return err == nil
+}
+
+// IsRerun returns true if the program is known as a rerun
+func (h *History) IsRerun(prog *Prog) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.Records[prog.ID]
+	return ok && r.RerunOf != ""
+}
+
+// RecordFailure counts up the failures of the program
+// and blacklists it for expiry once the failures reach the threshold
+func (h *History) RecordFailure(prog *Prog, cause error, threshold int, expiry time.Duration) (bool, error) {
+	if h == nil {
+		return false, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.record(prog)
+	r.Failures++
+	r.LastError = cause.Error()
+	blacklisted := false
+	if threshold > 0 && r.Failures >= threshold {
+		until := time.Now().Add(expiry)
+		r.BlacklistedUntil = &until
+		r.Failures = 0 // start over once the blacklist expires
+		blacklisted = true
+	}
+	return blacklisted, h.save()
+}
+
+// RecordSuccess saves the metadata of the program saved at path and resets the failures
+func (h *History) RecordSuccess(prog *Prog, path string) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	r := h.record(prog)
+	r.Pfm = prog.Pfm
+	r.Desc = prog.Desc
+	r.Info = prog.Info
+	r.Path = path
+	r.Omnibus = prog.Omnibus
+	r.Failures = 0
+	r.LastError = ""
+	r.BlacklistedUntil = nil
+	h.clearNoTimefree(prog)
+	err := h.save()
+	saved := *r
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// read the transcript outside the history lock
+	if err = h.searchIndex().Add(&saved); err != nil {
+		log.Printf("failed to save the search index: %s", err)
+	}
+	return nil
+}
+
+// RecordExpired marks the program as expired before saved
+func (h *History) RecordExpired(prog *Prog) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.record(prog)
+	r.Expired = true
+	r.LastError = ErrExpired.Error()
+	return h.save()
+}
+
+// RecordFingerprint saves the fingerprint of the program
+func (h *History) RecordFingerprint(prog *Prog, fp Fingerprint) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.record(prog).Fingerprint = fp
+	return h.save()
+}
+
+// RecordRerun marks the program as a rerun of the record with id
+func (h *History) RecordRerun(prog *Prog, id string) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.record(prog).RerunOf = id
+	return h.save()
+}
+
+// SetSummary saves the summary of the record
+func (h *History) SetSummary(id, summary string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.Records[id]
+	if !ok {
+		return fmt.Errorf("no such record: %s", id)
+	}
+	r.Summary = summary
+	r.UpdatedAt = time.Now()
+	return h.save()
+}
+
+// record returns the record for the program, creating one if not exists
+func (h *History) record(prog *Prog) *HistoryRecord {
+	r, ok := h.Records[prog.ID]
+	if !ok {
+		r = &HistoryRecord{
+			ID:        prog.ID,
+			StationID: prog.StationID,
+			Ft:        prog.Ft,
+			To:        prog.To,
+			Title:     prog.Title,
+		}
+		h.Records[prog.ID] = r
+	}
+	r.UpdatedAt = time.Now()
+	return r
+}
+
+// save writes the history to a temporary file, syncs it and renames it,
+// and backs it up at HistoryBackupHours
+func (h *History) save() error {
+	return h.update(nil)
+}
+
+// update applies fn to the history merged with the one saved by the other instances and saves it,
+// for the changes relative to the saved, e.g., adding up the usage or numbering the episodes
+func (h *History) update(fn func()) error {
+	if h.path == "" {
+		if fn != nil {
+			fn()
+		}
+		return nil
+	}
+	// the other instances sharing the history save one at a time
+	lock, err := WaitLock(h.path + LockFileSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to lock the history: %s", err)
+	}
+	defer lock.Unlock()
+	h.merge()
+	if fn != nil {
+		fn()
+	}
+
+	blob, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeFileSync(h.path, blob); err != nil {
+		return err
+	}
+	if err = backupHistory(h.path, blob, time.Now()); err != nil {
+		log.Printf("failed to back up the history: %s", err)
+	}
+	return nil
+}
+
+// merge takes the records, the usage, and the episodes saved by the other instances sharing the history
+func (h *History) merge() {
+	blob, err := os.ReadFile(h.path)
+	if err != nil {
+		return
+	}
+	saved := &History{}
+	if err = json.Unmarshal(blob, saved); err != nil || saved.Version != h.Version {
+		return
+	}
+	if h.Records == nil {
+		h.Records = map[string]*HistoryRecord{}
+	}
+	for id, r := range saved.Records {
+		if current, ok := h.Records[id]; !ok || r.UpdatedAt.After(current.UpdatedAt) {
+			h.Records[id] = r
+		}
+	}
+	// the usage only grows
+	if len(saved.Usage) > 0 && h.Usage == nil {
+		h.Usage = map[string]int64{}
+	}
+	for day, n := range saved.Usage {
+		if n > h.Usage[day] {
+			h.Usage[day] = n
+		}
+	}
+	if len(saved.Series) > 0 && h.Series == nil {
+		h.Series = map[string]*Series{}
+	}
+	for key, s := range saved.Series {
+		current, ok := h.Series[key]
+		if !ok {
+			h.Series[key] = s
+			continue
+		}
+		for id, n := range s.Episodes {
+			if _, ok := current.Episodes[id]; !ok {
+				current.Episodes[id] = n
+			}
+		}
+	}
+}
+
+// writeFileSync writes the blob to a temporary file, syncs it and renames it to the path,
+// not to leave a truncated file at a power cut
+func writeFileSync(path string, blob []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(blob); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	// persist the rename, not supported on some platforms
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// historyBackups returns the backups of the history, the newest first
+func historyBackups(path string) []string {
+	backups, _ := filepath.Glob(path + ".*" + HistoryBackupSuffix)
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// backupHistory copies the blob unless backed up within HistoryBackupHours,
+// keeping the HistoryBackupGenerations
+func backupHistory(path string, blob []byte, t time.Time) error {
+	backups := historyBackups(path)
+	if len(backups) > 0 {
+		if info, err := os.Stat(backups[0]); err == nil && t.Sub(info.ModTime()) < HistoryBackupHours*time.Hour {
+			return nil
+		}
+	}
+	backup := fmt.Sprintf("%s.%s%s", path, t.Format(DatetimeLayout), HistoryBackupSuffix)
+	if err := writeFileSync(backup, blob); err != nil {
+		return err
+	}
+	backups = append([]string{backup}, backups...)
+	for len(backups) > HistoryBackupGenerations {
+		if err := os.Remove(backups[len(backups)-1]); err != nil {
+			return err
+		}
+		backups = backups[:len(backups)-1]
+	}
+	return nil
+}
+
+// recoverHistory restores the newest valid backup after moving the corrupted history aside
+func recoverHistory(h *History, cause error) error {
+	for _, backup := range historyBackups(h.path) {
+		blob, err := os.ReadFile(backup)
+		if err != nil {
+			continue
+		}
+		restored := &History{}
+		if err = decodeHistory(restored, blob); err != nil {
+			continue
+		}
+		// replace all the fields, including the ones half-decoded from the corrupted
+		h.Version = restored.Version
+		h.Records = restored.Records
+		h.Usage = restored.Usage
+		h.Series = restored.Series
+		h.index = nil
+		// the backup upgraded to HistoryVersion
+		if blob, err = json.MarshalIndent(h, "", "  "); err != nil {
+			return err
+		}
+		corrupted := fmt.Sprintf("%s.%s%s", h.path, time.Now().Format(DatetimeLayout), HistoryCorruptSuffix)
+		if err = os.Rename(h.path, corrupted); err != nil {
+			return err
+		}
+		if err = writeFileSync(h.path, blob); err != nil {
+			return err
+		}
+		log.Printf("recovered the corrupted history (%s) from %s, moved it to %s", cause, backup, corrupted)
+		return nil
+	}
+	return cause
+}
+
+// decodeHistory unmarshals the blob upgraded to HistoryVersion into h
+func decodeHistory(h *History, blob []byte) error {
+	blob, _, err := upgradeHistory(blob)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(blob, h)
+}
+
+// LoadHistory loads the history from the path, or returns an empty History if not exists,
+// recovering from the backups if corrupted and migrating to HistoryVersion
+func LoadHistory(path string) (*History, error) {
+	h := &History{
+		Version: HistoryVersion,
+		Records: map[string]*HistoryRecord{},
+		path:    path,
+	}
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return h, err
+	}
+
+	upgraded, applied, err := upgradeHistory(blob)
+	if errors.Is(err, ErrHistoryTooNew) {
+		return h, err
+	} else if err != nil {
+		if err = recoverHistory(h, err); err != nil {
+			return h, err
+		}
+	} else if err = json.Unmarshal(upgraded, h); err != nil {
+		if err = recoverHistory(h, err); err != nil {
+			return h, err
+		}
+	}
+	if h.Records == nil {
+		h.Records = map[string]*HistoryRecord{}
+	}
+
+	// keep the history before the migrations to downgrade
+	if len(applied) > 0 {
+		if err = writeFileSync(fmt.Sprintf("%s.v%d", path, applied[0].Version-1), blob); err != nil {
+			return h, fmt.Errorf("failed to keep the history before the migrations: %s", err)
+		}
+		for _, m := range applied {
+			log.Printf("migrated the history to v%d: %s", m.Version, m.Description)
+		}
+		if err = h.save(); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// HistoryPath returns the history in ${RADICRON_HOME}
+func HistoryPath() (string, error) {
+	return getRadicronPath(HistoryFileName)
+}
+
+// NewHistory loads the history in ${RADICRON_HOME}
+func NewHistory() (*History, error) {
+	path, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadHistory(path)
+}
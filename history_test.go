@@ -0,0 +1,320 @@
+package radicron
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryBlacklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "12345", StationID: "FMT"}
+	cause := errors.New("playlist.m3u8 not available")
+
+	for i := 1; i <= 3; i++ {
+		blacklisted, err := h.RecordFailure(prog, cause, 3, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blacklisted != (i == 3) {
+			t.Errorf("RecordFailure #%v => %v, want %v", i, blacklisted, i == 3)
+		}
+	}
+
+	// reload from the file
+	h, err = LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if !h.IsBlacklisted(prog, now) {
+		t.Error("IsBlacklisted => false, want true")
+	}
+	if h.IsBlacklisted(prog, now.Add(2*time.Hour)) {
+		t.Error("IsBlacklisted after the expiry => true, want false")
+	}
+	if h.IsBlacklisted(&Prog{ID: "67890"}, now) {
+		t.Error("IsBlacklisted(67890) => true, want false")
+	}
+
+	if err = h.RecordSuccess(prog, ""); err != nil {
+		t.Fatal(err)
+	}
+	if h.IsBlacklisted(prog, now) {
+		t.Error("IsBlacklisted after RecordSuccess => true, want false")
+	}
+
+	// nil History is a no-op
+	var nh *History
+	if nh.IsBlacklisted(prog, now) {
+		t.Error("nil IsBlacklisted => true, want false")
+	}
+}
+
+func TestHistoryRecordExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "12345", StationID: "FMT"}
+	if err = h.RecordExpired(prog); err != nil {
+		t.Fatal(err)
+	}
+
+	// reload from the file
+	h, err = LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := h.Records[prog.ID]
+	if r == nil || !r.Expired || r.LastError != ErrExpired.Error() {
+		t.Errorf("RecordExpired => %+v, want expired", r)
+	}
+}
+
+func TestHistoryIsSaved(t *testing.T) {
+	dir := t.TempDir()
+	h, err := LoadHistory(filepath.Join(dir, HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "12345", StationID: "FMT"}
+	if h.IsSaved(prog) {
+		t.Error("IsSaved before saved => true, want false")
+	}
+	output := filepath.Join(dir, "202306051300_FMT_title.aac")
+	if err = os.WriteFile(output, []byte("audio"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.RecordSuccess(prog, output); err != nil {
+		t.Fatal(err)
+	}
+	if !h.IsSaved(prog) {
+		t.Error("IsSaved => false, want true")
+	}
+
+	// download again once the file is gone
+	if err = os.Remove(output); err != nil {
+		t.Fatal(err)
+	}
+	if h.IsSaved(prog) {
+		t.Error("IsSaved after removed => true, want false")
+	}
+	if (*History)(nil).IsSaved(prog) {
+		t.Error("IsSaved without the history => true, want false")
+	}
+}
+
+func TestHistoryBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	now := time.Now()
+	var backuptests = []struct {
+		t    time.Time
+		want int
+	}{
+		// the first backup, and skip within the hours
+		{now, 1},
+		{now.Add(time.Hour), 1},
+	}
+	for _, tt := range backuptests {
+		if err := backupHistory(path, []byte("{}"), tt.t); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(historyBackups(path)); got != tt.want {
+			t.Errorf("backupHistory(%v) => %v backups, want %v", tt.t, got, tt.want)
+		}
+	}
+
+	// rotate the old ones
+	for i := 1; i <= HistoryBackupGenerations+2; i++ {
+		if err := backupHistory(path, []byte("{}"), now.AddDate(0, 0, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := historyBackups(path)
+	if len(backups) != HistoryBackupGenerations {
+		t.Errorf("backups => %v, want %v", len(backups), HistoryBackupGenerations)
+	}
+	if want := path + "." + now.AddDate(0, 0, HistoryBackupGenerations+2).Format(DatetimeLayout) + HistoryBackupSuffix; backups[0] != want {
+		t.Errorf("the newest backup => %v, want %v", backups[0], want)
+	}
+}
+
+func TestHistoryRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230625050000"}
+	if err = h.RecordExpired(prog); err != nil {
+		t.Fatal(err)
+	}
+
+	// a truncated write
+	if err = os.WriteFile(path, []byte(`{"records": {"FMT_`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Records["12345"]; !ok {
+		t.Errorf("recovered records => %v, want 12345", h.Records)
+	}
+	if corrupted, _ := filepath.Glob(path + ".*" + HistoryCorruptSuffix); len(corrupted) != 1 {
+		t.Errorf("corrupted => %v, want the one moved aside", corrupted)
+	}
+
+	// no backup to recover from
+	for _, backup := range historyBackups(path) {
+		if err = os.Remove(backup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadHistory(path); err == nil {
+		t.Error("LoadHistory without the backups => nil, want an error")
+	}
+}
+
+func TestHistoryRecoverUsageSeries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230625050000", Title: "News"}
+	if err = h.AddUsage(map[string]int64{"20230625": 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.Episode(prog, false); err != nil {
+		t.Fatal(err)
+	}
+	// back up all of them
+	for _, backup := range historyBackups(path) {
+		if err = os.Remove(backup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = h.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// half-decodable into the usage
+	if err = os.WriteFile(path, []byte(`{"version": 1, "usage": {"20990101": 999}, "records": 1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, loaded := range []*History{h, mustLoadHistory(t, path)} {
+		if len(loaded.Usage) != 1 || loaded.Usage["20230625"] != 100 {
+			t.Errorf("recovered usage => %v, want 20230625: 100", loaded.Usage)
+		}
+		if s := loaded.Series[SeriesKey(prog)]; s == nil || s.Episodes[prog.ID] != 1 {
+			t.Errorf("recovered series => %+v, want %s: 1", s, prog.ID)
+		}
+	}
+}
+
+func mustLoadHistory(t *testing.T, path string) *History {
+	t.Helper()
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestHistoryMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	// two instances sharing the history
+	h1, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog1 := &Prog{ID: "12345", StationID: "FMT"}
+	prog2 := &Prog{ID: "67890", StationID: "TBS"}
+	if err = h1.RecordSuccess(prog1, "/tmp/12345.aac"); err != nil {
+		t.Fatal(err)
+	}
+	if err = h2.RecordSuccess(prog2, "/tmp/67890.aac"); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prog := range []*Prog{prog1, prog2} {
+		if r, ok := h.Records[prog.ID]; !ok || r.Path == "" {
+			t.Errorf("Records[%s] => %+v, want saved", prog.ID, r)
+		}
+	}
+}
+
+func TestHistoryMergeUsageSeries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	// two instances sharing the history
+	h1, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h1.AddUsage(map[string]int64{"20230101": 100, "20230102": 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err = h2.AddUsage(map[string]int64{"20230101": 50}); err != nil {
+		t.Fatal(err)
+	}
+	prog1 := &Prog{ID: "12345", StationID: "FMT", Title: "News"}
+	prog2 := &Prog{ID: "67890", StationID: "FMT", Title: "News"}
+	n1, err := h1.Episode(prog1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, err := h2.Episode(prog2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n1 == n2 {
+		t.Errorf("Episode() => %v and %v, want different", n1, n2)
+	}
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usagetests := []struct {
+		day  string
+		want int64
+	}{
+		{"20230101", 150},
+		{"20230102", 10},
+	}
+	for _, tt := range usagetests {
+		if got := h.Usage[tt.day]; got != tt.want {
+			t.Errorf("Usage[%s] => %v, want %v", tt.day, got, tt.want)
+		}
+	}
+	s := h.Series[SeriesKey(prog1)]
+	if s == nil || s.Episodes[prog1.ID] != n1 || s.Episodes[prog2.ID] != n2 {
+		t.Errorf("Series => %+v, want %s: %v and %s: %v", s, prog1.ID, n1, prog2.ID, n2)
+	}
+}
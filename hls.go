@@ -0,0 +1,144 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// RecordHLS downloads the audio in the HLS playlist at uri of any stream, not only radiko,
+// and saves it as name in the download dir with the same retry, concat, and tagging as the programs
+func RecordHLS(ctx context.Context, uri, name string) (string, error) {
+	asset := GetAsset(ctx)
+	prog, err := newHLSProg(uri, name, time.Now())
+	if err != nil {
+		return "", err
+	}
+	output, err := newOutputConfig(sanitizeFileName(prog.Title), asset.OutputFormat)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure output: %s", err)
+	}
+	if output.IsExist() {
+		return "", fmt.Errorf("%s already exists", output.AbsPath())
+	}
+
+	chunklist, err := liveChunklist(ctx, uri)
+	if err != nil {
+		return "", withReason(FailurePlaylist, fmt.Errorf("failed to get chunklist: %w", err))
+	}
+	if len(chunklist) == 0 {
+		return "", withReason(FailurePlaylist, fmt.Errorf("no segments in %s", uri))
+	}
+	if asset.DryRun {
+		log.Printf("would record %d segments (%v) of %s to %s", len(chunklist), chunklist.Duration(), uri, output.AbsPath())
+		return output.AbsPath(), nil
+	}
+	if err = output.SetupDir(); err != nil {
+		return "", fmt.Errorf("failed to setup the output dir: %s", err)
+	}
+
+	log.Printf("start downloading %s: %s", prog.Title, uri)
+	emit(ctx, EventStarted, prog, uri)
+	if err = saveHLS(withProgress(ctx, prog), prog, chunklist, output); err != nil {
+		emitFailure(ctx, prog, err)
+		return "", err
+	}
+	emit(ctx, EventCompleted, prog, output.AbsPath())
+	return output.AbsPath(), nil
+}
+
+// saveHLS downloads the segments, concatenates them to the output, and writes the tag
+func saveHLS(ctx context.Context, prog *Prog, chunklist Segments, output *radigo.OutputConfig) error {
+	asset := GetAsset(ctx)
+	aacDir, err := tempAACDir()
+	if err != nil {
+		return fmt.Errorf("failed to create the aac dir: %s", err)
+	}
+	defer os.RemoveAll(aacDir)
+
+	failed, err := bulkDownload(ctx, chunklist, aacDir)
+	if err != nil {
+		return fmt.Errorf("failed to download aac files: %w", err)
+	}
+	chunklist = chunklist.Without(failed)
+	prog.To = prog.Ft
+	if ft, err := time.ParseInLocation(DatetimeLayout, prog.Ft, Location); err == nil {
+		prog.To = ft.Add(chunklist.Duration()).Format(DatetimeLayout)
+	}
+
+	tctx, cancel := transcodeContext(ctx, chunklist.Duration())
+	defer cancel()
+	audio, err := concatHLS(tctx, aacDir, chunklist)
+	if err != nil {
+		return fmt.Errorf("failed to concat aac files: %s", err)
+	}
+	if err = saveAudio(ctx, prog, audio, output); err != nil {
+		return fmt.Errorf("failed to write the output file: %s", err)
+	}
+	return finishOutput(ctx, asset, prog, output)
+}
+
+// concatHLS concatenates the segments saved in dir to an aac,
+// joining and remuxing them if not in ADTS, e.g., in MPEG-TS
+func concatHLS(ctx context.Context, dir string, segments Segments) (string, error) {
+	asset := GetAsset(ctx)
+	ext := ".aac"
+	for _, s := range segments {
+		if e := filepath.Ext(s.FileName()); !strings.EqualFold(e, ".aac") {
+			ext = e
+			break
+		}
+	}
+	if ext == ".aac" {
+		if err := validateSegments(dir, segments, asset.StrictADTS); err != nil {
+			return "", err
+		}
+		return concatSegments(ctx, dir, segments, asset.GaplessPriming)
+	}
+
+	capture := filepath.Join(dir, "capture"+ext)
+	f, err := os.Create(capture)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range segments {
+		if err = appendFile(f, filepath.Join(dir, s.FileName())); err != nil {
+			f.Close()
+			return "", err
+		}
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	output := filepath.Join(dir, "concated.aac")
+	return output, runFFmpeg(ctx, nil, "-i", capture, "-vn", "-c:a", "copy", "-y", output)
+}
+
+// newHLSProg returns the program of the HLS stream at uri recorded at t,
+// titled with name, or with the host and the time if empty
+func newHLSProg(uri, name string, t time.Time) (*Prog, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid HLS URL: %s", uri)
+	}
+	ft := t.In(Location).Format(DatetimeLayout)
+	if name == "" {
+		name = fmt.Sprintf("%s_%s", t.In(Location).Format(OutputDatetimeLayout), u.Hostname())
+	}
+	return &Prog{
+		ID:        fmt.Sprintf("hls_%s_%s", u.Hostname(), ft),
+		StationID: u.Hostname(),
+		Ft:        ft,
+		To:        ft,
+		Title:     name,
+		Info:      uri,
+		Tags:      []string{},
+	}, nil
+}
@@ -0,0 +1,99 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+func TestNewHLSProg(t *testing.T) {
+	at := time.Date(2023, 6, 5, 13, 0, 0, 0, Location)
+	var hlstests = []struct {
+		uri       string
+		name      string
+		title     string
+		stationID string
+		valid     bool
+	}{
+		{"https://example.com/live/stream.m3u8", "My Show", "My Show", "example.com", true},
+		{"http://example.com:8080/stream.m3u8", "", "202306051300_example.com", "example.com", true},
+		{"ftp://example.com/stream.m3u8", "", "", "", false},
+		{"/stream.m3u8", "", "", "", false},
+	}
+	for _, tt := range hlstests {
+		prog, err := newHLSProg(tt.uri, tt.name, at)
+		if (err == nil) != tt.valid {
+			t.Errorf("newHLSProg(%q) => %v, want valid %v", tt.uri, err, tt.valid)
+			continue
+		}
+		if !tt.valid {
+			continue
+		}
+		if prog.Title != tt.title || prog.StationID != tt.stationID || prog.Ft != "20230605130000" || prog.Info != tt.uri {
+			t.Errorf("newHLSProg(%q, %q) => %+v", tt.uri, tt.name, prog)
+		}
+	}
+}
+
+func TestRecordHLS(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	playlist, err := os.ReadFile("test/chunklist-test.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the playlist with the segments not found
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stream.m3u8" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(bytes.ReplaceAll(playlist, []byte("https://radiko.jp"), []byte("http://"+r.Host)))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	asset := &Asset{DryRun: true, OutputFormat: radigo.AudioFormatAAC, Retry: &RetryPolicy{Attempts: 1}}
+	events := []string{}
+	ctx := WithEventFunc(context.WithValue(context.Background(), ContextKey("asset"), asset), func(e Event) {
+		events = append(events, e.Type)
+	})
+
+	// dry-run
+	output, err := RecordHLS(ctx, ts.URL+"/stream.m3u8", "My Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(output) != "My Show.aac" || !strings.Contains(buf.String(), "would record 4 segments (20s)") {
+		t.Errorf("RecordHLS() => %v, log %v", output, buf.String())
+	}
+	if _, err = os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("RecordHLS() => %v written in the dry-run", output)
+	}
+
+	// the segments fail
+	asset.DryRun = false
+	if _, err = RecordHLS(ctx, ts.URL+"/stream.m3u8", "My Show"); err == nil {
+		t.Error("RecordHLS() => nil, want the error of the segments")
+	}
+	if got := fmt.Sprint(events); got != "[started failed]" {
+		t.Errorf("RecordHLS() => events %v, want [started failed]", got)
+	}
+
+	// the playlist not found
+	if _, err = RecordHLS(ctx, ts.URL+"/none.m3u8", "My Show"); err == nil {
+		t.Error("RecordHLS() => nil, want the error of the playlist")
+	}
+}
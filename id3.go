@@ -0,0 +1,66 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/bogem/id3v2"
+)
+
+// ParseID3Version returns the ID3v2 major version for "2.4" or "2.3"
+func ParseID3Version(version string) (byte, error) {
+	switch version {
+	case "2.4", "":
+		return 4, nil
+	case "2.3":
+		return 3, nil
+	default:
+		return 0, fmt.Errorf("unsupported ID3 version: %s (2.4 or 2.3)", version)
+	}
+}
+
+// id3Encoding returns the text encoding for the ID3v2 major version,
+// UTF-16 with BOM for ID3v2.3 as it has no UTF-8
+func id3Encoding(version byte) id3v2.Encoding {
+	if version == 3 {
+		return id3v2.EncodingUTF16
+	}
+	return id3v2.EncodingUTF8
+}
+
+// tagProgram sets the frames for the program in its ID3v2 version
+func tagProgram(ctx context.Context, tag *id3v2.Tag, title string, prog *Prog) {
+	version := prog.ID3Version
+	if version == 0 {
+		version = 4
+	}
+	encoding := id3Encoding(version)
+	tag.SetVersion(version)
+	tag.SetDefaultEncoding(encoding)
+
+	tag.SetTitle(title)
+	tag.SetArtist(prog.Pfm)
+	tag.SetAlbum(prog.Title)
+	tag.SetYear(prog.Ft[:4])
+	if prog.Episode > 0 {
+		tag.AddTextFrame(tag.CommonID("Track number/Position in set"), encoding, strconv.Itoa(prog.Episode))
+	}
+	tag.AddCommentFrame(id3v2.CommentFrame{
+		Encoding:    encoding,
+		Language:    ID3v2LangJPN,
+		Description: prog.Info,
+	})
+	if prog.Explicit {
+		tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+			Encoding:    encoding,
+			Description: ID3v2DescAdvisory,
+			Value:       ID3v2AdvisoryExplicit,
+		})
+	}
+	// the artwork is not essential to the recording
+	if err := addArtwork(ctx, tag, prog, encoding); err != nil {
+		log.Printf("failed to add the artwork: %s", err)
+	}
+}
@@ -0,0 +1,97 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"reflect"
+	"testing"
+
+	"github.com/bogem/id3v2"
+)
+
+var (
+	//go:embed test/id3v23-reference.id3 test/id3v24-reference.id3
+	ID3ReferenceTest embed.FS
+)
+
+func TestParseID3Version(t *testing.T) {
+	var versiontests = []struct {
+		version string
+		want    byte
+		wantErr bool
+	}{
+		{"", 4, false},
+		{"2.4", 4, false},
+		{"2.3", 3, false},
+		{"2.2", 0, true},
+	}
+	for _, tt := range versiontests {
+		got, err := ParseID3Version(tt.version)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseID3Version(%q) => %v, %v, want %v", tt.version, got, err, tt.want)
+		}
+	}
+}
+
+func TestTagProgram(t *testing.T) {
+	var id3tests = []struct {
+		version   byte
+		reference string
+		encoding  byte
+	}{
+		{0, "test/id3v24-reference.id3", 0x03},
+		{3, "test/id3v23-reference.id3", 0x01},
+	}
+	for _, tt := range id3tests {
+		title := "202306051300_FMT_山崎怜奈の誰かに話したかったこと。"
+		prog := &Prog{
+			StationID:  "FMT",
+			Ft:         "20230605130000",
+			Title:      "山崎怜奈の誰かに話したかったこと。",
+			Pfm:        "山崎怜奈",
+			Info:       "番組情報",
+			Explicit:   true,
+			ID3Version: tt.version,
+		}
+		tag := id3v2.NewEmptyTag()
+		tagProgram(context.Background(), tag, title, prog)
+		var buf bytes.Buffer
+		if _, err := tag.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		// the frames are written in any order
+		reference, err := ID3ReferenceTest.Open(tt.reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := id3v2.ParseReader(reference, id3v2.Options{Parse: true})
+		reference.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := buf.Bytes()
+		parsed, err := id3v2.ParseReader(bytes.NewReader(got), id3v2.Options{Parse: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Version() != want.Version() || !reflect.DeepEqual(parsed.AllFrames(), want.AllFrames()) {
+			t.Errorf("tagProgram(v%d) differs from %s", tt.version, tt.reference)
+		}
+
+		// the header version and the text encoding of the frames
+		if got[3] != want.Version() {
+			t.Errorf("tagProgram(v%d) => version %v, want %v", tt.version, got[3], want.Version())
+		}
+		for _, id := range []string{"TIT2", "TPE1", "TALB"} {
+			if f, ok := parsed.GetLastFrame(id).(id3v2.TextFrame); !ok || f.Encoding.Key != tt.encoding {
+				t.Errorf("tagProgram(v%d) => %s in %v, want encoding %#x", tt.version, id, f.Encoding, tt.encoding)
+			}
+		}
+
+		// the players read back the same text
+		if parsed.Title() != title || parsed.Artist() != prog.Pfm || parsed.Album() != prog.Title {
+			t.Errorf("tagProgram(v%d) => %v / %v / %v", tt.version, parsed.Title(), parsed.Artist(), parsed.Album())
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Lease lets only one of the instances sharing RADICRON_HOME record at a time,
+// and the other take over once the lease expires
+type Lease struct {
+	Holder string
+	Path   string
+	TTL    time.Duration
+	mu     sync.Mutex
+	// until when this instance holds the lease
+	expires time.Time
+}
+
+// leaseRecord is saved in the lease file
+type leaseRecord struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// Acquire takes or renews the lease unless another instance holds it
+func (l *Lease) Acquire() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// the other instances check and take the lease one at a time
+	lock, err := WaitLock(l.Path + LockFileSuffix)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock the lease: %s", err)
+	}
+	defer lock.Unlock()
+	now := time.Now()
+	record, err := l.load()
+	if err != nil {
+		return false, err
+	}
+	if record != nil && record.Holder != l.Holder && now.Before(record.Expires) {
+		l.expires = time.Time{}
+		return false, nil
+	}
+
+	expires := now.Add(l.TTL)
+	blob, err := json.Marshal(&leaseRecord{Holder: l.Holder, Expires: expires})
+	if err != nil {
+		return false, err
+	}
+	tmp := l.Path + ".tmp-" + l.Holder
+	if err = os.WriteFile(tmp, blob, 0o600); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp, l.Path); err != nil {
+		return false, err
+	}
+	l.expires = expires
+	return true, nil
+}
+
+// Held returns true if this instance holds the lease
+func (l *Lease) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.expires)
+}
+
+// Keep renews the lease, or tries to take it over, until ctx is done
+func (l *Lease) Keep(ctx context.Context) {
+	ticker := time.NewTicker(l.TTL / LeaseRenewDivisor)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		held := l.Held()
+		ok, err := l.Acquire()
+		if err != nil {
+			log.Printf("failed to renew the lease: %s", err)
+			continue
+		}
+		if held && !ok {
+			log.Println("lost the lease to another instance")
+		}
+	}
+}
+
+// Release gives up the lease if this instance holds it
+func (l *Lease) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expires = time.Time{}
+	lock, err := WaitLock(l.Path + LockFileSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to lock the lease: %s", err)
+	}
+	defer lock.Unlock()
+	record, err := l.load()
+	if err != nil || record == nil || record.Holder != l.Holder {
+		return err
+	}
+	return os.Remove(l.Path)
+}
+
+// Wait blocks until this instance holds the lease
+func (l *Lease) Wait(ctx context.Context) error {
+	for !l.Held() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.TTL / LeaseRenewDivisor):
+		}
+	}
+	return nil
+}
+
+// load returns nil if no instance has taken the lease
+func (l *Lease) load() (*leaseRecord, error) {
+	blob, err := os.ReadFile(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	record := &leaseRecord{}
+	if err = json.Unmarshal(blob, record); err != nil {
+		return nil, fmt.Errorf("invalid lease %s: %s", l.Path, err)
+	}
+	return record, nil
+}
+
+// NewLease returns a Lease in ${RADICRON_HOME} for this host and process
+func NewLease(ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid lease duration: %v", ttl)
+	}
+	path, err := getRadicronPath(LeaseFileName)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{
+		Holder: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		Path:   path,
+		TTL:    ttl,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package radicron
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	primary := &Lease{Holder: "primary", Path: path, TTL: time.Minute}
+	backup := &Lease{Holder: "backup", Path: path, TTL: time.Minute}
+
+	var leasetests = []struct {
+		name  string
+		lease *Lease
+		want  bool
+	}{
+		{"primary acquires", primary, true},
+		{"backup stands by", backup, false},
+		{"primary renews", primary, true},
+	}
+	for _, tt := range leasetests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.lease.Acquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || tt.lease.Held() != tt.want {
+				t.Errorf("Acquire => %v, Held => %v, want %v", got, tt.lease.Held(), tt.want)
+			}
+		})
+	}
+
+	// the backup takes over once released
+	if err := primary.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if primary.Held() {
+		t.Error("Held after Release => true, want false")
+	}
+	if ok, err := backup.Acquire(); err != nil || !ok {
+		t.Errorf("Acquire after Release => %v, %v, want true", ok, err)
+	}
+	// releasing the other's lease is a no-op
+	if err := primary.Release(); err != nil {
+		t.Error(err)
+	}
+	if ok, _ := primary.Acquire(); ok {
+		t.Error("Acquire of the backup's lease => true, want false")
+	}
+
+	// the primary takes over once expired
+	backup.TTL = -time.Second
+	if ok, err := backup.Acquire(); err != nil || !ok {
+		t.Fatalf("Acquire => %v, %v, want true", ok, err)
+	}
+	if backup.Held() {
+		t.Error("Held after expiry => true, want false")
+	}
+	if ok, err := primary.Acquire(); err != nil || !ok {
+		t.Errorf("Acquire after expiry => %v, %v, want true", ok, err)
+	}
+}
+
+func TestLeaseConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var holders []string
+	for i := 0; i < 8; i++ {
+		l := &Lease{Holder: fmt.Sprintf("instance-%d", i), Path: path, TTL: time.Minute}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := l.Acquire()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				holders = append(holders, l.Holder)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(holders) != 1 {
+		t.Errorf("holders => %v, want exactly one", holders)
+	}
+}
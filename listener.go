@@ -0,0 +1,147 @@
+package radicron
+
+import (
+	cr "crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Listeners holds the tokens to access the podcast feed for each listener
+type Listeners struct {
+	Tokens  map[string]string `json:"tokens"`
+	path    string
+	modTime time.Time
+	// restricted once the tokens are saved, even if all revoked
+	restricted bool
+	mu         sync.RWMutex
+}
+
+// Add generates a new token for the listener and saves it
+func (l *Listeners) Add(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("empty listener name")
+	}
+	blob := make([]byte, ListenerTokenLength)
+	if _, err := cr.Read(blob); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(blob)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Tokens[name] = token
+	return token, l.save()
+}
+
+// Authorize returns the listener name for the token
+func (l *Listeners) Authorize(token string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for name, t := range l.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// IsEmpty returns true if no listener is registered
+func (l *Listeners) IsEmpty() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.Tokens) == 0
+}
+
+// IsRestricted returns true if the access requires a token, i.e., once any listener has been registered
+func (l *Listeners) IsRestricted() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.restricted || len(l.Tokens) > 0
+}
+
+// Revoke removes the token for the listener and saves it
+func (l *Listeners) Revoke(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.Tokens[name]; !ok {
+		return fmt.Errorf("no such listener: %s", name)
+	}
+	delete(l.Tokens, name)
+	return l.save()
+}
+
+// Reload loads the tokens again if the file has been modified, e.g., by another instance
+func (l *Listeners) Reload() error {
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if info.ModTime().Equal(l.modTime) {
+		return nil
+	}
+	blob, err := os.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+	tokens := struct {
+		Tokens map[string]string `json:"tokens"`
+	}{}
+	if err = json.Unmarshal(blob, &tokens); err != nil {
+		return err
+	}
+	if tokens.Tokens == nil {
+		tokens.Tokens = map[string]string{}
+	}
+	l.Tokens = tokens.Tokens
+	l.modTime = info.ModTime()
+	l.restricted = true
+	return nil
+}
+
+func (l *Listeners) save() error {
+	blob, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(l.path, blob, 0o600); err != nil {
+		return err
+	}
+	l.restricted = true
+	return nil
+}
+
+// LoadListeners loads the listeners from the path, or returns empty Listeners if not exists
+func LoadListeners(path string) (*Listeners, error) {
+	l := &Listeners{
+		Tokens: map[string]string{},
+		path:   path,
+	}
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	} else if err != nil {
+		return l, err
+	}
+	if err = json.Unmarshal(blob, l); err != nil {
+		return l, err
+	}
+	l.restricted = true
+	if info, err := os.Stat(path); err == nil {
+		l.modTime = info.ModTime()
+	}
+	if l.Tokens == nil {
+		l.Tokens = map[string]string{}
+	}
+	return l, nil
+}
@@ -0,0 +1,93 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListeners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ListenersFileName)
+	l, err := LoadListeners(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.IsEmpty() {
+		t.Error("IsEmpty => false, want true")
+	}
+	token, err := l.Add("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != ListenerTokenLength*2 {
+		t.Errorf("len(token) => %v, want %v", len(token), ListenerTokenLength*2)
+	}
+
+	// reload from the file
+	l, err = LoadListeners(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := l.Authorize(token); !ok || name != "alice" {
+		t.Errorf("Authorize => %v, %v, want alice, true", name, ok)
+	}
+	if _, ok := l.Authorize("invalid"); ok {
+		t.Error("Authorize(invalid) => true, want false")
+	}
+
+	if err = l.Revoke("alice"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := l.Authorize(token); ok {
+		t.Error("Authorize after Revoke => true, want false")
+	}
+	if err = l.Revoke("bob"); err == nil {
+		t.Error("Revoke(bob) => nil, want an error")
+	}
+}
+
+func TestListenersReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ListenersFileName)
+	replica, err := LoadListeners(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = replica.Reload(); err != nil {
+		t.Errorf("Reload without the file => %v, want nil", err)
+	}
+
+	// the primary adds a listener
+	primary, err := LoadListeners(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := primary.Add("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := replica.Authorize(token); ok {
+		t.Error("Authorize before Reload => true, want false")
+	}
+	if err = replica.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := replica.Authorize(token); !ok || name != "alice" {
+		t.Errorf("Authorize after Reload => %v, %v, want alice, true", name, ok)
+	}
+
+	// the primary revokes it
+	if err = primary.Revoke("alice"); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err = replica.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := replica.Authorize(token); ok {
+		t.Error("Authorize after the revoke => true, want false")
+	}
+}
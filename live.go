@@ -0,0 +1,120 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// IsLive returns true if the programs of the station are recorded from the live stream, always on radiru
+func (a *Asset) IsLive(stationID string) bool {
+	if IsRadiruStation(stationID) {
+		return true
+	}
+	if s, ok := a.StationSettings[stationID]; ok {
+		return s.Live
+	}
+	return false
+}
+
+// IsLiveFallback returns true if the program falls back to the live capture,
+// i.e., the timefree of the last airing never appeared
+func (a *Asset) IsLiveFallback(prog *Prog) bool {
+	return prog.LiveFallback && a.History.IsNoTimefree(prog)
+}
+
+// RecordNoTimefree marks the program as unavailable in the timefree to capture the next airing live
+func (h *History) RecordNoTimefree(prog *Prog) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.record(prog).NoTimefree = true
+	return h.save()
+}
+
+// IsNoTimefree returns true if an earlier airing of the program on the station was unavailable in the timefree,
+// and no airing has been saved since
+func (h *History) IsNoTimefree(prog *Prog) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := DedupKey(prog.Title, "")
+	for _, r := range h.Records {
+		if r.NoTimefree && r.Ft < prog.Ft && r.StationID == prog.StationID && DedupKey(r.Title, "") == key {
+			return true
+		}
+	}
+	return false
+}
+
+// clearNoTimefree ends the live fallback of the program once an airing is saved, live or from the timefree
+func (h *History) clearNoTimefree(prog *Prog) {
+	key := DedupKey(prog.Title, "")
+	for _, r := range h.Records {
+		if r.NoTimefree && r.StationID == prog.StationID && DedupKey(r.Title, "") == key {
+			r.NoTimefree = false
+			r.UpdatedAt = time.Now()
+		}
+	}
+}
+
+// LiveURI returns the playlist of the radiko live stream of the station
+func LiveURI(stationID string) string {
+	return fmt.Sprintf(APILiveM3U8, stationID)
+}
+
+// CaptureLive records the program from the live stream as it airs until the end,
+// or until ctx is canceled, in place of the timefree
+func CaptureLive(ctx context.Context, prog *Prog) error {
+	ctx, uri, err := liveStream(ctx, prog.StationID)
+	if err != nil {
+		return err
+	}
+	return captureLive(ctx, prog, uri)
+}
+
+// liveStream returns ctx for the requests and the playlist of the live stream of the station,
+// on radiru or radiko
+func liveStream(ctx context.Context, stationID string) (context.Context, string, error) {
+	if !IsRadiruStation(stationID) {
+		return liveContext(ctx, stationID), LiveURI(stationID), nil
+	}
+	asset := GetAsset(ctx)
+	if asset == nil || asset.Radiru == nil {
+		return ctx, "", fmt.Errorf("radiru is not loaded for %s", stationID)
+	}
+	uri, err := asset.Radiru.StreamURI(stationID)
+	return ctx, uri, err
+}
+
+// liveContext returns ctx with the headers authorized for the live stream of the station
+func liveContext(ctx context.Context, stationID string) context.Context {
+	asset := GetAsset(ctx)
+	areaID := asset.AuthAreaID(stationID)
+	return context.WithValue(ctx, ContextKey("header"), func(ctx context.Context) http.Header {
+		device, err := asset.AuthSessions.Get(areaID).Authorize(ctx, asset)
+		if err != nil {
+			log.Printf("failed to authorize the live stream of %s: %s", stationID, err)
+			return nil
+		}
+		return asset.GetHeaders(EndpointLive, stationID, map[string]string{
+			UserAgentHeader:       device.UserAgent,
+			RadikoAreaIDHeader:    areaID,
+			RadikoAuthTokenHeader: device.AuthToken,
+		})
+	})
+}
+
+// requestHeader returns the headers for the requests in ctx if any
+func requestHeader(ctx context.Context) http.Header {
+	if header, ok := ctx.Value(ContextKey("header")).(func(context.Context) http.Header); ok {
+		return header(ctx)
+	}
+	return nil
+}
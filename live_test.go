@@ -0,0 +1,156 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsLive(t *testing.T) {
+	asset := &Asset{StationSettings: StationSettings{
+		"FMT": {Live: true},
+		"TBS": {Simulcast: "https://example.com/live.m3u8"},
+	}}
+	var livetests = []struct {
+		stationID string
+		want      bool
+	}{
+		{"FMT", true},
+		{"TBS", false},
+		{"QRR", false},
+		{"NHK-FM", true},
+	}
+	for _, tt := range livetests {
+		if got := asset.IsLive(tt.stationID); got != tt.want {
+			t.Errorf("IsLive(%s) => %v, want %v", tt.stationID, got, tt.want)
+		}
+	}
+	if got, want := LiveURI("FMT"), "https://f-radiko.smartstream.ne.jp/FMT/_definst_/simul-stream.stream/playlist.m3u8"; got != want {
+		t.Errorf("LiveURI(FMT) => %v, want %v", got, want)
+	}
+}
+
+func TestIsLiveFallback(t *testing.T) {
+	h, err := LoadHistory(filepath.Join(t.TempDir(), HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset := &Asset{History: h}
+	missed := &Prog{ID: "FMT_20230605130000", StationID: "FMT", Ft: "20230605130000", Title: "THE TRAD"}
+	if err = h.RecordNoTimefree(missed); err != nil {
+		t.Fatal(err)
+	}
+
+	var fallbacktests = []struct {
+		prog *Prog
+		want bool
+	}{
+		{&Prog{ID: "FMT_20230612130000", StationID: "FMT", Ft: "20230612130000", Title: "THE TRAD", LiveFallback: true}, true},
+		{&Prog{ID: "FMT_20230612130000", StationID: "FMT", Ft: "20230612130000", Title: "ＴＨＥ　ＴＲＡＤ", LiveFallback: true}, true},
+		{&Prog{ID: "FMT_20230612130000", StationID: "FMT", Ft: "20230612130000", Title: "THE TRAD"}, false},
+		{&Prog{ID: "FMT_20230529130000", StationID: "FMT", Ft: "20230529130000", Title: "THE TRAD", LiveFallback: true}, false},
+		{&Prog{ID: "TBS_20230612130000", StationID: "TBS", Ft: "20230612130000", Title: "THE TRAD", LiveFallback: true}, false},
+		{&Prog{ID: "FMT_20230612150000", StationID: "FMT", Ft: "20230612150000", Title: "Other", LiveFallback: true}, false},
+	}
+	for _, tt := range fallbacktests {
+		if got := asset.IsLiveFallback(tt.prog); got != tt.want {
+			t.Errorf("IsLiveFallback([%s]%s (%s)) => %v, want %v", tt.prog.StationID, tt.prog.Title, tt.prog.Ft, got, tt.want)
+		}
+	}
+
+	// only the next airing once saved
+	next := fallbacktests[0].prog
+	if err = h.RecordSuccess(next, filepath.Join(t.TempDir(), "next.aac")); err != nil {
+		t.Fatal(err)
+	}
+	later := &Prog{ID: "FMT_20230619130000", StationID: "FMT", Ft: "20230619130000", Title: "THE TRAD", LiveFallback: true}
+	if asset.IsLiveFallback(later) {
+		t.Error("IsLiveFallback() after the next airing saved => true, want false")
+	}
+}
+
+func TestRecordLiveStopped(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// the live stream only with the token
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(RadikoAuthTokenHeader) != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/live.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:10\n"+
+				"#EXTINF:5.0,\n10.aac\n#EXTINF:5.0,\n11.aac\n")
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.WithValue(context.Background(), ContextKey("header"), func(context.Context) http.Header {
+		return http.Header{RadikoAuthTokenHeader: []string{"token"}}
+	})
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	dst := filepath.Join(t.TempDir(), "capture.aac")
+	// stopped long before the end
+	if err := recordLive(ctx, ts.URL+"/live.m3u8", time.Now(), time.Now().Add(time.Hour), dst); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte("/10.aac/11.aac"); !bytes.Equal(got, want) {
+		t.Errorf("recordLive => %q, want %q", got, want)
+	}
+}
+
+func TestRecordLiveFromStart(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// the segment 12 airs from the start
+	start := time.Now().Add(300 * time.Millisecond)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/live.m3u8":
+			playlist := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:10\n" +
+				"#EXTINF:5.0,\n10.aac\n#EXTINF:5.0,\n11.aac\n"
+			if !time.Now().Before(start) {
+				playlist += "#EXTINF:5.0,\n12.aac\n"
+			}
+			fmt.Fprint(w, playlist)
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	dst := filepath.Join(t.TempDir(), "capture.aac")
+	// booted ahead of the start
+	if err := recordLive(ctx, ts.URL+"/live.m3u8", start, time.Now().Add(time.Hour), dst); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte("/12.aac"); !bytes.Equal(got, want) {
+		t.Errorf("recordLive => %q, want %q", got, want)
+	}
+}
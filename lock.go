@@ -0,0 +1,127 @@
+package radicron
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// ErrLocked is returned if another instance holds the lock
+var ErrLocked = errors.New("locked by another instance")
+
+// programLocks held by this instance while downloading the programs
+var programLocks = struct {
+	sync.Mutex
+	m map[string]*FileLock
+}{m: map[string]*FileLock{}}
+
+// FileLock is an advisory lock on the file, held until unlocked or the process exits
+type FileLock struct {
+	Path string
+	f    *os.File
+	// remove the file once unlocked
+	remove bool
+}
+
+// TryLock takes the lock on path without waiting, or returns ErrLocked
+func TryLock(path string) (*FileLock, error) {
+	return openLock(path, false, false)
+}
+
+// WaitLock takes the lock on path, waiting for the other instance to unlock it
+func WaitLock(path string) (*FileLock, error) {
+	return openLock(path, true, false)
+}
+
+// LockDir takes the lock on dir for this instance, or returns an error telling the other holds it
+func LockDir(dir string) (*FileLock, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	l, err := TryLock(filepath.Join(dir, InstanceLockFileName))
+	if errors.Is(err, ErrLocked) {
+		return nil, fmt.Errorf("another radicron is running on %s", dir)
+	}
+	return l, err
+}
+
+// Unlock releases the lock, removing the file if temporary
+func (l *FileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	// remove before unlocking not to remove the file locked by the other
+	if l.remove {
+		_ = os.Remove(l.Path)
+	}
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// openLock opens path and locks it, opening it again if removed by the last holder meanwhile
+func openLock(path string, wait, remove bool) (*FileLock, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		if err = lockFile(f, wait); err != nil {
+			f.Close()
+			return nil, err
+		}
+		locked, err := f.Stat()
+		if err != nil {
+			unlockFile(f)
+			f.Close()
+			return nil, err
+		}
+		if current, err := os.Stat(path); err == nil && os.SameFile(locked, current) {
+			return &FileLock{Path: path, f: f, remove: remove}, nil
+		}
+		unlockFile(f)
+		f.Close()
+	}
+}
+
+// lockProgram takes the lock on the output of the program not to download it twice,
+// and returns false if another instance is downloading it
+func lockProgram(prog *Prog, output *radigo.OutputConfig) (bool, error) {
+	l, err := openLock(output.AbsPath()+LockFileSuffix, false, true)
+	if errors.Is(err, ErrLocked) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	programLocks.Lock()
+	defer programLocks.Unlock()
+	if _, ok := programLocks.m[prog.ID]; ok {
+		// downloading in this instance already to another output
+		l.Unlock()
+		return false, nil
+	}
+	programLocks.m[prog.ID] = l
+	return true, nil
+}
+
+// unlockProgram releases the lock on the output of the program
+func unlockProgram(prog *Prog) {
+	programLocks.Lock()
+	l, ok := programLocks.m[prog.ID]
+	delete(programLocks.m, prog.ID)
+	programLocks.Unlock()
+	if !ok {
+		return
+	}
+	if err := l.Unlock(); err != nil {
+		log.Printf("failed to unlock [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+	}
+}
@@ -0,0 +1,72 @@
+package radicron
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName+LockFileSuffix)
+	l, err := TryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("TryLock() while locked => %v, want %v", err, ErrLocked)
+	}
+	if err = l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if l, err = TryLock(path); err != nil {
+		t.Errorf("TryLock() after Unlock => %v", err)
+	}
+	l.Unlock()
+
+	// the instance on the output dir
+	dir := filepath.Join(t.TempDir(), "downloads")
+	l, err = LockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Unlock()
+	if _, err = LockDir(dir); err == nil {
+		t.Error("LockDir() while locked => nil, want the error")
+	}
+}
+
+func TestLockProgram(t *testing.T) {
+	output := &radigo.OutputConfig{DirFullPath: t.TempDir(), FileBaseName: "20230605130000_TBS_test", FileFormat: radigo.AudioFormatAAC}
+	prog := &Prog{ID: "TBS_20230605130000", StationID: "TBS", Ft: "20230605130000", Title: "test"}
+	other := &Prog{ID: "TBS_20230605130000_other", StationID: "TBS", Ft: "20230605130000", Title: "test"}
+
+	var locktests = []struct {
+		prog *Prog
+		want bool
+	}{
+		{prog, true},
+		{prog, false},
+		{other, false}, // another instance on the same output
+	}
+	for _, tt := range locktests {
+		got, err := lockProgram(tt.prog, output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("lockProgram(%s) => %v, want %v", tt.prog.ID, got, tt.want)
+		}
+	}
+
+	unlockProgram(prog)
+	if _, err := os.Stat(output.AbsPath() + LockFileSuffix); !os.IsNotExist(err) {
+		t.Errorf("unlockProgram() => %v, want the lock removed", err)
+	}
+	if got, err := lockProgram(other, output); err != nil || !got {
+		t.Errorf("lockProgram() after unlockProgram => %v, %v, want true", got, err)
+	}
+	unlockProgram(other)
+}
@@ -0,0 +1,113 @@
+package radicron
+
+import (
+	"strings"
+	"unicode"
+)
+
+// mergeTitleTrim from the common prefix of the titles, e.g., "番組（" of "番組（前半）" and "番組（後半）"
+const mergeTitleTrim = " 　-‐–—―~〜:：・|｜/／(（[［【「『<＜#＃第"
+
+// MergeConsecutive returns the programs in the order of time with the consecutive ones
+// matched by the same rule with merge combined into one recording
+func (rs Rules) MergeConsecutive(stationID string, progs Progs) Progs {
+	merged := Progs{}
+	var group Progs
+	var rule *Rule
+	flush := func() {
+		if len(group) > 0 {
+			merged = append(merged, combineProgs(group))
+		}
+		group, rule = nil, nil
+	}
+	for _, p := range progs {
+		if rule != nil && group[len(group)-1].To == p.Ft && rule.Match(stationID, p) {
+			group = append(group, p)
+			continue
+		}
+		flush()
+		if r := rs.mergeRule(stationID, p); r != nil {
+			group, rule = Progs{p}, r
+			continue
+		}
+		merged = append(merged, p)
+	}
+	flush()
+	return merged
+}
+
+// mergeRule returns the first rule with merge matching the program, or nil if none
+func (rs Rules) mergeRule(stationID string, p *Prog) *Rule {
+	for _, r := range rs {
+		if r.Merge && r.Match(stationID, p) {
+			return r
+		}
+	}
+	return nil
+}
+
+// combineProgs combines the consecutive programs into one from the start of the first to the end of the last
+func combineProgs(progs Progs) *Prog {
+	if len(progs) == 1 {
+		return progs[0]
+	}
+	first, last := progs[0], progs[len(progs)-1]
+	p := *first
+	p.To = last.To
+	ids := []string{}
+	titles := []string{}
+	pfms := []string{}
+	descs := []string{}
+	infos := []string{}
+	p.Tags = nil
+	for _, part := range progs {
+		ids = append(ids, part.ID)
+		titles = append(titles, part.Title)
+		pfms = appendUnique(pfms, part.Pfm)
+		descs = appendUnique(descs, part.Desc)
+		infos = appendUnique(infos, part.Info)
+		for _, tag := range part.Tags {
+			p.Tags = appendUnique(p.Tags, tag)
+		}
+	}
+	p.ID = strings.Join(ids, "+")
+	p.Title = mergeTitle(titles)
+	p.Pfm = strings.Join(pfms, "、")
+	p.Desc = strings.Join(descs, "\n")
+	p.Info = strings.Join(infos, "\n")
+	return &p
+}
+
+// mergeTitle returns the common prefix of the titles, or the first if none
+func mergeTitle(titles []string) string {
+	prefix := []rune(titles[0])
+	for _, title := range titles[1:] {
+		r := []rune(title)
+		n := 0
+		for n < len(prefix) && n < len(r) && prefix[n] == r[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	// drop the part number, e.g., "第1部" and "第2部" share "第"
+	title := strings.TrimRightFunc(string(prefix), func(r rune) bool {
+		return unicode.IsDigit(r) || strings.ContainsRune(mergeTitleTrim, r)
+	})
+	if title == "" {
+		return titles[0]
+	}
+	return title
+}
+
+// appendUnique appends s unless empty or already in ss
+func appendUnique(ss []string, s string) []string {
+	if s == "" {
+		return ss
+	}
+	for _, v := range ss {
+		if v == s {
+			return ss
+		}
+	}
+	return append(ss, s)
+}
@@ -0,0 +1,74 @@
+package radicron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeConsecutive(t *testing.T) {
+	Location, _ = time.LoadLocation(TZTokyo)
+	progs := Progs{
+		{ID: "1", Ft: "20230605130000", To: "20230605140000", Title: "Show（前半）", Pfm: "A", Info: "part 1", Tags: []string{"music"}},
+		{ID: "2", Ft: "20230605140000", To: "20230605150000", Title: "Show（後半）", Pfm: "A", Info: "part 2", Tags: []string{"music", "talk"}},
+		{ID: "3", Ft: "20230605150000", To: "20230605160000", Title: "News"},
+		{ID: "4", Ft: "20230606130000", To: "20230606140000", Title: "Show（前半）"},
+		{ID: "5", Ft: "20230606150000", To: "20230606160000", Title: "Show（後半）"},
+	}
+
+	var mergetests = []struct {
+		name  string
+		rule  *Rule
+		want  []string
+		title string
+	}{
+		{"merge", &Rule{Name: "merge", Title: "Show", Merge: true}, []string{"1+2", "3", "4", "5"}, "Show"},
+		{"no merge", &Rule{Name: "plain", Title: "Show"}, []string{"1", "2", "3", "4", "5"}, "Show（前半）"},
+	}
+	for _, tt := range mergetests {
+		merged := Rules{tt.rule}.MergeConsecutive("FMT", progs)
+		got := []string{}
+		for _, p := range merged {
+			got = append(got, p.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("MergeConsecutive(%s) => %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("MergeConsecutive(%s) => %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		if merged[0].Title != tt.title {
+			t.Errorf("MergeConsecutive(%s).Title => %q, want %q", tt.name, merged[0].Title, tt.title)
+		}
+	}
+
+	p := Rules{mergetests[0].rule}.MergeConsecutive("FMT", progs)[0]
+	if p.Ft != "20230605130000" || p.To != "20230605150000" {
+		t.Errorf("merged span => %s-%s, want 20230605130000-20230605150000", p.Ft, p.To)
+	}
+	if p.Pfm != "A" || p.Info != "part 1\npart 2" || len(p.Tags) != 2 {
+		t.Errorf("merged metadata => %q, %q, %v", p.Pfm, p.Info, p.Tags)
+	}
+	if progs[0].To != "20230605140000" {
+		t.Error("MergeConsecutive modified the original program")
+	}
+}
+
+func TestMergeTitle(t *testing.T) {
+	var titletests = []struct {
+		titles []string
+		want   string
+	}{
+		{[]string{"Show 第1部", "Show 第2部"}, "Show"},
+		{[]string{"Show Part1", "Show Part2"}, "Show Part"},
+		{[]string{"Show - 1", "Show - 2", "Show - 3"}, "Show"},
+		{[]string{"Morning", "Evening"}, "Morning"},
+	}
+	for _, tt := range titletests {
+		if got := mergeTitle(tt.titles); got != tt.want {
+			t.Errorf("mergeTitle(%v) => %q, want %q", tt.titles, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// MetadataDir returns the dir to save the metadata of the programs in the metadata-only mode
+func MetadataDir() (string, error) {
+	return getRadicronPath("metadata")
+}
+
+// saveMetadata writes the program metadata as JSON with its image in the metadata dir,
+// overwriting the older metadata of the same program
+func saveMetadata(ctx context.Context, prog *Prog, fileBaseName string) (string, error) {
+	dir, err := MetadataDir()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	blob, err := json.MarshalIndent(prog, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	output := filepath.Join(dir, fileBaseName+".json")
+	tmp := output + ".tmp"
+	if err = os.WriteFile(tmp, blob, 0o644); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp, output); err != nil {
+		return "", err
+	}
+
+	// the image is not essential to the index
+	if prog.Img != "" {
+		if err = saveImage(ctx, prog.Img, filepath.Join(dir, fileBaseName)); err != nil {
+			log.Printf("failed to save the image %s: %s", prog.Img, err)
+		}
+	}
+	return output, nil
+}
+
+// saveImage downloads the image to the path with the extension from uri unless exists
+func saveImage(ctx context.Context, uri, fileBasePath string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	output := fileBasePath + path.Ext(u.Path)
+	if _, err = os.Stat(output); err == nil {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", uri, resp.Status)
+	}
+	tmp := output + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, output)
+}
@@ -0,0 +1,68 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestDownloadMetadataOnly(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("jpeg"))
+	}))
+	defer ts.Close()
+
+	prog := &Prog{
+		ID:        "ID",
+		StationID: "FMT",
+		Ft:        "20230625050000",
+		To:        "20230625060000",
+		Title:     "Title/1",
+		Desc:      "Desc",
+		Img:       ts.URL + "/res/program/FMT/image.jpg",
+	}
+	asset := &Asset{MetadataOnly: true}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	var wg sync.WaitGroup
+	// save again without fetching the image twice
+	for i := 0; i < 2; i++ {
+		if err := Download(ctx, &wg, prog); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if requests != 1 {
+		t.Errorf("image requests => %v, want 1", requests)
+	}
+	if len(asset.Schedules) != 0 {
+		t.Errorf("Schedules => %v, want none", asset.Schedules)
+	}
+
+	dir, err := MetadataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, "202306250500_FMT_"+sanitizeFileName(prog.Title))
+	blob, err := os.ReadFile(base + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Prog{}
+	if err = json.Unmarshal(blob, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != prog.ID || got.Desc != prog.Desc || got.Img != prog.Img {
+		t.Errorf("metadata => %+v, want %+v", got, prog)
+	}
+	if img, err := os.ReadFile(base + ".jpg"); err != nil || string(img) != "jpeg" {
+		t.Errorf("image => %q, %v, want %q", img, err, "jpeg")
+	}
+}
@@ -0,0 +1,85 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrHistoryTooNew is returned for the history written by a later release
+var ErrHistoryTooNew = errors.New("the history is newer than this radicron")
+
+// HistoryMigration upgrades the history document from the version before
+type HistoryMigration struct {
+	Version     int
+	Description string
+	// Migrate edits the decoded JSON in place, not to depend on the latest History
+	Migrate func(doc map[string]any) error
+}
+
+// historyMigrations in the ascending order of the versions, the last is HistoryVersion
+var historyMigrations = []HistoryMigration{
+	{
+		Version:     1,
+		Description: "version the history",
+		Migrate:     func(doc map[string]any) error { return nil },
+	},
+}
+
+// historyVersion returns the version of the history document, 0 before versioned
+func historyVersion(doc map[string]any) (int, error) {
+	v, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("invalid history version: %v", v)
+	}
+	return int(f), nil
+}
+
+// upgradeHistory applies the pending migrations to the blob,
+// and returns the upgraded blob with the applied migrations
+func upgradeHistory(blob []byte) ([]byte, []HistoryMigration, error) {
+	doc := map[string]any{}
+	if err := json.Unmarshal(blob, &doc); err != nil {
+		return nil, nil, err
+	}
+	version, err := historyVersion(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > HistoryVersion {
+		return nil, nil, fmt.Errorf("%w: v%d > v%d", ErrHistoryTooNew, version, HistoryVersion)
+	}
+	applied := []HistoryMigration{}
+	for _, m := range historyMigrations {
+		if m.Version <= version {
+			continue
+		}
+		if err = m.Migrate(doc); err != nil {
+			return nil, applied, fmt.Errorf("failed to migrate the history to v%d: %s", m.Version, err)
+		}
+		doc["version"] = m.Version
+		applied = append(applied, m)
+	}
+	if len(applied) == 0 {
+		return blob, nil, nil
+	}
+	blob, err = json.Marshal(doc)
+	return blob, applied, err
+}
+
+// PendingHistoryMigrations returns the migrations to apply to the history at the path without applying them
+func PendingHistoryMigrations(path string) ([]HistoryMigration, error) {
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	_, pending, err := upgradeHistory(blob)
+	return pending, err
+}
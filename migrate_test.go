@@ -0,0 +1,93 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryMigrations(t *testing.T) {
+	for i, m := range historyMigrations {
+		if m.Version != i+1 {
+			t.Errorf("historyMigrations[%d].Version => %v, want %v", i, m.Version, i+1)
+		}
+	}
+	if last := historyMigrations[len(historyMigrations)-1].Version; last != HistoryVersion {
+		t.Errorf("the last migration => v%d, want HistoryVersion v%d", last, HistoryVersion)
+	}
+}
+
+func TestUpgradeHistory(t *testing.T) {
+	var upgradetests = []struct {
+		name    string
+		blob    string
+		applied int
+		tooNew  bool
+		ok      bool
+	}{
+		{"unversioned", `{"records": {}}`, HistoryVersion, false, true},
+		{"latest", `{"version": 1, "records": {}}`, 0, false, true},
+		{"newer", `{"version": 99, "records": {}}`, 0, true, false},
+		{"invalid version", `{"version": "1", "records": {}}`, 0, false, false},
+		{"truncated", `{"records": {`, 0, false, false},
+	}
+	for _, tt := range upgradetests {
+		blob, applied, err := upgradeHistory([]byte(tt.blob))
+		if (err == nil) != tt.ok {
+			t.Errorf("upgradeHistory(%s) => %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if errors.Is(err, ErrHistoryTooNew) != tt.tooNew {
+			t.Errorf("upgradeHistory(%s) => %v, want ErrHistoryTooNew %v", tt.name, err, tt.tooNew)
+		}
+		if !tt.ok {
+			continue
+		}
+		if len(applied) != tt.applied {
+			t.Errorf("upgradeHistory(%s) => %v migrations, want %v", tt.name, len(applied), tt.applied)
+		}
+		h := &History{}
+		if err = json.Unmarshal(blob, h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Version != HistoryVersion {
+			t.Errorf("upgradeHistory(%s) => v%d, want v%d", tt.name, h.Version, HistoryVersion)
+		}
+	}
+}
+
+func TestLoadHistoryMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	old := []byte(`{"records": {"12345": {"id": "12345", "station_id": "FMT", "ft": "20230625050000"}}}`)
+	if err := os.WriteFile(path, old, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := PendingHistoryMigrations(path)
+	if err != nil || len(pending) != HistoryVersion {
+		t.Fatalf("PendingHistoryMigrations => %v, %v, want %v", pending, err, HistoryVersion)
+	}
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Records["12345"]; !ok || h.Version != HistoryVersion {
+		t.Errorf("LoadHistory => v%d %v, want v%d with 12345", h.Version, h.Records, HistoryVersion)
+	}
+	if pending, err = PendingHistoryMigrations(path); err != nil || len(pending) != 0 {
+		t.Errorf("PendingHistoryMigrations after migrated => %v, %v, want none", pending, err)
+	}
+	// kept to downgrade
+	if blob, err := os.ReadFile(path + ".v0"); err != nil || string(blob) != string(old) {
+		t.Errorf("the history before the migrations => %s, %v, want %s", blob, err, old)
+	}
+
+	// never overwrite the history from a later release
+	if err = os.WriteFile(path, []byte(`{"version": 99, "records": {}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadHistory(path); !errors.Is(err, ErrHistoryTooNew) {
+		t.Errorf("LoadHistory the newer => %v, want ErrHistoryTooNew", err)
+	}
+}
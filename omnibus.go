@@ -0,0 +1,182 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bogem/id3v2"
+)
+
+// omnibusGroup is the recordings of a program in a week
+type omnibusGroup struct {
+	records []*HistoryRecord
+	weekEnd time.Time
+}
+
+// MergeOmnibus merges the recordings for the omnibus of each program
+// into a weekly file with the chapters once the week ends
+func MergeOmnibus(ctx context.Context, h *History, now time.Time) (int, error) {
+	merged := 0
+	for _, g := range h.omnibusGroups() {
+		if now.Before(g.weekEnd.Add(BufferMinutes * time.Minute)) {
+			continue // the week is not over yet
+		}
+		output, err := mergeOmnibus(ctx, g.records)
+		if err != nil {
+			// e.g., an episode deleted, not to block the other weeks
+			log.Printf("failed to merge the omnibus of [%s]%s (%s): %s", g.records[0].StationID, g.records[0].Title, g.records[0].Ft, err)
+			continue
+		}
+		if err = h.RecordOmnibus(g.records, output); err != nil {
+			return merged, err
+		}
+		// clean up the episodes
+		for _, r := range g.records {
+			if r.Path != output {
+				if err = os.Remove(r.Path); err != nil && !os.IsNotExist(err) {
+					log.Printf("failed to remove %s: %s", r.Path, err)
+				}
+			}
+		}
+		log.Printf("+omnibus saved: %s", output)
+		merged++
+	}
+	return merged, nil
+}
+
+// omnibusGroups returns the recordings for the omnibus not yet merged by program and week
+func (h *History) omnibusGroups() []*omnibusGroup {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	groups := map[string]*omnibusGroup{}
+	for _, r := range h.Records {
+		if !r.Omnibus || r.Path == "" || r.MergedInto != "" {
+			continue
+		}
+		ft, err := time.ParseInLocation(DatetimeLayout, r.Ft, Location)
+		if err != nil {
+			continue
+		}
+		weekStart := startOfWeek(ft)
+		key := fmt.Sprintf("%s|%s|%s", r.StationID, r.Title, weekStart.Format(ArchiveDayLayout))
+		g, ok := groups[key]
+		if !ok {
+			g = &omnibusGroup{weekEnd: weekStart.AddDate(0, 0, 7)}
+			groups[key] = g
+		}
+		g.records = append(g.records, r)
+	}
+
+	sorted := make([]*omnibusGroup, 0, len(groups))
+	for _, g := range groups {
+		sort.Slice(g.records, func(i, j int) bool {
+			return g.records[i].Ft < g.records[j].Ft
+		})
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].records[0].Ft < sorted[j].records[0].Ft
+	})
+	return sorted
+}
+
+// RecordOmnibus saves the path of the omnibus the records merged into
+func (h *History) RecordOmnibus(records []*HistoryRecord, output string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range records {
+		r.MergedInto = output
+		r.UpdatedAt = time.Now()
+	}
+	return h.save()
+}
+
+// IsMerged returns true if the program is merged into an omnibus
+func (h *History) IsMerged(prog *Prog) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.Records[prog.ID]
+	return ok && r.MergedInto != ""
+}
+
+// mergeOmnibus concatenates the recordings and writes the chapters
+func mergeOmnibus(ctx context.Context, records []*HistoryRecord) (string, error) {
+	first := records[0]
+	dir := filepath.Dir(first.Path)
+	episodes := Episodes{}
+	for _, r := range records {
+		e := parseEpisodeFileName(filepath.Base(r.Path))
+		e.Title = r.Title
+		// the episodes may be in the different dirs, e.g., after the output template changed
+		e.FileName = r.Path
+		episodes = append(episodes, e)
+	}
+	chapters, err := episodeChapters(ctx, "", episodes)
+	if err != nil {
+		return "", err
+	}
+
+	// name after the first episode to sort in the feed
+	ext := filepath.Ext(first.Path)
+	output := filepath.Join(dir, fmt.Sprintf("%s %s-%s%s",
+		strings.TrimSuffix(filepath.Base(first.Path), ext),
+		episodes[0].PubDate.Format(ArchiveDayLayout),
+		episodes[len(episodes)-1].PubDate.Format(ArchiveDayLayout),
+		ext,
+	))
+
+	// in the same dir to rename
+	tmpDir, err := os.MkdirTemp(dir, ".omnibus")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	list, err := writeConcatList(tmpDir, "", episodes)
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(tmpDir, "omnibus"+ext)
+	if err = runFFmpeg(ctx, nil, "-f", "concat", "-safe", "0", "-i", list, "-c", "copy", "-y", tmp); err != nil {
+		return "", err
+	}
+	if err = writeOmnibusTag(tmp, first); err != nil {
+		return "", err
+	}
+	if err = WriteChapters(tmp, chapters); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp, output); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// writeOmnibusTag writes the ID3v2 tag of the program
+func writeOmnibusTag(path string, r *HistoryRecord) error {
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return err
+	}
+	defer tag.Close()
+	tag.SetTitle(r.Title)
+	tag.SetArtist(r.Pfm)
+	tag.SetAlbum(r.Title)
+	tag.SetYear(r.Ft[:4])
+	return tag.Save()
+}
+
+// startOfWeek returns the Monday 00:00 of the week
+func startOfWeek(t time.Time) time.Time {
+	days := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.AddDate(0, 0, -days).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,96 @@
+package radicron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	monday := time.Date(2023, 6, 5, 0, 0, 0, 0, Location)
+	for _, d := range []int{0, 3, 6} {
+		in := monday.AddDate(0, 0, d).Add(13 * time.Hour)
+		if got := startOfWeek(in); !got.Equal(monday) {
+			t.Errorf("startOfWeek(%v) => %v, want %v", in, got, monday)
+		}
+	}
+}
+
+func TestOmnibusGroups(t *testing.T) {
+	dir := t.TempDir()
+	h, err := LoadHistory(filepath.Join(dir, HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*Prog{
+		{ID: "3", StationID: "FMT", Ft: "20230607050000", Title: "Daily", Omnibus: true},
+		{ID: "1", StationID: "FMT", Ft: "20230605050000", Title: "Daily", Omnibus: true},
+		{ID: "2", StationID: "FMT", Ft: "20230606050000", Title: "Daily", Omnibus: true},
+		{ID: "4", StationID: "FMT", Ft: "20230612050000", Title: "Daily", Omnibus: true},
+		{ID: "5", StationID: "FMT", Ft: "20230605130000", Title: "Weekly"},
+	} {
+		if err = h.RecordSuccess(p, filepath.Join(dir, p.ID+".aac")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups := h.omnibusGroups()
+	if len(groups) != 2 {
+		t.Fatalf("omnibusGroups => %v groups, want 2", len(groups))
+	}
+	if len(groups[0].records) != 3 || groups[0].records[0].ID != "1" {
+		t.Errorf("omnibusGroups[0] => %v records from %v, want 3 from 1", len(groups[0].records), groups[0].records[0].ID)
+	}
+	if want := time.Date(2023, 6, 12, 0, 0, 0, 0, Location); !groups[0].weekEnd.Equal(want) {
+		t.Errorf("weekEnd => %v, want %v", groups[0].weekEnd, want)
+	}
+
+	// the weeks are not over yet
+	n, err := MergeOmnibus(context.Background(), h, time.Date(2023, 6, 11, 23, 0, 0, 0, Location))
+	if err != nil || n != 0 {
+		t.Errorf("MergeOmnibus => %v, %v, want 0, nil", n, err)
+	}
+
+	// the weeks failing to merge, e.g., without the files, don't block the others
+	n, err = MergeOmnibus(context.Background(), h, time.Date(2023, 6, 30, 0, 0, 0, 0, Location))
+	if err != nil || n != 0 {
+		t.Errorf("MergeOmnibus without the files => %v, %v, want 0, nil", n, err)
+	}
+
+	if err = h.RecordOmnibus(groups[0].records, "omnibus.aac"); err != nil {
+		t.Fatal(err)
+	}
+	if !h.IsMerged(&Prog{ID: "1"}) {
+		t.Error("IsMerged => false, want true")
+	}
+	if h.IsMerged(&Prog{ID: "4"}) {
+		t.Error("IsMerged(4) => true, want false")
+	}
+	if got := len(h.omnibusGroups()); got != 1 {
+		t.Errorf("omnibusGroups after merged => %v groups, want 1", got)
+	}
+}
+
+func TestOmnibusConcatList(t *testing.T) {
+	dir := t.TempDir()
+	episodes := Episodes{
+		{FileName: filepath.Join(dir, "a", "202306050500_FMT_Daily.aac")},
+		{FileName: filepath.Join(dir, "b", "202306060500_FMT_Daily.aac")},
+	}
+	list, err := writeConcatList(dir, "", episodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.ReadFile(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range episodes {
+		if !strings.Contains(string(blob), "file '"+e.FileName+"'") {
+			t.Errorf("concat list => %v, want %v", string(blob), e.FileName)
+		}
+	}
+}
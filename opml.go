@@ -0,0 +1,53 @@
+package radicron
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// OPML lists the per-show feeds to import at once
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title string `xml:"title"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+type OPMLOutline struct {
+	Type   string `xml:"type,attr"`
+	Text   string `xml:"text,attr"`
+	Title  string `xml:"title,attr"`
+	XMLURL string `xml:"xmlUrl,attr"`
+}
+
+// NewOPML returns the OPML of the feeds for the shows under baseURL
+func NewOPML(title, baseURL, token string, shows []string) *OPML {
+	opml := &OPML{
+		Version: "2.0",
+		Head:    OPMLHead{Title: title},
+		Body:    OPMLBody{Outlines: []OPMLOutline{}},
+	}
+	for _, show := range shows {
+		query := url.Values{"show": {show}}
+		if token != "" {
+			query.Set("token", token)
+		}
+		opml.Body.Outlines = append(opml.Body.Outlines, OPMLOutline{
+			Type:   "rss",
+			Text:   show,
+			Title:  show,
+			XMLURL: fmt.Sprintf("%s/feed.xml?%s", strings.TrimSuffix(baseURL, "/"), query.Encode()),
+		})
+	}
+	return opml
+}
@@ -0,0 +1,25 @@
+package radicron
+
+import (
+	"testing"
+)
+
+func TestNewOPML(t *testing.T) {
+	opml := NewOPML("radicron", "http://localhost:8080/", "secret", []string{"a&b", "c"})
+	var opmltests = []struct {
+		text   string
+		xmlURL string
+	}{
+		{"a&b", "http://localhost:8080/feed.xml?show=a%26b&token=secret"},
+		{"c", "http://localhost:8080/feed.xml?show=c&token=secret"},
+	}
+	if len(opml.Body.Outlines) != len(opmltests) {
+		t.Fatalf("NewOPML => %v outlines, want %v", len(opml.Body.Outlines), len(opmltests))
+	}
+	for i, tt := range opmltests {
+		o := opml.Body.Outlines[i]
+		if o.Text != tt.text || o.XMLURL != tt.xmlURL {
+			t.Errorf("outline => %v %v, want %v %v", o.Text, o.XMLURL, tt.text, tt.xmlURL)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pending journals the programs in the download to resume them
+// if radicron crashes or restarts before they complete
+type Pending struct {
+	Programs map[string]*PendingProg `json:"programs"`
+	path     string
+	mu       sync.Mutex
+	// resumed from the last run until downloaded again
+	resumed map[string]*PendingProg
+}
+
+// PendingProg is a program in the download
+type PendingProg struct {
+	Prog   *Prog  `json:"prog"`
+	Output string `json:"output"`
+	// Playlist requested, to reuse the segments in TmpDir only for the same one
+	Playlist  string    `json:"playlist"`
+	TmpDir    string    `json:"tmp_dir,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Add journals the program downloading to the output
+func (p *Pending) Add(prog *Prog, output string) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp := &PendingProg{
+		Prog:      prog,
+		Output:    output,
+		Playlist:  buildM3U8RequestURI(prog),
+		StartedAt: time.Now(),
+	}
+	if last, ok := p.resumed[prog.ID]; ok {
+		delete(p.resumed, prog.ID)
+		if last.Playlist == pp.Playlist {
+			pp.TmpDir = last.TmpDir
+		} else if last.TmpDir != "" {
+			os.RemoveAll(last.TmpDir)
+		}
+	}
+	p.Programs[prog.ID] = pp
+	return p.save()
+}
+
+// Interrupted returns true if the program was in the download when the last run ended
+func (p *Pending) Interrupted(prog *Prog) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.resumed[prog.ID]
+	return ok
+}
+
+// TmpDir returns the dir for the segments of the program,
+// the one left by the interrupted download if any
+func (p *Pending) TmpDir(prog *Prog) (string, error) {
+	if p == nil {
+		return tempAACDir()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp, ok := p.Programs[prog.ID]
+	if !ok {
+		return tempAACDir()
+	}
+	if pp.TmpDir != "" {
+		if fi, err := os.Stat(pp.TmpDir); err == nil && fi.IsDir() {
+			log.Printf("resuming the segments of [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, pp.TmpDir)
+			return pp.TmpDir, nil
+		}
+	}
+	dir, err := tempAACDir()
+	if err != nil {
+		return "", err
+	}
+	pp.TmpDir = dir
+	return dir, p.save()
+}
+
+// Remove drops the program from the journal once downloaded or failed
+func (p *Pending) Remove(prog *Prog) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.Programs[prog.ID]; !ok {
+		return nil
+	}
+	delete(p.Programs, prog.ID)
+	return p.save()
+}
+
+// Resume removes and returns the programs left by the last run in the order of the start,
+// to download them again
+func (p *Pending) Resume() ([]*Prog, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	left := []*PendingProg{}
+	for id, pp := range p.Programs {
+		left = append(left, pp)
+		delete(p.Programs, id)
+	}
+	if len(left) == 0 {
+		return nil, nil
+	}
+	sort.Slice(left, func(i, j int) bool {
+		return left[i].Prog.Ft < left[j].Prog.Ft
+	})
+	progs := make([]*Prog, len(left))
+	for i, pp := range left {
+		p.resumed[pp.Prog.ID] = pp
+		progs[i] = pp.Prog
+	}
+	return progs, p.save()
+}
+
+// Discard removes the segments of the resumed programs not downloaded again
+func (p *Pending) Discard() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, pp := range p.resumed {
+		if pp.TmpDir != "" {
+			os.RemoveAll(pp.TmpDir)
+		}
+		delete(p.resumed, id)
+	}
+}
+
+func (p *Pending) save() error {
+	if p.path == "" {
+		return nil
+	}
+	blob, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileSync(p.path, blob)
+}
+
+// LoadPending loads the journal from the path, or returns an empty one if not exists
+func LoadPending(path string) (*Pending, error) {
+	p := &Pending{
+		Programs: map[string]*PendingProg{},
+		path:     path,
+		resumed:  map[string]*PendingProg{},
+	}
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return p, err
+	}
+	if err = json.Unmarshal(blob, p); err != nil {
+		return p, err
+	}
+	if p.Programs == nil {
+		p.Programs = map[string]*PendingProg{}
+	}
+	return p, nil
+}
+
+// NewPending loads the journal in ${RADICRON_HOME}
+func NewPending() (*Pending, error) {
+	path, err := getRadicronPath(PendingFileName)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return LoadPending(path)
+}
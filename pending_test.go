@@ -0,0 +1,98 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPending(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), PendingFileName)
+	p, err := LoadPending(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progs := []*Prog{
+		{ID: "later", StationID: "TBS", Ft: "20230625060000", To: "20230625070000", Title: "Later"},
+		{ID: "sooner", StationID: "FMT", Ft: "20230625050000", To: "20230625060000", Title: "Sooner"},
+		{ID: "done", StationID: "FMT", Ft: "20230625040000", To: "20230625050000", Title: "Done"},
+	}
+	dirs := map[string]string{}
+	for _, prog := range progs {
+		if err = p.Add(prog, prog.ID+".aac"); err != nil {
+			t.Fatal(err)
+		}
+		if dirs[prog.ID], err = p.TmpDir(prog); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = p.Remove(progs[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	// left by the crash
+	if p, err = LoadPending(path); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := p.Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resumetests = []struct {
+		id          string
+		interrupted bool
+		again       bool
+		reused      bool
+	}{
+		{"sooner", true, true, true},
+		{"later", true, false, false},
+		{"done", false, false, false},
+	}
+	if len(resumed) != 2 || resumed[0].ID != "sooner" || resumed[1].ID != "later" {
+		t.Fatalf("Resume => %v, want [sooner later]", resumed)
+	}
+	for _, tt := range resumetests {
+		prog := &Prog{ID: tt.id}
+		for _, q := range progs {
+			if q.ID == tt.id {
+				prog = q
+			}
+		}
+		if got := p.Interrupted(prog); got != tt.interrupted {
+			t.Errorf("Interrupted(%s) => %v, want %v", tt.id, got, tt.interrupted)
+		}
+		if !tt.again {
+			continue
+		}
+		if err = p.Add(prog, prog.ID+".aac"); err != nil {
+			t.Fatal(err)
+		}
+		dir, err := p.TmpDir(prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dir == dirs[tt.id]; got != tt.reused {
+			t.Errorf("TmpDir(%s) reused => %v, want %v", tt.id, got, tt.reused)
+		}
+	}
+
+	// the segments of the program not downloaded again
+	p.Discard()
+	if _, err = os.Stat(dirs["later"]); !os.IsNotExist(err) {
+		t.Errorf("Discard left %s: %v", dirs["later"], err)
+	}
+	if _, err = os.Stat(dirs["sooner"]); err != nil {
+		t.Errorf("Discard removed %s: %v", dirs["sooner"], err)
+	}
+	if p, err = LoadPending(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Programs) != 1 || p.Programs["sooner"] == nil {
+		t.Errorf("Programs => %v, want [sooner]", p.Programs)
+	}
+}
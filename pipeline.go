@@ -0,0 +1,123 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pipelineTranscode downloads the segments to dir and feeds them to ffmpeg in order as they arrive,
+// overlapping the mp3 conversion with the download
+func pipelineTranscode(ctx context.Context, segments Segments, dir string, offset, length time.Duration) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ready := make(map[*Segment]chan struct{}, len(segments))
+	for _, s := range segments {
+		ready[s] = make(chan struct{})
+	}
+	finished := make(chan struct{})
+
+	// the transcoder
+	output := filepath.Join(dir, "transcoded.mp3")
+	pr, pw := io.Pipe()
+	transcoded := make(chan error, 1)
+	go func() {
+		tctx, tcancel := transcodeContext(ctx, segments.Duration())
+		defer tcancel()
+		err := execFFmpeg(tctx, pr, nil, transcodeArgs(output, offset, length, segments.Duration()))
+		if err != nil {
+			cancel() // stop downloading
+		}
+		pr.CloseWithError(err)
+		transcoded <- err
+	}()
+
+	// the feeder
+	go func() {
+		pw.CloseWithError(feedSegments(ctx, segments, dir, ready, finished, pw))
+	}()
+
+	keys := newSegmentKeys()
+	unique, dups := segments.dedupe()
+	_, err := bulkFetch(ctx, unique, func(ctx context.Context, segment *Segment) error {
+		if err := downloadSegment(ctx, segment, keys, dir); err != nil {
+			return err
+		}
+		if err := copyDuplicates(dir, segment, dups[segment]); err != nil {
+			return err
+		}
+		close(ready[segment])
+		for _, dup := range dups[segment] {
+			close(ready[dup])
+		}
+		return nil
+	})
+	if err != nil {
+		cancel()
+		if ffmpegErr := <-transcoded; ffmpegErr != nil && errors.Is(err, context.Canceled) {
+			return "", ffmpegErr // ffmpeg stopped first
+		}
+		return "", err
+	}
+	close(finished)
+	if err = <-transcoded; err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// transcodeArgs returns the ffmpeg args to transcode the aac from stdin to output in mp3
+func transcodeArgs(output string, offset, length, total time.Duration) []string {
+	args := []string{"-f", "aac", "-i", "pipe:0"}
+	if offset > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", offset.Seconds()))
+	}
+	if length < total {
+		args = append(args, "-t", fmt.Sprintf("%.3f", length.Seconds()))
+	}
+	// the same settings as radigo.ConvertAACtoMP3
+	return append(args, "-c:a", "libmp3lame", "-ac", "2", "-q:a", "2", "-y", output)
+}
+
+// feedSegments writes the downloaded segments to w in order,
+// skipping the failed ones once all the downloads finished
+func feedSegments(
+	ctx context.Context,
+	segments Segments,
+	dir string,
+	ready map[*Segment]chan struct{},
+	finished <-chan struct{},
+	w io.Writer,
+) error {
+	for _, s := range segments {
+		select {
+		case <-ready[s]:
+		case <-finished:
+			select {
+			case <-ready[s]:
+			default:
+				continue // failed
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		path := filepath.Join(dir, s.FileName())
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = copySegment(w, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		// free the disk as it goes
+		os.Remove(path)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTranscodeArgs(t *testing.T) {
+	var argstests = []struct {
+		offset time.Duration
+		length time.Duration
+		want   []string
+	}{
+		{0, time.Hour, []string{"-f", "aac", "-i", "pipe:0", "-c:a", "libmp3lame", "-ac", "2", "-q:a", "2", "-y", "a.mp3"}},
+		{
+			1500 * time.Millisecond, 30 * time.Minute,
+			[]string{"-f", "aac", "-i", "pipe:0", "-ss", "1.500", "-t", "1800.000", "-c:a", "libmp3lame", "-ac", "2", "-q:a", "2", "-y", "a.mp3"},
+		},
+	}
+	for _, tt := range argstests {
+		if got := transcodeArgs("a.mp3", tt.offset, tt.length, time.Hour); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("transcodeArgs(%v, %v) => %v, want %v", tt.offset, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestFeedSegments(t *testing.T) {
+	dir := t.TempDir()
+	segments := Segments{}
+	ready := map[*Segment]chan struct{}{}
+	for i := 0; i < 4; i++ {
+		s := &Segment{Index: i, URI: "https://radiko.jp/a.aac"}
+		segments = append(segments, s)
+		ready[s] = make(chan struct{})
+	}
+	finished := make(chan struct{})
+
+	var w bytes.Buffer
+	fed := make(chan error)
+	go func() { fed <- feedSegments(context.Background(), segments, dir, ready, finished, &w) }()
+
+	// arrive out of order, and #2 fails
+	for _, i := range []int{3, 1, 0} {
+		if err := os.WriteFile(filepath.Join(dir, segments[i].FileName()), []byte{byte('0' + i)}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		close(ready[segments[i]])
+	}
+	close(finished)
+	if err := <-fed; err != nil {
+		t.Fatal(err)
+	}
+	if w.String() != "013" {
+		t.Errorf("feedSegments => %q, want %q", w.String(), "013")
+	}
+	if _, err := os.Stat(filepath.Join(dir, segments[0].FileName())); !os.IsNotExist(err) {
+		t.Errorf("the fed segment remains: %v", err)
+	}
+
+	// canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := feedSegments(ctx, Segments{{URI: "https://radiko.jp/a.aac"}}, dir, map[*Segment]chan struct{}{}, make(chan struct{}), &w); err == nil {
+		t.Error("feedSegments canceled => nil, want error")
+	}
+}
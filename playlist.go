@@ -0,0 +1,64 @@
+package radicron
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/grafov/m3u8"
+)
+
+// decodePlaylist decodes the playlist of the list type,
+// dumping the raw playlist to the debug dir if it fails
+func decodePlaylist(input io.Reader, listType m3u8.ListType, strict bool) (m3u8.Playlist, error) {
+	raw, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	playlist, lt, err := m3u8.Decode(*bytes.NewBuffer(raw), strict)
+	if err == nil && lt != listType {
+		err = fmt.Errorf("unexpected playlist type: %s", playlistType(lt))
+	}
+	if err != nil {
+		preview := raw
+		if len(preview) > PlaylistPreviewBytes {
+			preview = preview[:PlaylistPreviewBytes]
+		}
+		path, dumpErr := dumpPlaylist(raw)
+		if dumpErr != nil {
+			log.Printf("failed to dump the playlist: %s", dumpErr)
+		}
+		log.Printf("invalid playlist (%d bytes, dumped to %s): %q", len(raw), path, preview)
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// dumpPlaylist saves the raw playlist in the debug dir and returns the path
+func dumpPlaylist(raw []byte) (string, error) {
+	dir, err := getRadicronPath(DebugDirName)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("playlist-%s.m3u8", time.Now().Format("20060102-150405.000000")))
+	return path, os.WriteFile(path, raw, 0o600)
+}
+
+// playlistType returns the name of the list type
+func playlistType(lt m3u8.ListType) string {
+	switch lt {
+	case m3u8.MASTER:
+		return "master"
+	case m3u8.MEDIA:
+		return "media"
+	default:
+		return "unknown"
+	}
+}
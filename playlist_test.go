@@ -0,0 +1,44 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafov/m3u8"
+)
+
+func TestDecodePlaylist(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+
+	master := "#EXTM3U\n" +
+		"#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=52973\nlow/chunklist.m3u8\n" +
+		"#EXT-X-STREAM-INF:PROGRAM-ID=1,BANDWIDTH=96000\nhigh/chunklist.m3u8\n"
+
+	// unexpected type
+	if _, err := decodePlaylist(strings.NewReader(master), m3u8.MEDIA, true); err == nil {
+		t.Error("decodePlaylist(master as media) => nil, want error")
+	}
+	dir, _ := getRadicronPath(DebugDirName)
+	dumps, err := filepath.Glob(filepath.Join(dir, "playlist-*.m3u8"))
+	if err != nil || len(dumps) != 1 {
+		t.Fatalf("dumped playlists => %v, want 1", dumps)
+	}
+	blob, err := os.ReadFile(dumps[0])
+	if err != nil || string(blob) != master {
+		t.Errorf("dumped playlist => %q, want %q", blob, master)
+	}
+
+	// many variants
+	if _, err = getURI(strings.NewReader(master), APIPlaylistM3U8, true); err == nil {
+		t.Error("getURI(strict) => nil, want error")
+	}
+	uri, err := getURI(strings.NewReader(master), APIPlaylistM3U8, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://radiko.jp/v2/api/ts/low/chunklist.m3u8"; uri != want {
+		t.Errorf("getURI(lenient) => %v, want %v", uri, want)
+	}
+}
@@ -0,0 +1,181 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// Notifier is told of the lifecycle events of the programs, e.g., to chat or mail
+type Notifier interface {
+	Notify(ctx context.Context, event *Event) error
+}
+
+// Storage keeps the saved recording elsewhere, e.g., in a cloud bucket
+type Storage interface {
+	Store(ctx context.Context, prog *Prog, path string) error
+}
+
+// Provider fetches the audio of the program from another source than the timefree,
+// and returns the path of the aac or mp3 saved in dir
+type Provider interface {
+	Provide(ctx context.Context, prog *Prog, dir string) (string, error)
+}
+
+// Plugin is an external command called with the kind as the last argument,
+// reading the request in JSON from the stdin and writing the reply in JSON to the stdout
+type Plugin struct {
+	name    string
+	Command string   `mapstructure:"command"`
+	Kinds   []string `mapstructure:"kinds"`
+}
+
+// PluginStore is the request to the storage plugin
+type PluginStore struct {
+	Prog *Prog  `json:"prog"`
+	Path string `json:"path"`
+}
+
+// PluginProvide is the request to the provider plugin, to reply with the path
+type PluginProvide struct {
+	Prog *Prog  `json:"prog"`
+	Dir  string `json:"dir"`
+	Path string `json:"path,omitempty"`
+}
+
+// Plugins are the plugins by the name
+type Plugins map[string]*Plugin
+
+// Validate checks the commands and the kinds of the plugins
+func (ps Plugins) Validate() error {
+	for name, p := range ps {
+		if strings.TrimSpace(p.Command) == "" {
+			return fmt.Errorf("no command for the plugin %s", name)
+		}
+		if len(p.Kinds) == 0 {
+			return fmt.Errorf("no kinds for the plugin %s", name)
+		}
+		for _, kind := range p.Kinds {
+			switch kind {
+			case PluginEnricher, PluginNotifier, PluginProvider, PluginStorage:
+			default:
+				return fmt.Errorf("unknown kind for the plugin %s: %s", name, kind)
+			}
+		}
+	}
+	return nil
+}
+
+// Of returns the plugins of the kind in the order of the name
+func (ps Plugins) Of(kind string) []*Plugin {
+	plugins := []*Plugin{}
+	for name, p := range ps {
+		p.name = name
+		for _, k := range p.Kinds {
+			if k == kind {
+				plugins = append(plugins, p)
+				break
+			}
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].name < plugins[j].name
+	})
+	return plugins
+}
+
+// call runs the command for the kind with the request, decoding the reply into resp if not nil
+func (p *Plugin) call(ctx context.Context, kind string, req, resp any) error {
+	args := strings.Fields(p.Command)
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, PluginTimeoutSeconds*time.Second)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], kind)...) //nolint:gosec
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("plugin %s: %s: %s", p.name, err, lastLine(stderr.String()))
+	}
+	if resp == nil {
+		return nil
+	}
+	if err = json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return fmt.Errorf("plugin %s: invalid reply: %s", p.name, err)
+	}
+	return nil
+}
+
+// Name implements Enricher
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Enrich implements Enricher
+func (p *Plugin) Enrich(ctx context.Context, prog *Prog) error {
+	enriched := &Prog{}
+	if err := p.call(ctx, PluginEnricher, prog, enriched); err != nil {
+		return err
+	}
+	updateMetadata(prog, enriched)
+	return nil
+}
+
+// Notify implements Notifier
+func (p *Plugin) Notify(ctx context.Context, event *Event) error {
+	return p.call(ctx, PluginNotifier, event, nil)
+}
+
+// Store implements Storage
+func (p *Plugin) Store(ctx context.Context, prog *Prog, path string) error {
+	return p.call(ctx, PluginStorage, &PluginStore{Prog: prog, Path: path}, nil)
+}
+
+// Provide implements Provider
+func (p *Plugin) Provide(ctx context.Context, prog *Prog, dir string) (string, error) {
+	reply := &PluginProvide{}
+	if err := p.call(ctx, PluginProvider, &PluginProvide{Prog: prog, Dir: dir}, reply); err != nil {
+		return "", err
+	}
+	if reply.Path == "" {
+		return "", fmt.Errorf("plugin %s: no audio", p.name)
+	}
+	return reply.Path, nil
+}
+
+// provide saves the audio from the first provider succeeded to the output
+func provide(ctx context.Context, providers []Provider, prog *Prog, output *radigo.OutputConfig) error {
+	dir, err := tempAACDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	errs := []string{}
+	for _, p := range providers {
+		audio, err := p.Provide(ctx, prog, dir)
+		if err == nil {
+			err = saveAudio(ctx, prog, audio, output)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return fmt.Errorf("no provider: %s", strings.Join(errs, "; "))
+}
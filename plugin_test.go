@@ -0,0 +1,105 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// testPlugin writes a shell script handling the kinds
+func testPlugin(t *testing.T, dir string) *Plugin {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	script := filepath.Join(dir, "plugin.sh")
+	body := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+enricher) sed s/Before/After/ ;;
+notifier|storage) cat > %[1]s/$1.json ;;
+provider) printf aac > %[1]s/provided.aac; echo '{"path": "%[1]s/provided.aac"}' ;;
+*) exit 1 ;;
+esac
+`, dir)
+	if err := os.WriteFile(script, []byte(body), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	ps := Plugins{"test": {Command: "sh " + script, Kinds: []string{PluginEnricher, PluginNotifier, PluginProvider, PluginStorage}}}
+	return ps.Of(PluginEnricher)[0]
+}
+
+func TestPlugin(t *testing.T) {
+	dir := t.TempDir()
+	p := testPlugin(t, dir)
+	ctx := context.Background()
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230605130000", Title: "Before"}
+
+	if err := p.Enrich(ctx, prog); err != nil || prog.Title != "After" || prog.ID != "12345" {
+		t.Errorf("Enrich => %+v, %v", prog, err)
+	}
+	if err := p.Notify(ctx, &Event{Type: EventCompleted, ID: prog.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Store(ctx, prog, "/downloads/after.aac"); err != nil {
+		t.Fatal(err)
+	}
+	for kind, want := range map[string]string{
+		PluginNotifier: `"type":"completed"`,
+		PluginStorage:  `"path":"/downloads/after.aac"`,
+	} {
+		blob, err := os.ReadFile(filepath.Join(dir, kind+".json"))
+		if err != nil || !strings.Contains(string(blob), want) {
+			t.Errorf("%s => %s, %v, want %s", kind, blob, err, want)
+		}
+	}
+
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	output := &radigo.OutputConfig{DirFullPath: t.TempDir(), FileBaseName: "provided", FileFormat: radigo.AudioFormatAAC}
+	if err := provide(ctx, []Provider{p}, prog, output); err != nil {
+		t.Fatal(err)
+	}
+	if blob, err := os.ReadFile(output.AbsPath()); err != nil || string(blob) != "aac" {
+		t.Errorf("provide => %q, %v, want aac", blob, err)
+	}
+}
+
+func TestPluginsValidate(t *testing.T) {
+	var validatetests = []struct {
+		name    string
+		plugin  *Plugin
+		wantErr bool
+	}{
+		{"valid", &Plugin{Command: "notify", Kinds: []string{PluginNotifier}}, false},
+		{"no command", &Plugin{Kinds: []string{PluginNotifier}}, true},
+		{"no kinds", &Plugin{Command: "notify"}, true},
+		{"unknown kind", &Plugin{Command: "notify", Kinds: []string{"player"}}, true},
+	}
+	for _, tt := range validatetests {
+		if err := (Plugins{tt.name: tt.plugin}).Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%s) => %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	ps := Plugins{
+		"b": {Command: "b", Kinds: []string{PluginStorage, PluginNotifier}},
+		"a": {Command: "a", Kinds: []string{PluginNotifier}},
+		"c": {Command: "c", Kinds: []string{PluginEnricher}},
+	}
+	got := []string{}
+	for _, p := range ps.Of(PluginNotifier) {
+		got = append(got, p.Name())
+	}
+	if strings.Join(got, ",") != "a,b" {
+		t.Errorf("Of(notifier) => %v, want [a b]", got)
+	}
+}
@@ -0,0 +1,179 @@
+package radicron
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// errNoPodcast is returned when the official feed has no episode for the program
+var errNoPodcast = errors.New("no podcast episode")
+
+// podcastDateLayouts for the pubDate in the feeds in the wild
+var podcastDateLayouts = []string{time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"}
+
+// fetchPodcastFeed returns the official podcast feed at uri
+func fetchPodcastFeed(ctx context.Context, uri string) (*RSS, error) {
+	ctx, cancel := context.WithTimeout(ctx, playlistTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the feed: %s", resp.Status)
+	}
+	feed := &RSS{}
+	if err = xml.NewDecoder(resp.Body).Decode(feed); err != nil {
+		return nil, fmt.Errorf("invalid feed: %s", err)
+	}
+	return feed, nil
+}
+
+// parsePubDate parses the pubDate of a feed item
+func parsePubDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range podcastDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid pubDate: %s", s)
+}
+
+// podcastItem returns the item published closest to the broadcast within PodcastMatchHours,
+// or nil if none
+func podcastItem(feed *RSS, prog *Prog) *RSSItem {
+	ft, err := time.ParseInLocation(DatetimeLayout, prog.Ft, Location)
+	if err != nil {
+		return nil
+	}
+	var item *RSSItem
+	var closest time.Duration
+	for i := range feed.Channel.Items {
+		it := &feed.Channel.Items[i]
+		if it.Enclosure.URL == "" {
+			continue
+		}
+		pubDate, err := parsePubDate(it.PubDate)
+		if err != nil {
+			continue
+		}
+		d := pubDate.Sub(ft)
+		if d < 0 {
+			d = -d
+		}
+		if d > PodcastMatchHours*time.Hour {
+			continue
+		}
+		if item == nil || d < closest {
+			item, closest = it, d
+		}
+	}
+	return item
+}
+
+// podcastFormat returns the audio format of the enclosure, or empty if unknown
+func podcastFormat(enclosure *RSSEnclosure) string {
+	switch strings.ToLower(enclosure.Type) {
+	case "audio/mpeg", "audio/mp3":
+		return radigo.AudioFormatMP3
+	case "audio/aac", "audio/aacp":
+		return radigo.AudioFormatAAC
+	}
+	u := enclosure.URL
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	switch strings.ToLower(path.Ext(u)) {
+	case ".mp3":
+		return radigo.AudioFormatMP3
+	case ".aac":
+		return radigo.AudioFormatAAC
+	}
+	return ""
+}
+
+// savePodcast saves the official podcast episode of the program to the output,
+// converting it to mp3 if needed, and returns errNoPodcast if not available
+func savePodcast(ctx context.Context, prog *Prog, output *radigo.OutputConfig) error {
+	feed, err := fetchPodcastFeed(ctx, prog.Podcast)
+	if err != nil {
+		return err
+	}
+	item := podcastItem(feed, prog)
+	if item == nil {
+		return errNoPodcast
+	}
+	format := podcastFormat(&item.Enclosure)
+	// the mp3 does not fit in the aac
+	if format == "" || (format != output.AudioFormat() && output.AudioFormat() != radigo.AudioFormatMP3) {
+		return fmt.Errorf("%w in %s: %s", errNoPodcast, output.AudioFormat(), item.Enclosure.URL)
+	}
+
+	dir, err := tempAACDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	audio := filepath.Join(dir, "podcast."+format)
+	if err = fetchPodcastAudio(ctx, item.Enclosure.URL, audio); err != nil {
+		return err
+	}
+	return saveAudio(ctx, prog, audio, output)
+}
+
+// saveAudio moves the aac or mp3 of the program from another source to the output, converting it to mp3 if needed
+func saveAudio(ctx context.Context, prog *Prog, audio string, output *radigo.OutputConfig) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(audio)), ".")
+	switch {
+	case format == output.AudioFormat():
+		return moveFile(audio, output.AbsPath())
+	case format == radigo.AudioFormatAAC && output.AudioFormat() == radigo.AudioFormatMP3:
+		ctx, cancel := transcodeContext(ctx, prog.Duration())
+		defer cancel()
+		return radigo.ConvertAACtoMP3(ctx, audio, output.AbsPath())
+	}
+	return fmt.Errorf("cannot save %s in %s", filepath.Base(audio), output.AudioFormat())
+}
+
+// fetchPodcastAudio downloads the enclosure to dst
+func fetchPodcastAudio(ctx context.Context, uri, dst string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch the podcast audio: %s", resp.Status)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, throttle(ctx, resp.Body))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
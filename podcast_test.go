@@ -0,0 +1,107 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+func TestPodcastItem(t *testing.T) {
+	Location, _ = time.LoadLocation(TZTokyo)
+	feed := &RSS{Channel: RSSChannel{Items: []RSSItem{
+		{GUID: "previous", PubDate: "Mon, 29 May 2023 15:00:00 +0900", Enclosure: RSSEnclosure{URL: "previous.mp3"}},
+		{GUID: "this", PubDate: "Mon, 05 Jun 2023 15:00:00 +0900", Enclosure: RSSEnclosure{URL: "this.mp3"}},
+		{GUID: "no audio", PubDate: "Mon, 05 Jun 2023 13:00:00 +0900"},
+		{GUID: "invalid", PubDate: "2023-06-05", Enclosure: RSSEnclosure{URL: "invalid.mp3"}},
+	}}}
+
+	var podcasttests = []struct {
+		ft   string
+		want string
+	}{
+		{"20230605130000", "this"},
+		{"20230529130000", "previous"},
+		{"20230602130000", ""},
+	}
+	for _, tt := range podcasttests {
+		got := ""
+		if item := podcastItem(feed, &Prog{Ft: tt.ft}); item != nil {
+			got = item.GUID
+		}
+		if got != tt.want {
+			t.Errorf("podcastItem(%s) => %q, want %q", tt.ft, got, tt.want)
+		}
+	}
+}
+
+func TestPodcastFormat(t *testing.T) {
+	var formattests = []struct {
+		enclosure RSSEnclosure
+		want      string
+	}{
+		{RSSEnclosure{URL: "https://example.com/ep.bin", Type: "audio/mpeg"}, radigo.AudioFormatMP3},
+		{RSSEnclosure{URL: "https://example.com/ep.aac?token=x"}, radigo.AudioFormatAAC},
+		{RSSEnclosure{URL: "https://example.com/ep.MP3"}, radigo.AudioFormatMP3},
+		{RSSEnclosure{URL: "https://example.com/ep.m4a", Type: "audio/x-m4a"}, ""},
+	}
+	for _, tt := range formattests {
+		if got := podcastFormat(&tt.enclosure); got != tt.want {
+			t.Errorf("podcastFormat(%s) => %q, want %q", tt.enclosure.URL, got, tt.want)
+		}
+	}
+}
+
+func TestSavePodcast(t *testing.T) {
+	Location, _ = time.LoadLocation(TZTokyo)
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	audio := bytes.Repeat([]byte{0xff, 0xf1}, 512)
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Show</title>
+<item><title>ep</title><pubDate>Mon, 05 Jun 2023 15:00:00 +0900</pubDate>
+<enclosure url="%s/ep.aac" length="1024" type="audio/aac"/></item></channel></rss>`, ts.URL)
+		case "/ep.aac":
+			_, _ = w.Write(audio)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	output := &radigo.OutputConfig{
+		DirFullPath:  t.TempDir(),
+		FileBaseName: "202306051300_FMT_Show",
+		FileFormat:   radigo.AudioFormatAAC,
+	}
+	prog := &Prog{StationID: "FMT", Ft: "20230605130000", Podcast: ts.URL + "/feed.xml"}
+	if err := savePodcast(context.Background(), prog, output); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(output.AbsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, audio) {
+		t.Errorf("savePodcast => %d bytes, want %d", len(got), len(audio))
+	}
+
+	// fall back to the timefree
+	prog.Ft = "20230612130000"
+	if err = savePodcast(context.Background(), prog, output); err != errNoPodcast {
+		t.Errorf("savePodcast the week after => %v, want %v", err, errNoPodcast)
+	}
+}
@@ -0,0 +1,45 @@
+package radicron
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// copyBuffers are reused for copying the segments to the files
+var copyBuffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, CopyBufferSize)
+		return &b
+	},
+}
+
+// segmentBuffers are reused for reading the whole segments in memory
+var segmentBuffers = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// copySegment copies src to dst with a pooled buffer
+func copySegment(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	// hide io.ReaderFrom of os.File not to allocate another buffer
+	return io.CopyBuffer(struct{ io.Writer }{dst}, src, *buf)
+}
+
+// getSegmentBuffer returns an empty pooled buffer
+func getSegmentBuffer() *bytes.Buffer {
+	buf := segmentBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putSegmentBuffer returns the buffer to the pool unless it grew too large
+func putSegmentBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > MaxPooledBufferSize {
+		return
+	}
+	segmentBuffers.Put(buf)
+}
@@ -0,0 +1,44 @@
+package radicron
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCopySegment(t *testing.T) {
+	src := bytes.Repeat([]byte{0x21}, 3*CopyBufferSize+1)
+	var dst bytes.Buffer
+	n, err := copySegment(&dst, bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(src)) || !bytes.Equal(dst.Bytes(), src) {
+		t.Errorf("copySegment => %v bytes, want %v", n, len(src))
+	}
+}
+
+func TestSegmentBuffer(t *testing.T) {
+	buf := getSegmentBuffer()
+	buf.WriteString("aac")
+	putSegmentBuffer(buf)
+	if got := getSegmentBuffer(); got.Len() != 0 {
+		t.Errorf("getSegmentBuffer => %v bytes, want empty", got.Len())
+	}
+
+	// the large buffer is not pooled
+	large := getSegmentBuffer()
+	large.Grow(2 * MaxPooledBufferSize)
+	putSegmentBuffer(large)
+}
+
+func BenchmarkCopySegment(b *testing.B) {
+	src := bytes.Repeat([]byte{0x21}, 80*Kilobytes) // a 5s chunk
+	var dst bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst.Reset()
+		if _, err := copySegment(&dst, bytes.NewReader(src)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package radicron
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate reserves size bytes for f, falling back to a sparse file
+func preallocate(f *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	if err := unix.Fallocate(int(f.Fd()), 0, 0, size); err == nil {
+		return nil
+	}
+	// the filesystem may not support fallocate
+	return f.Truncate(size)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package radicron
+
+import "os"
+
+// preallocate extends f to size bytes
+func preallocate(f *os.File, size int64) error {
+	return f.Truncate(size)
+}
@@ -0,0 +1,126 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// Premium is the radiko premium membership to record the stations outside the area (area-free)
+type Premium struct {
+	// AreaID of the member to authorize all the stations for
+	AreaID   string
+	AreaFree bool
+	Mail     string
+	Password string
+	Session  string
+	mu       sync.Mutex
+}
+
+// premiumLogin is the response of the premium login
+type premiumLogin struct {
+	Session    string `json:"radiko_session"`
+	PaidMember string `json:"paid_member"`
+	AreaFree   string `json:"areafree"`
+}
+
+// Login logs in to radiko premium and keeps the session cookie in the client
+func (p *Premium) Login(ctx context.Context, client *radiko.Client) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	form := url.Values{}
+	form.Set("mail", p.Mail)
+	form.Set("pass", p.Password)
+	resp, err := postForm(ctx, client, APIPremiumLogin, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to log in to radiko premium: %s", resp.Status)
+	}
+	login := &premiumLogin{}
+	if err = json.NewDecoder(resp.Body).Decode(login); err != nil {
+		return fmt.Errorf("invalid radiko premium login: %s", err)
+	}
+	if login.Session == "" {
+		return fmt.Errorf("no %s in the radiko premium login", RadikoSessionKey)
+	}
+	ReplaceSecret("premium-session", login.Session)
+	p.Session = login.Session
+	p.AreaFree = login.AreaFree == "1"
+
+	// the session cookie for the following requests
+	if jar := client.Jar(); jar != nil {
+		u, _ := url.Parse(APIPremiumLogin)
+		jar.SetCookies(u, []*http.Cookie{{Name: RadikoSessionKey, Value: p.Session, Path: "/"}})
+	}
+	if !p.AreaFree {
+		log.Printf("warning: logged in to radiko premium without the area-free")
+	}
+	return nil
+}
+
+// Logout logs out of radiko premium
+func (p *Premium) Logout(ctx context.Context, client *radiko.Client) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Session == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	form := url.Values{}
+	form.Set(RadikoSessionKey, p.Session)
+	resp, err := postForm(ctx, client, APIPremiumLogout, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	p.Session = ""
+	p.AreaFree = false
+	return nil
+}
+
+// IsAreaFree returns true if logged in as an area-free member
+func (p *Premium) IsAreaFree() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.AreaFree && p.Session != ""
+}
+
+// session returns the session if logged in
+func (p *Premium) session() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Session
+}
+
+// postForm posts the form to uri with the client
+func postForm(ctx context.Context, client *radiko.Client, uri string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return client.Do(req)
+}
@@ -0,0 +1,96 @@
+package radicron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/yyoshiki41/go-radiko"
+)
+
+// rewriteTransport sends the requests to the test server
+type rewriteTransport struct {
+	server *url.URL
+	next   http.RoundTripper
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = rt.server.Scheme, rt.server.Host
+	return rt.next.RoundTrip(req)
+}
+
+func TestPremium(t *testing.T) {
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loggedOut := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/api/member/login":
+			if r.FormValue("mail") != "member@example.com" || r.FormValue("pass") != "secret" {
+				http.Error(w, `{"status":"401"}`, http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"radiko_session":"s3ss10n","paid_member":"1","areafree":"1"}`))
+		case "/v4/api/member/logout":
+			loggedOut = r.FormValue(RadikoSessionKey)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	server, _ := url.Parse(ts.URL)
+	transport := http.DefaultTransport
+	http.DefaultTransport = rewriteTransport{server: server, next: transport}
+	defer func() { http.DefaultTransport = transport }()
+
+	var premiumtests = []struct {
+		password string
+		areaFree bool
+		session  string
+	}{
+		{"wrong", false, ""},
+		{"secret", true, "s3ss10n"},
+	}
+	for _, tt := range premiumtests {
+		p := &Premium{AreaID: "JP13", Mail: "member@example.com", Password: tt.password}
+		err := p.Login(context.Background(), client)
+		if (err == nil) != (tt.session != "") {
+			t.Errorf("Login(%s) => %v", tt.password, err)
+		}
+		if p.IsAreaFree() != tt.areaFree || p.session() != tt.session {
+			t.Errorf("Login(%s) => area-free %v, session %q, want %v, %q", tt.password, p.IsAreaFree(), p.session(), tt.areaFree, tt.session)
+		}
+		if err != nil {
+			continue
+		}
+
+		// the session cookie for the following requests
+		u, _ := url.Parse(APIPlaylistM3U8)
+		found := false
+		for _, c := range client.Jar().Cookies(u) {
+			found = found || (c.Name == RadikoSessionKey && c.Value == tt.session)
+		}
+		if !found {
+			t.Errorf("Login(%s) => cookies %v, want %s", tt.password, client.Jar().Cookies(u), RadikoSessionKey)
+		}
+
+		if err = p.Logout(context.Background(), client); err != nil {
+			t.Error(err)
+		}
+		if loggedOut != tt.session || p.IsAreaFree() || p.session() != "" {
+			t.Errorf("Logout() => %q logged out, area-free %v", loggedOut, p.IsAreaFree())
+		}
+	}
+
+	// not logged in
+	var p *Premium
+	if p.IsAreaFree() || p.session() != "" {
+		t.Error("nil Premium => logged in")
+	}
+}
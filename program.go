@@ -1,62 +1,121 @@
 package radicron
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"sort"
+	"time"
 )
 
 // Prog contains the solicited program metadata
 type Prog struct {
-	ID        string
-	StationID string
-	Ft        string
-	To        string
-	Title     string
-	Desc      string
-	Info      string
-	Pfm       string
-	Tags      []string
-	Genre     ProgGenre
-	M3U8      string
+	ID         string    `json:"id"`
+	StationID  string    `json:"station_id"`
+	Ft         string    `json:"ft"`
+	To         string    `json:"to"`
+	Title      string    `json:"title"`
+	Desc       string    `json:"desc"`
+	Info       string    `json:"info"`
+	Pfm        string    `json:"pfm"`
+	Tags       []string  `json:"tags"`
+	Genre      ProgGenre `json:"genre"`
+	Img        string    `json:"img,omitempty"`
+	M3U8       string    `json:"-"`
+	SkipRerun  bool      `json:"-"`
+	Omnibus    bool      `json:"-"`
+	Artwork    string    `json:"-"`
+	Explicit   bool      `json:"-"`
+	ID3Version byte      `json:"-"`
+	Follow     bool      `json:"-"`
+	// Episode number in the followed series, kept for the workers
+	Episode int `json:"episode,omitempty"`
+	// Podcast feed to prefer over the timefree, kept for the workers
+	Podcast string `json:"podcast,omitempty"`
+	// PadBefore and PadAfter the program to record, kept for the workers
+	PadBefore time.Duration `json:"pad_before,omitempty"`
+	PadAfter  time.Duration `json:"pad_after,omitempty"`
+	// LiveFallback to capture the next airing live if the timefree is unavailable
+	LiveFallback bool `json:"-"`
+}
+
+// Duration returns the length of the program
+func (p *Prog) Duration() time.Duration {
+	ft, err := time.ParseInLocation(DatetimeLayout, p.Ft, Location)
+	if err != nil {
+		return 0
+	}
+	to, err := time.ParseInLocation(DatetimeLayout, p.To, Location)
+	if err != nil {
+		return 0
+	}
+	return to.Sub(ft)
+}
+
+// Span returns the start and the end of the recording with the padding
+func (p *Prog) Span() (ft, to time.Time, err error) {
+	if ft, err = time.ParseInLocation(DatetimeLayout, p.Ft, Location); err != nil {
+		return ft, to, err
+	}
+	if to, err = time.ParseInLocation(DatetimeLayout, p.To, Location); err != nil {
+		return ft, to, err
+	}
+	return ft.Add(-p.PadBefore), to.Add(p.PadAfter), nil
 }
 
 type ProgGenre struct {
-	Personality string
-	Program     string
+	Personality string `json:"personality"`
+	Program     string `json:"program"`
 }
 
 // Progs is a slice of Prog.
 type Progs []*Prog
 
+// SortByExpiry sorts the programs by the timefree expiry, i.e., the start
+func (ps Progs) SortByExpiry() {
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].Ft < ps[j].Ft
+	})
+}
+
 func (ps *Progs) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var xw XMLWeekly
 	if err := d.DecodeElement(&xw, &start); err != nil {
 		return err
 	}
 
-	stationID := xw.XMLStations.Station[0].StationID
-	for _, p := range xw.XMLStations.Station[0].Progs.Prog {
-		prog := &Prog{
-			ID:        p.ID,
-			StationID: stationID,
-			Ft:        p.Ft,
-			To:        p.To,
-			Title:     p.Title,
-			Desc:      p.Desc,
-			Info:      p.Info,
-			Pfm:       p.Pfm,
-			M3U8:      "",
-		}
-		prog.Genre = ProgGenre{
-			Personality: p.Genre.Personality.Name,
-			Program:     p.Genre.Program.Name,
-		}
-		for _, t := range p.Tag.Item {
-			prog.Tags = append(prog.Tags, t.Name)
+	// the weekly program has a station, the now program has the stations in the area
+	for _, station := range xw.XMLStations.Station {
+		for _, p := range station.Progs.Prog {
+			prog := &Prog{
+				ID:         p.ID,
+				StationID:  station.StationID,
+				Ft:         p.Ft,
+				To:         p.To,
+				Title:      p.Title,
+				Desc:       p.Desc,
+				Info:       p.Info,
+				Pfm:        p.Pfm,
+				Img:        p.Img,
+				M3U8:       "",
+				SkipRerun:  false,
+				Omnibus:    false,
+				Artwork:    "",
+				Explicit:   false,
+				ID3Version: 0,
+			}
+			prog.Genre = ProgGenre{
+				Personality: p.Genre.Personality.Name,
+				Program:     p.Genre.Program.Name,
+			}
+			for _, t := range p.Tag.Item {
+				prog.Tags = append(prog.Tags, t.Name)
+			}
+			*ps = append(*ps, prog)
 		}
-		*ps = append(*ps, prog)
 	}
 
 	return nil
@@ -71,6 +130,7 @@ type XMLProg struct {
 	Desc  string `xml:"desc"`
 	Info  string `xml:"info"`
 	Pfm   string `xml:"pfm"`
+	Img   string `xml:"img"`
 	Tag   struct {
 		Item []XMLProgItem `xml:"item"`
 	} `xml:"tag"`
@@ -105,10 +165,26 @@ type XMLWeeklyStation struct {
 }
 
 // FetchWeeklyPrograms returns the weekly programs.
-func FetchWeeklyPrograms(stationID string) (Progs, error) {
+func FetchWeeklyPrograms(ctx context.Context, stationID string) (Progs, error) {
 	endpoint := fmt.Sprintf(APIWeeklyProgram, stationID)
 
-	resp, err := http.Get(endpoint) //nolint:gosec,noctx
+	return fetchPrograms(ctx, endpoint)
+}
+
+// FetchNowPrograms returns the programs on air in the area.
+func FetchNowPrograms(ctx context.Context, areaID string) (Progs, error) {
+	endpoint := fmt.Sprintf(APINowProgram, areaID)
+
+	return fetchPrograms(ctx, endpoint)
+}
+
+// fetchPrograms returns the programs at the endpoint
+func fetchPrograms(ctx context.Context, endpoint string) (Progs, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return Progs{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return Progs{}, err
 	}
@@ -117,6 +193,16 @@ func FetchWeeklyPrograms(stationID string) (Progs, error) {
 	return decodeWeeklyProgram(resp.Body)
 }
 
+// LoadWeeklyPrograms returns the programs in a weekly program XML file
+func LoadWeeklyPrograms(path string) (Progs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Progs{}, err
+	}
+	defer f.Close()
+	return decodeWeeklyProgram(f)
+}
+
 func decodeWeeklyProgram(iorc io.ReadCloser) (Progs, error) {
 	progs := Progs{}
 	body, err := io.ReadAll(iorc)
@@ -4,11 +4,14 @@ import (
 	"embed"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
 	//go:embed test/weekly-program-test.xml
 	WeeklyProgramTestXML embed.FS
+	//go:embed test/now-program-test.xml
+	NowProgramTestXML embed.FS
 )
 
 func TestWeeklyProgramUnmarshal(t *testing.T) {
@@ -57,6 +60,12 @@ func TestWeeklyProgramUnmarshal(t *testing.T) {
 		t.Errorf("p.Pfm => %v, want %v", got, want)
 	}
 
+	got = p.Img
+	want = "https://radiko.jp/res/program/DEFAULT_IMAGE/FMT/u2vys0cxtq.jpg"
+	if got != want {
+		t.Errorf("p.Img => %v, want %v", got, want)
+	}
+
 	got = p.Genre.Personality
 	want = "タレント"
 	if got != want {
@@ -75,3 +84,54 @@ func TestWeeklyProgramUnmarshal(t *testing.T) {
 		t.Errorf("p.Tags => %v, want %v", got, want)
 	}
 }
+
+func TestNowProgramUnmarshal(t *testing.T) {
+	xmlFile, err := NowProgramTestXML.Open("test/now-program-test.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	progs, err := decodeWeeklyProgram(xmlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nowtests = []struct {
+		stationID string
+		title     string
+	}{
+		{"TBS", "荻上チキ・Session"},
+		{"FMT", "THE TRAD"},
+	}
+	if len(progs) != len(nowtests) {
+		t.Fatalf("unmarshal => %v programs, want %v", len(progs), len(nowtests))
+	}
+	for i, tt := range nowtests {
+		if progs[i].StationID != tt.stationID || progs[i].Title != tt.title {
+			t.Errorf("progs[%d] => [%s]%s, want [%s]%s", i, progs[i].StationID, progs[i].Title, tt.stationID, tt.title)
+		}
+	}
+}
+
+func TestProgDuration(t *testing.T) {
+	p := &Prog{Ft: "20230625235000", To: "20230626011000"}
+	if got := p.Duration(); got != 80*time.Minute {
+		t.Errorf("Duration => %v, want 80m", got)
+	}
+	if got := (&Prog{Ft: "invalid"}).Duration(); got != 0 {
+		t.Errorf("Duration => %v, want 0", got)
+	}
+}
+
+func TestProgsSortByExpiry(t *testing.T) {
+	progs := Progs{
+		{ID: "new", Ft: "20230625050000"},
+		{ID: "old", Ft: "20230620230000"},
+		{ID: "mid", Ft: "20230622120000"},
+	}
+	progs.SortByExpiry()
+	for i, want := range []string{"old", "mid", "new"} {
+		if progs[i].ID != want {
+			t.Errorf("progs[%d] => %s, want %s", i, progs[i].ID, want)
+		}
+	}
+}
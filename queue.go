@@ -0,0 +1,192 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// claimedSuffix for the jobs taken by a worker
+const claimedSuffix = ".claimed"
+
+// JobQueue passes the programs from the scheduler to the download workers
+type JobQueue interface {
+	// Claim takes the next job not taken by another worker, or returns nil if none
+	Claim() (*Job, error)
+	// Done removes the job
+	Done(job *Job) error
+	// Enqueue adds the program unless it is already queued or claimed
+	Enqueue(prog *Prog) (bool, error)
+	// Requeue gives the jobs claimed before the timeout back to the other workers
+	Requeue(timeout time.Duration) (int, error)
+}
+
+// Job is a program to download by a worker
+type Job struct {
+	Prog *Prog
+	// the claimed file or the key in the queue
+	key string
+}
+
+// FileJobQueue is the embedded JobQueue on the shared storage
+type FileJobQueue struct {
+	Dir string
+}
+
+// Claim implements JobQueue
+func (q *FileJobQueue) Claim() (*Job, error) {
+	entries, err := os.ReadDir(q.Dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	modTimes := map[string]time.Time{}
+	fts := map[string]string{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		names = append(names, e.Name())
+		modTimes[e.Name()] = info.ModTime()
+		fts[e.Name()] = jobFt(filepath.Join(q.Dir, e.Name()))
+	}
+	// the job expiring first, i.e., the earliest start
+	sort.SliceStable(names, func(i, j int) bool {
+		if fts[names[i]] != fts[names[j]] {
+			return fts[names[i]] < fts[names[j]]
+		}
+		return modTimes[names[i]].Before(modTimes[names[j]])
+	})
+
+	for _, name := range names {
+		path := filepath.Join(q.Dir, name)
+		claimed := path + claimedSuffix
+		// another worker took it first
+		if err = os.Rename(path, claimed); err != nil {
+			continue
+		}
+		now := time.Now()
+		if err = os.Chtimes(claimed, now, now); err != nil {
+			return nil, err
+		}
+		blob, err := os.ReadFile(claimed)
+		if err != nil {
+			return nil, err
+		}
+		prog := &Prog{}
+		if err = json.Unmarshal(blob, prog); err != nil {
+			_ = os.Remove(claimed)
+			return nil, fmt.Errorf("invalid job %s: %s", name, err)
+		}
+		return &Job{Prog: prog, key: claimed}, nil
+	}
+	return nil, nil
+}
+
+// Done implements JobQueue
+func (q *FileJobQueue) Done(job *Job) error {
+	return os.Remove(job.key)
+}
+
+// Enqueue implements JobQueue
+func (q *FileJobQueue) Enqueue(prog *Prog) (bool, error) {
+	path := q.jobPath(prog)
+	for _, p := range []string{path, path + claimedSuffix} {
+		if _, err := os.Stat(p); err == nil {
+			return false, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	blob, err := json.Marshal(prog)
+	if err != nil {
+		return false, err
+	}
+	// not to be claimed while writing
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, blob, 0o644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// Requeue implements JobQueue
+func (q *FileJobQueue) Requeue(timeout time.Duration) (int, error) {
+	entries, err := os.ReadDir(q.Dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), claimedSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < timeout {
+			continue
+		}
+		path := filepath.Join(q.Dir, e.Name())
+		if err = os.Rename(path, strings.TrimSuffix(path, claimedSuffix)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// jobFt returns the start of the program in the job, or empty if unreadable
+func jobFt(path string) string {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	prog := &Prog{}
+	if err = json.Unmarshal(blob, prog); err != nil {
+		return ""
+	}
+	return prog.Ft
+}
+
+func (q *FileJobQueue) jobPath(prog *Prog) string {
+	return filepath.Join(q.Dir, sanitizeFileName(prog.StationID+"_"+prog.ID)+".json")
+}
+
+// NewFileJobQueue returns the FileJobQueue in ${RADICRON_HOME}
+func NewFileJobQueue() (*FileJobQueue, error) {
+	dir, err := getRadicronPath(JobsDirName)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileJobQueue{Dir: dir}, nil
+}
+
+// NewJobQueue returns the JobQueue for the URL, or the FileJobQueue if empty
+// - "redis://[:password@]host:port[/db]" for the RedisJobQueue
+func NewJobQueue(rawURL string) (JobQueue, error) {
+	if rawURL == "" {
+		return NewFileJobQueue()
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job queue: %s", err)
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedisJobQueue(u)
+	default:
+		return nil, fmt.Errorf("unsupported job queue: %s", u.Scheme)
+	}
+}
@@ -0,0 +1,119 @@
+package radicron
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobQueue(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	q, err := NewFileJobQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", To: "20230625060000", Title: "Title"}
+
+	var enqueuetests = []struct {
+		name string
+		want bool
+	}{
+		{"new", true},
+		{"queued", false},
+	}
+	for _, tt := range enqueuetests {
+		got, err := q.Enqueue(prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Enqueue(%s) => %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	job, err := q.Claim()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.Prog.ID != prog.ID || job.Prog.Title != prog.Title {
+		t.Fatalf("Claim => %+v, want %v", job, prog.ID)
+	}
+	if job, err = q.Claim(); err != nil || job != nil {
+		t.Errorf("Claim the claimed => %v, %v, want nil", job, err)
+	}
+	if ok, _ := q.Enqueue(prog); ok {
+		t.Error("Enqueue the claimed => true, want false")
+	}
+
+	// the worker has gone away
+	if n, err := q.Requeue(time.Hour); err != nil || n != 0 {
+		t.Errorf("Requeue the fresh claim => %v, %v, want 0", n, err)
+	}
+	if n, err := q.Requeue(0); err != nil || n != 1 {
+		t.Errorf("Requeue => %v, %v, want 1", n, err)
+	}
+	job, err = q.Claim()
+	if err != nil || job == nil {
+		t.Fatalf("Claim the requeued => %v, %v", job, err)
+	}
+	if err = q.Done(job); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(q.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("jobs after Done => %v, want none", entries)
+	}
+}
+
+func TestJobQueueExpiryOrder(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	q, err := NewFileJobQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// queued later but expiring first
+	for _, prog := range []*Prog{
+		{ID: "new", StationID: "FMT", Ft: "20230625050000"},
+		{ID: "old", StationID: "FMT", Ft: "20230620050000"},
+	} {
+		if _, err = q.Enqueue(prog); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"old", "new"} {
+		job, err := q.Claim()
+		if err != nil || job == nil {
+			t.Fatalf("Claim => %v, %v", job, err)
+		}
+		if job.Prog.ID != want {
+			t.Errorf("Claim => %s, want %s", job.Prog.ID, want)
+		}
+	}
+}
+
+func TestDownloadQueue(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	q, err := NewFileJobQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", To: "20230625060000", Title: "Title"}
+	CurrentTime = time.Date(2023, 6, 26, 0, 0, 0, 0, Location)
+
+	asset := &Asset{Queue: q}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	var wg sync.WaitGroup
+	if err = Download(ctx, &wg, prog); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	job, err := q.Claim()
+	if err != nil || job == nil || job.Prog.ID != prog.ID {
+		t.Errorf("Claim => %v, %v, want the queued %v", job, err, prog.ID)
+	}
+}
@@ -0,0 +1,179 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// radiruServices are the services of the radiru stations
+var radiruServices = map[string]string{
+	"NHK-R1": "r1",
+	"NHK-R2": "r2",
+	"NHK-FM": "r3",
+}
+
+// RadiruStationIDs are the stations of NHK らじる★らじる
+var RadiruStationIDs = []string{"NHK-R1", "NHK-R2", "NHK-FM"}
+
+// Radiru is NHK らじる★らじる in the area, the second source of the programs besides radiko
+type Radiru struct {
+	Area *RadiruArea
+}
+
+// RadiruArea is the area in the radiru config with the live streams
+type RadiruArea struct {
+	Name   string `xml:"area"`
+	NameJP string `xml:"areajp"`
+	Key    string `xml:"areakey"`
+	R1HLS  string `xml:"r1hls"`
+	R2HLS  string `xml:"r2hls"`
+	FMHLS  string `xml:"fmhls"`
+}
+
+// radiruConfig is the config of radiru
+type radiruConfig struct {
+	Areas []*RadiruArea `xml:"stream_url>data"`
+}
+
+// radiruProgram is a program in the radiru guide
+type radiruProgram struct {
+	ID        string `json:"id"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Title     string `json:"title"`
+	Subtitle  string `json:"subtitle"`
+	Content   string `json:"content"`
+	Act       string `json:"act"`
+}
+
+// radiruGuide is the radiru programs of a day by the service
+type radiruGuide struct {
+	List map[string][]*radiruProgram `json:"list"`
+}
+
+// IsRadiruStation returns true if the station is of radiru, e.g., NHK-FM
+func IsRadiruStation(stationID string) bool {
+	_, ok := radiruServices[stationID]
+	return ok
+}
+
+// LoadRadiru returns radiru in the area, e.g., tokyo
+func LoadRadiru(ctx context.Context, area string) (*Radiru, error) {
+	body, err := radiruGet(ctx, APIRadiruConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the radiru config: %s", err)
+	}
+	defer body.Close()
+	return decodeRadiruConfig(body, area)
+}
+
+// decodeRadiruConfig returns radiru in the area from the config
+func decodeRadiruConfig(r io.Reader, area string) (*Radiru, error) {
+	config := &radiruConfig{}
+	if err := xml.NewDecoder(r).Decode(config); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, a := range config.Areas {
+		if strings.EqualFold(a.Name, area) || a.NameJP == area {
+			return &Radiru{Area: a}, nil
+		}
+		names = append(names, a.Name)
+	}
+	return nil, fmt.Errorf("unknown radiru area: %s (%s)", area, strings.Join(names, ", "))
+}
+
+// StreamURI returns the live stream of the station
+func (rr *Radiru) StreamURI(stationID string) (string, error) {
+	uri := ""
+	switch radiruServices[stationID] {
+	case "r1":
+		uri = rr.Area.R1HLS
+	case "r2":
+		uri = rr.Area.R2HLS
+	case "r3":
+		uri = rr.Area.FMHLS
+	}
+	if uri == "" {
+		return "", fmt.Errorf("no radiru stream of %s in %s", stationID, rr.Area.Name)
+	}
+	return uri, nil
+}
+
+// FetchPrograms returns the programs of the station for RadiruGuideDays from today
+func (rr *Radiru) FetchPrograms(ctx context.Context, stationID string) (Progs, error) {
+	service, ok := radiruServices[stationID]
+	if !ok {
+		return Progs{}, fmt.Errorf("unknown radiru station: %s", stationID)
+	}
+	progs := Progs{}
+	today := time.Now().In(Location)
+	for d := 0; d < RadiruGuideDays; d++ {
+		date := today.AddDate(0, 0, d).Format(RadiruDateLayout)
+		body, err := radiruGet(ctx, fmt.Sprintf(APIRadiruProgramDay, rr.Area.Key, service, date))
+		if err != nil {
+			return progs, err
+		}
+		day, err := decodeRadiruPrograms(body, stationID, service)
+		body.Close()
+		if err != nil {
+			return progs, fmt.Errorf("invalid radiru programs of %s on %s: %s", stationID, date, err)
+		}
+		progs = append(progs, day...)
+	}
+	return progs, nil
+}
+
+// decodeRadiruPrograms returns the programs of the station in the radiru guide of the service
+func decodeRadiruPrograms(r io.Reader, stationID, service string) (Progs, error) {
+	guide := &radiruGuide{}
+	if err := json.NewDecoder(r).Decode(guide); err != nil {
+		return nil, err
+	}
+	progs := Progs{}
+	for _, p := range guide.List[service] {
+		ft, err := time.Parse(time.RFC3339, p.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		to, err := time.Parse(time.RFC3339, p.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		progs = append(progs, &Prog{
+			ID:        "radiru_" + p.ID,
+			StationID: stationID,
+			Ft:        ft.In(Location).Format(DatetimeLayout),
+			To:        to.In(Location).Format(DatetimeLayout),
+			Title:     p.Title,
+			Desc:      p.Content,
+			Info:      p.Subtitle,
+			Pfm:       p.Act,
+			Tags:      []string{},
+		})
+	}
+	return progs, nil
+}
+
+// radiruGet returns the body of the radiru API at the uri
+func radiruGet(ctx context.Context, uri string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s: %s", uri, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,77 @@
+package radicron
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDecodeRadiruConfig(t *testing.T) {
+	var configtests = []struct {
+		area string
+		key  string
+		fm   string
+	}{
+		{"tokyo", "130", "https://radio-stream.nhk.jp/hls/live/2023507/nhkradiruakfm/master.m3u8"},
+		{"札幌", "010", "https://radio-stream.nhk.jp/hls/live/2023546/nhkradirubkfm/master.m3u8"},
+		{"naha", "", ""},
+	}
+	for _, tt := range configtests {
+		f, err := os.Open("test/radiru-config-test.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		radiru, err := decodeRadiruConfig(f, tt.area)
+		f.Close()
+		if tt.key == "" {
+			if err == nil {
+				t.Errorf("decodeRadiruConfig(%s) => %v, want an error", tt.area, radiru.Area)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if radiru.Area.Key != tt.key {
+			t.Errorf("decodeRadiruConfig(%s) => %v, want %v", tt.area, radiru.Area.Key, tt.key)
+		}
+		if got, err := radiru.StreamURI("NHK-FM"); err != nil || got != tt.fm {
+			t.Errorf("StreamURI(NHK-FM) => %v, %v, want %v", got, err, tt.fm)
+		}
+	}
+	if _, err := (&Radiru{Area: &RadiruArea{Name: "tokyo"}}).StreamURI("FMT"); err == nil {
+		t.Error("StreamURI(FMT) => no error")
+	}
+}
+
+func TestDecodeRadiruPrograms(t *testing.T) {
+	f, err := os.Open("test/radiru-program-test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	progs, err := decodeRadiruPrograms(f, "NHK-FM", "r3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progs) != 2 {
+		t.Fatalf("decodeRadiruPrograms => %d programs, want 2", len(progs))
+	}
+	want := &Prog{
+		ID:        "radiru_2023060512345",
+		StationID: "NHK-FM",
+		Ft:        "20230605140000",
+		To:        "20230605155000",
+		Title:     "ミュージックライン",
+		Desc:      "新しい音楽を紹介します",
+		Info:      "今週のテーマ",
+		Pfm:       "南沢奈央",
+	}
+	got := progs[0]
+	if got.ID != want.ID || got.StationID != want.StationID || got.Ft != want.Ft || got.To != want.To ||
+		got.Title != want.Title || got.Desc != want.Desc || got.Info != want.Info || got.Pfm != want.Pfm {
+		t.Errorf("decodeRadiruPrograms => %+v, want %+v", got, want)
+	}
+	if !IsRadiruStation(got.StationID) || IsRadiruStation("JOAK-FM") {
+		t.Error("IsRadiruStation => wrong station")
+	}
+}
@@ -0,0 +1,107 @@
+package radicron
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// LogRedactor is the default Redactor to set as the log output
+var LogRedactor = NewRedactor(os.Stderr)
+
+// secretParamPattern matches the secret query parameters in URLs,
+// only the whole names, e.g., not bypass= or monkey=
+var secretParamPattern = regexp.MustCompile(`(?i)((?:^|[?&;\s"'])(?:token|session|password|pass|secret|key)=)[^&\s"']+`)
+
+// Redactor masks the registered secrets before writing the logs
+type Redactor struct {
+	enabled bool
+	out     io.Writer
+	secrets []*redactedSecret
+	mu      sync.RWMutex
+}
+
+// redactedSecret is a secret to mask, replaced by the next one of the key
+type redactedSecret struct {
+	key   string
+	value []byte
+}
+
+// AddSecret registers a secret to mask
+func (r *Redactor) AddSecret(secret string) {
+	r.SetSecret(secret, secret)
+}
+
+// SetSecret registers a secret to mask in place of the previous one of the key,
+// e.g., the auth token refreshed for an area, not to keep all the expired
+func (r *Redactor) SetSecret(key, secret string) {
+	if len(secret) < MinSecretLength {
+		return // too short to mask without breaking the logs
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.secrets {
+		if s.key == key {
+			s.value = []byte(secret)
+			return
+		}
+	}
+	r.secrets = append(r.secrets, &redactedSecret{key: key, value: []byte(secret)})
+}
+
+// Redact returns p with the secrets masked
+func (r *Redactor) Redact(p []byte) []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.enabled {
+		return p
+	}
+	for _, s := range r.secrets {
+		p = bytes.ReplaceAll(p, s.value, []byte(RedactedMask))
+	}
+	return secretParamPattern.ReplaceAll(p, []byte("${1}"+RedactedMask))
+}
+
+// SetEnabled toggles the redaction, e.g., to show the secrets for debugging
+func (r *Redactor) SetEnabled(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = enabled
+}
+
+// SetOutput sets the destination of the redacted logs
+func (r *Redactor) SetOutput(out io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.out = out
+}
+
+// Write implements io.Writer
+func (r *Redactor) Write(p []byte) (int, error) {
+	redacted := r.Redact(p)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, err := r.out.Write(redacted); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func NewRedactor(out io.Writer) *Redactor {
+	return &Redactor{
+		enabled: true,
+		out:     out,
+	}
+}
+
+// RegisterSecret registers a secret to mask in the LogRedactor
+func RegisterSecret(secret string) {
+	LogRedactor.AddSecret(secret)
+}
+
+// ReplaceSecret registers a secret to mask in the LogRedactor in place of the previous one of the key
+func ReplaceSecret(key, secret string) {
+	LogRedactor.SetSecret(key, secret)
+}
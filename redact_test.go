@@ -0,0 +1,87 @@
+package radicron
+
+import (
+	"bytes"
+	"testing"
+)
+
+var redacttests = []struct {
+	in  string
+	out string
+}{
+	{
+		"authorized with abcdefghijklmnop",
+		"authorized with " + RedactedMask,
+	},
+	{
+		"https://example.com/hook?token=foobar&x=1",
+		"https://example.com/hook?token=" + RedactedMask + "&x=1",
+	},
+	{
+		"nothing to hide",
+		"nothing to hide",
+	},
+	{
+		"key=foobar",
+		"key=" + RedactedMask,
+	},
+	{
+		"login with password=foobar&session=bazqux",
+		"login with password=" + RedactedMask + "&session=" + RedactedMask,
+	},
+	{
+		"https://example.com/?bypass=1&monkey=2&passkey=3&apikey_id=4",
+		"https://example.com/?bypass=1&monkey=2&passkey=3&apikey_id=4",
+	},
+	{
+		"https://example.com/?a=1;token=foobar",
+		"https://example.com/?a=1;token=" + RedactedMask,
+	},
+}
+
+func TestRedactor(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRedactor(&buf)
+	r.AddSecret("abcdefghijklmnop")
+	r.AddSecret("abc") // too short
+	for _, tt := range redacttests {
+		buf.Reset()
+		n, err := r.Write([]byte(tt.in))
+		if err != nil {
+			t.Error(err)
+		}
+		if n != len(tt.in) {
+			t.Errorf("Write(%v) => %v, want %v", tt.in, n, len(tt.in))
+		}
+		if buf.String() != tt.out {
+			t.Errorf("Write(%v) => %v, want %v", tt.in, buf.String(), tt.out)
+		}
+	}
+
+	// opt-out
+	buf.Reset()
+	r.SetEnabled(false)
+	if _, err := r.Write([]byte(redacttests[0].in)); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != redacttests[0].in {
+		t.Errorf("Write(%v) => %v, want %v", redacttests[0].in, buf.String(), redacttests[0].in)
+	}
+}
+
+func TestRedactorSetSecret(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRedactor(&buf)
+	r.SetSecret("auth-token:JP13", "token-abcdefghijklmnop")
+	r.SetSecret("auth-token:JP13", "token-qrstuvwxyz012345")
+	r.SetSecret("auth-token:JP27", "token-6789abcdefghijkl")
+	if len(r.secrets) != 2 {
+		t.Errorf("secrets => %v, want 2", len(r.secrets))
+	}
+	if _, err := r.Write([]byte("token-qrstuvwxyz012345 token-6789abcdefghijkl")); err != nil {
+		t.Error(err)
+	}
+	if want := RedactedMask + " " + RedactedMask; buf.String() != want {
+		t.Errorf("Write => %v, want %v", buf.String(), want)
+	}
+}
@@ -0,0 +1,268 @@
+package radicron
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisJobQueue is the JobQueue on Redis (6.2 or later) for the workers without the shared storage
+type RedisJobQueue struct {
+	// Prefix of the keys
+	Prefix string
+	conn   *redisConn
+}
+
+// Claim implements JobQueue
+func (q *RedisJobQueue) Claim() (*Job, error) {
+	reply, err := q.conn.do("LMOVE", q.key("pending"), q.key("claimed"), "LEFT", "RIGHT")
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	id, _ := reply.(string)
+	if _, err = q.conn.do("HSET", q.key("claimed-at"), id, strconv.FormatInt(time.Now().Unix(), 10)); err != nil {
+		return nil, err
+	}
+	reply, err = q.conn.do("GET", q.key("job", id))
+	if err != nil {
+		return nil, err
+	}
+	blob, ok := reply.(string)
+	if !ok {
+		_ = q.Done(&Job{key: id})
+		return nil, fmt.Errorf("missing job %s", id)
+	}
+	prog := &Prog{}
+	if err = json.Unmarshal([]byte(blob), prog); err != nil {
+		_ = q.Done(&Job{key: id})
+		return nil, fmt.Errorf("invalid job %s: %s", id, err)
+	}
+	return &Job{Prog: prog, key: id}, nil
+}
+
+// Done implements JobQueue
+func (q *RedisJobQueue) Done(job *Job) error {
+	for _, args := range [][]string{
+		{"LREM", q.key("claimed"), "0", job.key},
+		{"HDEL", q.key("claimed-at"), job.key},
+		{"DEL", q.key("job", job.key)},
+	} {
+		if _, err := q.conn.do(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue implements JobQueue
+func (q *RedisJobQueue) Enqueue(prog *Prog) (bool, error) {
+	blob, err := json.Marshal(prog)
+	if err != nil {
+		return false, err
+	}
+	id := prog.StationID + "_" + prog.ID
+	// the job stays until done
+	reply, err := q.conn.do("SET", q.key("job", id), string(blob), "NX")
+	if err != nil || reply == nil {
+		return false, err
+	}
+	if _, err = q.conn.do("RPUSH", q.key("pending"), id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Requeue implements JobQueue
+func (q *RedisJobQueue) Requeue(timeout time.Duration) (int, error) {
+	reply, err := q.conn.do("LRANGE", q.key("claimed"), "0", "-1")
+	if err != nil {
+		return 0, err
+	}
+	ids, _ := reply.([]any)
+	n := 0
+	for _, v := range ids {
+		id, _ := v.(string)
+		reply, err = q.conn.do("HGET", q.key("claimed-at"), id)
+		if err != nil {
+			return n, err
+		}
+		// the claim without the time is from a worker gone away while claiming
+		if s, ok := reply.(string); ok {
+			if claimedAt, err := strconv.ParseInt(s, 10, 64); err == nil && time.Since(time.Unix(claimedAt, 0)) < timeout {
+				continue
+			}
+		}
+		// another scheduler requeued it first
+		if reply, err = q.conn.do("LREM", q.key("claimed"), "1", id); err != nil {
+			return n, err
+		} else if removed, _ := reply.(int64); removed == 0 {
+			continue
+		}
+		if _, err = q.conn.do("HDEL", q.key("claimed-at"), id); err != nil {
+			return n, err
+		}
+		if _, err = q.conn.do("RPUSH", q.key("pending"), id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (q *RedisJobQueue) key(name ...string) string {
+	return q.Prefix + strings.Join(name, ":")
+}
+
+// NewRedisJobQueue returns the RedisJobQueue for redis://[:password@]host:port[/db]
+func NewRedisJobQueue(u *url.URL) (*RedisJobQueue, error) {
+	conn := &redisConn{addr: u.Host}
+	if conn.addr == "" {
+		return nil, errors.New("missing the redis host")
+	}
+	if _, _, err := net.SplitHostPort(conn.addr); err != nil {
+		conn.addr = net.JoinHostPort(conn.addr, RedisDefaultPort)
+	}
+	if password, ok := u.User.Password(); ok {
+		RegisterSecret(password)
+		conn.password = password
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if _, err := strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis db: %s", db)
+		}
+		conn.db = db
+	}
+	q := &RedisJobQueue{Prefix: RedisKeyPrefix, conn: conn}
+	// fail early with the wrong address or password
+	if _, err := conn.do("PING"); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// redisConn is a minimal client of the Redis protocol (RESP2) for the job queue
+type redisConn struct {
+	addr     string
+	password string
+	db       string
+	mu       sync.Mutex
+	conn     net.Conn
+	r        *bufio.Reader
+}
+
+// do sends the command and returns the reply: string, int64, []any, or nil
+func (c *redisConn) do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(args)
+	// reconnect on the next command
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+func (c *redisConn) dial() error {
+	conn, err := net.DialTimeout("tcp", c.addr, RedisTimeoutSeconds*time.Second)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err = c.roundTrip([]string{"AUTH", c.password}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return fmt.Errorf("failed to authenticate to redis: %s", err)
+		}
+	}
+	if c.db != "" {
+		if _, err = c.roundTrip([]string{"SELECT", c.db}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return fmt.Errorf("failed to select the redis db: %s", err)
+		}
+	}
+	return nil
+}
+
+func (c *redisConn) roundTrip(args []string) (any, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(RedisTimeoutSeconds * time.Second)); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(c.r)
+}
+
+// redisError is the error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// readRESP reads a reply in RESP2
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		blob := make([]byte, n+2)
+		if _, err = io.ReadFull(r, blob); err != nil {
+			return nil, err
+		}
+		return string(blob[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("invalid redis reply: %q", line)
+	}
+}
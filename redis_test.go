@@ -0,0 +1,267 @@
+package radicron
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the commands used by the RedisJobQueue in memory
+type fakeRedis struct {
+	mu       sync.Mutex
+	password string
+	strings  map[string]string
+	lists    map[string][]string
+	hashes   map[string]map[string]string
+	commands []string
+}
+
+func (f *fakeRedis) serve(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		v, err := readRESP(r)
+		if err != nil {
+			return
+		}
+		values, _ := v.([]any)
+		args := make([]string, len(values))
+		for i, a := range values {
+			args[i], _ = a.(string)
+		}
+		if _, err = conn.Write([]byte(f.exec(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, args[0])
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+	integer := func(n int) string { return ":" + strconv.Itoa(n) + "\r\n" }
+	lrem := func(key, value string, count int) int {
+		n := 0
+		list := []string{}
+		for _, v := range f.lists[key] {
+			if v == value && (count == 0 || n < count) {
+				n++
+				continue
+			}
+			list = append(list, v)
+		}
+		f.lists[key] = list
+		return n
+	}
+	switch args[0] {
+	case "AUTH":
+		if args[1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		if _, ok := f.strings[args[1]]; ok {
+			return "$-1\r\n"
+		}
+		f.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		if v, ok := f.strings[args[1]]; ok {
+			return bulk(v)
+		}
+		return "$-1\r\n"
+	case "DEL":
+		delete(f.strings, args[1])
+		return integer(1)
+	case "RPUSH":
+		f.lists[args[1]] = append(f.lists[args[1]], args[2])
+		return integer(len(f.lists[args[1]]))
+	case "LMOVE":
+		if len(f.lists[args[1]]) == 0 {
+			return "$-1\r\n"
+		}
+		v := f.lists[args[1]][0]
+		f.lists[args[1]] = f.lists[args[1]][1:]
+		f.lists[args[2]] = append(f.lists[args[2]], v)
+		return bulk(v)
+	case "LRANGE":
+		reply := fmt.Sprintf("*%d\r\n", len(f.lists[args[1]]))
+		for _, v := range f.lists[args[1]] {
+			reply += bulk(v)
+		}
+		return reply
+	case "LREM":
+		count, _ := strconv.Atoi(args[2])
+		return integer(lrem(args[1], args[3], count))
+	case "HSET":
+		if f.hashes[args[1]] == nil {
+			f.hashes[args[1]] = map[string]string{}
+		}
+		f.hashes[args[1]][args[2]] = args[3]
+		return integer(1)
+	case "HGET":
+		if v, ok := f.hashes[args[1]][args[2]]; ok {
+			return bulk(v)
+		}
+		return "$-1\r\n"
+	case "HDEL":
+		delete(f.hashes[args[1]], args[2])
+		return integer(1)
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestRedisJobQueue(t *testing.T) {
+	f := &fakeRedis{
+		password: "secret",
+		strings:  map[string]string{},
+		lists:    map[string][]string{},
+		hashes:   map[string]map[string]string{},
+	}
+	addr := f.serve(t)
+
+	if _, err := NewJobQueue("redis://:wrong@" + addr); err == nil {
+		t.Error("NewJobQueue with the wrong password => nil, want an error")
+	}
+	jq, err := NewJobQueue("redis://:secret@" + addr + "/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, ok := jq.(*RedisJobQueue)
+	if !ok {
+		t.Fatalf("NewJobQueue => %T, want *RedisJobQueue", jq)
+	}
+
+	prog := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", To: "20230625060000", Title: "Title"}
+	var enqueuetests = []struct {
+		name string
+		want bool
+	}{
+		{"new", true},
+		{"queued", false},
+	}
+	for _, tt := range enqueuetests {
+		got, err := q.Enqueue(prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Enqueue(%s) => %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	job, err := q.Claim()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.Prog.ID != prog.ID || job.Prog.Title != prog.Title {
+		t.Fatalf("Claim => %+v, want %v", job, prog.ID)
+	}
+	if job, err = q.Claim(); err != nil || job != nil {
+		t.Errorf("Claim the claimed => %v, %v, want nil", job, err)
+	}
+	if ok, _ := q.Enqueue(prog); ok {
+		t.Error("Enqueue the claimed => true, want false")
+	}
+
+	// the worker has gone away
+	if n, err := q.Requeue(time.Hour); err != nil || n != 0 {
+		t.Errorf("Requeue the fresh claim => %v, %v, want 0", n, err)
+	}
+	if n, err := q.Requeue(0); err != nil || n != 1 {
+		t.Errorf("Requeue => %v, %v, want 1", n, err)
+	}
+	job, err = q.Claim()
+	if err != nil || job == nil {
+		t.Fatalf("Claim the requeued => %v, %v", job, err)
+	}
+	if err = q.Done(job); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.strings) != 0 || len(f.lists[q.key("pending")]) != 0 || len(f.lists[q.key("claimed")]) != 0 {
+		t.Errorf("after Done => %v, %v, want none", f.strings, f.lists)
+	}
+	if got := strings.Join(f.commands[:3], " "); got != "AUTH AUTH SELECT" {
+		t.Errorf("handshake => %v, want AUTH AUTH SELECT", got)
+	}
+}
+
+func TestNewJobQueue(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	var jobqueuetests = []struct {
+		rawURL string
+		want   string
+		ok     bool
+	}{
+		{"", "*radicron.FileJobQueue", true},
+		{"amqp://localhost", "", false},
+		{"redis://", "", false},
+		{"redis://localhost/db", "", false},
+	}
+	for _, tt := range jobqueuetests {
+		q, err := NewJobQueue(tt.rawURL)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewJobQueue(%q) => %v, want ok %v", tt.rawURL, err, tt.ok)
+		}
+		if tt.ok && reflect.TypeOf(q).String() != tt.want {
+			t.Errorf("NewJobQueue(%q) => %T, want %v", tt.rawURL, q, tt.want)
+		}
+	}
+}
+
+func TestReadRESP(t *testing.T) {
+	var resptests = []struct {
+		input string
+		want  any
+		ok    bool
+	}{
+		{"+OK\r\n", "OK", true},
+		{"-ERR wrong\r\n", nil, false},
+		{":42\r\n", int64(42), true},
+		{"$5\r\nhello\r\n", "hello", true},
+		{"$-1\r\n", nil, true},
+		{"*2\r\n$1\r\na\r\n:1\r\n", []any{"a", int64(1)}, true},
+		{"?\r\n", nil, false},
+	}
+	for _, tt := range resptests {
+		got, err := readRESP(bufio.NewReader(strings.NewReader(tt.input)))
+		if (err == nil) != tt.ok {
+			t.Errorf("readRESP(%q) => %v, want ok %v", tt.input, err, tt.ok)
+		}
+		if tt.ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readRESP(%q) => %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package radicron
 
 import (
+	"context"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 )
 
+// areaPattern matches the area in the response of the area check
+var areaPattern = regexp.MustCompile(`class="(JP[0-9]+|OUT)"`)
+
 type XMLRegion struct {
 	Region []XMLRegionStations `xml:"stations"`
 }
@@ -23,10 +29,14 @@ type XMLRegionStation struct {
 	Ruby   string `xml:"ruby"`
 }
 
-func FetchXMLRegion() (XMLRegion, error) {
+func FetchXMLRegion(ctx context.Context) (XMLRegion, error) {
 	region := XMLRegion{}
 
-	resp, err := http.Get(APIRegionFull) //nolint:noctx
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, APIRegionFull, http.NoBody)
+	if err != nil {
+		return region, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return region, err
 	}
@@ -42,3 +52,63 @@ func FetchXMLRegion() (XMLRegion, error) {
 
 	return region, nil
 }
+
+// XMLStationList is the stations on air in the area
+type XMLStationList struct {
+	AreaID   string             `xml:"area_id,attr"`
+	Stations []XMLRegionStation `xml:"station"`
+}
+
+// FetchStationList returns the stations on air in areaID
+func FetchStationList(ctx context.Context, areaID string) (XMLStationList, error) {
+	list := XMLStationList{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(APIStationList, areaID), http.NoBody)
+	if err != nil {
+		return list, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return list, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return list, fmt.Errorf("failed to get the stations in %s: %s", areaID, resp.Status)
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return list, err
+	}
+	return list, nil
+}
+
+// DetectAreaID returns the area of this host by the IP address with the area check of radiko
+func DetectAreaID(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, APIArea, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to check the area: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	m := areaPattern.FindSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("unknown area: %s", body)
+	}
+	if string(m[1]) == "OUT" {
+		return "", fmt.Errorf("out of the service area of radiko")
+	}
+	return string(m[1]), nil
+}
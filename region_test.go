@@ -1,6 +1,10 @@
 package radicron
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
@@ -8,7 +12,7 @@ func TestFetchXMLRegion(t *testing.T) {
 	const nRegions = 8
 	const nStations = 110
 
-	region, err := FetchXMLRegion()
+	region, err := FetchXMLRegion(context.Background())
 	if err != nil {
 		t.Error("failed to fetch the full region list")
 	}
@@ -26,3 +30,38 @@ func TestFetchXMLRegion(t *testing.T) {
 		t.Errorf("failed to fetch all the stations (%v instead of %v)", stationCount, nStations)
 	}
 }
+
+func TestDetectAreaID(t *testing.T) {
+	var response string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	defer ts.Close()
+	server, _ := url.Parse(ts.URL)
+	transport := http.DefaultTransport
+	http.DefaultTransport = rewriteTransport{server: server, next: transport}
+	defer func() { http.DefaultTransport = transport }()
+
+	var areatests = []struct {
+		response string
+		areaID   string
+		valid    bool
+	}{
+		{`document.write('<span class="JP13">TOKYO JAPAN</span>');`, "JP13", true},
+		{`document.write('<span class="JP27">OSAKA JAPAN</span>');`, "JP27", true},
+		{`document.write('<span class="OUT">OUT</span>');`, "", false},
+		{`<html></html>`, "", false},
+		{"", "", false},
+	}
+	for _, tt := range areatests {
+		response = tt.response
+		areaID, err := DetectAreaID(context.Background())
+		if (err == nil) != tt.valid || areaID != tt.areaID {
+			t.Errorf("DetectAreaID() with %q => %q, %v, want %q", tt.response, areaID, err, tt.areaID)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package radicron
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordingReport is saved next to the audio for the bug reports about the glitches
+type RecordingReport struct {
+	ID        string `json:"id"`
+	StationID string `json:"station_id"`
+	Ft        string `json:"ft"`
+	To        string `json:"to"`
+	Title     string `json:"title"`
+	Output    string `json:"output"`
+	// Segments downloaded, FailedSegments missing within the threshold,
+	// and RetriedSegments succeeded after the retries
+	Segments        int           `json:"segments"`
+	FailedSegments  int           `json:"failed_segments"`
+	RetriedSegments int           `json:"retried_segments"`
+	Retries         int           `json:"retries"`
+	Stages          []ReportStage `json:"stages"`
+	SHA256          string        `json:"sha256"`
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at"`
+	mu              sync.Mutex
+}
+
+// ReportStage is the duration of a stage in the recording
+type ReportStage struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// getReport returns the report in the context, or nil if not reporting
+func getReport(ctx context.Context) *RecordingReport {
+	r, _ := ctx.Value(ContextKey("report")).(*RecordingReport)
+	return r
+}
+
+// newRecordingReport returns the report of the program
+func newRecordingReport(prog *Prog) *RecordingReport {
+	return &RecordingReport{
+		ID:        prog.ID,
+		StationID: prog.StationID,
+		Ft:        prog.Ft,
+		To:        prog.To,
+		Title:     prog.Title,
+		StartedAt: time.Now(),
+	}
+}
+
+// addSegments counts the segments fetched and the failed ones
+func (r *RecordingReport) addSegments(total, failed, retried int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Segments += total
+	r.FailedSegments += failed
+	r.RetriedSegments += retried
+}
+
+// addRetries counts the retries of a segment
+func (r *RecordingReport) addRetries(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Retries += n
+}
+
+// stage records the duration of the stage since start
+func (r *RecordingReport) stage(name string, start time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Stages = append(r.Stages, ReportStage{Name: name, Seconds: time.Since(start).Seconds()})
+}
+
+// write saves the report with the checksum of the audio next to it
+func (r *RecordingReport) write(audioPath string) error {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Output = audioPath
+	r.SHA256 = hex.EncodeToString(h.Sum(nil))
+	r.FinishedAt = time.Now()
+	blob, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ReportPath(audioPath), blob, 0o644) //nolint:gosec
+}
+
+// ReportPath returns the report next to the audio
+func ReportPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ReportFileSuffix
+}
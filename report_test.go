@@ -0,0 +1,95 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordingReport(t *testing.T) {
+	// the first attempt of /1.aac fails
+	var mu sync.Mutex
+	flaky := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/1.aac" && flaky {
+			flaky = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/broken.aac" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("aac"))
+	}))
+	defer ts.Close()
+
+	prog := &Prog{ID: "12345", StationID: "FMT", Ft: "20230625050000", To: "20230625060000", Title: "Title"}
+	report := newRecordingReport(prog)
+	asset := &Asset{Retry: &RetryPolicy{Attempts: 2, InitialDelay: time.Millisecond}, SegmentFailureThreshold: 0.5}
+	ctx := context.WithValue(context.Background(), ContextKey("asset"), asset)
+	ctx = context.WithValue(ctx, ContextKey("report"), report)
+	segments := Segments{}
+	for i := 0; i < 4; i++ {
+		uri := fmt.Sprintf("%s/%d.aac", ts.URL, i)
+		if i == 3 {
+			uri = ts.URL + "/broken.aac"
+		}
+		segments = append(segments, &Segment{Index: i, URI: uri})
+	}
+	start := time.Now()
+	if _, err := bulkDownload(ctx, segments, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	report.stage("download", start)
+
+	audio := filepath.Join(t.TempDir(), "202306250500_FMT_Title.aac")
+	if err := os.WriteFile(audio, []byte("audio"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := report.write(audio); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.ReadFile(filepath.Join(filepath.Dir(audio), "202306250500_FMT_Title"+ReportFileSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &RecordingReport{}
+	if err = json.Unmarshal(blob, got); err != nil {
+		t.Fatal(err)
+	}
+
+	var reporttests = []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"segments", got.Segments, 4},
+		{"failed", got.FailedSegments, 1},
+		{"retried", got.RetriedSegments, 1},
+		// the broken one is retried in vain
+		{"retries", got.Retries, 2},
+		{"stages", len(got.Stages), 1},
+		{"sha256", got.SHA256, "6ed8919ce20490a5e3ad8630a4fab69475297abd07db73918dd5f36fcfaeb11b"},
+		{"output", got.Output, audio},
+	}
+	for _, tt := range reporttests {
+		if tt.got != tt.want {
+			t.Errorf("report.%s => %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// nil-safe without the report
+	(*RecordingReport)(nil).addSegments(1, 0, 0)
+	(*RecordingReport)(nil).addRetries(1)
+	(*RecordingReport)(nil).stage("download", start)
+}
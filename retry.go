@@ -0,0 +1,63 @@
+package radicron
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy for the segment downloads
+type RetryPolicy struct {
+	Attempts     int           `mapstructure:"attempts"`
+	InitialDelay time.Duration `mapstructure:"initial-delay"`
+	MaxDelay     time.Duration `mapstructure:"max-delay"`
+}
+
+// Delay returns the backoff after the failed attempt (starting from 1),
+// doubling from InitialDelay up to MaxDelay with a jitter of up to a half
+func (rp *RetryPolicy) Delay(attempt int) time.Duration {
+	if rp.InitialDelay <= 0 || attempt < 1 {
+		return 0
+	}
+	d := rp.InitialDelay
+	for i := 1; i < attempt && (rp.MaxDelay <= 0 || d < rp.MaxDelay); i++ {
+		d *= 2
+	}
+	if rp.MaxDelay > 0 && d > rp.MaxDelay {
+		d = rp.MaxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)) //nolint:gosec
+}
+
+// Wait sleeps for the backoff after the failed attempt or until the ctx is done
+func (rp *RetryPolicy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(rp.Delay(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Urgent returns the policy with the shorter backoff for the programs about to expire
+func (rp *RetryPolicy) Urgent() *RetryPolicy {
+	return &RetryPolicy{
+		Attempts:     rp.Attempts,
+		InitialDelay: rp.InitialDelay / UrgentBackoffDivisor,
+		MaxDelay:     rp.MaxDelay / UrgentBackoffDivisor,
+	}
+}
+
+// NewRetryPolicy returns the default RetryPolicy
+func NewRetryPolicy() *RetryPolicy {
+	initialDelay, _ := time.ParseDuration(DefaultRetryInitialDelay)
+	maxDelay, _ := time.ParseDuration(DefaultRetryMaxDelay)
+	return &RetryPolicy{
+		Attempts:     MaxRetryAttempts,
+		InitialDelay: initialDelay,
+		MaxDelay:     maxDelay,
+	}
+}
@@ -0,0 +1,52 @@
+package radicron
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	rp := &RetryPolicy{Attempts: 8, InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	var delaytests = []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second}, // capped
+		{60, 5 * time.Second},
+	}
+	for _, tt := range delaytests {
+		for i := 0; i < 10; i++ {
+			d := rp.Delay(tt.attempt)
+			if d < tt.max/2 || d > tt.max {
+				t.Errorf("Delay(%v) => %v, want in [%v, %v]", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+	if d := (&RetryPolicy{}).Delay(1); d != 0 {
+		t.Errorf("Delay without the initial delay => %v, want 0", d)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	rp := &RetryPolicy{Attempts: 2, InitialDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rp.Wait(ctx, 1); err == nil {
+		t.Error("Wait with the canceled context => nil, want error")
+	}
+}
+
+func TestRetryPolicyUrgent(t *testing.T) {
+	rp := &RetryPolicy{Attempts: 3, InitialDelay: 4 * time.Second, MaxDelay: 40 * time.Second}
+	urgent := rp.Urgent()
+	if urgent.Attempts != rp.Attempts {
+		t.Errorf("Urgent().Attempts => %v, want %v", urgent.Attempts, rp.Attempts)
+	}
+	if urgent.InitialDelay != time.Second || urgent.MaxDelay != 10*time.Second {
+		t.Errorf("Urgent() => %+v, want 1s initial and 10s max delay", urgent)
+	}
+}
@@ -1,7 +1,9 @@
 package radicron
 
 import (
+	"fmt"
 	"log"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -17,9 +19,157 @@ func (rs Rules) HasMatch(stationID string, p *Prog) bool {
 	return false
 }
 
+// Matching returns the rules matching the program, to read the options from without matching again
+func (rs Rules) Matching(stationID string, p *Prog) Rules {
+	matched := Rules{}
+	for _, r := range rs {
+		if r.Match(stationID, p) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// SkipRerun returns true if all the matched rules skip the reruns
+func (rs Rules) SkipRerun() bool {
+	for _, r := range rs {
+		if !r.SkipRerun {
+			return false
+		}
+	}
+	return len(rs) > 0
+}
+
+// Omnibus returns true if any matched rule merges the week's episodes
+func (rs Rules) Omnibus() bool {
+	for _, r := range rs {
+		if r.Omnibus {
+			return true
+		}
+	}
+	return false
+}
+
+// Follow returns true if any matched rule follows the series
+func (rs Rules) Follow() bool {
+	for _, r := range rs {
+		if r.Follow {
+			return true
+		}
+	}
+	return false
+}
+
+// LiveFallback returns true if any matched rule falls back to the live capture
+func (rs Rules) LiveFallback() bool {
+	for _, r := range rs {
+		if r.LiveFallback {
+			return true
+		}
+	}
+	return false
+}
+
+// Podcast returns the official podcast feed of the first matched rule with one
+func (rs Rules) Podcast() string {
+	for _, r := range rs {
+		if r.Podcast != "" {
+			return r.Podcast
+		}
+	}
+	return ""
+}
+
+// Artwork returns the artwork of the first matched rule with one
+func (rs Rules) Artwork() string {
+	for _, r := range rs {
+		if r.Artwork != "" {
+			return r.Artwork
+		}
+	}
+	return ""
+}
+
+// Explicit returns true if any matched rule marks the program as explicit
+func (rs Rules) Explicit() bool {
+	for _, r := range rs {
+		if r.Explicit {
+			return true
+		}
+	}
+	return false
+}
+
+// Padding returns the margins of the first matched rule with any
+func (rs Rules) Padding() (before, after time.Duration) {
+	for _, r := range rs {
+		if r.PadBefore == "" && r.PadAfter == "" {
+			continue
+		}
+		var err error
+		if before, err = parsePadding(r.PadBefore); err != nil {
+			log.Printf("parsing [%s].pad-before failed: %v (ignored)", r.Name, err)
+		}
+		if after, err = parsePadding(r.PadAfter); err != nil {
+			log.Printf("parsing [%s].pad-after failed: %v (ignored)", r.Name, err)
+		}
+		return before, after
+	}
+	return 0, 0
+}
+
+// parsePadding returns the margin, or 0 if empty
+func parsePadding(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 || d > MaxPaddingMinutes*time.Minute {
+		return 0, fmt.Errorf("out of range: %s (within %dm)", s, MaxPaddingMinutes)
+	}
+	return d, nil
+}
+
+// ID3Version returns the ID3v2 major version of the first matched rule with one,
+// or 0 for the default
+func (rs Rules) ID3Version() byte {
+	for _, r := range rs {
+		if r.ID3Version == "" {
+			continue
+		}
+		version, err := ParseID3Version(r.ID3Version)
+		if err != nil {
+			log.Printf("parsing [%s].id3-version failed: %v (ignored)", r.Name, err)
+			continue
+		}
+		return version
+	}
+	return 0
+}
+
+// Oversized returns skip if all the matched rules skip the program over their max-duration,
+// and warn if any of them finds it over the max-duration
+func (rs Rules) Oversized(p *Prog) (skip, warn bool) {
+	skip = true
+	for _, r := range rs {
+		if !r.ExceedsMaxDuration(p) {
+			skip = false
+			continue
+		}
+		warn = true
+		if r.Oversize == OversizeWarn {
+			skip = false
+		}
+	}
+	return len(rs) > 0 && skip, warn
+}
+
 func (rs Rules) HasRuleWithoutStationID() bool {
 	for _, r := range rs {
-		if !r.HasStationID() {
+		if !r.HasStationID() && !r.IsRadiru() {
 			return true
 		}
 	}
@@ -35,6 +185,27 @@ func (rs Rules) HasRuleForStationID(stationID string) bool {
 	return false
 }
 
+// RadiruStations returns the radiru stations to scan for the rules
+func (rs Rules) RadiruStations() []string {
+	stations := []string{}
+	for _, r := range rs {
+		if !r.IsRadiru() {
+			continue
+		}
+		if !r.HasStationID() {
+			return RadiruStationIDs
+		}
+		found := false
+		for _, s := range stations {
+			found = found || s == r.StationID
+		}
+		if !found {
+			stations = append(stations, r.StationID)
+		}
+	}
+	return stations
+}
+
 type Rule struct {
 	Name      string   `mapstructure:"name"`       // required
 	Title     string   `mapstructure:"title"`      // required if pfm and keyword are unset
@@ -43,11 +214,37 @@ type Rule struct {
 	Pfm       string   `mapstructure:"pfm"`        // optional
 	StationID string   `mapstructure:"station-id"` // optional
 	Window    string   `mapstructure:"window"`     // optional
+	SkipRerun bool     `mapstructure:"skip-rerun"` // optional
+	Omnibus   bool     `mapstructure:"omnibus"`    // optional
+	// MaxDuration to skip the longer programs, e.g., the special blocks matched by a broad keyword
+	MaxDuration string `mapstructure:"max-duration"` // optional
+	Oversize    string `mapstructure:"oversize"`     // optional, skip (default) or warn
+	// Artwork overrides the program image in the tags and feeds, a local file or URL
+	Artwork string `mapstructure:"artwork"` // optional
+	// Explicit to mark the programs as explicit, e.g., for the family-shared libraries
+	Explicit bool `mapstructure:"explicit"` // optional
+	// ID3Version for the players reading only ID3v2.3 with UTF-16, e.g., the car stereos
+	ID3Version string `mapstructure:"id3-version"` // optional, 2.4 (default) or 2.3
+	// Slot to match the programs by the broadcast time in the radio day, e.g., 25:00-27:00
+	Slot string `mapstructure:"slot"` // optional
+	// Follow to number the episodes of the program by the title and the station
+	Follow bool `mapstructure:"follow"` // optional
+	// Podcast feed of the official episodes to prefer over the timefree, e.g., RadioCloud
+	Podcast string `mapstructure:"podcast"` // optional
+	// PadBefore and PadAfter widen the recording against the clock drift of the station, e.g., 1m
+	PadBefore string `mapstructure:"pad-before"` // optional
+	PadAfter  string `mapstructure:"pad-after"`  // optional
+	// Merge the consecutive programs matched into one recording, e.g., part 1 and part 2
+	Merge bool `mapstructure:"merge"` // optional
+	// Source of the programs, radiko (default) or radiru for NHK らじる★らじる
+	Source string `mapstructure:"source"` // optional
+	// LiveFallback to capture the next airing live once the timefree playlist never appears, e.g., excluded from the timefree
+	LiveFallback bool `mapstructure:"live-fallback"` // optional
 }
 
 // Match returns true if the rule matches the program
 // 1. check the Window filter
-// 2. check the DoW filter
+// 2. check the DoW and Slot filters
 // 3. check the StationID
 // 4. match the criteria
 func (r *Rule) Match(stationID string, p *Prog) bool {
@@ -55,8 +252,8 @@ func (r *Rule) Match(stationID string, p *Prog) bool {
 	if !r.MatchWindow(p.Ft) {
 		return false
 	}
-	// 2. check dow
-	if !r.MatchDoW(p.Ft) {
+	// 2. check dow and slot
+	if !r.MatchDoW(p.Ft) || !r.MatchSlot(p.Ft) {
 		return false
 	}
 	// 3. check station-id
@@ -71,10 +268,67 @@ func (r *Rule) Match(stationID string, p *Prog) bool {
 	return false
 }
 
+// Explain returns whether the rule matches the program
+// with the reasons for each criterion in the order of Match
+func (r *Rule) Explain(stationID string, p *Prog) (bool, []string) {
+	reasons := []string{}
+	if r.HasWindow() {
+		if !r.MatchWindow(p.Ft) {
+			return false, append(reasons, fmt.Sprintf("the program is outside the window %s", r.Window))
+		}
+		reasons = append(reasons, fmt.Sprintf("the program is within the window %s", r.Window))
+	}
+	if r.HasDoW() {
+		if !r.MatchDoW(p.Ft) {
+			return false, append(reasons, fmt.Sprintf("the program is not on %s", strings.Join(r.DoW, "/")))
+		}
+		reasons = append(reasons, fmt.Sprintf("the program is on %s", strings.Join(r.DoW, "/")))
+	}
+	if r.HasSlot() {
+		if !r.MatchSlot(p.Ft) {
+			return false, append(reasons, fmt.Sprintf("the program is outside the slot %s", r.Slot))
+		}
+		reasons = append(reasons, fmt.Sprintf("the program is in the slot %s", r.Slot))
+	}
+	if r.IsRadiru() != IsRadiruStation(stationID) {
+		return false, append(reasons, fmt.Sprintf("the station is not on %s", r.source()))
+	}
+	if r.HasStationID() {
+		if !r.MatchStationID(stationID) {
+			return false, append(reasons, fmt.Sprintf("the station is not %s", r.StationID))
+		}
+		reasons = append(reasons, fmt.Sprintf("the station is %s", r.StationID))
+	}
+	if r.HasPfm() {
+		if !strings.Contains(p.Pfm, r.Pfm) {
+			return false, append(reasons, fmt.Sprintf("the pfm '%s' does not contain '%s'", p.Pfm, r.Pfm))
+		}
+		reasons = append(reasons, fmt.Sprintf("the pfm '%s' contains '%s'", p.Pfm, r.Pfm))
+	}
+	if r.HasTitle() {
+		if !strings.Contains(p.Title, r.Title) {
+			return false, append(reasons, fmt.Sprintf("the title '%s' does not contain '%s'", p.Title, r.Title))
+		}
+		reasons = append(reasons, fmt.Sprintf("the title '%s' contains '%s'", p.Title, r.Title))
+	}
+	if r.HasKeyword() {
+		field, value := r.matchKeywordField(p)
+		if field == "" {
+			return false, append(reasons, fmt.Sprintf("no field contains the keyword '%s'", r.Keyword))
+		}
+		reasons = append(reasons, fmt.Sprintf("the %s '%s' contains the keyword '%s'", field, value, r.Keyword))
+	}
+	return true, reasons
+}
+
 func (r *Rule) HasDoW() bool {
 	return len(r.DoW) > 0
 }
 
+func (r *Rule) HasMaxDuration() bool {
+	return r.MaxDuration != ""
+}
+
 func (r *Rule) HasPfm() bool {
 	return r.Pfm != ""
 }
@@ -83,6 +337,10 @@ func (r *Rule) HasKeyword() bool {
 	return r.Keyword != ""
 }
 
+func (r *Rule) HasSlot() bool {
+	return r.Slot != ""
+}
+
 func (r *Rule) HasStationID() bool {
 	if r.StationID == "" ||
 		r.StationID == "*" {
@@ -113,6 +371,10 @@ func (r *Rule) MatchDoW(ft string) bool {
 		"sat": time.Saturday,
 	}
 	st, _ := time.ParseInLocation(DatetimeLayout, ft, Location)
+	// the day of the radio day with the slot, e.g., 25:00 on Wednesday
+	if r.HasSlot() {
+		st, _ = radioDay(st)
+	}
 	for _, d := range r.DoW {
 		if st.Weekday() == dow[strings.ToLower(d)] {
 			return true
@@ -121,31 +383,49 @@ func (r *Rule) MatchDoW(ft string) bool {
 	return false
 }
 
+// ExceedsMaxDuration returns true if the program is longer than the max-duration
+func (r *Rule) ExceedsMaxDuration(p *Prog) bool {
+	if !r.HasMaxDuration() {
+		return false
+	}
+	maxDuration, err := time.ParseDuration(r.MaxDuration)
+	if err != nil {
+		log.Printf("parsing [%s].max-duration failed: %v (ignored)", r.Name, err)
+		return false
+	}
+	return p.Duration() > maxDuration
+}
+
 func (r *Rule) MatchKeyword(p *Prog) bool {
 	if !r.HasKeyword() {
 		return true // if no keyward, match all
 	}
 
+	field, value := r.matchKeywordField(p)
+	if field == "" {
+		return false
+	}
+	log.Printf("rule[%s] matched with %s: '%s'", r.Name, field, value)
+	return true
+}
+
+// matchKeywordField returns the first field of the program containing the keyword
+func (r *Rule) matchKeywordField(p *Prog) (field, value string) {
 	if strings.Contains(p.Title, r.Keyword) {
-		log.Printf("rule[%s] matched with title: '%s'", r.Name, p.Title)
-		return true
+		return "title", p.Title
 	} else if strings.Contains(p.Pfm, r.Keyword) {
-		log.Printf("rule[%s] matched with pfm: '%s'", r.Name, p.Pfm)
-		return true
+		return "pfm", p.Pfm
 	} else if strings.Contains(p.Info, r.Keyword) {
-		log.Printf("rule[%s] matched with info: %s", r.Name, strings.ReplaceAll(p.Info, "\n", ""))
-		return true
+		return "info", strings.ReplaceAll(p.Info, "\n", "")
 	} else if strings.Contains(p.Desc, r.Keyword) {
-		log.Printf("rule[%s] matched with desc: '%s'", r.Name, strings.ReplaceAll(p.Desc, "\n", ""))
-		return true
+		return "desc", strings.ReplaceAll(p.Desc, "\n", "")
 	}
 	for _, tag := range p.Tags {
 		if strings.Contains(tag, r.Keyword) {
-			log.Printf("rule[%s] matched with tag: '%s'", r.Name, tag)
-			return true
+			return "tag", tag
 		}
 	}
-	return false
+	return "", ""
 }
 
 func (r *Rule) MatchPfm(pfm string) bool {
@@ -160,6 +440,9 @@ func (r *Rule) MatchPfm(pfm string) bool {
 }
 
 func (r *Rule) MatchStationID(stationID string) bool {
+	if r.IsRadiru() != IsRadiruStation(stationID) {
+		return false // the station of the other source
+	}
 	if !r.HasStationID() {
 		return true // if no station-id, match all
 	}
@@ -180,6 +463,63 @@ func (r *Rule) MatchTitle(title string) bool {
 	return false
 }
 
+// MatchSlot returns true if the program starts within the slot in the radio day
+func (r *Rule) MatchSlot(ft string) bool {
+	if !r.HasSlot() {
+		return true
+	}
+	from, to, err := ParseSlot(r.Slot)
+	if err != nil {
+		log.Printf("parsing [%s].slot failed: %v (no match)", r.Name, err)
+		return false
+	}
+	startTime, err := time.ParseInLocation(DatetimeLayout, ft, Location)
+	if err != nil {
+		log.Printf("invalid start time format '%s': %s", ft, err)
+		return false
+	}
+	_, start := radioDay(startTime)
+	return from <= start && start < to
+}
+
+// ParseSlot returns the start and the end of the slot HH:MM-HH:MM in the radio day,
+// where the hours from 24 are the early morning of the next day, e.g., 25:00-27:00
+func ParseSlot(slot string) (from, to time.Duration, err error) {
+	parts := strings.Split(slot, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid slot: %s", slot)
+	}
+	if from, err = parseSlotTime(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parseSlotTime(parts[1]); err != nil {
+		return 0, 0, err
+	}
+	if from < RadioDayStartHour*time.Hour || to > (RadioDayStartHour+24)*time.Hour || from >= to {
+		return 0, 0, fmt.Errorf("invalid slot: %s (within %02d:00-%02d:00)", slot, RadioDayStartHour, RadioDayStartHour+24)
+	}
+	return from, to, nil
+}
+
+// parseSlotTime parses HH:MM allowing the hours over 24
+func parseSlotTime(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m); err != nil || n != 2 || h < 0 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid slot time: %s", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// radioDay returns the day of the radio day starting at RadioDayStartHour, and the time from its midnight,
+// e.g., 01:00 on Thursday is 25:00 on Wednesday
+func radioDay(t time.Time) (time.Time, time.Duration) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if t.Hour() < RadioDayStartHour {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day, t.Sub(day)
+}
+
 func (r *Rule) MatchWindow(ft string) bool {
 	if !r.HasWindow() {
 		return true
@@ -201,6 +541,86 @@ func (r *Rule) MatchWindow(ft string) bool {
 	return true
 }
 
+// RuleError is the invalid field of the rule
+type RuleError struct {
+	Field string
+	Err   error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+// Validate returns the first invalid field of the rule ignored or unmatched at the runtime
+func (r *Rule) Validate() error {
+	if r.HasWindow() {
+		if _, err := time.ParseDuration(r.Window); err != nil {
+			return &RuleError{"window", err}
+		}
+	}
+	for _, d := range r.DoW {
+		switch strings.ToLower(d) {
+		case "sun", "mon", "tue", "wed", "thu", "fri", "sat":
+		default:
+			return &RuleError{"dow", fmt.Errorf("invalid day of the week: %s (sun, mon, ..., or sat)", d)}
+		}
+	}
+	if r.HasSlot() {
+		if _, _, err := ParseSlot(r.Slot); err != nil {
+			return &RuleError{"slot", err}
+		}
+	}
+	if r.HasMaxDuration() {
+		if _, err := time.ParseDuration(r.MaxDuration); err != nil {
+			return &RuleError{"max-duration", err}
+		}
+	}
+	if r.Oversize != "" && r.Oversize != "skip" && r.Oversize != OversizeWarn {
+		return &RuleError{"oversize", fmt.Errorf("invalid oversize: %s (skip or warn)", r.Oversize)}
+	}
+	if _, err := ParseID3Version(r.ID3Version); err != nil {
+		return &RuleError{"id3-version", err}
+	}
+	if r.Podcast != "" {
+		if u, err := url.Parse(r.Podcast); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return &RuleError{"podcast", fmt.Errorf("invalid feed URL: %s", r.Podcast)}
+		}
+	}
+	if _, err := parsePadding(r.PadBefore); err != nil {
+		return &RuleError{"pad-before", err}
+	}
+	if _, err := parsePadding(r.PadAfter); err != nil {
+		return &RuleError{"pad-after", err}
+	}
+	switch r.Source {
+	case "":
+	case SourceRadiko:
+		if IsRadiruStation(r.StationID) {
+			return &RuleError{"source", fmt.Errorf("%s is on radiru, not on radiko", r.StationID)}
+		}
+	case SourceRadiru:
+		if r.HasStationID() && !IsRadiruStation(r.StationID) {
+			return &RuleError{"station-id", fmt.Errorf("unknown radiru station: %s (%s)", r.StationID, strings.Join(RadiruStationIDs, ", "))}
+		}
+	default:
+		return &RuleError{"source", fmt.Errorf("invalid source: %s (radiko or radiru)", r.Source)}
+	}
+	return nil
+}
+
+// IsRadiru returns true if the rule matches the radiru programs, by the source or the station
+func (r *Rule) IsRadiru() bool {
+	return r.Source == SourceRadiru || (r.Source == "" && IsRadiruStation(r.StationID))
+}
+
+// source returns the source of the programs the rule matches
+func (r *Rule) source() string {
+	if r.IsRadiru() {
+		return SourceRadiru
+	}
+	return SourceRadiko
+}
+
 func (r *Rule) SetName(name string) {
 	r.Name = name
 }
@@ -1,6 +1,8 @@
 package radicron
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -12,7 +14,7 @@ var matchtests = []struct {
 	out       bool
 }{
 	{
-		&Rule{"matchtests", "Title", []string{}, "Keyword", "Pfm", "FMT", ""},
+		&Rule{"matchtests", "Title", []string{}, "Keyword", "Pfm", "FMT", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"FMT",
 		&Prog{
 			"ID",
@@ -26,11 +28,23 @@ var matchtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"matchtests", "RadioProgram", []string{}, "Keyword", "Pfm", "FMT", ""},
+		&Rule{"matchtests", "RadioProgram", []string{}, "Keyword", "Pfm", "FMT", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"FMT",
 		&Prog{
 			"ID",
@@ -44,11 +58,23 @@ var matchtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		false,
 	},
 	{
-		&Rule{"matchtests", "RadioProgram", []string{}, "", "Someone", "FMT", ""},
+		&Rule{"matchtests", "RadioProgram", []string{}, "", "Someone", "FMT", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"FMT",
 		&Prog{
 			"ID",
@@ -62,6 +88,18 @@ var matchtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		false,
 	},
@@ -82,17 +120,17 @@ var dowtests = []struct {
 	out bool
 }{
 	{
-		&Rule{"dowtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"dowtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"20230625050000", // sun
 		true,
 	},
 	{
-		&Rule{"dowtests", "Title", []string{"sun"}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"dowtests", "Title", []string{"sun"}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"20230625050000", // sun
 		true,
 	},
 	{
-		&Rule{"dowtests", "Title", []string{"mon", "tue"}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"dowtests", "Title", []string{"mon", "tue"}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"20230625050000", // sun
 		false,
 	},
@@ -107,13 +145,66 @@ func TestMatchDoW(t *testing.T) {
 	}
 }
 
+var slottests = []struct {
+	in  *Rule
+	ft  string
+	out bool
+}{
+	{&Rule{Name: "slottests"}, "20230628010000", true},
+	// 25:00 on Wednesday is 01:00 on Thursday
+	{&Rule{Name: "slottests", DoW: []string{"wed"}, Slot: "25:00-27:00"}, "20230629010000", true},
+	{&Rule{Name: "slottests", DoW: []string{"thu"}, Slot: "25:00-27:00"}, "20230629010000", false},
+	{&Rule{Name: "slottests", DoW: []string{"wed"}, Slot: "25:00-27:00"}, "20230629030000", false},
+	{&Rule{Name: "slottests", DoW: []string{"wed"}, Slot: "25:00-27:00"}, "20230628010000", false},
+	{&Rule{Name: "slottests", Slot: "13:00-15:30"}, "20230628152959", true},
+	{&Rule{Name: "slottests", Slot: "13:00-15:30"}, "20230628125959", false},
+	{&Rule{Name: "slottests", Slot: "27:00-25:00"}, "20230629010000", false},
+	{&Rule{Name: "slottests", Slot: "invalid"}, "20230629010000", false},
+}
+
+func TestMatchSlot(t *testing.T) {
+	for _, tt := range slottests {
+		got := tt.in.MatchSlot(tt.ft) && tt.in.MatchDoW(tt.ft)
+		if got != tt.out {
+			t.Errorf("(%v).MatchSlot(%s) => %v, want %v", tt.in, tt.ft, got, tt.out)
+		}
+	}
+}
+
+func TestParseSlot(t *testing.T) {
+	var parseslottests = []struct {
+		slot string
+		from time.Duration
+		to   time.Duration
+		ok   bool
+	}{
+		{"25:00-27:00", 25 * time.Hour, 27 * time.Hour, true},
+		{"5:00-29:00", 5 * time.Hour, 29 * time.Hour, true},
+		{" 13:00 - 15:30 ", 13 * time.Hour, 15*time.Hour + 30*time.Minute, true},
+		{"04:00-06:00", 0, 0, false},
+		{"28:00-30:00", 0, 0, false},
+		{"15:00-13:00", 0, 0, false},
+		{"13:60-15:00", 0, 0, false},
+		{"13:00", 0, 0, false},
+	}
+	for _, tt := range parseslottests {
+		from, to, err := ParseSlot(tt.slot)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseSlot(%q) => %v, want ok %v", tt.slot, err, tt.ok)
+		}
+		if from != tt.from || to != tt.to {
+			t.Errorf("ParseSlot(%q) => %v-%v, want %v-%v", tt.slot, from, to, tt.from, tt.to)
+		}
+	}
+}
+
 var keywordtests = []struct {
 	in   *Rule
 	prog *Prog
 	out  bool
 }{
 	{
-		&Rule{"keywordtests", "Title", []string{}, "", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"ID",
 			"StationID",
@@ -126,11 +217,23 @@ var keywordtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"ID",
 			"StationID",
@@ -143,11 +246,23 @@ var keywordtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"ID",
 			"StationID",
@@ -160,11 +275,23 @@ var keywordtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"ID",
 			"StationID",
@@ -177,11 +304,23 @@ var keywordtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"test",
 			"test",
@@ -194,11 +333,23 @@ var keywordtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"test",
 			"test",
@@ -210,12 +361,24 @@ var keywordtests = []struct {
 			"test",
 			[]string{"Keyword"}, // match
 			ProgGenre{},
+			"",
 			"test",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		true,
 	},
 	{
-		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"keywordtests", "Title", []string{}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		&Prog{
 			"ID",
 			"StationID",
@@ -228,6 +391,18 @@ var keywordtests = []struct {
 			[]string{},
 			ProgGenre{},
 			"",
+			"",
+			false,
+			false,
+			"",
+			false,
+			0,
+			false,
+			0,
+			"",
+			0,
+			0,
+			false,
 		},
 		false,
 	},
@@ -248,17 +423,17 @@ var pfmtests = []struct {
 	out bool
 }{
 	{
-		&Rule{"pfmtests", "Title", []string{"sun"}, "Keyword", "", "StationID", "Window"},
+		&Rule{"pfmtests", "Title", []string{"sun"}, "Keyword", "", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"Pfm",
 		true,
 	},
 	{
-		&Rule{"pfmtests", "", []string{}, "", "Pfm", "", ""},
+		&Rule{"pfmtests", "", []string{}, "", "Pfm", "", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"Pfm",
 		true,
 	},
 	{
-		&Rule{"pfmtests", "", []string{}, "", "Pfm", "", ""},
+		&Rule{"pfmtests", "", []string{}, "", "Pfm", "", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"Someone",
 		false,
 	},
@@ -279,17 +454,17 @@ var stationtests = []struct {
 	out       bool
 }{
 	{
-		&Rule{"stationtests", "Title", []string{"sun"}, "Keyword", "Pfm", "FMT", "Window"},
+		&Rule{"stationtests", "Title", []string{"sun"}, "Keyword", "Pfm", "FMT", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"FMT",
 		true,
 	},
 	{
-		&Rule{"stationtests", "", []string{}, "", "", "", ""},
+		&Rule{"stationtests", "", []string{}, "", "", "", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"FMT",
 		true,
 	},
 	{
-		&Rule{"stationtests", "", []string{}, "", "", "FMT", ""},
+		&Rule{"stationtests", "", []string{}, "", "", "FMT", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"TBS",
 		false,
 	},
@@ -310,17 +485,17 @@ var titletests = []struct {
 	out   bool
 }{
 	{
-		&Rule{"titletests", "Title", []string{"sun"}, "Keyword", "Pfm", "FMT", "Window"},
+		&Rule{"titletests", "Title", []string{"sun"}, "Keyword", "Pfm", "FMT", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"Title",
 		true,
 	},
 	{
-		&Rule{"titletests", "", []string{}, "", "", "", ""},
+		&Rule{"titletests", "", []string{}, "", "", "", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"Title",
 		true,
 	},
 	{
-		&Rule{"titletests", "Title", []string{}, "", "", "FMT", ""},
+		&Rule{"titletests", "Title", []string{}, "", "", "FMT", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"Radio",
 		false,
 	},
@@ -341,17 +516,17 @@ var windowtests = []struct {
 	out bool
 }{
 	{
-		&Rule{"windowtests", "Title", []string{"sun"}, "Keyword", "Pfm", "FMT", ""},
+		&Rule{"windowtests", "Title", []string{"sun"}, "Keyword", "Pfm", "FMT", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		"20230625050000",
 		true,
 	},
 	{
-		&Rule{"windowtests", "", []string{}, "", "", "", "24h"},
+		&Rule{"windowtests", "", []string{}, "", "", "", "24h", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		time.Now().Add(-1 * time.Hour).Format("20060102150405"),
 		true,
 	},
 	{
-		&Rule{"windowtests", "", []string{}, "", "", "", "24h"},
+		&Rule{"windowtests", "", []string{}, "", "", "", "24h", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		time.Now().Add(time.Duration(-48) * time.Hour).Format("20060102150405"),
 		false,
 	},
@@ -374,11 +549,11 @@ var ruletests = []struct {
 	out bool
 }{
 	{
-		&Rule{"ruletests", "Title", []string{"sun"}, "Keyword", "Pfm", "StationID", "Window"},
+		&Rule{"ruletests", "Title", []string{"sun"}, "Keyword", "Pfm", "StationID", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		true,
 	},
 	{
-		&Rule{"ruletests", "", []string{}, "", "", "", ""},
+		&Rule{"ruletests", "", []string{}, "", "", "", "", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 		false,
 	},
 }
@@ -447,16 +622,16 @@ func TestHasRuleFor(t *testing.T) {
 	}{
 		{
 			Rules{
-				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "FMT", "Window"},
-				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window"},
+				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "FMT", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
+				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 			},
 			"FMT",
 			true,
 		},
 		{
 			Rules{
-				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "FMT", "Window"},
-				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window"},
+				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "FMT", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
+				&Rule{"rulestests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 			},
 			"MBS",
 			false,
@@ -477,15 +652,15 @@ func TestHasRuleWithoutStationID(t *testing.T) {
 	}{
 		{
 			Rules{
-				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "", "Window"},
-				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window"},
+				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
+				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 			},
 			true,
 		},
 		{
 			Rules{
-				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "FMT", "Window"},
-				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window"},
+				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "FMT", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
+				&Rule{"hrwsitests", "Title", []string{}, "Keyword", "Pfm", "TBS", "Window", false, false, "", "", "", false, "", "", false, "", "", "", false, "", false},
 			},
 			false,
 		},
@@ -497,3 +672,265 @@ func TestHasRuleWithoutStationID(t *testing.T) {
 		}
 	}
 }
+
+func TestExplain(t *testing.T) {
+	p := &Prog{
+		ID:        "ID",
+		StationID: "FMT",
+		Ft:        "20230625050000", // sun
+		To:        "20230625060000",
+		Title:     "Title",
+		Pfm:       "Pfm",
+		Tags:      []string{"Keyword"},
+	}
+	var explaintests = []struct {
+		in      *Rule
+		out     bool
+		reasons int
+	}{
+		{&Rule{Name: "all"}, true, 0},
+		{&Rule{Name: "keyword", Keyword: "Keyword"}, true, 1},
+		{&Rule{Name: "dow", DoW: []string{"sun"}, StationID: "FMT", Title: "Title"}, true, 3},
+		{&Rule{Name: "station", StationID: "TBS", Title: "Title"}, false, 1},
+		{&Rule{Name: "pfm", Pfm: "Someone", Title: "Title"}, false, 1},
+	}
+	for _, tt := range explaintests {
+		got, reasons := tt.in.Explain(p.StationID, p)
+		if got != tt.out {
+			t.Errorf("(%v).Explain => %v, want %v", tt.in.Name, got, tt.out)
+		}
+		if len(reasons) != tt.reasons {
+			t.Errorf("(%v).Explain => %v, want %v reasons", tt.in.Name, reasons, tt.reasons)
+		}
+	}
+}
+
+func TestMatching(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	rules := Rules{&Rule{Name: "a", Title: "Title"}, &Rule{Name: "b", Title: "Other"}, &Rule{Name: "c", Title: "Title"}}
+	got := rules.Matching(p.StationID, p)
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Errorf("Matching => %v rules, want a and c", len(got))
+	}
+	if got = (Rules{}).Matching(p.StationID, p); len(got) != 0 {
+		t.Errorf("Matching => %v rules, want none", len(got))
+	}
+}
+
+func TestSkipRerun(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	var skipreruntests = []struct {
+		in  Rules
+		out bool
+	}{
+		{Rules{}, false},
+		{Rules{&Rule{Name: "skip", Title: "Title", SkipRerun: true}}, true},
+		{Rules{&Rule{Name: "skip", Title: "Title", SkipRerun: true}, &Rule{Name: "keep", Title: "Title"}}, false},
+		{Rules{&Rule{Name: "skip", Title: "Title", SkipRerun: true}, &Rule{Name: "other", Title: "Other"}}, true},
+	}
+	for _, tt := range skipreruntests {
+		if got := tt.in.Matching(p.StationID, p).SkipRerun(); got != tt.out {
+			t.Errorf("(%v).SkipRerun => %v, want %v", len(tt.in), got, tt.out)
+		}
+	}
+}
+
+func TestOmnibus(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	rules := Rules{&Rule{Name: "keep", Title: "Title"}, &Rule{Name: "omnibus", Title: "Title", Omnibus: true}}
+	if !rules.Matching(p.StationID, p).Omnibus() {
+		t.Error("Omnibus => false, want true")
+	}
+	if rules[:1].Matching(p.StationID, p).Omnibus() {
+		t.Error("Omnibus without the option => true, want false")
+	}
+}
+
+func TestOversized(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", To: "20230625130000", Title: "Title"}
+	var oversizedtests = []struct {
+		name  string
+		rules Rules
+		skip  bool
+		warn  bool
+	}{
+		{"no max-duration", Rules{&Rule{Name: "r", Title: "Title"}}, false, false},
+		{"under max-duration", Rules{&Rule{Name: "r", Title: "Title", MaxDuration: "8h"}}, false, false},
+		{"over max-duration", Rules{&Rule{Name: "r", Title: "Title", MaxDuration: "3h"}}, true, true},
+		{"warn", Rules{&Rule{Name: "r", Title: "Title", MaxDuration: "3h", Oversize: OversizeWarn}}, false, true},
+		{"another rule without limit", Rules{&Rule{Name: "r", Title: "Title", MaxDuration: "3h"}, &Rule{Name: "s", Title: "Title"}}, false, true},
+		{"unmatched rule", Rules{&Rule{Name: "r", Title: "Other", MaxDuration: "3h"}}, false, false},
+		{"invalid max-duration", Rules{&Rule{Name: "r", Title: "Title", MaxDuration: "long"}}, false, false},
+	}
+	for _, tt := range oversizedtests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, warn := tt.rules.Matching(p.StationID, p).Oversized(p)
+			if skip != tt.skip || warn != tt.warn {
+				t.Errorf("Oversized => (%v, %v), want (%v, %v)", skip, warn, tt.skip, tt.warn)
+			}
+		})
+	}
+}
+
+func TestRulesArtwork(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	rules := Rules{
+		&Rule{Name: "other", Title: "Other", Artwork: "other.jpg"},
+		&Rule{Name: "plain", Title: "Title"},
+		&Rule{Name: "cover", Title: "Title", Artwork: "cover.jpg"},
+	}
+	if got := rules.Matching(p.StationID, p).Artwork(); got != "cover.jpg" {
+		t.Errorf("Artwork => %v, want cover.jpg", got)
+	}
+	if got := rules[:2].Matching(p.StationID, p).Artwork(); got != "" {
+		t.Errorf("Artwork without the option => %v, want none", got)
+	}
+}
+
+func TestRulesExplicit(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	rules := Rules{&Rule{Name: "plain", Title: "Title"}, &Rule{Name: "explicit", Title: "Title", Explicit: true}}
+	if !rules.Matching(p.StationID, p).Explicit() {
+		t.Error("Explicit => false, want true")
+	}
+	if rules[:1].Matching(p.StationID, p).Explicit() {
+		t.Error("Explicit without the option => true, want false")
+	}
+}
+
+func TestRulesFollow(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	rules := Rules{&Rule{Name: "plain", Title: "Title"}, &Rule{Name: "follow", Title: "Title", Follow: true}}
+	if !rules.Matching(p.StationID, p).Follow() {
+		t.Error("Follow => false, want true")
+	}
+	if rules[:1].Matching(p.StationID, p).Follow() {
+		t.Error("Follow without the option => true, want false")
+	}
+}
+
+func TestRulesLiveFallback(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	rules := Rules{&Rule{Name: "plain", Title: "Title"}, &Rule{Name: "fallback", Title: "Title", LiveFallback: true}}
+	if !rules.Matching(p.StationID, p).LiveFallback() {
+		t.Error("LiveFallback => false, want true")
+	}
+	if rules[:1].Matching(p.StationID, p).LiveFallback() {
+		t.Error("LiveFallback without the option => true, want false")
+	}
+}
+
+func TestRulesPadding(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	var paddingtests = []struct {
+		name   string
+		rule   *Rule
+		before time.Duration
+		after  time.Duration
+	}{
+		{"none", &Rule{Name: "none", Title: "Title"}, 0, 0},
+		{"both", &Rule{Name: "both", Title: "Title", PadBefore: "1m", PadAfter: "2m"}, time.Minute, 2 * time.Minute},
+		{"after", &Rule{Name: "after", Title: "Title", PadAfter: "30s"}, 0, 30 * time.Second},
+		{"invalid", &Rule{Name: "invalid", Title: "Title", PadBefore: "-1m", PadAfter: "1h"}, 0, 0},
+		{"unmatched", &Rule{Name: "unmatched", Title: "Other", PadBefore: "1m"}, 0, 0},
+	}
+	for _, tt := range paddingtests {
+		before, after := Rules{tt.rule}.Matching(p.StationID, p).Padding()
+		if before != tt.before || after != tt.after {
+			t.Errorf("Padding(%s) => %v, %v, want %v, %v", tt.name, before, after, tt.before, tt.after)
+		}
+	}
+}
+
+func TestRulesID3Version(t *testing.T) {
+	p := &Prog{ID: "ID", StationID: "FMT", Ft: "20230625050000", Title: "Title"}
+	var id3tests = []struct {
+		rules Rules
+		want  byte
+	}{
+		{Rules{&Rule{Name: "plain", Title: "Title"}}, 0},
+		{Rules{&Rule{Name: "plain", Title: "Title"}, &Rule{Name: "car", Title: "Title", ID3Version: "2.3"}}, 3},
+		{Rules{&Rule{Name: "invalid", Title: "Title", ID3Version: "1.0"}, &Rule{Name: "modern", Title: "Title", ID3Version: "2.4"}}, 4},
+		{Rules{&Rule{Name: "other", Title: "Other", ID3Version: "2.3"}}, 0},
+	}
+	for _, tt := range id3tests {
+		if got := tt.rules.Matching(p.StationID, p).ID3Version(); got != tt.want {
+			t.Errorf("ID3Version => %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	var validatetests = []struct {
+		rule  *Rule
+		field string
+	}{
+		{&Rule{Name: "valid", Title: "Title", Window: "48h", DoW: []string{"Mon", "fri"}, Slot: "25:00-27:00", MaxDuration: "2h", Oversize: "warn", ID3Version: "2.3", Podcast: "https://example.com/feed.xml", PadBefore: "1m"}, ""},
+		{&Rule{Name: "station", StationID: "TBS"}, ""},
+		{&Rule{Name: "window", Window: "2d"}, "window"},
+		{&Rule{Name: "dow", DoW: []string{"monday"}}, "dow"},
+		{&Rule{Name: "slot", Slot: "27:00-25:00"}, "slot"},
+		{&Rule{Name: "max-duration", MaxDuration: "two hours"}, "max-duration"},
+		{&Rule{Name: "oversize", Oversize: "keep"}, "oversize"},
+		{&Rule{Name: "id3-version", ID3Version: "1.0"}, "id3-version"},
+		{&Rule{Name: "podcast", Podcast: "feed.xml"}, "podcast"},
+		{&Rule{Name: "pad-before", PadBefore: "-1m"}, "pad-before"},
+		{&Rule{Name: "pad-after", PadAfter: "1h"}, "pad-after"},
+		{&Rule{Name: "radiru", Source: "radiru", StationID: "NHK-FM"}, ""},
+		{&Rule{Name: "radiru-station", StationID: "NHK-FM"}, ""},
+		{&Rule{Name: "radiru-radiko", Source: "radiru", StationID: "TBS"}, "station-id"},
+		{&Rule{Name: "radiko-radiru", Source: "radiko", StationID: "NHK-R1"}, "source"},
+		{&Rule{Name: "source", Source: "nhk"}, "source"},
+	}
+	for _, tt := range validatetests {
+		field := ""
+		var ruleErr *RuleError
+		if err := tt.rule.Validate(); errors.As(err, &ruleErr) {
+			field = ruleErr.Field
+		}
+		if field != tt.field {
+			t.Errorf("Validate(%s) => %q, want %q", tt.rule.Name, field, tt.field)
+		}
+	}
+}
+
+func TestRulesSource(t *testing.T) {
+	prog := &Prog{Ft: "20230605130000", To: "20230605140000", Title: "Title"}
+	var sourcetests = []struct {
+		rule      *Rule
+		stationID string
+		want      bool
+	}{
+		{&Rule{Name: "radiko", Title: "Title"}, "FMT", true},
+		{&Rule{Name: "radiko", Title: "Title"}, "NHK-FM", false},
+		{&Rule{Name: "radiru", Title: "Title", Source: "radiru"}, "NHK-FM", true},
+		{&Rule{Name: "radiru", Title: "Title", Source: "radiru"}, "FMT", false},
+		{&Rule{Name: "radiru-station", Title: "Title", StationID: "NHK-R1"}, "NHK-R1", true},
+		{&Rule{Name: "radiru-station", Title: "Title", StationID: "NHK-R1"}, "NHK-FM", false},
+	}
+	for _, tt := range sourcetests {
+		if got := tt.rule.Match(tt.stationID, prog); got != tt.want {
+			t.Errorf("%s.Match(%s) => %v, want %v", tt.rule.Name, tt.stationID, got, tt.want)
+		}
+		if got, _ := tt.rule.Explain(tt.stationID, prog); got != tt.want {
+			t.Errorf("%s.Explain(%s) => %v, want %v", tt.rule.Name, tt.stationID, got, tt.want)
+		}
+	}
+
+	var stationstests = []struct {
+		rules Rules
+		want  []string
+	}{
+		{Rules{&Rule{Name: "radiko"}}, []string{}},
+		{Rules{&Rule{Name: "fm", StationID: "NHK-FM"}, &Rule{Name: "fm2", Source: "radiru", StationID: "NHK-FM"}}, []string{"NHK-FM"}},
+		{Rules{&Rule{Name: "fm", StationID: "NHK-FM"}, &Rule{Name: "all", Source: "radiru"}}, RadiruStationIDs},
+	}
+	for _, tt := range stationstests {
+		if got := tt.rules.RadiruStations(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("RadiruStations => %v, want %v", got, tt.want)
+		}
+	}
+	if (Rules{&Rule{Name: "all", Source: "radiru"}}).HasRuleWithoutStationID() {
+		t.Error("HasRuleWithoutStationID => true for the radiru rule")
+	}
+}
@@ -0,0 +1,137 @@
+package radicron
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Script is the hooks written in Starlark for the matching and the naming beyond the rules:
+//
+//	def match(station_id, prog): # True to record, False to skip, or None to leave it to the rules
+//	def filename(prog):          # the file name without the extension, or None for the default
+type Script struct {
+	path     string
+	match    starlark.Callable
+	filename starlark.Callable
+}
+
+// LoadScript runs the script at path and returns its hooks
+func LoadScript(path string) (*Script, error) {
+	thread := newScriptThread(path)
+	globals, err := starlark.ExecFile(thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the script: %s", err)
+	}
+	globals.Freeze()
+	s := &Script{path: path}
+	for name, hook := range map[string]*starlark.Callable{"match": &s.match, "filename": &s.filename} {
+		v, ok := globals[name]
+		if !ok {
+			continue
+		}
+		if *hook, ok = v.(starlark.Callable); !ok {
+			return nil, fmt.Errorf("%s in the script is not a function: %s", name, v.Type())
+		}
+	}
+	return s, nil
+}
+
+// HasMatch returns true if the script decides the matching
+func (s *Script) HasMatch() bool {
+	return s != nil && s.match != nil
+}
+
+// Match returns whether to record the program, or matched by the rules if the script leaves it to them
+func (s *Script) Match(stationID string, p *Prog, matched bool) bool {
+	if record, decided := s.Decide(stationID, p); decided {
+		return record
+	}
+	return matched
+}
+
+// Decide returns whether to record the program, and decided false if the script leaves it to the rules
+func (s *Script) Decide(stationID string, p *Prog) (record, decided bool) {
+	if !s.HasMatch() {
+		return false, false
+	}
+	v, err := s.call(s.match, starlark.String(stationID), progValue(p))
+	if err != nil {
+		log.Printf("script match failed for [%s]%s (%s): %s", stationID, p.Title, p.Ft, err)
+		return false, false
+	}
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return false, false
+	case starlark.Bool:
+		return bool(v), true
+	}
+	log.Printf("script match returned %s for [%s]%s (%s), not a bool", v.Type(), stationID, p.Title, p.Ft)
+	return false, false
+}
+
+// FileName returns the file name of the program without the extension, or empty for the default
+func (s *Script) FileName(p *Prog) (string, error) {
+	if s == nil || s.filename == nil {
+		return "", nil
+	}
+	v, err := s.call(s.filename, progValue(p))
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return "", nil
+	case starlark.String:
+		return sanitizeFileName(strings.TrimSpace(string(v))), nil
+	}
+	return "", fmt.Errorf("filename returned %s, not a string", v.Type())
+}
+
+// call runs the hook in a new thread limited to ScriptMaxSteps
+func (s *Script) call(fn starlark.Callable, args ...starlark.Value) (starlark.Value, error) {
+	return starlark.Call(newScriptThread(s.path), fn, args, nil)
+}
+
+// newScriptThread returns a thread printing to the log
+func newScriptThread(path string) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: path,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Printf("script: %s", msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(ScriptMaxSteps)
+	return thread
+}
+
+// progValue returns the program as a struct for the script, e.g., prog.title
+func progValue(p *Prog) starlark.Value {
+	tags := make([]starlark.Value, len(p.Tags))
+	for i, tag := range p.Tags {
+		tags[i] = starlark.String(tag)
+	}
+	weekday := ""
+	if ft, err := time.ParseInLocation(DatetimeLayout, p.Ft, Location); err == nil {
+		weekday = strings.ToLower(ft.Weekday().String()[:3])
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"id":         starlark.String(p.ID),
+		"station_id": starlark.String(p.StationID),
+		"ft":         starlark.String(p.Ft),
+		"to":         starlark.String(p.To),
+		"weekday":    starlark.String(weekday),
+		"minutes":    starlark.MakeInt(int(p.Duration() / time.Minute)),
+		"title":      starlark.String(p.Title),
+		"desc":       starlark.String(p.Desc),
+		"info":       starlark.String(p.Info),
+		"pfm":        starlark.String(p.Pfm),
+		"tags":       starlark.NewList(tags),
+		"genre":      starlark.String(p.Genre.Program),
+		"episode":    starlark.MakeInt(p.Episode),
+	})
+}
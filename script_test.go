@@ -0,0 +1,105 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testScript = `
+def match(station_id, prog):
+    if "再放送" in prog.title:
+        return False
+    if station_id == "FMT" and prog.weekday == "mon" and prog.minutes >= 60:
+        return True
+    return None
+
+def filename(prog):
+    if prog.station_id == "TBS":
+        return None
+    return "%s/%s" % (prog.title, prog.ft[:8])
+`
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hooks.star")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScriptMatch(t *testing.T) {
+	s, err := LoadScript(writeScript(t, testScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matchtests = []struct {
+		stationID string
+		prog      *Prog
+		matched   bool
+		want      bool
+	}{
+		{"FMT", &Prog{Title: "THE TRAD", Ft: "20230605130000", To: "20230605145500"}, false, true},
+		{"FMT", &Prog{Title: "THE TRAD", Ft: "20230606130000", To: "20230606145500"}, false, false},
+		{"FMT", &Prog{Title: "THE TRAD", Ft: "20230606130000", To: "20230606145500"}, true, true},
+		{"FMT", &Prog{Title: "THE TRAD（再放送）", Ft: "20230605130000", To: "20230605145500"}, true, false},
+		{"TBS", &Prog{Title: "JUNK", Ft: "20230605010000", To: "20230605030000"}, false, false},
+	}
+	for _, tt := range matchtests {
+		if got := s.Match(tt.stationID, tt.prog, tt.matched); got != tt.want {
+			t.Errorf("Match(%s, %s, %v) => %v, want %v", tt.stationID, tt.prog.Title, tt.matched, got, tt.want)
+		}
+	}
+
+	var nilScript *Script
+	if nilScript.HasMatch() || !nilScript.Match("FMT", &Prog{}, true) {
+		t.Error("nil script does not leave the matching to the rules")
+	}
+}
+
+func TestScriptFileName(t *testing.T) {
+	s, err := LoadScript(writeScript(t, testScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filenametests = []struct {
+		prog *Prog
+		want string
+	}{
+		{&Prog{StationID: "FMT", Title: "THE TRAD", Ft: "20230605130000"}, "THE TRAD_20230605"},
+		{&Prog{StationID: "TBS", Title: "JUNK", Ft: "20230605010000"}, ""},
+	}
+	for _, tt := range filenametests {
+		got, err := s.FileName(tt.prog)
+		if err != nil || got != tt.want {
+			t.Errorf("FileName(%s) => %q, %v, want %q", tt.prog.Title, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	var loadscripttests = []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"syntax", "def match(station_id, prog)\n", true},
+		{"not a function", "filename = 'radiko'\n", true},
+	}
+	for _, tt := range loadscripttests {
+		if _, err := LoadScript(writeScript(t, tt.src)); (err != nil) != tt.wantErr {
+			t.Errorf("LoadScript(%s) => %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// a hook running forever fails and leaves it to the rules
+	s, err := LoadScript(writeScript(t, "def match(station_id, prog):\n    for i in range(1000000000):\n        pass\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Match("FMT", &Prog{}, true) {
+		t.Error("Match of the endless hook => false, want true")
+	}
+}
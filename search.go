@@ -0,0 +1,375 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// transcriptExts are the sidecar transcript files next to the audio
+var transcriptExts = []string{".txt", ".vtt", ".srt"}
+
+// SearchResult is a record matching the search query
+type SearchResult struct {
+	Record  *HistoryRecord `json:"record"`
+	Field   string         `json:"field"`
+	Snippet string         `json:"snippet"`
+	Score   int            `json:"score"`
+}
+
+type SearchResults []*SearchResult
+
+// SearchIndex is the inverted index of the saved records from the bigrams
+// in the metadata and the transcripts, saved next to the history
+type SearchIndex struct {
+	// Docs by the record ID
+	Docs map[string]*SearchDoc `json:"docs"`
+	// Grams to the IDs of the docs containing the bigram
+	Grams map[string]map[string]bool `json:"grams"`
+	path  string
+	mu    sync.Mutex
+}
+
+// SearchDoc is the text of a record in the index
+type SearchDoc struct {
+	Title      string `json:"title,omitempty"`
+	Pfm        string `json:"pfm,omitempty"`
+	Desc       string `json:"desc,omitempty"`
+	Info       string `json:"info,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+	// UpdatedAt of the record indexed
+	UpdatedAt time.Time `json:"updated_at"`
+	// TranscriptModTime of the transcript indexed
+	TranscriptModTime time.Time `json:"transcript_mod_time,omitempty"`
+}
+
+// searchField is a field of the doc to search
+type searchField struct {
+	name string
+	text string
+}
+
+// fields returns the fields in the order to pick the snippet from
+func (d *SearchDoc) fields() []searchField {
+	return []searchField{
+		{"title", d.Title},
+		{"pfm", d.Pfm},
+		{"desc", d.Desc},
+		{"info", d.Info},
+		{"transcript", d.Transcript},
+	}
+}
+
+// grams returns the bigrams of the fields in lower case, without the spaces
+func (d *SearchDoc) grams() map[string]bool {
+	grams := map[string]bool{}
+	for _, f := range d.fields() {
+		for _, g := range bigrams(strings.ToLower(f.text)) {
+			grams[g] = true
+		}
+	}
+	return grams
+}
+
+// bigrams returns the pairs of the adjacent runes without a space
+func bigrams(text string) []string {
+	grams := []string{}
+	runes := []rune(text)
+	for i := 0; i+1 < len(runes); i++ {
+		if unicode.IsSpace(runes[i]) || unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// newSearchDoc returns the doc of the record with the transcript next to the audio
+func newSearchDoc(r *HistoryRecord) *SearchDoc {
+	transcript, modTime := readTranscript(r.Path)
+	return &SearchDoc{
+		Title:             r.Title,
+		Pfm:               r.Pfm,
+		Desc:              r.Desc,
+		Info:              r.Info,
+		Transcript:        transcript,
+		UpdatedAt:         r.UpdatedAt,
+		TranscriptModTime: modTime,
+	}
+}
+
+// add indexes the doc, replacing the previous one of the id
+func (idx *SearchIndex) add(id string, doc *SearchDoc) {
+	idx.remove(id)
+	idx.Docs[id] = doc
+	for g := range doc.grams() {
+		if idx.Grams[g] == nil {
+			idx.Grams[g] = map[string]bool{}
+		}
+		idx.Grams[g][id] = true
+	}
+}
+
+// remove drops the doc of the id from the index
+func (idx *SearchIndex) remove(id string) {
+	doc, ok := idx.Docs[id]
+	if !ok {
+		return
+	}
+	for g := range doc.grams() {
+		delete(idx.Grams[g], id)
+		if len(idx.Grams[g]) == 0 {
+			delete(idx.Grams, g)
+		}
+	}
+	delete(idx.Docs, id)
+}
+
+// Add indexes the saved record and saves the index
+func (idx *SearchIndex) Add(r *HistoryRecord) error {
+	doc := newSearchDoc(r)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.add(r.ID, doc)
+	return idx.save()
+}
+
+// sync indexes the saved records updated or with the transcript changed since indexed,
+// e.g., by the other instances or the transcription afterwards, and drops the others
+func (idx *SearchIndex) sync(records map[string]*HistoryRecord) bool {
+	changed := false
+	for id := range idx.Docs {
+		if r, ok := records[id]; !ok || r.Path == "" {
+			idx.remove(id)
+			changed = true
+		}
+	}
+	for id, r := range records {
+		if r.Path == "" {
+			continue // not saved
+		}
+		doc, ok := idx.Docs[id]
+		if ok && !r.UpdatedAt.After(doc.UpdatedAt) && transcriptModTime(r.Path).Equal(doc.TranscriptModTime) {
+			continue
+		}
+		idx.add(id, newSearchDoc(r))
+		changed = true
+	}
+	return changed
+}
+
+// save writes the index next to the history
+func (idx *SearchIndex) save() error {
+	if idx.path == "" {
+		return nil
+	}
+	blob, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return writeFileSync(idx.path, blob)
+}
+
+// query returns the results with the IDs of the docs containing all the terms
+func (idx *SearchIndex) query(terms []string) map[string]*SearchResult {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	// the docs containing all the bigrams of the terms
+	var candidates map[string]bool
+	for _, term := range terms {
+		for _, g := range bigrams(term) {
+			ids := idx.Grams[g]
+			if candidates == nil {
+				candidates = map[string]bool{}
+				for id := range ids {
+					candidates[id] = true
+				}
+				continue
+			}
+			for id := range candidates {
+				if !ids[id] {
+					delete(candidates, id)
+				}
+			}
+		}
+	}
+	if candidates == nil { // only the terms of a rune
+		candidates = map[string]bool{}
+		for id := range idx.Docs {
+			candidates[id] = true
+		}
+	}
+
+	results := map[string]*SearchResult{}
+	for id := range candidates {
+		var result *SearchResult
+		matched := map[string]bool{}
+		for _, f := range idx.Docs[id].fields() {
+			text := strings.ToLower(f.text)
+			for _, term := range terms {
+				n := strings.Count(text, term)
+				if n == 0 {
+					continue
+				}
+				matched[term] = true
+				if result == nil {
+					result = &SearchResult{
+						Field:   f.name,
+						Snippet: snippet(f.text, term),
+					}
+				}
+				result.Score += n
+			}
+		}
+		if result != nil && len(matched) == len(terms) {
+			results[id] = result
+		}
+	}
+	return results
+}
+
+// loadSearchIndex loads the index at path, or returns an empty one if not exists or invalid
+func loadSearchIndex(path string) *SearchIndex {
+	idx := &SearchIndex{path: path}
+	if path != "" {
+		blob, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(blob, idx)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("rebuilding the search index: %s", err)
+			idx = &SearchIndex{path: path}
+		}
+	}
+	if idx.Docs == nil {
+		idx.Docs = map[string]*SearchDoc{}
+	}
+	if idx.Grams == nil {
+		idx.Grams = map[string]map[string]bool{}
+	}
+	return idx
+}
+
+// searchIndex returns the index loaded and synced with the records once
+func (h *History) searchIndex() *SearchIndex {
+	h.mu.Lock()
+	if h.index != nil {
+		defer h.mu.Unlock()
+		return h.index
+	}
+	path := ""
+	if h.path != "" {
+		path = filepath.Join(filepath.Dir(h.path), SearchIndexFileName)
+	}
+	idx := loadSearchIndex(path)
+	h.index = idx
+	records := make(map[string]*HistoryRecord, len(h.Records))
+	for id, r := range h.Records {
+		c := *r
+		records[id] = &c
+	}
+	// sync outside the history lock, the others wait for the index
+	idx.mu.Lock()
+	h.mu.Unlock()
+	defer idx.mu.Unlock()
+	if idx.sync(records) {
+		if err := idx.save(); err != nil {
+			log.Printf("failed to save the search index: %s", err)
+		}
+	}
+	return idx
+}
+
+// Search returns the saved records containing all the terms in the query
+// in the title, pfm, desc, info, or the transcript, sorted by the score
+func (h *History) Search(query string) SearchResults {
+	results := SearchResults{}
+	// unique terms to count all of them matched
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	if h == nil || len(terms) == 0 {
+		return results
+	}
+
+	matches := h.searchIndex().query(terms)
+
+	h.mu.Lock()
+	for id, result := range matches {
+		r, ok := h.Records[id]
+		if !ok || r.Path == "" {
+			continue
+		}
+		result.Record = r
+		results = append(results, result)
+	}
+	h.mu.Unlock()
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Record.Ft > results[j].Record.Ft
+		}
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// readTranscript returns the transcript next to the audio with its modification time if exists
+func readTranscript(audioPath string) (string, time.Time) {
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	for _, ext := range transcriptExts {
+		info, err := os.Stat(base + ext)
+		if err != nil {
+			continue
+		}
+		blob, err := os.ReadFile(base + ext)
+		if err == nil {
+			return string(blob), info.ModTime()
+		}
+	}
+	return "", time.Time{}
+}
+
+// transcriptModTime returns the modification time of the transcript next to the audio if exists
+func transcriptModTime(audioPath string) time.Time {
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	for _, ext := range transcriptExts {
+		if info, err := os.Stat(base + ext); err == nil {
+			return info.ModTime()
+		}
+	}
+	return time.Time{}
+}
+
+// snippet returns the text around the first term (case-insensitive) in the text
+func snippet(text, term string) string {
+	runes := []rune(strings.ReplaceAll(text, "\n", " "))
+	lower := []rune(strings.ToLower(string(runes)))
+	termRunes := []rune(term)
+	for i := 0; i+len(termRunes) <= len(lower); i++ {
+		if string(lower[i:i+len(termRunes)]) != term {
+			continue
+		}
+		start := i - SnippetRunes
+		if start < 0 {
+			start = 0
+		}
+		end := i + len(termRunes) + SnippetRunes
+		if end > len(runes) {
+			end = len(runes)
+		}
+		return string(runes[start:end])
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package radicron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistorySearch(t *testing.T) {
+	dir := t.TempDir()
+	h, err := LoadHistory(filepath.Join(dir, HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := filepath.Join(dir, "202306051300_FMT_title.aac")
+	if err = os.WriteFile(audio[:len(audio)-len(".aac")]+".txt", []byte("今日はシティポップの話をしました"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	progs := []*Prog{
+		{ID: "1", StationID: "FMT", Ft: "20230605130000", Title: "山崎怜奈の誰かに話したかったこと。", Pfm: "山崎怜奈"},
+		{ID: "2", StationID: "TBS", Ft: "20230606130000", Title: "Citypop Radio", Desc: "CITYPOP all night"},
+		{ID: "3", StationID: "QRR", Ft: "20230607130000", Title: "Citypop Radio"},
+	}
+	for i, p := range progs {
+		path := filepath.Join(dir, p.ID+".aac")
+		if i == 0 {
+			path = audio
+		}
+		if i == 2 {
+			if _, err = h.RecordFailure(p, os.ErrNotExist, 0, 0); err != nil { // not saved
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err = h.RecordSuccess(p, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var searchtests = []struct {
+		in  string
+		ids []string
+	}{
+		{"シティポップ", []string{"1"}},
+		{"citypop", []string{"2"}},
+		{"山崎怜奈 シティポップ", []string{"1"}},
+		{"山崎怜奈 citypop", []string{}},
+		{"citypop citypop", []string{"2"}},
+		{"シティポップ 山崎怜奈 シティポップ", []string{"1"}},
+		{"", []string{}},
+	}
+	for _, tt := range searchtests {
+		got := h.Search(tt.in)
+		if len(got) != len(tt.ids) {
+			t.Errorf("Search(%v) => %v results, want %v", tt.in, len(got), len(tt.ids))
+			continue
+		}
+		for i, id := range tt.ids {
+			if got[i].Record.ID != id {
+				t.Errorf("Search(%v)[%v] => %v, want %v", tt.in, i, got[i].Record.ID, id)
+			}
+		}
+	}
+	if got := h.Search("citypop"); got[0].Score != 2 {
+		t.Errorf("Search(citypop).Score => %v, want %v", got[0].Score, 2)
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := filepath.Join(dir, "1.aac")
+	prog := &Prog{ID: "1", StationID: "FMT", Ft: "20230605130000", Title: "Citypop Radio"}
+	if err = h.RecordSuccess(prog, audio); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, SearchIndexFileName)); err != nil {
+		t.Fatalf("search index => %v, want saved", err)
+	}
+	if got := h.Search("シティポップ"); len(got) != 0 {
+		t.Errorf("Search before the transcript => %v results, want 0", len(got))
+	}
+
+	// the transcript written afterwards is indexed on the next load
+	if err = os.WriteFile(filepath.Join(dir, "1.txt"), []byte("今日はシティポップの話をしました"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h, err = LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indextests = []struct {
+		in    string
+		field string
+		want  int
+	}{
+		{"シティポップ", "transcript", 1},
+		{"radio", "title", 1},
+		{"c", "title", 1},
+		{"jazz", "", 0},
+	}
+	for _, tt := range indextests {
+		got := h.Search(tt.in)
+		if len(got) != tt.want {
+			t.Errorf("Search(%v) => %v results, want %v", tt.in, len(got), tt.want)
+			continue
+		}
+		if tt.want > 0 && got[0].Field != tt.field {
+			t.Errorf("Search(%v).Field => %v, want %v", tt.in, got[0].Field, tt.field)
+		}
+	}
+}
+
+func TestBigrams(t *testing.T) {
+	got := bigrams("ab c日本")
+	want := []string{"ab", "c日", "日本"}
+	if len(got) != len(want) {
+		t.Fatalf("bigrams => %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bigrams[%v] => %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	got := snippet("Hello World", "world")
+	if got != "Hello World" {
+		t.Errorf("snippet => %v, want %v", got, "Hello World")
+	}
+	if got = snippet("Hello World", "none"); got != "" {
+		t.Errorf("snippet => %v, want empty", got)
+	}
+}
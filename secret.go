@@ -0,0 +1,93 @@
+package radicron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// LookupSecret returns the secret for the config key name
+// 1. the file in ${RADICRON_<NAME>_FILE}
+// 2. the docker secret in /run/secrets/<name>
+// 3. the value resolved by ResolveSecret
+func LookupSecret(ctx context.Context, name, value string) (string, error) {
+	env := fmt.Sprintf("RADICRON_%s_FILE", strings.ToUpper(strings.ReplaceAll(name, "-", "_")))
+	if path := os.Getenv(env); path != "" {
+		return readSecretFile(path)
+	}
+	if secret, err := readSecretFile(filepath.Join(DockerSecretsDir, name)); err == nil {
+		return secret, nil
+	}
+	return ResolveSecret(ctx, value)
+}
+
+// ResolveSecret returns the secret referred by the value
+// - "env:NAME" reads the environment variable NAME
+// - "file:PATH" reads the file at PATH
+// - "keychain:SERVICE/ACCOUNT" looks up the OS keychain
+// otherwise the value itself is the secret
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	var secret string
+	var err error
+
+	scheme, ref, found := strings.Cut(value, ":")
+	switch {
+	case found && scheme == "env":
+		secret = os.Getenv(ref)
+		if secret == "" {
+			err = fmt.Errorf("the environment variable %s is empty", ref)
+		}
+	case found && scheme == "file":
+		secret, err = readSecretFile(ref)
+	case found && scheme == "keychain":
+		secret, err = lookupKeychain(ctx, ref)
+	default:
+		secret = value
+	}
+	if err != nil {
+		return "", err
+	}
+	RegisterSecret(secret)
+	return secret, nil
+}
+
+// lookupKeychain looks up the secret for "service/account" in the OS keychain
+func lookupKeychain(ctx context.Context, ref string) (string, error) {
+	service, account, found := strings.Cut(ref, "/")
+	if !found {
+		return "", fmt.Errorf("invalid keychain reference: %s", ref)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", fmt.Errorf("keychain is not supported on %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain lookup failed for %s: %s", ref, err)
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if secret == "" {
+		return "", errors.New("empty secret in the keychain")
+	}
+	return secret, nil
+}
+
+// readSecretFile reads the secret from the file without the trailing newline
+func readSecretFile(path string) (string, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(blob), "\r\n"), nil
+}
@@ -0,0 +1,63 @@
+package radicron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RADICRON_TEST_SECRET", "from-env")
+
+	var secrettests = []struct {
+		in  string
+		out string
+		err bool
+	}{
+		{"plain-value", "plain-value", false},
+		{"env:RADICRON_TEST_SECRET", "from-env", false},
+		{"env:RADICRON_TEST_NONEXISTENT", "", true},
+		{"file:" + path, "from-file", false},
+		{"file:" + filepath.Join(dir, "nonexistent"), "", true},
+		{"keychain:invalid", "", true},
+	}
+	for _, tt := range secrettests {
+		got, err := ResolveSecret(context.Background(), tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ResolveSecret(%v) error => %v", tt.in, err)
+		}
+		if got != tt.out {
+			t.Errorf("ResolveSecret(%v) => %v, want %v", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestLookupSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LookupSecret(context.Background(), "test-password", "from-config")
+	if err != nil {
+		t.Error(err)
+	}
+	if got != "from-config" {
+		t.Errorf("LookupSecret => %v, want %v", got, "from-config")
+	}
+
+	t.Setenv("RADICRON_TEST_PASSWORD_FILE", path)
+	got, err = LookupSecret(context.Background(), "test-password", "from-config")
+	if err != nil {
+		t.Error(err)
+	}
+	if got != "from-file" {
+		t.Errorf("LookupSecret => %v, want %v", got, "from-file")
+	}
+}
@@ -0,0 +1,304 @@
+package radicron
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Segment is a media segment in the chunklist
+type Segment struct {
+	Index           int
+	SeqID           uint64
+	URI             string
+	Duration        time.Duration
+	ProgramDateTime time.Time
+	Discontinuity   bool
+	Key             *SegmentKey
+	Limit           int64 // the length of the byte range, 0 for the whole resource
+	Offset          int64 // the start of the byte range
+	Map             *SegmentMap
+}
+
+// SegmentMap is the EXT-X-MAP init section to prepend to the segment,
+// only set when the init section changes
+type SegmentMap struct {
+	URI    string
+	Limit  int64
+	Offset int64
+}
+
+// Range returns the HTTP Range header value for the init section
+func (m *SegmentMap) Range() string {
+	return byteRange(m.Limit, m.Offset)
+}
+
+// SegmentKey is the EXT-X-KEY to decrypt the segment
+type SegmentKey struct {
+	Method string
+	URI    string
+	IV     string
+}
+
+// Range returns the HTTP Range header value for the byte range
+func (s *Segment) Range() string {
+	return byteRange(s.Limit, s.Offset)
+}
+
+// IV returns the initialization vector of the segment,
+// or the media sequence number if not specified
+func (s *Segment) IV() ([]byte, error) {
+	if s.Key.IV == "" {
+		iv := make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], s.SeqID)
+		return iv, nil
+	}
+	iv, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s.Key.IV, "0x"), "0X"))
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid IV: %s", s.Key.IV)
+	}
+	return iv, nil
+}
+
+// IsEncrypted returns true if the segment needs to be decrypted
+func (s *Segment) IsEncrypted() bool {
+	return s.Key != nil && s.Key.Method != "" && s.Key.Method != KeyMethodNone
+}
+
+// FileName returns the file name to save the segment in order
+func (s *Segment) FileName() string {
+	ext := ".aac"
+	if u, err := url.Parse(s.URI); err == nil && path.Ext(u.Path) != "" {
+		ext = path.Ext(u.Path)
+	}
+	return fmt.Sprintf("%06d%s", s.Index, ext)
+}
+
+type Segments []*Segment
+
+// Groups splits the segments at each discontinuity
+func (ss Segments) Groups() []Segments {
+	groups := []Segments{}
+	for _, s := range ss {
+		if len(groups) == 0 || s.Discontinuity {
+			groups = append(groups, Segments{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], s)
+	}
+	return groups
+}
+
+// Without returns the segments except the given ones
+func (ss Segments) Without(excluded Segments) Segments {
+	if len(excluded) == 0 {
+		return ss
+	}
+	skip := map[int]bool{}
+	for _, s := range excluded {
+		skip[s.Index] = true
+	}
+	filtered := Segments{}
+	for _, s := range ss {
+		if !skip[s.Index] {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// Duration returns the total duration of the segments
+func (ss Segments) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range ss {
+		d += s.Duration
+	}
+	return d
+}
+
+// Trim returns the segments overlapping [ft, to) by the program date-time,
+// the offset to cut at the start, and the length of the program
+// the segments are returned as is without the date-time
+func (ss Segments) Trim(ft, to time.Time) (Segments, time.Duration, time.Duration) {
+	if len(ss) == 0 || ss[0].ProgramDateTime.IsZero() {
+		return ss, 0, ss.Duration()
+	}
+	trimmed := Segments{}
+	for _, s := range ss {
+		if s.ProgramDateTime.IsZero() {
+			// cannot tell the boundaries
+			return ss, 0, ss.Duration()
+		}
+		end := s.ProgramDateTime.Add(s.Duration)
+		if !end.After(ft) || !s.ProgramDateTime.Before(to) {
+			continue
+		}
+		trimmed = append(trimmed, s)
+	}
+	if len(trimmed) == 0 {
+		return ss, 0, ss.Duration()
+	}
+	offset := ft.Sub(trimmed[0].ProgramDateTime)
+	if offset < 0 {
+		offset = 0
+	}
+	length := trimmed.Duration() - offset
+	last := trimmed[len(trimmed)-1]
+	if spill := last.ProgramDateTime.Add(last.Duration).Sub(to); spill > 0 {
+		length -= spill
+	}
+	return trimmed, offset, length
+}
+
+// segmentKeys caches the keys to decrypt the segments
+type segmentKeys struct {
+	keys map[string][]byte
+	mu   sync.Mutex
+}
+
+// Decrypt returns the segment data decrypted with AES-128
+func (sk *segmentKeys) Decrypt(ctx context.Context, s *Segment, data []byte) ([]byte, error) {
+	if s.Key.Method != KeyMethodAES128 {
+		return nil, fmt.Errorf("unsupported encryption: %s", s.Key.Method)
+	}
+	key, err := sk.get(ctx, s.Key.URI)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := s.IV()
+	if err != nil {
+		return nil, err
+	}
+	return decryptAES128(data, key, iv)
+}
+
+// get returns the key at uri, fetching it once
+func (sk *segmentKeys) get(ctx context.Context, uri string) ([]byte, error) {
+	sk.mu.Lock()
+	defer sk.mu.Unlock()
+	if key, ok := sk.keys[uri]; ok {
+		return key, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get the key: %s", resp.Status)
+	}
+	key, err := io.ReadAll(io.LimitReader(resp.Body, aes.BlockSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid key length: %d", len(key))
+	}
+	sk.keys[uri] = key
+	return key, nil
+}
+
+// decryptAES128 decrypts the data in AES-128-CBC with PKCS#7 padding
+func decryptAES128(data, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted data length: %d", len(data))
+	}
+	decrypted := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errors.New("invalid padding")
+	}
+	return decrypted[:len(decrypted)-padding], nil
+}
+
+// byteRange returns the HTTP Range header value
+func byteRange(limit, offset int64) string {
+	if limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+limit-1)
+}
+
+// fetchRange returns the resource at uri in the byte range if any
+func fetchRange(ctx context.Context, uri, byteRange string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, errors.New(resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func newSegmentKeys() *segmentKeys {
+	return &segmentKeys{keys: map[string][]byte{}}
+}
+
+// identity returns the key to find the same bytes in the segments
+func (s *Segment) identity() string {
+	id := s.URI + "|" + s.Range()
+	if s.IsEncrypted() {
+		id += fmt.Sprintf("|%s|%s|%d", s.Key.URI, s.Key.IV, s.SeqID)
+	}
+	if s.Map != nil {
+		id += "|" + s.Map.URI + "|" + s.Map.Range()
+	}
+	return id
+}
+
+// dedupe returns the segments with the unique content
+// and the later segments repeating each of them
+func (ss Segments) dedupe() (Segments, map[*Segment]Segments) {
+	seen := map[string]*Segment{}
+	unique := Segments{}
+	dups := map[*Segment]Segments{}
+	for _, s := range ss {
+		id := s.identity()
+		if orig, ok := seen[id]; ok {
+			dups[orig] = append(dups[orig], s)
+			continue
+		}
+		seen[id] = s
+		unique = append(unique, s)
+	}
+	return unique, dups
+}
+
+// directWritable returns true if the segments can be concatenated as they are
+func (ss Segments) directWritable() bool {
+	for i, s := range ss {
+		if s.IsEncrypted() || s.Map != nil || s.Limit > 0 || (i > 0 && s.Discontinuity) ||
+			!strings.EqualFold(path.Ext(s.FileName()), ".aac") {
+			return false
+		}
+	}
+	return len(ss) > 0
+}
@@ -0,0 +1,159 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSegmentsTrim(t *testing.T) {
+	pdt := time.Date(2023, 6, 5, 12, 59, 57, 0, Location)
+	ss := Segments{}
+	for i := 0; i < 6; i++ {
+		ss = append(ss, &Segment{
+			Index:           i,
+			URI:             "https://radiko.jp/sound/a.aac",
+			Duration:        5 * time.Second,
+			ProgramDateTime: pdt.Add(time.Duration(i) * 5 * time.Second),
+		})
+	}
+	ft := time.Date(2023, 6, 5, 13, 0, 0, 0, Location)
+
+	var trimtests = []struct {
+		to     time.Time
+		n      int
+		offset time.Duration
+		length time.Duration
+	}{
+		// 12:59:57-13:00:27 => 13:00:00-13:00:20
+		{ft.Add(20 * time.Second), 5, 3 * time.Second, 20 * time.Second},
+		// the playlist ends before to
+		{ft.Add(time.Minute), 6, 3 * time.Second, 27 * time.Second},
+	}
+	for _, tt := range trimtests {
+		trimmed, offset, length := ss.Trim(ft, tt.to)
+		if len(trimmed) != tt.n || offset != tt.offset || length != tt.length {
+			t.Errorf("Trim(%v) => %v, %v, %v, want %v, %v, %v", tt.to, len(trimmed), offset, length, tt.n, tt.offset, tt.length)
+		}
+	}
+
+	// without the date-time
+	ss[2].ProgramDateTime = time.Time{}
+	trimmed, offset, length := ss.Trim(ft, ft.Add(20*time.Second))
+	if len(trimmed) != 6 || offset != 0 || length != 30*time.Second {
+		t.Errorf("Trim without the date-time => %v, %v, %v, want 6, 0, 30s", len(trimmed), offset, length)
+	}
+}
+
+func TestSegmentFileName(t *testing.T) {
+	var filenametests = []struct {
+		in  *Segment
+		out string
+	}{
+		{&Segment{Index: 12, URI: "https://radiko.jp/sound/b/FMT/20230605_130000_AbCdE.aac"}, "000012.aac"},
+		{&Segment{Index: 3, URI: "https://example.com/seg?id=3"}, "000003.aac"},
+		{&Segment{Index: 0, URI: "https://example.com/seg.ts?token=abc"}, "000000.ts"},
+	}
+	for _, tt := range filenametests {
+		if got := tt.in.FileName(); got != tt.out {
+			t.Errorf("(%v).FileName() => %v, want %v", tt.in.URI, got, tt.out)
+		}
+	}
+}
+
+func TestSegmentsDirectWritable(t *testing.T) {
+	aac := "https://radiko.jp/a.aac"
+	var writabletests = []struct {
+		name string
+		in   Segments
+		out  bool
+	}{
+		{"plain", Segments{{URI: aac}, {URI: aac}}, true},
+		{"discontinuity at the start", Segments{{URI: aac, Discontinuity: true}, {URI: aac}}, true},
+		{"discontinuity", Segments{{URI: aac}, {URI: aac, Discontinuity: true}}, false},
+		{"encrypted", Segments{{URI: aac, Key: &SegmentKey{Method: KeyMethodAES128}}}, false},
+		{"byterange", Segments{{URI: aac, Limit: 100}}, false},
+		{"init section", Segments{{URI: aac, Map: &SegmentMap{URI: aac}}}, false},
+		{"ts", Segments{{URI: "https://example.com/a.ts"}}, false},
+		{"empty", Segments{}, false},
+	}
+	for _, tt := range writabletests {
+		if got := tt.in.directWritable(); got != tt.out {
+			t.Errorf("directWritable(%v) => %v, want %v", tt.name, got, tt.out)
+		}
+	}
+}
+
+func TestSegmentsDedupe(t *testing.T) {
+	a := "https://radiko.jp/a.aac"
+	b := "https://radiko.jp/b.aac"
+	segments := Segments{
+		{Index: 0, URI: a},
+		{Index: 1, URI: b},
+		{Index: 2, URI: a},
+		{Index: 3, URI: a, Limit: 100},
+		{Index: 4, URI: a, SeqID: 4, Key: &SegmentKey{Method: KeyMethodAES128, URI: "https://radiko.jp/key"}},
+		{Index: 5, URI: a, SeqID: 5, Key: &SegmentKey{Method: KeyMethodAES128, URI: "https://radiko.jp/key"}},
+		{Index: 6, URI: b},
+	}
+	unique, dups := segments.dedupe()
+	if len(unique) != 5 {
+		t.Errorf("dedupe => %v unique segments, want 5", len(unique))
+	}
+	if len(dups[segments[0]]) != 1 || dups[segments[0]][0].Index != 2 {
+		t.Errorf("dedupe => %v repeating #0, want #2", dups[segments[0]])
+	}
+	if len(dups[segments[1]]) != 1 || dups[segments[1]][0].Index != 6 {
+		t.Errorf("dedupe => %v repeating #1, want #6", dups[segments[1]])
+	}
+}
+
+func TestSegmentKeysDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	plain := []byte("ADTS frames of the segment")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(key)
+	}))
+	defer ts.Close()
+
+	segment := &Segment{SeqID: 7, Key: &SegmentKey{Method: KeyMethodAES128, URI: ts.URL}}
+	iv, err := segment.IV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iv[15] != 7 {
+		t.Errorf("IV => %x, want the sequence number", iv)
+	}
+
+	// encrypt with PKCS#7 padding
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	block, _ := aes.NewCipher(key)
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	keys := newSegmentKeys()
+	decrypted, err := keys.Decrypt(context.Background(), segment, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plain) {
+		t.Errorf("Decrypt => %q, want %q", decrypted, plain)
+	}
+	if _, err = keys.Decrypt(context.Background(), segment, encrypted[:5]); err == nil {
+		t.Error("Decrypt the truncated data => nil, want error")
+	}
+	segment.Key.Method = "SAMPLE-AES"
+	if _, err = keys.Decrypt(context.Background(), segment, encrypted); err == nil {
+		t.Error("Decrypt SAMPLE-AES => nil, want error")
+	}
+	segment.Key.IV = "0x00"
+	if _, err = segment.IV(); err == nil {
+		t.Error("IV 0x00 => nil, want error")
+	}
+}
@@ -0,0 +1,80 @@
+package radicron
+
+import (
+	"sort"
+)
+
+// Series is a followed program numbering its episodes in the order of the broadcast
+type Series struct {
+	StationID string `json:"station_id"`
+	Title     string `json:"title"`
+	// Episodes numbered by the program ID
+	Episodes map[string]int `json:"episodes"`
+}
+
+// SeriesKey returns the key of the series of the program, the station and the title
+func SeriesKey(prog *Prog) string {
+	return prog.StationID + "/" + prog.Title
+}
+
+// IDs returns the program IDs in the order of the episodes
+func (s *Series) IDs() []string {
+	ids := make([]string, 0, len(s.Episodes))
+	for id := range s.Episodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.Episodes[ids[i]] < s.Episodes[ids[j]]
+	})
+	return ids
+}
+
+// Episode returns the number of the program in its series,
+// numbering it next to the last if new and saving it unless dry
+func (h *History) Episode(prog *Prog, dry bool) (int, error) {
+	if h == nil {
+		return 0, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := SeriesKey(prog)
+	if dry {
+		return h.episode(key, prog, false), nil
+	}
+	// number it next to the episodes saved by the other instances
+	var n int
+	err := h.update(func() {
+		n = h.episode(key, prog, true)
+	})
+	return n, err
+}
+
+// episode returns the number of the program in the series of key, adding it if add
+func (h *History) episode(key string, prog *Prog, add bool) int {
+	s, ok := h.Series[key]
+	if !ok {
+		s = &Series{
+			StationID: prog.StationID,
+			Title:     prog.Title,
+			Episodes:  map[string]int{},
+		}
+	}
+	if n, ok := s.Episodes[prog.ID]; ok {
+		return n
+	}
+	n := 1
+	for _, e := range s.Episodes {
+		if e >= n {
+			n = e + 1
+		}
+	}
+	if !add {
+		return n
+	}
+	if h.Series == nil {
+		h.Series = map[string]*Series{}
+	}
+	s.Episodes[prog.ID] = n
+	h.Series[key] = s
+	return n
+}
@@ -0,0 +1,54 @@
+package radicron
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryEpisode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var episodetests = []struct {
+		id    string
+		title string
+		dry   bool
+		want  int
+	}{
+		{"1", "Series", false, 1},
+		{"2", "Series", true, 2},
+		{"2", "Series", false, 2},
+		{"1", "Series", false, 1},
+		{"3", "Another", false, 1},
+		{"4", "Series", false, 3},
+	}
+	for _, tt := range episodetests {
+		prog := &Prog{ID: tt.id, StationID: "FMT", Title: tt.title}
+		got, err := h.Episode(prog, tt.dry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Episode(%s, %v) => %d, want %d", tt.id, tt.dry, got, tt.want)
+		}
+	}
+
+	// the numbers survive the restart
+	h, err = LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := h.Series["FMT/Series"]
+	if !ok {
+		t.Fatalf("Series => %v, want FMT/Series", h.Series)
+	}
+	if got := s.IDs(); len(got) != 3 || got[0] != "1" || got[1] != "2" || got[2] != "4" {
+		t.Errorf("IDs => %v, want [1 2 4]", got)
+	}
+	if n, _ := (*History)(nil).Episode(&Prog{ID: "1"}, false); n != 0 {
+		t.Errorf("Episode without the history => %d, want 0", n)
+	}
+}
@@ -0,0 +1,208 @@
+package radicron
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yyoshiki41/radigo"
+)
+
+// Server serves the podcast feed of the downloaded files
+type Server struct {
+	BaseURL     string
+	DownloadDir string
+	// EpisodeTitle template for the episode titles, e.g., "{title} {date:2006-01-02}"
+	EpisodeTitle string
+	EventLogPath string
+	HistoryPath  string
+	Listeners    *Listeners
+	Title        string
+}
+
+// Handler returns the http.Handler for the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed.xml", s.authorize(s.handleFeed))
+	mux.HandleFunc("/feeds.opml", s.authorize(s.handleOPML))
+	mux.HandleFunc("/audio/", s.authorize(s.handleAudio))
+	mux.HandleFunc("/artwork/", s.authorize(s.handleArtwork))
+	mux.HandleFunc("/api/search", s.authorize(s.handleSearch))
+	mux.HandleFunc("/api/events", s.authorize(s.handleEvents))
+	// the web UI forwards the token to the API
+	web, _ := fs.Sub(WebAssets, "assets/web")
+	mux.Handle("/", http.FileServer(http.FS(web)))
+	return mux
+}
+
+// authorize allows the request with a valid listener token once any listener has been registered
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// reload the listeners added or revoked by another process, e.g., `radicron listeners`
+		if s.Listeners != nil {
+			if err := s.Listeners.Reload(); err != nil {
+				log.Printf("failed to reload the listeners: %s", err)
+			}
+		}
+		if s.Listeners != nil && s.Listeners.IsRestricted() {
+			if _, ok := s.Listeners.Authorize(r.URL.Query().Get("token")); !ok {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) baseURL(r *http.Request) string {
+	if s.BaseURL != "" {
+		return s.BaseURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+func (s *Server) handleAudio(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(strings.TrimPrefix(r.URL.Path, "/audio/"))
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if strings.HasPrefix(name, ".") || (ext != radigo.AudioFormatAAC && ext != radigo.AudioFormatMP3) {
+		http.NotFound(w, r) // serve only the audio files
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(s.DownloadDir, name))
+}
+
+func (s *Server) handleArtwork(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(strings.TrimPrefix(r.URL.Path, "/artwork/"))
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if strings.HasPrefix(name, ".") || (ext != radigo.AudioFormatAAC && ext != radigo.AudioFormatMP3) {
+		http.NotFound(w, r) // only from the audio files
+		return
+	}
+	data, mimeType, err := ReadArtwork(filepath.Join(s.DownloadDir, name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	_, _ = w.Write(data)
+}
+
+// handleEvents returns the latest events for the timeline, of the program with the id if given
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := LoadEvents(s.EventLogPath, r.URL.Query().Get("id"), EventsAPILimit)
+	if err != nil {
+		log.Printf("failed to load the events: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(events); err != nil {
+		log.Printf("failed to encode the events: %s", err)
+	}
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	// load the latest history saved by the recorder
+	history, err := LoadHistory(s.HistoryPath)
+	if err != nil {
+		log.Printf("failed to load the history: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(history.Search(r.URL.Query().Get("q"))); err != nil {
+		log.Printf("failed to encode the search results: %s", err)
+	}
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	episodes, err := LoadEpisodes(s.DownloadDir)
+	if err != nil {
+		log.Printf("failed to load the episodes: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// the family-friendly feed
+	if r.URL.Query().Get("clean") == "true" {
+		episodes = episodes.Clean()
+	}
+	// the per-show feed
+	title := s.Title
+	if show := r.URL.Query().Get("show"); show != "" {
+		episodes = episodes.Show(show)
+		title = fmt.Sprintf("%s - %s", s.Title, show)
+	}
+	rss := NewPodcastFeed(title, s.baseURL(r), r.URL.Query().Get("token"), episodes)
+	// for the podcast apps sorting by the title
+	if s.EpisodeTitle != "" {
+		for i, e := range episodes {
+			rss.Channel.Items[i].Title = FormatEpisodeTitle(s.EpisodeTitle, e.Title, e.StationID, e.PubDate)
+		}
+	}
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err = enc.Encode(rss); err != nil {
+		log.Printf("failed to encode the feed: %s", err)
+	}
+}
+
+func (s *Server) handleOPML(w http.ResponseWriter, r *http.Request) {
+	episodes, err := LoadEpisodes(s.DownloadDir)
+	if err != nil {
+		log.Printf("failed to load the episodes: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	opml := NewOPML(s.Title, s.baseURL(r), r.URL.Query().Get("token"), episodes.Shows())
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err = enc.Encode(opml); err != nil {
+		log.Printf("failed to encode the opml: %s", err)
+	}
+}
+
+// NewServer returns a Server for the downloads in ${RADICRON_HOME}
+func NewServer(title, baseURL string) (*Server, error) {
+	downloadDir, err := DownloadDir()
+	if err != nil {
+		return nil, err
+	}
+	eventLogPath, err := EventLogPath()
+	if err != nil {
+		return nil, err
+	}
+	historyPath, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	listenersPath, err := getRadicronPath(ListenersFileName)
+	if err != nil {
+		return nil, err
+	}
+	listeners, err := LoadListeners(listenersPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		DownloadDir:  downloadDir,
+		EventLogPath: eventLogPath,
+		HistoryPath:  historyPath,
+		Listeners:    listeners,
+		Title:        title,
+	}, nil
+}
@@ -0,0 +1,148 @@
+package radicron
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "202306051300_FMT_title.aac"), []byte("audio"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	listeners, err := LoadListeners(filepath.Join(dir, ListenersFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		DownloadDir:  dir,
+		EventLogPath: filepath.Join(dir, EventLogFileName),
+		HistoryPath:  filepath.Join(dir, HistoryFileName),
+		Listeners:    listeners,
+		Title:        DefaultFeedTitle,
+	}
+	handler := s.Handler()
+
+	// without listeners, the feed is open
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.xml", http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /feed.xml => %v, want %v", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "http://example.com/audio/202306051300_FMT_title.aac") {
+		t.Errorf("GET /feed.xml => %v", rec.Body.String())
+	}
+
+	// the episode title template
+	s.EpisodeTitle = "{title} {date}"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.xml", http.NoBody))
+	if !strings.Contains(rec.Body.String(), "<title>title 2023-06-05</title>") {
+		t.Errorf("GET /feed.xml with the episode title => %v", rec.Body.String())
+	}
+	s.EpisodeTitle = ""
+
+	// the per-show feeds
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feeds.opml", http.NoBody))
+	if !strings.Contains(rec.Body.String(), `xmlUrl="http://example.com/feed.xml?show=title"`) {
+		t.Errorf("GET /feeds.opml => %v", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.xml?show=other", http.NoBody))
+	if strings.Contains(rec.Body.String(), "<item>") {
+		t.Errorf("GET /feed.xml?show=other => %v", rec.Body.String())
+	}
+
+	token, err := listeners.Add("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var servertests = []struct {
+		target string
+		code   int
+	}{
+		{"/feed.xml", http.StatusUnauthorized},
+		{"/feed.xml?token=invalid", http.StatusUnauthorized},
+		{"/feed.xml?token=" + token, http.StatusOK},
+		{"/feed.xml?clean=true&token=" + token, http.StatusOK},
+		{"/feeds.opml", http.StatusUnauthorized},
+		{"/feeds.opml?token=" + token, http.StatusOK},
+		{"/audio/202306051300_FMT_title.aac", http.StatusUnauthorized},
+		{"/audio/202306051300_FMT_title.aac?token=" + token, http.StatusOK},
+		{"/audio/" + ListenersFileName + "?token=" + token, http.StatusNotFound},
+		{"/artwork/202306051300_FMT_title.aac", http.StatusUnauthorized},
+		{"/artwork/202306051300_FMT_title.aac?token=" + token, http.StatusNotFound},
+		{"/api/search?q=title", http.StatusUnauthorized},
+		{"/api/search?q=title&token=" + token, http.StatusOK},
+		{"/api/events", http.StatusUnauthorized},
+		{"/api/events?id=12345&token=" + token, http.StatusOK},
+		{"/", http.StatusOK},
+		{"/missing.html", http.StatusNotFound},
+	}
+	for _, tt := range servertests {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, http.NoBody))
+		if rec.Code != tt.code {
+			t.Errorf("GET %v => %v, want %v", tt.target, rec.Code, tt.code)
+		}
+	}
+
+	// revoking the only listener keeps the feed closed
+	if err = listeners.Revoke("alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"/feed.xml", "/feed.xml?token=" + token, "/audio/202306051300_FMT_title.aac", "/api/search?q=title"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET %v after revoked => %v, want %v", target, rec.Code, http.StatusUnauthorized)
+		}
+	}
+	// nor reopened on restart
+	if listeners, err = LoadListeners(filepath.Join(dir, ListenersFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if !listeners.IsRestricted() {
+		t.Error("IsRestricted after revoked and reloaded => false, want true")
+	}
+}
+
+func TestServerReloadListeners(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	s, err := NewServer(DefaultFeedTitle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(s.DownloadDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	handler := s.Handler()
+	// another process, e.g., `radicron listeners`, manages the listeners
+	manager, err := NewServer(DefaultFeedTitle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := manager.Listeners.Add("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.xml?token="+token, http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /feed.xml after added => %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	if err = manager.Listeners.Revoke("alice"); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.xml?token="+token, http.NoBody))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /feed.xml after revoked => %v, want %v", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,107 @@
+package radicron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AuthSession shares an authorized Device among the downloads for an area
+type AuthSession struct {
+	AreaID    string
+	Device    *Device
+	ExpiresAt time.Time
+	mu        sync.Mutex
+}
+
+// Authorize returns the authorized Device and refreshes the token within authTimeout
+// if expired or expiring in TokenRefreshMinutes
+func (s *AuthSession) Authorize(ctx context.Context, a *Asset) (*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.IsValid(time.Now().Add(TokenRefreshMinutes * time.Minute)) {
+		return s.Device, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authTimeout)
+	defer cancel()
+	device, err := a.NewDevice(ctx, s.AreaID)
+	if err != nil {
+		return nil, err
+	}
+	s.Device = device
+	s.ExpiresAt = time.Now().Add(TokenLifetimeMinutes * time.Minute)
+	log.Printf("authorized for %s until %v", s.AreaID, s.ExpiresAt.In(Location))
+	return device, nil
+}
+
+// Invalidate discards the token to force the next Authorize to refresh
+func (s *AuthSession) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Device = nil
+}
+
+// IsValid returns true if the session has a token not expired at t
+func (s *AuthSession) IsValid(t time.Time) bool {
+	return s.Device != nil && t.Before(s.ExpiresAt)
+}
+
+// AuthSessions holds an AuthSession for each area
+type AuthSessions struct {
+	sessions map[string]*AuthSession
+	mu       sync.Mutex
+}
+
+// Get returns the AuthSession for the area, creating one if not exists
+func (ss *AuthSessions) Get(areaID string) *AuthSession {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	s, ok := ss.sessions[areaID]
+	if !ok {
+		s = &AuthSession{AreaID: areaID}
+		ss.sessions[areaID] = s
+	}
+	return s
+}
+
+// AuthorizeAll authorizes the areas in parallel, e.g., before the downloads from the stations in them,
+// and returns the errors of the areas failed
+func (ss *AuthSessions) AuthorizeAll(ctx context.Context, a *Asset, areaIDs []string) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := []error{}
+	for _, areaID := range areaIDs {
+		wg.Add(1)
+		go func(s *AuthSession) {
+			defer wg.Done()
+			if _, err := s.Authorize(ctx, a); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to authorize for %s: %w", s.AreaID, err))
+				mu.Unlock()
+			}
+		}(ss.Get(areaID))
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	// errors.Join requires go1.20, wrap the first and append the others
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	err := errs[0]
+	for _, e := range errs[1:] {
+		err = fmt.Errorf("%w; %s", err, e)
+	}
+	return err
+}
+
+func NewAuthSessions() *AuthSessions {
+	return &AuthSessions{
+		sessions: map[string]*AuthSession{},
+	}
+}
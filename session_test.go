@@ -0,0 +1,91 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yyoshiki41/go-radiko"
+)
+
+func TestAuthSessions(t *testing.T) {
+	ss := NewAuthSessions()
+	s := ss.Get("JP13")
+	if s.AreaID != "JP13" {
+		t.Errorf("s.AreaID => %v, want %v", s.AreaID, "JP13")
+	}
+	if ss.Get("JP13") != s {
+		t.Error("Get(JP13) returned a different session")
+	}
+	if ss.Get("JP27") == s {
+		t.Error("Get(JP27) returned the session for JP13")
+	}
+}
+
+func TestAuthSessionIsValid(t *testing.T) {
+	now := time.Now()
+	s := &AuthSession{
+		AreaID:    "JP13",
+		Device:    &Device{AuthToken: "token"},
+		ExpiresAt: now.Add(time.Minute),
+	}
+	if !s.IsValid(now) {
+		t.Error("IsValid => false, want true")
+	}
+	if s.IsValid(now.Add(2 * time.Minute)) {
+		t.Error("IsValid after the expiry => true, want false")
+	}
+	s.Invalidate()
+	if s.IsValid(now) {
+		t.Error("IsValid after Invalidate => true, want false")
+	}
+}
+
+func TestAuthorizeAll(t *testing.T) {
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset := &Asset{AuthSessions: NewAuthSessions(), DefaultClient: client}
+	blob, err := VersionsJSON.ReadFile("assets/versions.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(blob, &asset.Versions); err != nil {
+		t.Fatal(err)
+	}
+
+	// the areas authorized or expiring
+	valid := asset.AuthSessions.Get("JP13")
+	valid.Device, valid.ExpiresAt = &Device{AuthToken: "token"}, time.Now().Add(time.Hour)
+	expiring := asset.AuthSessions.Get("JP14")
+	expiring.Device, expiring.ExpiresAt = &Device{AuthToken: "token"}, time.Now().Add(time.Minute)
+
+	timeout := 200 * time.Millisecond
+	shortenTimeouts(t, timeout)
+	transport := http.DefaultTransport
+	http.DefaultTransport = stallTransport{}
+	defer func() { http.DefaultTransport = transport }()
+
+	start := time.Now()
+	err = asset.AuthSessions.AuthorizeAll(context.Background(), asset, []string{"JP1", "JP13", "JP14", "JP27", "JP40"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AuthorizeAll() => %v, want %v", err, context.DeadlineExceeded)
+	}
+	// in parallel, not one by one
+	if elapsed := time.Since(start); elapsed > 3*timeout {
+		t.Errorf("AuthorizeAll() => took %v for 4 areas timing out in %v", elapsed, timeout)
+	}
+	for _, areaID := range []string{"JP1", "JP14", "JP27", "JP40"} {
+		if err == nil || !strings.Contains(err.Error(), "for "+areaID+":") {
+			t.Errorf("AuthorizeAll() => %v, want the error for %s", err, areaID)
+		}
+	}
+	if err != nil && strings.Contains(err.Error(), "JP13") {
+		t.Errorf("AuthorizeAll() => %v, want JP13 cached", err)
+	}
+}
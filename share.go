@@ -0,0 +1,49 @@
+package radicron
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ParseShareURL returns the station and the time in a radiko URL, either
+// - the share URL: https://radiko.jp/share/?sid=TBS&t=20230605130000
+// - the timefree URL: https://radiko.jp/#!/ts/TBS/20230605130000
+func ParseShareURL(rawURL string) (stationID, t string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid radiko URL: %s", err)
+	}
+	if u.Host != "radiko.jp" && !strings.HasSuffix(u.Host, ".radiko.jp") {
+		return "", "", fmt.Errorf("not a radiko URL: %s", rawURL)
+	}
+
+	switch {
+	case u.Query().Get("sid") != "":
+		stationID = u.Query().Get("sid")
+		t = u.Query().Get("t")
+	case strings.HasPrefix(u.Fragment, "!/ts/"):
+		parts := strings.Split(strings.TrimPrefix(u.Fragment, "!/ts/"), "/")
+		if len(parts) >= 2 {
+			stationID, t = parts[0], parts[1]
+		}
+	}
+	if stationID == "" || t == "" {
+		return "", "", fmt.Errorf("no station or time in the URL: %s", rawURL)
+	}
+	if _, err = time.ParseInLocation(DatetimeLayout, t, Location); err != nil {
+		return "", "", fmt.Errorf("invalid time in the URL: %s", t)
+	}
+	return stationID, t, nil
+}
+
+// At returns the program on air at t, or nil if none
+func (ps Progs) At(t string) *Prog {
+	for _, p := range ps {
+		if p.Ft <= t && t < p.To {
+			return p
+		}
+	}
+	return nil
+}
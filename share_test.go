@@ -0,0 +1,58 @@
+package radicron
+
+import "testing"
+
+func TestParseShareURL(t *testing.T) {
+	var sharetests = []struct {
+		rawURL    string
+		stationID string
+		t         string
+		ok        bool
+	}{
+		{"https://radiko.jp/share/?sid=TBS&t=20230605130000", "TBS", "20230605130000", true},
+		{"https://radiko.jp/share/?t=20230605130000&sid=FMT&noreload=1", "FMT", "20230605130000", true},
+		{"https://radiko.jp/#!/ts/QRR/20230605220000", "QRR", "20230605220000", true},
+		{"http://www.radiko.jp/#!/ts/INT/20230605220000/", "INT", "20230605220000", true},
+		{"https://radiko.jp/#!/live/TBS", "", "", false},
+		{"https://radiko.jp/share/?sid=TBS", "", "", false},
+		{"https://radiko.jp/share/?sid=TBS&t=yesterday", "", "", false},
+		{"https://example.com/share/?sid=TBS&t=20230605130000", "", "", false},
+		{"://radiko.jp", "", "", false},
+	}
+	for _, tt := range sharetests {
+		stationID, got, err := ParseShareURL(tt.rawURL)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseShareURL(%q) => %v, want ok %v", tt.rawURL, err, tt.ok)
+			continue
+		}
+		if stationID != tt.stationID || got != tt.t {
+			t.Errorf("ParseShareURL(%q) => %v, %v, want %v, %v", tt.rawURL, stationID, got, tt.stationID, tt.t)
+		}
+	}
+}
+
+func TestProgsAt(t *testing.T) {
+	progs := Progs{
+		{ID: "1", Ft: "20230605130000", To: "20230605145500"},
+		{ID: "2", Ft: "20230605145500", To: "20230605160000"},
+	}
+	var attests = []struct {
+		t    string
+		want string
+	}{
+		{"20230605130000", "1"},
+		{"20230605140000", "1"},
+		{"20230605145500", "2"},
+		{"20230605160000", ""},
+		{"20230605120000", ""},
+	}
+	for _, tt := range attests {
+		got := ""
+		if p := progs.At(tt.t); p != nil {
+			got = p.ID
+		}
+		if got != tt.want {
+			t.Errorf("At(%v) => %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafov/m3u8"
+	"github.com/yyoshiki41/radigo"
+)
+
+// simulcasts are the live captures in progress by the program ID
+var simulcasts sync.Map
+
+// GetSimulcast returns the station-owned simulcast of the station, or empty if none
+func (a *Asset) GetSimulcast(stationID string) string {
+	if s, ok := a.StationSettings[stationID]; ok {
+		return s.Simulcast
+	}
+	return ""
+}
+
+// SimulcastPath returns the live capture of the program in ${RADICRON_HOME}
+func SimulcastPath(prog *Prog) (string, error) {
+	return getRadicronPath(filepath.Join(SimulcastDirName, sanitizeFileName(prog.StationID+"_"+prog.ID)+".aac"))
+}
+
+// simulcastCapture returns the live capture of the program, or empty if none
+func simulcastCapture(prog *Prog) string {
+	path, err := SimulcastPath(prog)
+	if err != nil {
+		return ""
+	}
+	if _, err = os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// captureSimulcast records the station-owned simulcast of the program live
+// as the standby for the timefree, once at a time for each program
+func captureSimulcast(ctx context.Context, prog *Prog, uri string) {
+	if _, loaded := simulcasts.LoadOrStore(prog.ID, true); loaded {
+		return
+	}
+	defer simulcasts.Delete(prog.ID)
+	log.Printf("+scheduled the live capture [%s]%s (%s)", prog.StationID, prog.Title, prog.Ft)
+	if err := captureLive(ctx, prog, uri); err != nil {
+		log.Printf("failed to capture the simulcast [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, err)
+	}
+}
+
+// captureLive waits for the broadcast of the program and records the live playlist at uri in ${RADICRON_HOME}
+// from the start to the end with the paddings, booting ahead to authorize and skip the segments before the start
+func captureLive(ctx context.Context, prog *Prog, uri string) error {
+	if simulcastCapture(prog) != "" {
+		return nil
+	}
+	ft, to, err := prog.Span()
+	if err != nil {
+		return err
+	}
+	path, err := SimulcastPath(prog)
+	if err != nil {
+		return err
+	}
+	start, end := ft.Add(-prog.PadBefore), to.Add(prog.PadAfter)
+	bootAhead := time.Duration(0)
+	if asset := GetAsset(ctx); asset != nil {
+		bootAhead = asset.LiveBootAhead
+	}
+
+	// wait for the broadcast
+	timer := time.NewTimer(time.Until(start.Add(-bootAhead)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	log.Printf("capturing the simulcast [%s]%s (%s): %s", prog.StationID, prog.Title, prog.Ft, uri)
+	if err = recordLive(ctx, uri, start, end, path); err != nil {
+		return err
+	}
+	log.Printf("+captured the simulcast: %s", path)
+	return nil
+}
+
+// recordLive appends the new segments of the live playlist at uri to dst in aac from the start
+// until the end, or until ctx is canceled to keep what is captured so far
+func recordLive(ctx context.Context, uri string, start, end time.Time, dst string) error {
+	dir, err := tempAACDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	keys := newSegmentKeys()
+	seen := map[string]bool{}
+	capture := ""
+	var f *os.File
+	for stopped := false; !stopped && time.Now().Before(end); {
+		segments, err := liveChunklist(ctx, uri)
+		if err != nil {
+			log.Printf("failed to get the simulcast playlist: %s", err)
+		}
+		// the segments before the start aired ahead of the program
+		booting := time.Now().Before(start)
+		for _, s := range segments {
+			id := s.identity()
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if booting {
+				continue
+			}
+			s.Index = len(seen)
+			if err = downloadSegment(ctx, s, keys, dir); err != nil {
+				log.Printf("failed to get the simulcast segment: %s", err)
+				continue
+			}
+			// the container of the first segment, e.g., ts
+			if f == nil {
+				capture = filepath.Join(dir, "capture"+filepath.Ext(s.FileName()))
+				if f, err = os.Create(capture); err != nil {
+					return err
+				}
+				defer f.Close()
+			}
+			if err = appendFile(f, filepath.Join(dir, s.FileName())); err != nil {
+				return err
+			}
+		}
+
+		// not to overshoot the start or the end
+		wait := SimulcastPollSeconds * time.Second
+		if d := time.Until(start); booting && d < wait {
+			wait = d
+		}
+		if d := time.Until(end); d < wait {
+			wait = d
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			stopped = true
+		}
+	}
+	if f == nil {
+		return errors.New("no segments captured")
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(capture), ".aac") {
+		return moveFile(capture, dst)
+	}
+	// finalize even if stopped
+	ctx, cancel := transcodeContext(context.Background(), end.Sub(start))
+	defer cancel()
+	return runFFmpeg(ctx, nil, "-i", capture, "-vn", "-c:a", "copy", "-y", dst)
+}
+
+// liveChunklist returns the segments in the live playlist, following the first variant of the master
+func liveChunklist(ctx context.Context, uri string) (Segments, error) {
+	ctx, cancel := context.WithTimeout(ctx, playlistTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if header := requestHeader(ctx); header != nil {
+		req.Header = header
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", uri, resp.Status)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if _, lt, err := m3u8.DecodeFrom(bytes.NewReader(raw), false); err == nil && lt == m3u8.MASTER {
+		variant, err := getURI(bytes.NewReader(raw), uri, false)
+		if err != nil {
+			return nil, err
+		}
+		return liveChunklist(ctx, variant)
+	}
+	return getChunklist(bytes.NewReader(raw), uri, false)
+}
+
+// appendFile appends the segment file to f and removes it
+func appendFile(f *os.File, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// saveSimulcast saves the live capture of the program to the output and writes the tag
+func saveSimulcast(ctx context.Context, prog *Prog, capture string, output *radigo.OutputConfig) error {
+	asset := GetAsset(ctx)
+	emit(ctx, EventProgress, prog, "saving the simulcast")
+	var err error
+	switch output.AudioFormat() {
+	case radigo.AudioFormatAAC:
+		err = moveFile(capture, output.AbsPath())
+	case radigo.AudioFormatMP3:
+		tctx, cancel := transcodeContext(ctx, prog.Duration())
+		defer cancel()
+		if err = radigo.ConvertAACtoMP3(tctx, capture, output.AbsPath()); err == nil {
+			err = os.Remove(capture)
+		}
+	default:
+		err = fmt.Errorf("invalid file format")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the output file: %s", err)
+	}
+	return finishOutput(ctx, asset, prog, output)
+}
+
+// removeSimulcast removes the live capture of the program no longer needed
+func removeSimulcast(prog *Prog) {
+	if capture := simulcastCapture(prog); capture != "" {
+		if err := os.Remove(capture); err != nil {
+			log.Printf("failed to remove the simulcast: %s", err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordLive(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvRadicronHome, home)
+	if err := os.Mkdir(filepath.Join(home, "tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=64000\nlive.m3u8\n")
+		case "/live.m3u8":
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:10\n"+
+				"#EXTINF:5.0,\n10.aac\n#EXTINF:5.0,\n11.aac\n")
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	segments, err := liveChunklist(context.Background(), ts.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 || segments[0].URI != ts.URL+"/10.aac" {
+		t.Fatalf("liveChunklist => %v, want 2 segments from %s/10.aac", segments, ts.URL)
+	}
+
+	dst := filepath.Join(t.TempDir(), "capture.aac")
+	if err = recordLive(context.Background(), ts.URL+"/master.m3u8", time.Now(), time.Now().Add(200*time.Millisecond), dst); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the segments repeated in the reloaded playlist only once
+	if want := []byte("/10.aac/11.aac"); !bytes.Equal(got, want) {
+		t.Errorf("recordLive => %q, want %q", got, want)
+	}
+}
+
+func TestSimulcastCapture(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	prog := &Prog{ID: "12345", StationID: "FMT"}
+	asset := &Asset{StationSettings: StationSettings{"FMT": {Simulcast: "https://example.com/live.m3u8"}}}
+	if got := asset.GetSimulcast("FMT"); got != "https://example.com/live.m3u8" {
+		t.Errorf("GetSimulcast(FMT) => %q", got)
+	}
+	if got := asset.GetSimulcast("TBS"); got != "" {
+		t.Errorf("GetSimulcast(TBS) => %q, want empty", got)
+	}
+
+	if got := simulcastCapture(prog); got != "" {
+		t.Errorf("simulcastCapture before captured => %q, want empty", got)
+	}
+	path, err := SimulcastPath(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, []byte("aac"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := simulcastCapture(prog); got != path {
+		t.Errorf("simulcastCapture => %q, want %q", got, path)
+	}
+	removeSimulcast(prog)
+	if got := simulcastCapture(prog); got != "" {
+		t.Errorf("simulcastCapture after removed => %q, want empty", got)
+	}
+}
@@ -0,0 +1,148 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stationsMu guards the stations cached in ${RADICRON_HOME}
+var stationsMu sync.Mutex
+
+// cachedStations is the stations fetched from radiko at FetchedAt
+type cachedStations struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Stations  Stations  `json:"stations"`
+}
+
+// LoadStations returns the stations with all the areas they are on air in,
+// cached in ${RADICRON_HOME} for StationsCacheHours, and falls back to the stale cache if radiko fails
+func LoadStations(ctx context.Context, areaIDs []string) (Stations, error) {
+	stationsMu.Lock()
+	defer stationsMu.Unlock()
+
+	path, err := getRadicronPath(StationsFileName)
+	if err != nil {
+		return nil, err
+	}
+	cache, cerr := readStations(path)
+	if cerr == nil && time.Since(cache.FetchedAt) < StationsCacheHours*time.Hour {
+		return cache.Stations, nil
+	}
+
+	stations, err := FetchStations(ctx, areaIDs)
+	if err != nil {
+		if cerr == nil {
+			log.Printf("using the stations cached at %s: %s", cache.FetchedAt.In(Location).Format(time.RFC3339), err)
+			return cache.Stations, nil
+		}
+		return nil, err
+	}
+	if err = writeStations(path, &cachedStations{FetchedAt: time.Now(), Stations: stations}); err != nil {
+		log.Printf("failed to cache the stations: %s", err)
+	}
+	return stations, nil
+}
+
+// FetchStations returns the stations in the full region with the home area first,
+// and the other areas they are on air in from the station lists of areaIDs
+func FetchStations(ctx context.Context, areaIDs []string) (Stations, error) {
+	ctx, cancel := context.WithTimeout(ctx, playlistTimeout)
+	defer cancel()
+
+	xmlRegion, err := FetchXMLRegion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stations := Stations{}
+	for _, xmlStations := range xmlRegion.Region {
+		for _, xmlStation := range xmlStations.Stations {
+			stations.add(xmlStation, xmlStation.AreaID)
+		}
+	}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("no stations in %s", APIRegionFull)
+	}
+
+	// the station lists in parallel, merged in the order of areaIDs
+	lists := make([]XMLStationList, len(areaIDs))
+	sem := make(chan struct{}, StationListConcurrency)
+	var wg sync.WaitGroup
+	for i, areaID := range areaIDs {
+		wg.Add(1)
+		go func(i int, areaID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			list, err := FetchStationList(ctx, areaID)
+			if err != nil {
+				log.Printf("failed to get the stations in %s: %s", areaID, err)
+				return
+			}
+			lists[i] = list
+		}(i, areaID)
+	}
+	wg.Wait()
+	for i, list := range lists {
+		for _, xmlStation := range list.Stations {
+			stations.add(xmlStation, areaIDs[i])
+		}
+	}
+	return stations, nil
+}
+
+// add adds the station on air in areaID unless already added there
+func (ss Stations) add(xmlStation XMLRegionStation, areaID string) {
+	station, ok := ss[xmlStation.ID]
+	if !ok {
+		ss[xmlStation.ID] = &Station{
+			Areas: []string{areaID},
+			Name:  xmlStation.Name,
+			Ruby:  xmlStation.Ruby,
+		}
+		return
+	}
+	for _, a := range station.Areas {
+		if a == areaID {
+			return
+		}
+	}
+	station.Areas = append(station.Areas, areaID)
+}
+
+// readStations reads the stations cached at path
+func readStations(path string) (*cachedStations, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := &cachedStations{}
+	if err = json.Unmarshal(blob, cache); err != nil {
+		return nil, err
+	}
+	if len(cache.Stations) == 0 {
+		return nil, fmt.Errorf("no stations in %s", path)
+	}
+	return cache, nil
+}
+
+// writeStations caches the stations at path
+func writeStations(path string, cache *cachedStations) error {
+	blob, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, blob, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,99 @@
+package radicron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadStations(t *testing.T) {
+	t.Setenv(EnvRadicronHome, t.TempDir())
+	down := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/v3/station/region/full.xml":
+			w.Write([]byte(`<region>
+<stations region_id="kanto" region_name="関東"><station><id>TBS</id><name>TBSラジオ</name><area_id>JP13</area_id></station></stations>
+<stations region_id="kinki" region_name="近畿"><station><id>ABC</id><name>ABCラジオ</name><area_id>JP27</area_id></station></stations>
+</region>`))
+		case "/v3/station/list/JP1.xml":
+			w.Write([]byte(`<stations area_id="JP1"><station><id>NEW</id><name>New Radio</name></station><station><id>TBS</id><name>TBSラジオ</name></station></stations>`))
+		case "/v3/station/list/JP13.xml":
+			w.Write([]byte(`<stations area_id="JP13"><station><id>TBS</id><name>TBSラジオ</name></station></stations>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	server, _ := url.Parse(ts.URL)
+	transport := http.DefaultTransport
+	http.DefaultTransport = rewriteTransport{server: server, next: transport}
+	defer func() { http.DefaultTransport = transport }()
+
+	areaIDs := []string{"JP1", "JP13", "JP27"}
+	want := Stations{
+		"ABC": {Areas: []string{"JP27"}, Name: "ABCラジオ"},
+		"NEW": {Areas: []string{"JP1"}, Name: "New Radio"},
+		"TBS": {Areas: []string{"JP13", "JP1"}, Name: "TBSラジオ"},
+	}
+	path, err := getRadicronPath(StationsFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stationtests = []struct {
+		name    string
+		down    bool
+		stale   bool
+		noCache bool
+		valid   bool
+	}{
+		{"fetched", false, false, false, true},
+		{"cached", true, false, false, true},
+		{"stale cache", true, true, false, true},
+		{"no cache", true, false, true, false},
+	}
+	for _, tt := range stationtests {
+		down = tt.down
+		if tt.stale {
+			cache, err := readStations(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cache.FetchedAt = time.Now().Add(-StationsCacheHours * time.Hour)
+			if err = writeStations(path, cache); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.noCache {
+			t.Setenv(EnvRadicronHome, filepath.Join(t.TempDir(), "empty"))
+		}
+		got, err := LoadStations(context.Background(), areaIDs)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: LoadStations() => %v, want valid %v", tt.name, err, tt.valid)
+			continue
+		}
+		if tt.valid && !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: LoadStations() => %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestRegionsAreaIDs(t *testing.T) {
+	rs := Regions{
+		"kanto": {{ID: "JP13", Name: "東京"}, {ID: "JP11", Name: "埼玉"}},
+		"kinki": {{ID: "JP27", Name: "大阪"}},
+	}
+	if got, want := rs.AreaIDs(), []string{"JP11", "JP13", "JP27"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AreaIDs() => %v, want %v", got, want)
+	}
+}
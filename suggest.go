@@ -0,0 +1,59 @@
+package radicron
+
+import (
+	"sort"
+	"strings"
+)
+
+// RuleSuggestion is a rule proposed from the saved programs
+type RuleSuggestion struct {
+	Rule  *Rule
+	Count int
+}
+
+// SuggestRules proposes a rule for each show saved at least min times
+// without any of the rules matching it, the most saved first
+func (h *History) SuggestRules(rules Rules, min int) []*RuleSuggestion {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := map[[2]string]int{}
+	for _, r := range h.Records {
+		if r.Path == "" {
+			continue // not saved
+		}
+		prog := &Prog{ID: r.ID, StationID: r.StationID, Ft: r.Ft, To: r.To, Title: r.Title, Pfm: r.Pfm, Desc: r.Desc, Info: r.Info}
+		if rules.HasMatch(r.StationID, prog) {
+			continue
+		}
+		counts[[2]string{r.StationID, r.Title}]++
+	}
+
+	suggestions := []*RuleSuggestion{}
+	for show, n := range counts {
+		if n < min {
+			continue
+		}
+		suggestions = append(suggestions, &RuleSuggestion{
+			Rule: &Rule{
+				Name:      suggestedRuleName(show[0], show[1]),
+				StationID: show[0],
+				Title:     show[1],
+			},
+			Count: n,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		return suggestions[i].Rule.Name < suggestions[j].Rule.Name
+	})
+	return suggestions
+}
+
+// suggestedRuleName returns the rule name usable as a config key
+func suggestedRuleName(stationID, title string) string {
+	r := strings.NewReplacer(".", "", " ", "-", "　", "-")
+	return strings.ToLower(stationID + "-" + r.Replace(title))
+}
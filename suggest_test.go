@@ -0,0 +1,49 @@
+package radicron
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSuggestRules(t *testing.T) {
+	h := &History{Records: map[string]*HistoryRecord{}}
+	add := func(stationID, title string, n int, saved bool) {
+		for i := 0; i < n; i++ {
+			id := fmt.Sprintf("%s%s%d", stationID, title, i)
+			r := &HistoryRecord{ID: id, StationID: stationID, Ft: fmt.Sprintf("202306%02d050000", i+1), Title: title}
+			if saved {
+				r.Path = id + ".aac"
+			}
+			h.Records[id] = r
+		}
+	}
+	add("FMT", "Morning Show", 4, true)
+	add("TBS", "Night.Talk", 3, true)
+	add("TBS", "Rare", 1, true)
+	add("QRR", "Failed", 5, false)
+	add("LFR", "Covered", 6, true)
+
+	rules := Rules{&Rule{Name: "covered", Title: "Covered"}}
+	got := h.SuggestRules(rules, 2)
+	var suggesttests = []struct {
+		name  string
+		count int
+	}{
+		{"fmt-morning-show", 4},
+		{"tbs-nighttalk", 3},
+	}
+	if len(got) != len(suggesttests) {
+		t.Fatalf("SuggestRules => %d suggestions, want %d", len(got), len(suggesttests))
+	}
+	for i, tt := range suggesttests {
+		if got[i].Rule.Name != tt.name || got[i].Count != tt.count {
+			t.Errorf("SuggestRules[%d] => %s (%d), want %s (%d)", i, got[i].Rule.Name, got[i].Count, tt.name, tt.count)
+		}
+	}
+
+	// the suggested rule matches the show
+	p := &Prog{StationID: "TBS", Ft: "20230610050000", Title: "Night.Talk"}
+	if !got[1].Rule.Match(p.StationID, p) {
+		t.Errorf("%s does not match %s", got[1].Rule.Name, p.Title)
+	}
+}
@@ -0,0 +1,174 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Summarizer requests the episode summary to an OpenAI-compatible API
+type Summarizer struct {
+	APIKey   string
+	Client   *http.Client
+	Endpoint string
+	Model    string
+	Prompt   string
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Probe lists the models at the endpoint to check the address and the API key without summarizing
+func (s *Summarizer) Probe(ctx context.Context) error {
+	uri := strings.TrimSuffix(s.Endpoint, "/") + "/models"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return err
+	}
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("the API key is rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+// Summarize returns a short summary of the transcript
+func (s *Summarizer) Summarize(ctx context.Context, transcript string) (string, error) {
+	runes := []rune(transcript)
+	if len(runes) > SummaryInputRunes {
+		runes = runes[:SummaryInputRunes]
+	}
+	blob, err := json.Marshal(chatRequest{
+		Model: s.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: s.Prompt},
+			{Role: "user", Content: string(runes)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	uri := strings.TrimSuffix(s.Endpoint, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(blob))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("summarization failed: %s", resp.Status)
+	}
+
+	var cr chatResponse
+	if err = json.NewDecoder(resp.Body).Decode(&cr); err != nil {
+		return "", err
+	}
+	if len(cr.Choices) == 0 || cr.Choices[0].Message.Content == "" {
+		return "", errors.New("empty summary")
+	}
+	return strings.TrimSpace(cr.Choices[0].Message.Content), nil
+}
+
+// SummarizePending summarizes the saved records with a transcript but without a summary,
+// skipping the ones failed not to block the others
+func (s *Summarizer) SummarizePending(ctx context.Context, h *History) (int, error) {
+	pending := []*HistoryRecord{}
+	h.mu.Lock()
+	for _, r := range h.Records {
+		if r.Path != "" && r.Summary == "" {
+			pending = append(pending, r)
+		}
+	}
+	h.mu.Unlock()
+
+	n, failed := 0, 0
+	for _, r := range pending {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		transcript, _ := readTranscript(r.Path)
+		if transcript == "" {
+			continue
+		}
+		summary, err := s.Summarize(ctx, transcript)
+		if err != nil {
+			log.Printf("failed to summarize %s: %s", r.Path, err)
+			failed++
+			continue
+		}
+		if err = WriteSummary(r.Path, summary); err != nil {
+			log.Printf("failed to write the summary of %s: %s", r.Path, err)
+			failed++
+			continue
+		}
+		if err = h.SetSummary(r.ID, summary); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if failed > 0 {
+		return n, fmt.Errorf("failed to summarize %d programs", failed)
+	}
+	return n, nil
+}
+
+// WriteSummary writes the summary next to the audio
+func WriteSummary(audioPath, summary string) error {
+	return os.WriteFile(summaryPath(audioPath), []byte(summary), 0o644) //nolint:gosec
+}
+
+// readSummary returns the summary next to the audio if exists
+func readSummary(audioPath string) string {
+	blob, err := os.ReadFile(summaryPath(audioPath))
+	if err != nil {
+		return ""
+	}
+	return string(blob)
+}
+
+func summaryPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + SummaryFileSuffix
+}
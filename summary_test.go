@@ -0,0 +1,106 @@
+package radicron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummarizePending(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) != 2 || strings.Contains(req.Messages[1].Content, "rejected") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" シティポップ特集 \n"}}]}`))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	h, err := LoadHistory(filepath.Join(dir, HistoryFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := filepath.Join(dir, "202301010000_FMT_test.aac")
+	if err = h.RecordSuccess(&Prog{ID: "1", StationID: "FMT"}, audio); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.RecordSuccess(&Prog{ID: "2", StationID: "FMT"}, filepath.Join(dir, "no-transcript.aac")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "202301010000_FMT_test.txt"), []byte("今日はシティポップ特集"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the endpoint rejects, not to block the others
+	if err = h.RecordSuccess(&Prog{ID: "3", StationID: "FMT"}, filepath.Join(dir, "rejected.aac")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "rejected.txt"), []byte("rejected"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Summarizer{APIKey: "sk-test", Endpoint: ts.URL + "/v1/", Model: DefaultSummarizeModel, Prompt: DefaultSummarizePrompt}
+	n, err := s.SummarizePending(context.Background(), h)
+	if err == nil {
+		t.Error("SummarizePending => nil, want the error of the rejected")
+	}
+	if n != 1 {
+		t.Errorf("SummarizePending => %v, want 1", n)
+	}
+	want := "シティポップ特集"
+	if got := h.Records["1"].Summary; got != want {
+		t.Errorf("Summary => %q, want %q", got, want)
+	}
+	if got := readSummary(audio); got != want {
+		t.Errorf("readSummary => %q, want %q", got, want)
+	}
+
+	// unauthorized
+	s.APIKey = "wrong"
+	if _, err = s.Summarize(context.Background(), "test"); err == nil {
+		t.Error("Summarize with a wrong key => nil, want error")
+	}
+}
+
+func TestSummarizerProbe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer ts.Close()
+
+	var probetests = []struct {
+		apiKey  string
+		wantErr bool
+	}{
+		{"sk-test", false},
+		{"sk-wrong", true},
+	}
+	for _, tt := range probetests {
+		s := &Summarizer{APIKey: tt.apiKey, Endpoint: ts.URL + "/v1"}
+		if err := s.Probe(context.Background()); (err != nil) != tt.wantErr {
+			t.Errorf("Probe(%s) => %v, want error %v", tt.apiKey, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package radicron
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// episodeTitlePlaceholder matches {title}, {station}, {date}, or {date:layout}
+var episodeTitlePlaceholder = regexp.MustCompile(`\{(title|station|date)(?::([^}]*))?\}`)
+
+// episodeTitleBraces matches anything in the braces to find the typos in the placeholders
+var episodeTitleBraces = regexp.MustCompile(`\{[^}]*\}`)
+
+// ValidateEpisodeTitle returns an error if the template has an unknown placeholder
+func ValidateEpisodeTitle(template string) error {
+	for _, placeholder := range episodeTitleBraces.FindAllString(template, -1) {
+		if !episodeTitlePlaceholder.MatchString(placeholder) {
+			return fmt.Errorf("unknown placeholder %s ({title}, {station}, {date}, or {date:layout})", placeholder)
+		}
+	}
+	return nil
+}
+
+// FormatEpisodeTitle fills the template, e.g., "{title} {date:2006-01-02}",
+// with the program broadcast at date
+func FormatEpisodeTitle(template, title, stationID string, date time.Time) string {
+	return episodeTitlePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		m := episodeTitlePlaceholder.FindStringSubmatch(placeholder)
+		switch m[1] {
+		case "title":
+			return title
+		case "station":
+			return stationID
+		default:
+			layout := m[2]
+			if layout == "" {
+				layout = EpisodeTitleDateLayout
+			}
+			return date.In(Location).Format(layout)
+		}
+	})
+}
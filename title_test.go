@@ -0,0 +1,41 @@
+package radicron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatEpisodeTitle(t *testing.T) {
+	date := time.Date(2023, 6, 5, 13, 0, 0, 0, Location)
+	var titletests = []struct {
+		template string
+		want     string
+	}{
+		{"{title}", "Title"},
+		{"{title} {date}", "Title 2023-06-05"},
+		{"{date:20060102} {title} ({station})", "20230605 Title (FMT)"},
+		{"#{date:2006-01-02 15:04} {unknown}", "#2023-06-05 13:00 {unknown}"},
+	}
+	for _, tt := range titletests {
+		if got := FormatEpisodeTitle(tt.template, "Title", "FMT", date); got != tt.want {
+			t.Errorf("FormatEpisodeTitle(%v) => %v, want %v", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEpisodeTitle(t *testing.T) {
+	var validatetests = []struct {
+		template string
+		wantErr  bool
+	}{
+		{"", false},
+		{"{title} {date:2006-01-02} ({station})", false},
+		{"{title} {data}", true},
+		{"{Title}", true},
+	}
+	for _, tt := range validatetests {
+		if err := ValidateEpisodeTitle(tt.template); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateEpisodeTitle(%v) => %v, want error %v", tt.template, err, tt.wantErr)
+		}
+	}
+}
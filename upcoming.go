@@ -0,0 +1,220 @@
+package radicron
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upcoming remembers the matched programs until the timefree becomes available,
+// to record them even if they drop out of the guide or radicron restarts meanwhile
+type Upcoming struct {
+	Programs map[string]*UpcomingProg `json:"programs"`
+	path     string
+	mu       sync.Mutex
+}
+
+// UpcomingProg is a program to record once available
+type UpcomingProg struct {
+	Prog        *Prog     `json:"prog"`
+	AvailableAt time.Time `json:"available_at"`
+	// Dropped from the guide, reported once
+	Dropped bool `json:"dropped,omitempty"`
+}
+
+// GuideChange is an upcoming program moved or dropped in the guide
+type GuideChange struct {
+	// Prog as remembered
+	Prog *Prog
+	// Moved to the program in the guide, nil if dropped
+	Moved *Prog
+}
+
+// Diff compares the upcoming programs of the station not started at t with the guide,
+// and returns the changes not reported yet; the moved ones are rescheduled,
+// or left to the rules if they have another ID, and the dropped ones are kept
+func (u *Upcoming) Diff(stationID string, guide Progs, t time.Time) ([]*GuideChange, error) {
+	if len(guide) == 0 {
+		return nil, nil
+	}
+	// the span of the guide
+	first, last := guide[0].Ft, guide[0].To
+	for _, p := range guide {
+		if p.Ft < first {
+			first = p.Ft
+		}
+		if p.To > last {
+			last = p.To
+		}
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	changes := []*GuideChange{}
+	for id, up := range u.Programs {
+		prog := up.Prog
+		ft, err := time.ParseInLocation(DatetimeLayout, prog.Ft, Location)
+		if err != nil || prog.StationID != stationID || !ft.After(t) || prog.Ft < first || prog.Ft >= last {
+			continue
+		}
+		moved := u.counterpart(guide, prog)
+		switch {
+		case moved == nil:
+			if !up.Dropped {
+				up.Dropped = true
+				changes = append(changes, &GuideChange{Prog: prog})
+			}
+		case moved.ID == prog.ID:
+			up.Dropped = false
+			if moved.Ft == prog.Ft && moved.To == prog.To {
+				continue
+			}
+			changes = append(changes, &GuideChange{Prog: prog, Moved: moved})
+			to, err := time.ParseInLocation(DatetimeLayout, moved.To, Location)
+			if err == nil {
+				oldTo, _ := time.ParseInLocation(DatetimeLayout, prog.To, Location)
+				up.AvailableAt = up.AvailableAt.Add(to.Sub(oldTo))
+			}
+			rescheduled := *prog
+			rescheduled.Ft, rescheduled.To = moved.Ft, moved.To
+			up.Prog = &rescheduled
+		default:
+			delete(u.Programs, id)
+			if moved.Ft != prog.Ft || moved.To != prog.To {
+				changes = append(changes, &GuideChange{Prog: prog, Moved: moved})
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, u.save()
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Prog.Ft < changes[j].Prog.Ft
+	})
+	return changes, u.save()
+}
+
+// counterpart returns the program in the guide with the ID, or the one with the same title
+// closest to the start but not another upcoming episode, or nil if none
+func (u *Upcoming) counterpart(guide Progs, prog *Prog) *Prog {
+	var closest *Prog
+	var closestDiff time.Duration
+	ft, _ := time.ParseInLocation(DatetimeLayout, prog.Ft, Location)
+	key := DedupKey(prog.Title, "")
+	for _, p := range guide {
+		if p.ID == prog.ID {
+			return p
+		}
+		if _, ok := u.Programs[p.ID]; ok || DedupKey(p.Title, "") != key {
+			continue
+		}
+		pft, err := time.ParseInLocation(DatetimeLayout, p.Ft, Location)
+		if err != nil {
+			continue
+		}
+		d := pft.Sub(ft)
+		if d < 0 {
+			d = -d
+		}
+		if closest == nil || d < closestDiff {
+			closest, closestDiff = p, d
+		}
+	}
+	return closest
+}
+
+// Add remembers the program, and returns false if already added
+func (u *Upcoming) Add(prog *Prog, availableAt time.Time) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.Programs[prog.ID]; ok {
+		return false, nil
+	}
+	u.Programs[prog.ID] = &UpcomingProg{Prog: prog, AvailableAt: availableAt}
+	return true, u.save()
+}
+
+// Due removes and returns the programs available at t in the order of the availability
+func (u *Upcoming) Due(t time.Time) ([]*Prog, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	due := []*UpcomingProg{}
+	for id, up := range u.Programs {
+		if !up.AvailableAt.After(t) {
+			due = append(due, up)
+			delete(u.Programs, id)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].AvailableAt.Before(due[j].AvailableAt)
+	})
+	progs := make([]*Prog, len(due))
+	for i, up := range due {
+		progs[i] = up.Prog
+	}
+	return progs, u.save()
+}
+
+// Next returns the earliest availability, or nil if none
+func (u *Upcoming) Next() *time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var next *time.Time
+	for _, up := range u.Programs {
+		if next == nil || up.AvailableAt.Before(*next) {
+			t := up.AvailableAt
+			next = &t
+		}
+	}
+	return next
+}
+
+func (u *Upcoming) save() error {
+	if u.path == "" {
+		return nil
+	}
+	blob, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileSync(u.path, blob)
+}
+
+// LoadUpcoming loads the upcoming programs from the path, or returns an empty one if not exists
+func LoadUpcoming(path string) (*Upcoming, error) {
+	u := &Upcoming{
+		Programs: map[string]*UpcomingProg{},
+		path:     path,
+	}
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return u, nil
+	} else if err != nil {
+		return u, err
+	}
+	if err = json.Unmarshal(blob, u); err != nil {
+		return u, err
+	}
+	if u.Programs == nil {
+		u.Programs = map[string]*UpcomingProg{}
+	}
+	return u, nil
+}
+
+// NewUpcoming loads the upcoming programs in ${RADICRON_HOME}
+func NewUpcoming() (*Upcoming, error) {
+	path, err := getRadicronPath(UpcomingFileName)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return LoadUpcoming(path)
+}
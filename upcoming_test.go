@@ -0,0 +1,134 @@
+package radicron
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpcoming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), UpcomingFileName)
+	u, err := LoadUpcoming(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Next() != nil {
+		t.Errorf("Next without the programs => %v, want nil", u.Next())
+	}
+	now := time.Date(2023, 6, 25, 5, 0, 0, 0, time.UTC)
+	var addtests = []struct {
+		prog        *Prog
+		availableAt time.Time
+		want        bool
+	}{
+		{&Prog{ID: "later", StationID: "TBS", Title: "Later"}, now.Add(2 * time.Hour), true},
+		{&Prog{ID: "sooner", StationID: "FMT", Title: "Sooner"}, now.Add(time.Hour), true},
+		{&Prog{ID: "sooner", StationID: "FMT", Title: "Sooner"}, now.Add(time.Hour), false},
+		{&Prog{ID: "next-week", StationID: "FMT", Title: "Next week"}, now.AddDate(0, 0, 7), true},
+	}
+	for _, tt := range addtests {
+		got, err := u.Add(tt.prog, tt.availableAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Add(%s) => %v, want %v", tt.prog.ID, got, tt.want)
+		}
+	}
+	if next := u.Next(); next == nil || !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("Next => %v, want %v", next, now.Add(time.Hour))
+	}
+
+	// remembered across the restarts
+	if u, err = LoadUpcoming(path); err != nil {
+		t.Fatal(err)
+	}
+	var duetests = []struct {
+		t    time.Time
+		want []string
+	}{
+		{now, []string{}},
+		{now.Add(3 * time.Hour), []string{"sooner", "later"}},
+		{now.Add(3 * time.Hour), []string{}},
+	}
+	for _, tt := range duetests {
+		progs, err := u.Due(tt.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(progs) != len(tt.want) {
+			t.Errorf("Due(%v) => %v programs, want %v", tt.t, len(progs), tt.want)
+			continue
+		}
+		for i, p := range progs {
+			if p.ID != tt.want[i] {
+				t.Errorf("Due(%v)[%d] => %v, want %v", tt.t, i, p.ID, tt.want[i])
+			}
+		}
+	}
+	if u, err = LoadUpcoming(path); err != nil || len(u.Programs) != 1 {
+		t.Errorf("LoadUpcoming after Due => %v, %v, want next-week", u.Programs, err)
+	}
+}
+
+func TestUpcomingDiff(t *testing.T) {
+	u, err := LoadUpcoming(filepath.Join(t.TempDir(), UpcomingFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 6, 5, 12, 0, 0, 0, Location)
+	for _, p := range []*Prog{
+		{ID: "trad-mon", StationID: "FMT", Title: "THE TRAD", Ft: "20230605150000", To: "20230605165500"},
+		{ID: "trad-tue", StationID: "FMT", Title: "THE TRAD", Ft: "20230606150000", To: "20230606165500"},
+		{ID: "special", StationID: "FMT", Title: "特番", Ft: "20230607200000", To: "20230607210000"},
+		{ID: "jazz", StationID: "FMT", Title: "JAZZ", Ft: "20230608230000", To: "20230609000000"},
+		{ID: "aired", StationID: "FMT", Title: "Aired", Ft: "20230605090000", To: "20230605100000"},
+		{ID: "junk", StationID: "TBS", Title: "JUNK", Ft: "20230606010000", To: "20230606030000"},
+	} {
+		to, _ := time.ParseInLocation(DatetimeLayout, p.To, Location)
+		if _, err = u.Add(p, to.Add(5*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	guide := Progs{
+		{ID: "morning", StationID: "FMT", Title: "Morning", Ft: "20230605050000", To: "20230605090000"},
+		// the same ID later
+		{ID: "trad-mon", StationID: "FMT", Title: "THE TRAD", Ft: "20230605160000", To: "20230605175500"},
+		{ID: "trad-tue", StationID: "FMT", Title: "THE TRAD", Ft: "20230606150000", To: "20230606165500"},
+		// another ID the next day
+		{ID: "jazz-new", StationID: "FMT", Title: "JAZZ", Ft: "20230609230000", To: "20230610000000"},
+		{ID: "night", StationID: "FMT", Title: "Night", Ft: "20230610000000", To: "20230612050000"},
+	}
+
+	changes, err := u.Diff("FMT", guide, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, c := range changes {
+		if c.Moved == nil {
+			got = append(got, c.Prog.ID+" dropped")
+		} else {
+			got = append(got, c.Prog.ID+" moved to "+c.Moved.ID+" "+c.Moved.Ft)
+		}
+	}
+	want := []string{"trad-mon moved to trad-mon 20230605160000", "special dropped", "jazz moved to jazz-new 20230609230000"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Diff => %v, want %v", got, want)
+	}
+	if up := u.Programs["trad-mon"]; up.Prog.Ft != "20230605160000" || up.Prog.Title != "THE TRAD" {
+		t.Errorf("rescheduled => %+v", up.Prog)
+	}
+	if _, ok := u.Programs["jazz"]; ok {
+		t.Error("jazz is not left to the rules")
+	}
+	if up, ok := u.Programs["special"]; !ok || !up.Dropped {
+		t.Error("special is not kept as dropped")
+	}
+
+	// reported once
+	if changes, err = u.Diff("FMT", guide, now); err != nil || len(changes) != 0 {
+		t.Errorf("Diff again => %v, %v, want none", changes, err)
+	}
+}
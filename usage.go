@@ -0,0 +1,125 @@
+package radicron
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usage counts the bytes downloaded per day until saved in the history
+var usage = &usageCounter{days: map[string]int64{}}
+
+// usageCounter accumulates the bytes not to save the history on every read
+type usageCounter struct {
+	mu   sync.Mutex
+	days map[string]int64
+}
+
+// add counts n bytes downloaded at t
+func (u *usageCounter) add(n int, t time.Time) {
+	if Location != nil {
+		t = t.In(Location)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.days[t.Format(UsageDayLayout)] += int64(n)
+}
+
+// take returns the bytes per day counted so far and resets them
+func (u *usageCounter) take() map[string]int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	days := u.days
+	u.days = map[string]int64{}
+	return days
+}
+
+// pending returns the bytes not yet saved
+func (u *usageCounter) pending() int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var total int64
+	for _, n := range u.days {
+		total += n
+	}
+	return total
+}
+
+// saveUsage moves the bytes counted so far into the history
+func saveUsage(h *History) {
+	days := usage.take()
+	if len(days) == 0 {
+		return
+	}
+	if err := h.AddUsage(days); err != nil {
+		log.Printf("failed to save the usage: %s", err)
+	}
+}
+
+// AddUsage adds the bytes downloaded per day
+func (h *History) AddUsage(days map[string]int64) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// add up to the usage saved by the other instances
+	return h.update(func() {
+		if h.Usage == nil {
+			h.Usage = map[string]int64{}
+		}
+		for day, n := range days {
+			h.Usage[day] += n
+		}
+	})
+}
+
+// UsageStats is the bytes downloaded per day and month
+type UsageStats struct {
+	Daily   map[string]int64 `json:"daily"`
+	Monthly map[string]int64 `json:"monthly"`
+	// Pending bytes downloaded but not yet saved in the history
+	Pending int64 `json:"pending"`
+}
+
+// Months returns the months in the stats in the order of time
+func (us *UsageStats) Months() []string {
+	months := make([]string, 0, len(us.Monthly))
+	for m := range us.Monthly {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	return months
+}
+
+// Days returns the days of the month in the stats in the order of time
+func (us *UsageStats) Days(month string) []string {
+	days := []string{}
+	for d := range us.Daily {
+		if strings.HasPrefix(d, month+"-") {
+			days = append(days, d)
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+// UsageStats returns the bytes downloaded per day and month
+func (h *History) UsageStats() *UsageStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	us := &UsageStats{
+		Daily:   map[string]int64{},
+		Monthly: map[string]int64{},
+		Pending: usage.pending(),
+	}
+	for day, n := range h.Usage {
+		us.Daily[day] = n
+		if len(day) >= len(UsageMonthLayout) {
+			us.Monthly[day[:len(UsageMonthLayout)]] += n
+		}
+	}
+	return us
+}
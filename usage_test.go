@@ -0,0 +1,59 @@
+package radicron
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	usage.take()
+	path := filepath.Join(t.TempDir(), HistoryFileName)
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the segments read through the throttle
+	if _, err = io.Copy(io.Discard, throttle(context.Background(), bytes.NewReader(make([]byte, 1000)))); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now()
+	if Location != nil {
+		today = today.In(Location)
+	}
+	if got := h.UsageStats().Pending; got != 1000 {
+		t.Errorf("Pending => %v, want 1000", got)
+	}
+	saveUsage(h)
+
+	if err = h.AddUsage(map[string]int64{"2023-05-31": 300, "2023-06-01": 100, "2023-06-02": 200}); err != nil {
+		t.Fatal(err)
+	}
+	// saved across the runs
+	if h, err = LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	stats := h.UsageStats()
+	var usagetests = []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"pending", stats.Pending, int64(0)},
+		{"today", stats.Daily[today.Format(UsageDayLayout)], int64(1000)},
+		{"2023-06", stats.Monthly["2023-06"], int64(300)},
+		{"2023-05", stats.Monthly["2023-05"], int64(300)},
+		{"months", stats.Months(), []string{"2023-05", "2023-06", today.Format(UsageMonthLayout)}},
+		{"days", stats.Days("2023-06"), []string{"2023-06-01", "2023-06-02"}},
+	}
+	for _, tt := range usagetests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s => %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}